@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/galaxy-future/costpilot/internal/config"
+)
+
+const _configUsage = `usage: costpilot config validate [file]   check the config file merged with the environment, default conf/config.yaml
+       costpilot config schema            print the json schema of the config file`
+
+// runConfigCommand the config subcommands, returns the exit code
+func runConfigCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, _configUsage)
+		return 2
+	}
+	switch args[0] {
+	case "validate":
+		file := "conf/config.yaml"
+		if len(args) > 1 {
+			file = args[1]
+		}
+		if err := config.Validate(file); err != nil {
+			fmt.Fprintf(os.Stderr, "%s is invalid:\n%v\n", file, err)
+			return 1
+		}
+		fmt.Printf("%s is valid\n", file)
+		return 0
+	case "schema":
+		b, err := config.JSONSchema()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		os.Stdout.Write(b)
+		return 0
+	}
+	fmt.Fprintln(os.Stderr, _configUsage)
+	return 2
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/galaxy-future/costpilot/internal/api"
+	"github.com/galaxy-future/costpilot/internal/metrics"
+	"github.com/galaxy-future/costpilot/internal/scheduler"
+	"github.com/galaxy-future/costpilot/internal/types"
+)
+
+// _runDaemon serve while the analysis reruns by the schedule, each successful run replaces the data served
+func _runDaemon(ctx context.Context, cfg types.Daemon) error {
+	schedule, err := scheduler.ParseCron(cfg.GetSchedule())
+	if err != nil {
+		log.Printf("E! %v\n", err)
+		return err
+	}
+	server, collector := api.NewServer(), metrics.NewCollector()
+	runner := scheduler.NewRunner(schedule, func(ctx context.Context) error {
+		snapshot, err := collect(ctx, collector)
+		if err != nil {
+			return err
+		}
+		server.SetSnapshot(snapshot)
+		return nil
+	})
+	server.SetRunner(runner)
+	r, err := _newRouter(server, collector)
+	if err != nil {
+		log.Printf("E! %v\n", err)
+		return err
+	}
+	runner.Trigger(ctx, scheduler.TriggerStartup)
+	go runner.Start(ctx)
+
+	log.Printf("I! daemon listens on %s, analysis is scheduled by %q, check /website for the report and /api/v1/status for the runs", cfg.GetListen(), schedule)
+	if err := r.Run(cfg.GetListen()); err != nil {
+		log.Printf("E! %v\n", err)
+		return err
+	}
+	return nil
+}
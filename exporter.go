@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/galaxy-future/costpilot/internal/metrics"
+	"github.com/galaxy-future/costpilot/internal/scheduler"
+	"github.com/galaxy-future/costpilot/internal/types"
+)
+
+// _runExporter serve /metrics only while the analysis reruns by the schedule
+func _runExporter(ctx context.Context, cfg types.Exporter) error {
+	schedule, err := scheduler.ParseCron(cfg.GetSchedule())
+	if err != nil {
+		log.Printf("E! %v\n", err)
+		return err
+	}
+	collector := metrics.NewCollector()
+	runner := scheduler.NewRunner(schedule, func(ctx context.Context) error {
+		_, err := collect(ctx, collector)
+		return err
+	})
+	r, err := _newExporterRouter(collector)
+	if err != nil {
+		log.Printf("E! %v\n", err)
+		return err
+	}
+	runner.Trigger(ctx, scheduler.TriggerStartup)
+	go runner.Start(ctx)
+
+	log.Printf("I! exporter listens on %s, analysis is scheduled by %q, scrape /metrics", cfg.GetListen(), schedule)
+	if err := r.Run(cfg.GetListen()); err != nil {
+		log.Printf("E! %v\n", err)
+		return err
+	}
+	return nil
+}
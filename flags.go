@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"strings"
+
+	"github.com/galaxy-future/costpilot/internal/config"
+	"github.com/galaxy-future/costpilot/internal/export"
+	"github.com/galaxy-future/costpilot/internal/report"
+)
+
+var (
+	_exportFormats = flag.String("export", "", "comma separated export formats: json,csv,xlsx,parquet, overrides export.formats of the config")
+	_exportDir     = flag.String("export-dir", "", "directory of the exported files, overrides export.dir of the config")
+	_format        = flag.String("format", "", "print the report to the terminal as text or markdown instead of opening the browser")
+	_exportHTML    = flag.String("html", "", "write the report as a single html file to the path instead of opening the browser, overrides export.html of the config")
+)
+
+// applyFlags the command line overrides the config
+func applyFlags(cfg *config.Config) error {
+	if *_format != "" {
+		if err := report.CheckFormat(*_format); err != nil {
+			log.Printf("E! %v\n", err)
+			return err
+		}
+	}
+	if *_exportFormats != "" {
+		cfg.Export.Formats = nil
+		for _, f := range strings.Split(*_exportFormats, ",") {
+			f = strings.ToLower(strings.TrimSpace(f))
+			if f == "" {
+				continue
+			}
+			if _, err := export.New(f); err != nil {
+				log.Printf("E! %v\n", err)
+				return err
+			}
+			cfg.Export.Formats = append(cfg.Export.Formats, f)
+		}
+	}
+	if *_exportDir != "" {
+		cfg.Export.Dir = *_exportDir
+	}
+	if *_exportHTML != "" {
+		cfg.Export.HTML = *_exportHTML
+	}
+	return nil
+}
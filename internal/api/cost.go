@@ -0,0 +1,252 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/tools"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	_defaultDays   = 30
+	_defaultMonths = 12
+)
+
+type CostItem struct {
+	Period    string  `json:"period"` // 2022-10-01 | 2022-10
+	Account   string  `json:"account"`
+	Provider  string  `json:"provider"`
+	Currency  string  `json:"currency"` // empty if the account has no bill with a currency
+	Amount    float64 `json:"amount"`
+	Amortized bool    `json:"amortized"`
+}
+
+// Totals key : currency, the accounts may bill in different currencies
+type Totals map[string]float64
+
+func (t Totals) add(currency string, amount float64) {
+	t[currency] = tools.Float64Add(t[currency], amount)
+}
+
+type CostResponse struct {
+	Start  string     `json:"start"`
+	End    string     `json:"end"`
+	Totals Totals     `json:"totals"`
+	Items  []CostItem `json:"items"`
+	// Unattributed bills without product detail, left out when filtered by product
+	Unattributed []CostItem `json:"unattributed,omitempty"`
+}
+
+type ProductCostItem struct {
+	Account     string  `json:"account"`
+	Provider    string  `json:"provider"`
+	PipCode     string  `json:"pip_code"`
+	ProductName string  `json:"product_name"`
+	Currency    string  `json:"currency"`
+	Amount      float64 `json:"amount"`
+}
+
+type ProductCostResponse struct {
+	Start  string            `json:"start"`
+	End    string            `json:"end"`
+	Totals Totals            `json:"totals"`
+	Items  []ProductCostItem `json:"items"` // sorted by amount desc
+	// Unattributed bills without product detail
+	Unattributed []CostItem `json:"unattributed,omitempty"`
+}
+
+// getDailyCost default range is the recent 30 days, today is not included
+func (s *Server) getDailyCost(c *gin.Context) {
+	snapshot, ok := s.loadSnapshot(c)
+	if !ok {
+		return
+	}
+	yesterday := snapshot.GeneratedAt.AddDate(0, 0, -1)
+	q, err := parseQuery(c, _dayLayout, yesterday.AddDate(0, 0, 1-_defaultDays).Format(_dayLayout), yesterday.Format(_dayLayout))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, s.costResponse(snapshot, q, loadDailyBilling))
+}
+
+// getMonthlyCost default range is the recent 12 months, the current month is summed up by its days
+func (s *Server) getMonthlyCost(c *gin.Context) {
+	snapshot, ok := s.loadSnapshot(c)
+	if !ok {
+		return
+	}
+	recentMonth := recentMonth(snapshot.GeneratedAt)
+	q, err := parseQuery(c, _monthLayout, recentMonth.AddDate(0, 1-_defaultMonths, 0).Format(_monthLayout), recentMonth.Format(_monthLayout))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, s.costResponse(snapshot, q, loadMonthlyBilling(recentMonth.Format(_monthLayout))))
+}
+
+// getProductsCost cost by product summed over the range, which is days or months by the format of start,
+// default range is the recent month
+func (s *Server) getProductsCost(c *gin.Context) {
+	snapshot, ok := s.loadSnapshot(c)
+	if !ok {
+		return
+	}
+	recentMonth := recentMonth(snapshot.GeneratedAt)
+	layout, load := _dayLayout, loadDailyBilling
+	if len(c.Query("start")) == len(_monthLayout) {
+		layout, load = _monthLayout, loadMonthlyBilling(recentMonth.Format(_monthLayout))
+	}
+	defaultEnd := snapshot.GeneratedAt.AddDate(0, 0, -1).Format(_dayLayout)
+	if layout == _monthLayout {
+		defaultEnd = recentMonth.Format(_monthLayout)
+	}
+	q, err := parseQuery(c, layout, recentMonth.Format(layout), defaultEnd)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	resp := ProductCostResponse{Start: q.Start, End: q.End, Totals: Totals{}, Items: []ProductCostItem{}}
+	for _, a := range snapshot.AccountBillings {
+		if !q.matchAccount(a.AccountName, a.Provider.String()) {
+			continue
+		}
+		currency := tools.AccountCurrency(a)
+		products := make(map[string]*ProductCostItem) // key : pip code
+		var pipCodes []string
+		for _, period := range q.periods() {
+			total, productsBilling, _, ok := load(a, period)
+			if !ok {
+				continue
+			}
+			if !data.IsGroupedByProduct(productsBilling) {
+				resp.Unattributed = append(resp.Unattributed, CostItem{Period: period, Account: a.AccountName, Provider: a.Provider.String(),
+					Currency: currency, Amount: total})
+				continue
+			}
+			if p, ok := productsBilling[""]; ok { // days without product detail in a month
+				resp.Unattributed = append(resp.Unattributed, CostItem{Period: period, Account: a.AccountName, Provider: a.Provider.String(),
+					Currency: currency, Amount: p.TotalAmount})
+			}
+			for pipCode, p := range productsBilling {
+				if pipCode == "" || !q.matchProduct(pipCode, p.ProductName) {
+					continue
+				}
+				item, ok := products[pipCode]
+				if !ok {
+					item = &ProductCostItem{Account: a.AccountName, Provider: a.Provider.String(), PipCode: pipCode, ProductName: p.ProductName}
+					products[pipCode] = item
+					pipCodes = append(pipCodes, pipCode)
+				}
+				if item.Currency == "" && len(p.Items) > 0 {
+					item.Currency = p.Items[0].Currency
+				}
+				if item.Currency == "" {
+					item.Currency = currency
+				}
+				item.Amount = tools.Float64Add(item.Amount, p.TotalAmount)
+			}
+		}
+		for _, pipCode := range pipCodes {
+			resp.Items = append(resp.Items, *products[pipCode])
+			resp.Totals.add(products[pipCode].Currency, products[pipCode].Amount)
+		}
+	}
+	sort.SliceStable(resp.Items, func(i, j int) bool {
+		return resp.Items[i].Amount > resp.Items[j].Amount
+	})
+	c.JSON(http.StatusOK, resp)
+}
+
+// billingLoader total amount and product detail of an account in the period, ok is false if there is no bill
+type billingLoader func(a data.AccountBillingMap, period string) (total float64, productsBilling map[string]data.ProductBilling, amortized bool, ok bool)
+
+func loadDailyBilling(a data.AccountBillingMap, day string) (float64, map[string]data.ProductBilling, bool, bool) {
+	if a.DaysBilling == nil {
+		return 0, nil, false, false
+	}
+	val, ok := a.DaysBilling.Load(day)
+	if !ok {
+		return 0, nil, false, false
+	}
+	d := val.(data.DailyBilling)
+	return d.TotalAmount, d.ProductsBilling, d.Amortized, true
+}
+
+// loadMonthlyBilling the recent month is not billed monthly yet, its days are summed up
+func loadMonthlyBilling(recentMonth string) billingLoader {
+	return func(a data.AccountBillingMap, month string) (float64, map[string]data.ProductBilling, bool, bool) {
+		if a.MonthsBilling != nil {
+			if val, ok := a.MonthsBilling.Load(month); ok {
+				m := val.(data.MonthlyBilling)
+				return m.TotalAmount, m.ProductsBilling, m.Amortized, true
+			}
+		}
+		if month != recentMonth || a.DaysBilling == nil {
+			return 0, nil, false, false
+		}
+		var sum data.DailyBilling
+		found := false
+		a.DaysBilling.Range(func(key, value interface{}) bool {
+			if strings.HasPrefix(key.(string), month) {
+				if !found {
+					sum.Amortized = value.(data.DailyBilling).Amortized
+				}
+				sum = tools.AddDailyBilling(sum, value.(data.DailyBilling))
+				found = true
+			}
+			return true
+		})
+		return sum.TotalAmount, sum.ProductsBilling, sum.Amortized, found
+	}
+}
+
+func (s *Server) costResponse(snapshot *Snapshot, q *query, load billingLoader) CostResponse {
+	resp := CostResponse{Start: q.Start, End: q.End, Totals: Totals{}, Items: []CostItem{}}
+	for _, a := range snapshot.AccountBillings {
+		if !q.matchAccount(a.AccountName, a.Provider.String()) {
+			continue
+		}
+		currency := tools.AccountCurrency(a)
+		for _, period := range q.periods() {
+			total, productsBilling, amortized, ok := load(a, period)
+			if !ok {
+				continue
+			}
+			item := CostItem{Period: period, Account: a.AccountName, Provider: a.Provider.String(), Currency: currency, Amount: total, Amortized: amortized}
+			if c := tools.ExtractCurrency(productsBilling); c != "" {
+				item.Currency = c
+			}
+			if q.filterProducts() {
+				if !data.IsGroupedByProduct(productsBilling) {
+					resp.Unattributed = append(resp.Unattributed, item)
+					continue
+				}
+				if p, ok := productsBilling[""]; ok {
+					resp.Unattributed = append(resp.Unattributed, CostItem{Period: period, Account: a.AccountName, Provider: a.Provider.String(),
+						Currency: item.Currency, Amount: p.TotalAmount})
+				}
+				item.Amount = 0
+				for pipCode, p := range productsBilling {
+					if pipCode != "" && q.matchProduct(pipCode, p.ProductName) {
+						item.Amount = tools.Float64Add(item.Amount, p.TotalAmount)
+					}
+				}
+			}
+			resp.Items = append(resp.Items, item)
+			resp.Totals.add(item.Currency, item.Amount)
+		}
+	}
+	return resp
+}
+
+// recentMonth month of yesterday, the bills of today are not ready
+func recentMonth(t time.Time) time.Time {
+	y := t.AddDate(0, 0, -1)
+	return time.Date(y.Year(), y.Month(), 1, 0, 0, 0, 0, time.Local)
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/auth"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	_dayLayout   = "2006-01-02"
+	_monthLayout = "2006-01"
+
+	_maxPeriods = 1000 // guard against unbounded ranges
+)
+
+// query common filters, every filter accepts repeated or comma separated values
+type query struct {
+	Start, End string // inclusive, formatted by layout
+	layout     string
+
+	accounts  map[string]bool
+	providers map[string]bool
+	products  map[string]bool // pip code or product name
+
+	principal *auth.Principal // nil if the server is not behind the auth middleware
+}
+
+// parseQuery start and end default to the given ones, layout is empty if the endpoint has no range
+func parseQuery(c *gin.Context, layout, defaultStart, defaultEnd string) (*query, error) {
+	q := &query{
+		Start:     c.DefaultQuery("start", defaultStart),
+		End:       c.DefaultQuery("end", defaultEnd),
+		layout:    layout,
+		accounts:  queryValues(c, "account"),
+		providers: queryValues(c, "provider"),
+		products:  queryValues(c, "product"),
+		principal: auth.FromContext(c),
+	}
+	if layout == "" {
+		return q, nil
+	}
+	start, err := time.ParseInLocation(layout, q.Start, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start %q, expect format %s", q.Start, layout)
+	}
+	end, err := time.ParseInLocation(layout, q.End, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end %q, expect format %s", q.End, layout)
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("end %s is before start %s", q.End, q.Start)
+	}
+	if len(q.periods()) > _maxPeriods {
+		return nil, fmt.Errorf("range is too large, at most %d periods", _maxPeriods)
+	}
+	return q, nil
+}
+
+func queryValues(c *gin.Context, key string) map[string]bool {
+	var values map[string]bool
+	for _, v := range c.QueryArray(key) {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s == "" {
+				continue
+			}
+			if values == nil {
+				values = make(map[string]bool)
+			}
+			values[s] = true
+		}
+	}
+	return values
+}
+
+// periods days or months in [Start, End]
+func (q *query) periods() []string {
+	start, _ := time.ParseInLocation(q.layout, q.Start, time.Local)
+	end, _ := time.ParseInLocation(q.layout, q.End, time.Local)
+	var ret []string
+	for t := start; !t.After(end) && len(ret) <= _maxPeriods; t = q.next(t) {
+		ret = append(ret, t.Format(q.layout))
+	}
+	return ret
+}
+
+func (q *query) next(t time.Time) time.Time {
+	if q.layout == _monthLayout {
+		return t.AddDate(0, 1, 0)
+	}
+	return t.AddDate(0, 0, 1)
+}
+
+// matchAccount accounts which are not granted to the principal never match
+func (q *query) matchAccount(name, provider string) bool {
+	if q.principal != nil && !q.principal.CanAccessAccount(name) {
+		return false
+	}
+	return (q.accounts == nil || q.accounts[name]) && (q.providers == nil || q.providers[provider])
+}
+
+func (q *query) matchProduct(pipCode, productName string) bool {
+	return q.products == nil || q.products[pipCode] || q.products[productName]
+}
+
+func (q *query) filterProducts() bool {
+	return q.products != nil
+}
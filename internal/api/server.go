@@ -0,0 +1,178 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/auth"
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/scheduler"
+	"github.com/galaxy-future/costpilot/internal/template"
+	"github.com/galaxy-future/costpilot/internal/types"
+	"github.com/gin-gonic/gin"
+)
+
+// Snapshot analysis data served by the api, replaced as a whole after each analysis
+type Snapshot struct {
+	GeneratedAt         time.Time
+	Accounts            []types.CloudAccount
+	AccountBillings     []data.AccountBillingMap
+	AccountUtilizations []data.AccountUtilizationMap
+	Unsupported         []data.Unsupported      // the data left out as the providers lack the capabilities
+	DiscoveryFailures   []data.DiscoveryFailure // the accounts whose members are left out of the run
+
+	// the data of each account the website data is rebuilt from for the viewers granted some accounts
+	AlternativeBillings []data.AccountBillingMap // in the other cost view, empty if the amortization is disabled
+	AccountCommitments  []data.AccountCommitments
+	BudgetStatuses      []data.BudgetStatus
+	Anomalies           []data.CostAnomaly
+	IdleInstances       []data.IdleInstance
+	Opportunities       []data.OptimizationOpportunity
+
+	// the data of the website and the report
+	CostAnalysis    template.AnalysisData
+	UtilizeAnalysis template.UtilizeAnalysis
+	BudgetAnalysis  template.BudgetAnalysis
+	AnomalyAnalysis template.AnomalyAnalysis
+	IdleAnalysis    template.IdleInstanceAnalysis
+	Optimization    template.OptimizationAnalysis
+	Commitment      template.CommitmentAnalysis
+}
+
+// Runner reruns the analysis in daemon mode
+type Runner interface {
+	Trigger(ctx context.Context, trigger string) bool
+	Status() scheduler.Status
+}
+
+// Server json endpoints over the latest snapshot
+type Server struct {
+	snapshot atomic.Value // *Snapshot
+	runner   Runner
+	dataSets sync.Map // key : the accounts granted, val : dataSet
+}
+
+func NewServer() *Server {
+	return &Server{}
+}
+
+// SetSnapshot replace the data served, safe to call while serving
+func (s *Server) SetSnapshot(snapshot *Snapshot) *Server {
+	s.snapshot.Store(snapshot)
+	return s
+}
+
+// SetRunner enable the status and refresh endpoints
+func (s *Server) SetRunner(runner Runner) *Server {
+	s.runner = runner
+	return s
+}
+
+// GetSnapshot nil before the first analysis is done
+func (s *Server) GetSnapshot() *Snapshot {
+	snapshot, _ := s.snapshot.Load().(*Snapshot)
+	return snapshot
+}
+
+// Register mount the endpoints under /api/v1
+func (s *Server) Register(r gin.IRouter) {
+	v1 := r.Group("/api/v1")
+	v1.GET("/accounts", s.listAccounts)
+	v1.GET("/cost/daily", s.getDailyCost)
+	v1.GET("/cost/monthly", s.getMonthlyCost)
+	v1.GET("/cost/products", s.getProductsCost)
+	v1.GET("/utilization", s.getUtilization)
+	v1.GET("/status", s.getStatus)
+	v1.POST("/refresh", auth.RequireAdmin(), s.refresh)
+}
+
+// loadSnapshot responds 503 if no analysis is done yet
+func (s *Server) loadSnapshot(c *gin.Context) (*Snapshot, bool) {
+	snapshot := s.GetSnapshot()
+	if snapshot == nil {
+		abortWithError(c, http.StatusServiceUnavailable, "analysis data is not ready")
+		return nil, false
+	}
+	return snapshot, true
+}
+
+func abortWithError(c *gin.Context, code int, message string) {
+	c.AbortWithStatusJSON(code, gin.H{"error": message})
+}
+
+// getStatus status of the current or last run, and when the snapshot served was generated
+func (s *Server) getStatus(c *gin.Context) {
+	if s.runner == nil {
+		abortWithError(c, http.StatusNotFound, "daemon mode is disabled")
+		return
+	}
+	resp := gin.H{"run": s.runner.Status()}
+	if snapshot := s.GetSnapshot(); snapshot != nil {
+		resp["generated_at"] = snapshot.GeneratedAt
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// refresh rerun the analysis in background, 409 if a run is in progress, admin only
+func (s *Server) refresh(c *gin.Context) {
+	if s.runner == nil {
+		abortWithError(c, http.StatusNotFound, "daemon mode is disabled")
+		return
+	}
+	// the run outlives the request
+	if !s.runner.Trigger(context.Background(), scheduler.TriggerManual) {
+		abortWithError(c, http.StatusConflict, scheduler.ErrRunning.Error())
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"run": s.runner.Status()})
+}
+
+type Account struct {
+	Name           string             `json:"name"`
+	Provider       string             `json:"provider"`
+	RegionID       string             `json:"region_id"`
+	RegionScan     *data.RegionScan   `json:"region_scan,omitempty"`     // the regions scanned for instances in the run
+	Unsupported    []data.Unsupported `json:"unsupported,omitempty"`     // the data left out of the run
+	DiscoveryError string             `json:"discovery_error,omitempty"` // the member accounts are not discovered in the run
+}
+
+// listAccounts credentials are never exposed
+func (s *Server) listAccounts(c *gin.Context) {
+	snapshot, ok := s.loadSnapshot(c)
+	if !ok {
+		return
+	}
+	q, err := parseQuery(c, "", "", "")
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	accounts := make([]Account, 0, len(snapshot.Accounts))
+	for _, a := range snapshot.Accounts {
+		if !q.matchAccount(a.Name, a.Provider.String()) {
+			continue
+		}
+		account := Account{Name: a.Name, Provider: a.Provider.String(), RegionID: a.RegionID}
+		for _, u := range snapshot.AccountUtilizations {
+			if u.AccountName == a.Name && !u.RegionScan.IsEmpty() {
+				scan := u.RegionScan
+				account.RegionScan = &scan
+			}
+		}
+		for _, u := range snapshot.Unsupported {
+			if u.AccountName == a.Name {
+				account.Unsupported = append(account.Unsupported, u)
+			}
+		}
+		for _, f := range snapshot.DiscoveryFailures {
+			if f.AccountName == a.Name {
+				account.DiscoveryError = f.Reason
+			}
+		}
+		accounts = append(accounts, account)
+	}
+	c.JSON(http.StatusOK, gin.H{"generated_at": snapshot.GeneratedAt, "accounts": accounts})
+}
@@ -0,0 +1,355 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/auth"
+	"github.com/galaxy-future/costpilot/internal/config"
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/scheduler"
+	"github.com/galaxy-future/costpilot/internal/types"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSnapshot() *Snapshot {
+	var months, days, cpu, memory sync.Map
+	months.Store("2022-09", data.MonthlyBilling{Month: "2022-09", TotalAmount: 300})
+	days.Store("2022-10-01", data.DailyBilling{Day: "2022-10-01", TotalAmount: 10, ProductsBilling: map[string]data.ProductBilling{
+		"ecs": {ProductName: "云服务器 ECS", TotalAmount: 7, Items: []data.ItemInProductBilling{{PipCode: "ecs", PretaxAmount: 7, Currency: "CNY"}}},
+		"oss": {ProductName: "对象存储", TotalAmount: 3, Items: []data.ItemInProductBilling{{PipCode: "oss", PretaxAmount: 3, Currency: "CNY"}}},
+	}})
+	days.Store("2022-10-02", data.DailyBilling{Day: "2022-10-02", TotalAmount: 20, ProductsBilling: map[string]data.ProductBilling{
+		"": {TotalAmount: 20},
+	}})
+	cpu.Store("2022-10-01", data.DailyCpuUtilization{Day: "2022-10-01", Utilization: []data.InstanceCpuUtilization{
+		{InstanceId: "i-1", UsedUtilization: 10, MaxUtilization: 50},
+		{InstanceId: "i-2", UsedUtilization: 30},
+	}})
+	memory.Store("2022-10-01", data.DailyMemoryUtilization{Day: "2022-10-01", Utilization: []data.InstanceMemoryUtilization{
+		{InstanceId: "i-1", UsedUtilization: 40, MaxUtilization: 60},
+	}})
+	return &Snapshot{
+		GeneratedAt: time.Date(2022, 10, 3, 10, 0, 0, 0, time.Local),
+		Accounts: []types.CloudAccount{
+			{Provider: cloud.AlibabaCloud, Name: "ali", AK: "ak", SK: "sk", RegionID: "cn-hangzhou"},
+			{Provider: cloud.AWSCloud, Name: "aws", AK: "ak", SK: "sk", RegionID: "us-east-1"},
+		},
+		AccountBillings:     []data.AccountBillingMap{{AccountName: "ali", Provider: cloud.AlibabaCloud, MonthsBilling: &months, DaysBilling: &days}},
+		AccountUtilizations: []data.AccountUtilizationMap{{AccountName: "ali", Provider: cloud.AlibabaCloud, DailyCpu: &cpu, DailyMemory: &memory}},
+	}
+}
+
+func doRequest(t *testing.T, s *Server, url string, resp interface{}) int {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	s.Register(r)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+	if resp != nil && w.Code == http.StatusOK {
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), resp))
+	}
+	return w.Code
+}
+
+func TestServer_NotReady(t *testing.T) {
+	assert.Equal(t, http.StatusServiceUnavailable, doRequest(t, NewServer(), "/api/v1/cost/daily", nil))
+}
+
+func TestServer_Accounts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	NewServer().SetSnapshot(newTestSnapshot()).Register(r)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/accounts?provider=AWSCloud", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, strings.Contains(w.Body.String(), `"sk"`))
+	var resp struct {
+		Accounts []Account `json:"accounts"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []Account{{Name: "aws", Provider: "AWSCloud", RegionID: "us-east-1"}}, resp.Accounts)
+}
+
+func TestServer_DailyCost(t *testing.T) {
+	s := NewServer().SetSnapshot(newTestSnapshot())
+
+	var resp CostResponse
+	assert.Equal(t, http.StatusOK, doRequest(t, s, "/api/v1/cost/daily", &resp))
+	assert.Equal(t, "2022-09-03", resp.Start) // recent 30 days
+	assert.Equal(t, "2022-10-02", resp.End)
+	assert.Equal(t, 2, len(resp.Items))
+	assert.Equal(t, Totals{"CNY": 30}, resp.Totals)
+	assert.Equal(t, "CNY", resp.Items[1].Currency) // of the account, the day has no product detail
+
+	resp = CostResponse{}
+	assert.Equal(t, http.StatusOK, doRequest(t, s, "/api/v1/cost/daily?start=2022-10-01&end=2022-10-02&product=ecs,对象存储", &resp))
+	if assert.Equal(t, 1, len(resp.Items)) {
+		assert.Equal(t, "2022-10-01", resp.Items[0].Period)
+		assert.InDelta(t, 10, resp.Items[0].Amount, 0.001)
+	}
+	if assert.Equal(t, 1, len(resp.Unattributed)) {
+		assert.Equal(t, "2022-10-02", resp.Unattributed[0].Period)
+	}
+
+	resp = CostResponse{}
+	assert.Equal(t, http.StatusOK, doRequest(t, s, "/api/v1/cost/daily?start=2022-10-01&end=2022-10-02&account=aws", &resp))
+	assert.Equal(t, 0, len(resp.Items))
+
+	assert.Equal(t, http.StatusBadRequest, doRequest(t, s, "/api/v1/cost/daily?start=2022-10", nil))
+	assert.Equal(t, http.StatusBadRequest, doRequest(t, s, "/api/v1/cost/daily?start=2022-10-02&end=2022-10-01", nil))
+	assert.Equal(t, http.StatusBadRequest, doRequest(t, s, "/api/v1/cost/daily?start=2000-01-01&end=2022-10-01", nil))
+}
+
+func TestServer_MonthlyCost(t *testing.T) {
+	s := NewServer().SetSnapshot(newTestSnapshot())
+	var resp CostResponse
+	assert.Equal(t, http.StatusOK, doRequest(t, s, "/api/v1/cost/monthly?start=2022-08", &resp))
+	if assert.Equal(t, 2, len(resp.Items)) {
+		assert.Equal(t, "2022-09", resp.Items[0].Period)
+		assert.Equal(t, "2022-10", resp.Items[1].Period) // summed up by days
+		assert.InDelta(t, 30, resp.Items[1].Amount, 0.001)
+	}
+	assert.Equal(t, Totals{"CNY": 330}, resp.Totals)
+}
+
+func TestServer_ProductsCost(t *testing.T) {
+	s := NewServer().SetSnapshot(newTestSnapshot())
+	var resp ProductCostResponse
+	assert.Equal(t, http.StatusOK, doRequest(t, s, "/api/v1/cost/products", &resp))
+	assert.Equal(t, "2022-10-01", resp.Start)
+	if assert.Equal(t, 2, len(resp.Items)) {
+		assert.Equal(t, ProductCostItem{Account: "ali", Provider: "AlibabaCloud", PipCode: "ecs", ProductName: "云服务器 ECS", Currency: "CNY", Amount: 7}, resp.Items[0])
+	}
+	assert.Equal(t, 1, len(resp.Unattributed))
+
+	resp = ProductCostResponse{}
+	assert.Equal(t, http.StatusOK, doRequest(t, s, "/api/v1/cost/products?start=2022-10", &resp))
+	assert.Equal(t, "2022-10", resp.End)
+	assert.Equal(t, 2, len(resp.Items)) // the days of the recent month are summed up
+	if assert.Equal(t, 1, len(resp.Unattributed)) {
+		assert.Equal(t, "2022-10", resp.Unattributed[0].Period)
+		assert.InDelta(t, 20, resp.Unattributed[0].Amount, 0.001)
+	}
+}
+
+func TestServer_Utilization(t *testing.T) {
+	s := NewServer().SetSnapshot(newTestSnapshot())
+	var resp UtilizationResponse
+	assert.Equal(t, http.StatusOK, doRequest(t, s, "/api/v1/utilization?start=2022-10-01&end=2022-10-02", &resp))
+	if assert.Equal(t, 1, len(resp.Items)) {
+		item := resp.Items[0]
+		assert.Equal(t, 2, item.Instances)
+		assert.InDelta(t, 20, item.CpuAvg, 0.001)
+		assert.InDelta(t, 50, item.CpuMax, 0.001)
+		assert.InDelta(t, 40, item.MemoryAvg, 0.001)
+		assert.InDelta(t, 60, item.MemoryMax, 0.001)
+	}
+}
+
+type fakeRunner struct {
+	running bool
+}
+
+func (r *fakeRunner) Trigger(_ context.Context, _ string) bool {
+	if r.running {
+		return false
+	}
+	r.running = true
+	return true
+}
+
+func (r *fakeRunner) Status() scheduler.Status {
+	return scheduler.Status{Running: r.running}
+}
+
+func TestServer_Refresh(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	post := func(s *Server) int {
+		r := gin.New()
+		s.Register(r)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/refresh", nil))
+		return w.Code
+	}
+	assert.Equal(t, http.StatusNotFound, post(NewServer()))
+	assert.Equal(t, http.StatusNotFound, doRequest(t, NewServer(), "/api/v1/status", nil))
+
+	s := NewServer().SetRunner(&fakeRunner{})
+	assert.Equal(t, http.StatusAccepted, post(s))
+	assert.Equal(t, http.StatusConflict, post(s))
+
+	var resp struct {
+		Run scheduler.Status `json:"run"`
+	}
+	assert.Equal(t, http.StatusOK, doRequest(t, s, "/api/v1/status", &resp))
+	assert.True(t, resp.Run.Running)
+}
+
+func TestServer_Auth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	a, err := auth.New(types.Auth{Tokens: []types.AuthToken{
+		{Name: "admin", Token: "admin-token", Grant: types.Grant{Role: types.RoleAdmin}},
+		{Name: "aws-team", Token: "viewer-token", Grant: types.Grant{Role: types.RoleViewer, Accounts: []string{"aws"}}},
+		{Name: "ali-team", Token: "ali-token", Grant: types.Grant{Role: types.RoleViewer, Accounts: []string{"ali"}}},
+	}})
+	assert.NoError(t, err)
+	r := gin.New()
+	NewServer().SetSnapshot(newTestSnapshot()).SetRunner(&fakeRunner{}).Register(r.Group("/", a.Middleware()))
+	request := func(method, url, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	var accounts struct {
+		Accounts []Account `json:"accounts"`
+	}
+	w := request(http.MethodGet, "/api/v1/accounts", "viewer-token")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &accounts))
+	assert.Equal(t, []Account{{Name: "aws", Provider: "AWSCloud", RegionID: "us-east-1"}}, accounts.Accounts)
+
+	// the account is not granted even if it is asked for
+	var cost CostResponse
+	w = request(http.MethodGet, "/api/v1/cost/daily?start=2022-10-01&end=2022-10-02&account=ali", "viewer-token")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &cost))
+	assert.Empty(t, cost.Items)
+	assert.Empty(t, cost.Totals)
+
+	// every endpoint filters by the grant
+	for _, url := range []string{"/api/v1/cost/monthly?start=2022-09", "/api/v1/cost/products?start=2022-10-01&end=2022-10-02",
+		"/api/v1/utilization?start=2022-10-01&end=2022-10-02"} {
+		var items struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		w = request(http.MethodGet, url, "viewer-token")
+		assert.Equal(t, http.StatusOK, w.Code, url)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &items))
+		assert.Empty(t, items.Items, url)
+
+		items.Items = nil
+		w = request(http.MethodGet, url, "ali-token")
+		assert.Equal(t, http.StatusOK, w.Code, url)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &items))
+		assert.NotEmpty(t, items.Items, url)
+	}
+	var utilization UtilizationResponse
+	w = request(http.MethodGet, "/api/v1/utilization?start=2022-10-01&end=2022-10-02", "ali-token")
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &utilization))
+	for _, item := range utilization.Items {
+		assert.Equal(t, "ali", item.Account)
+	}
+
+	assert.Equal(t, http.StatusForbidden, request(http.MethodPost, "/api/v1/refresh", "viewer-token").Code)
+	assert.Equal(t, http.StatusAccepted, request(http.MethodPost, "/api/v1/refresh", "admin-token").Code)
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, "/api/v1/accounts", "wrong").Code)
+}
+
+func TestServer_CostCurrencies(t *testing.T) {
+	snapshot := newTestSnapshot()
+	var days sync.Map
+	days.Store("2022-10-01", data.DailyBilling{Day: "2022-10-01", TotalAmount: 4, ProductsBilling: map[string]data.ProductBilling{
+		"AmazonEC2": {ProductName: "Amazon EC2", TotalAmount: 4, Items: []data.ItemInProductBilling{{PipCode: "AmazonEC2", PretaxAmount: 4, Currency: "USD"}}},
+	}})
+	snapshot.AccountBillings = append(snapshot.AccountBillings, data.AccountBillingMap{AccountName: "aws", Provider: cloud.AWSCloud, DaysBilling: &days})
+	s := NewServer().SetSnapshot(snapshot)
+
+	var resp CostResponse
+	assert.Equal(t, http.StatusOK, doRequest(t, s, "/api/v1/cost/daily?start=2022-10-01&end=2022-10-02", &resp))
+	assert.Equal(t, Totals{"CNY": 30, "USD": 4}, resp.Totals)
+
+	var products ProductCostResponse
+	assert.Equal(t, http.StatusOK, doRequest(t, s, "/api/v1/cost/products?start=2022-10-01&end=2022-10-02", &products))
+	assert.Equal(t, Totals{"CNY": 10, "USD": 4}, products.Totals)
+	if assert.Len(t, products.Unattributed, 1) {
+		assert.Equal(t, "CNY", products.Unattributed[0].Currency)
+	}
+}
+
+func TestServer_FilterWebsite(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(`
+cloud_accounts:
+  - provider: AlibabaCloud
+    name: ali
+    ak: ak
+    sk: sk
+    region_id: cn-hangzhou
+anomaly_detection:
+  enabled: true
+`), 0644))
+	require.NoError(t, config.InitFileConfig(file))
+
+	gin.SetMode(gin.TestMode)
+	a, err := auth.New(types.Auth{Tokens: []types.AuthToken{
+		{Name: "admin", Token: "admin-token", Grant: types.Grant{Role: types.RoleAdmin}},
+		{Name: "ali-team", Token: "ali-token", Grant: types.Grant{Role: types.RoleViewer, Accounts: []string{"ali"}}},
+		{Name: "aws-team", Token: "aws-token", Grant: types.Grant{Role: types.RoleViewer, Accounts: []string{"aws"}}},
+	}})
+	require.NoError(t, err)
+	snapshot := newTestSnapshot()
+	snapshot.Anomalies = []data.CostAnomaly{
+		{Day: "2022-10-02", AccountName: "ali", Provider: cloud.AlibabaCloud, Amount: 20, Baseline: 10},
+		{Day: "2022-10-02", AccountName: "aws", Provider: cloud.AWSCloud, Amount: 30, Baseline: 10},
+	}
+	snapshot.BudgetStatuses = []data.BudgetStatus{
+		{Budget: types.Budget{Name: "ali-budget", Scope: types.BudgetScopeAccount, Target: "ali"}},
+		{Budget: types.Budget{Name: "aws-budget", Scope: types.BudgetScopeAccount, Target: "aws"}},
+		{Budget: types.Budget{Name: "total-budget", Scope: types.BudgetScopeAll}},
+	}
+	s := NewServer().SetSnapshot(snapshot)
+	r := gin.New()
+	r.Group("/", a.Middleware(), s.FilterWebsite(func() []string { return []string{"ali", "aws"} })).
+		GET("/website/*filepath", func(c *gin.Context) { c.String(http.StatusOK, "all accounts") })
+	request := func(url, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, "all accounts", request("/website/static/analysis/data-set.js", "admin-token").Body.String())
+	assert.Equal(t, "all accounts", request("/website/index.html", "ali-token").Body.String())
+	assert.Equal(t, http.StatusForbidden, request("/website/static/analysis/anomalies.json", "ali-token").Code)
+	assert.Equal(t, http.StatusForbidden, request("/website/static/js/../analysis/anomalies.json", "ali-token").Code)
+
+	w := request("/website/static/analysis/data-set.js", "ali-token")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/javascript; charset=utf-8", w.Header().Get("Content-Type"))
+	body := w.Body.String()
+	assert.Contains(t, body, "window.costAnalysis = ")
+	assert.Contains(t, body, "window.utilizeAnalysis = ")
+	assert.Contains(t, body, `"account":"ali"`)
+	assert.NotContains(t, body, `"account":"aws"`)
+	assert.Contains(t, body, "ali-budget")
+	assert.NotContains(t, body, "aws-budget")
+	assert.NotContains(t, body, "total-budget") // combines the accounts not granted
+	assert.Equal(t, body, request("/website/static/analysis/data-set.js", "ali-token").Body.String())
+
+	// the account is not billed
+	w = request("/website/static/analysis/data-set.js", "aws-token")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"account":"aws"`)
+	assert.NotContains(t, w.Body.String(), `"account":"ali"`)
+
+	// rebuilt once the snapshot is replaced
+	next := newTestSnapshot()
+	s.SetSnapshot(next)
+	assert.NotContains(t, request("/website/static/analysis/data-set.js", "ali-token").Body.String(), `"account":"ali"`)
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/gin-gonic/gin"
+)
+
+// UtilizationItem average and peak utilization of the instances of an account in one day, in percent
+type UtilizationItem struct {
+	Period    string  `json:"period"` // 2022-10-01
+	Account   string  `json:"account"`
+	Provider  string  `json:"provider"`
+	Instances int     `json:"instances"`
+	CpuAvg    float64 `json:"cpu_avg"`
+	CpuMax    float64 `json:"cpu_max"`
+	MemoryAvg float64 `json:"memory_avg"`
+	MemoryMax float64 `json:"memory_max"`
+}
+
+type UtilizationResponse struct {
+	Start string            `json:"start"`
+	End   string            `json:"end"`
+	Items []UtilizationItem `json:"items"`
+}
+
+// getUtilization default range is the recent 30 days, today is not included
+func (s *Server) getUtilization(c *gin.Context) {
+	snapshot, ok := s.loadSnapshot(c)
+	if !ok {
+		return
+	}
+	yesterday := snapshot.GeneratedAt.AddDate(0, 0, -1)
+	q, err := parseQuery(c, _dayLayout, yesterday.AddDate(0, 0, 1-_defaultDays).Format(_dayLayout), yesterday.Format(_dayLayout))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	resp := UtilizationResponse{Start: q.Start, End: q.End, Items: []UtilizationItem{}}
+	for _, a := range snapshot.AccountUtilizations {
+		if !q.matchAccount(a.AccountName, a.Provider.String()) {
+			continue
+		}
+		for _, day := range q.periods() {
+			item := UtilizationItem{Period: day, Account: a.AccountName, Provider: a.Provider.String()}
+			found := false
+			if a.DailyCpu != nil {
+				if val, ok := a.DailyCpu.Load(day); ok {
+					found = true
+					var values, peaks []float64
+					for _, u := range val.(data.DailyCpuUtilization).Utilization {
+						values, peaks = append(values, u.UsedUtilization), append(peaks, u.MaxUtilization)
+					}
+					item.Instances = len(values)
+					item.CpuAvg, item.CpuMax = mean(values), peak(peaks, values)
+				}
+			}
+			if a.DailyMemory != nil {
+				if val, ok := a.DailyMemory.Load(day); ok {
+					found = true
+					var values, peaks []float64
+					for _, u := range val.(data.DailyMemoryUtilization).Utilization {
+						values, peaks = append(values, u.UsedUtilization), append(peaks, u.MaxUtilization)
+					}
+					if len(values) > item.Instances {
+						item.Instances = len(values)
+					}
+					item.MemoryAvg, item.MemoryMax = mean(values), peak(peaks, values)
+				}
+			}
+			if found {
+				resp.Items = append(resp.Items, item)
+			}
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// peak the averages are used for the instances without peak, eg: the provider does not report it
+func peak(peaks, values []float64) float64 {
+	var ret float64
+	for i := range values {
+		v := peaks[i]
+		if v == 0 {
+			v = values[i]
+		}
+		if v > ret {
+			ret = v
+		}
+	}
+	return ret
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/galaxy-future/costpilot/internal/auth"
+	"github.com/galaxy-future/costpilot/internal/constants"
+	"github.com/galaxy-future/costpilot/internal/domain"
+	"github.com/galaxy-future/costpilot/internal/types"
+	"github.com/gin-gonic/gin"
+)
+
+const _websiteAnalysisDir = "/website/static/analysis/"
+
+// dataSet data-set.js rebuilt for a grant, kept until the snapshot is replaced
+type dataSet struct {
+	snapshot *Snapshot
+	content  string
+}
+
+// DataSet the data of the accounts granted to the principal, budgets are kept only if scoped to one of the accounts
+func (s *Snapshot) DataSet(p *auth.Principal) domain.DataSet {
+	allow := func(account string) bool {
+		return p == nil || p.CanAccessAccount(account)
+	}
+	ds := domain.DataSet{NowT: s.GeneratedAt}
+	for _, a := range s.AccountBillings {
+		if allow(a.AccountName) {
+			ds.AccountBillings = append(ds.AccountBillings, a)
+		}
+	}
+	for _, a := range s.AlternativeBillings {
+		if allow(a.AccountName) {
+			ds.AlternativeBillings = append(ds.AlternativeBillings, a)
+		}
+	}
+	for _, a := range s.AccountUtilizations {
+		if allow(a.AccountName) {
+			ds.AccountUtilizations = append(ds.AccountUtilizations, a)
+		}
+	}
+	for _, a := range s.AccountCommitments {
+		if allow(a.AccountName) {
+			ds.AccountCommitments = append(ds.AccountCommitments, a)
+		}
+	}
+	for _, b := range s.BudgetStatuses {
+		if b.Budget.Scope == types.BudgetScopeAccount && allow(b.Budget.Target) {
+			ds.BudgetStatuses = append(ds.BudgetStatuses, b)
+		}
+	}
+	for _, a := range s.Anomalies {
+		if allow(a.AccountName) {
+			ds.Anomalies = append(ds.Anomalies, a)
+		}
+	}
+	for _, i := range s.IdleInstances {
+		if allow(i.AccountName) {
+			ds.IdleInstances = append(ds.IdleInstances, i)
+		}
+	}
+	for _, o := range s.Opportunities {
+		if allow(o.AccountName) {
+			ds.Opportunities = append(ds.Opportunities, o)
+		}
+	}
+	for _, u := range s.Unsupported {
+		if allow(u.AccountName) {
+			ds.Unsupported = append(ds.Unsupported, u)
+		}
+	}
+	return ds
+}
+
+// FilterWebsite the website is served as it is to those granted all the accounts, the others get data-set.js
+// rebuilt from the accounts granted, and are refused the other files generated for all the accounts
+func (s *Server) FilterWebsite(accounts func() []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p := auth.FromContext(c)
+		file := path.Clean(c.Request.URL.Path)
+		if p == nil || p.CanAccessAll(accounts()) || !strings.HasPrefix(file+"/", _websiteAnalysisDir) {
+			c.Next()
+			return
+		}
+		if file != "/website/"+constants.JsDataFile {
+			abortWithError(c, http.StatusForbidden, "the file combines all accounts and is only served to those granted all of them, "+
+				"get the granted accounts from /api/v1")
+			return
+		}
+		s.getDataSet(c, p)
+		c.Abort()
+	}
+}
+
+// getDataSet data-set.js of the accounts granted, rebuilt once per grant and snapshot
+func (s *Server) getDataSet(c *gin.Context, p *auth.Principal) {
+	snapshot, ok := s.loadSnapshot(c)
+	if !ok {
+		return
+	}
+	var granted []string
+	for _, a := range snapshot.Accounts {
+		if p.CanAccessAccount(a.Name) {
+			granted = append(granted, a.Name)
+		}
+	}
+	sort.Strings(granted)
+	key := strings.Join(granted, "\n")
+	if val, ok := s.dataSets.Load(key); ok && val.(dataSet).snapshot == snapshot {
+		writeDataSet(c, val.(dataSet).content)
+		return
+	}
+	content, err := snapshot.DataSet(p).Render(c.Request.Context())
+	if err != nil {
+		abortWithError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.dataSets.Store(key, dataSet{snapshot: snapshot, content: content})
+	writeDataSet(c, content)
+}
+
+// writeDataSet the content differs by the principal, so it is not cached by the shared caches
+func writeDataSet(c *gin.Context, content string) {
+	c.Header("Cache-Control", "private, no-cache")
+	c.Data(http.StatusOK, "application/javascript; charset=utf-8", []byte(content))
+}
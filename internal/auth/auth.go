@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/galaxy-future/costpilot/internal/types"
+	"github.com/gin-gonic/gin"
+)
+
+// Authenticator returns nil, nil if the request does not carry its credentials,
+// and an error if they are carried but invalid
+type Authenticator interface {
+	Authenticate(c *gin.Context) (*Principal, error)
+}
+
+// Auth pluggable authentication of the web server, the authenticators are tried in order
+type Auth struct {
+	enabled        bool
+	authenticators []Authenticator
+	basic          bool
+	oidc           *oidcAuthenticator
+}
+
+func New(cfg types.Auth) (*Auth, error) {
+	a := &Auth{enabled: cfg.Enabled()}
+	if !a.enabled {
+		log.Println("W! auth is not configured, the server is open to everyone")
+		return a, nil
+	}
+	if len(cfg.Tokens) > 0 {
+		a.authenticators = append(a.authenticators, newTokenAuthenticator(cfg.Tokens))
+	}
+	if len(cfg.BasicUsers) > 0 {
+		a.authenticators = append(a.authenticators, newBasicAuthenticator(cfg.BasicUsers))
+		a.basic = true
+	}
+	if cfg.OIDC != nil {
+		o, err := newOIDCAuthenticator(*cfg.OIDC, cfg.SessionSecret, cfg.GetSessionTTL())
+		if err != nil {
+			return nil, err
+		}
+		a.authenticators = append(a.authenticators, o)
+		a.oidc = o
+	}
+	return a, nil
+}
+
+// Use append an authenticator
+func (a *Auth) Use(authenticator Authenticator) *Auth {
+	a.enabled = true
+	a.authenticators = append(a.authenticators, authenticator)
+	return a
+}
+
+// Register the login routes of oidc under /auth
+func (a *Auth) Register(r gin.IRouter) {
+	if a.oidc == nil {
+		return
+	}
+	g := r.Group("/auth")
+	g.GET("/login", a.oidc.login)
+	g.GET("/callback", a.oidc.callback)
+	g.GET("/logout", a.oidc.logout)
+}
+
+// Middleware authenticate the request, everyone is an admin if auth is not configured
+func (a *Auth) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !a.enabled {
+			setPrincipal(c, &Principal{Name: MethodAnonymous, Method: MethodAnonymous, Role: types.RoleAdmin})
+			c.Next()
+			return
+		}
+		for _, authenticator := range a.authenticators {
+			p, err := authenticator.Authenticate(c)
+			if err != nil {
+				log.Printf("W! authenticate %s %s failed: %v", c.Request.Method, c.Request.URL.Path, err)
+				a.challenge(c, err.Error())
+				return
+			}
+			if p != nil {
+				setPrincipal(c, p)
+				c.Next()
+				return
+			}
+		}
+		a.challenge(c, "authentication required")
+	}
+}
+
+// challenge browsers are redirected to the oidc login, otherwise basic auth is requested if configured
+func (a *Auth) challenge(c *gin.Context, message string) {
+	if a.oidc != nil && c.Request.Method == http.MethodGet && strings.Contains(c.GetHeader("Accept"), "text/html") {
+		c.Redirect(http.StatusFound, "/auth/login?next="+url.QueryEscape(c.Request.URL.RequestURI()))
+		c.Abort()
+		return
+	}
+	if a.basic {
+		c.Header("WWW-Authenticate", `Basic realm="costpilot", charset="UTF-8"`)
+	}
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": message})
+}
+
+// RequireAdmin
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if p := FromContext(c); p != nil && !p.IsAdmin() {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin role is required"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,212 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/galaxy-future/costpilot/internal/types"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestRouter(t *testing.T, cfg types.Auth) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	a, err := New(cfg)
+	require.NoError(t, err)
+	r := gin.New()
+	a.Register(r)
+	g := r.Group("/", a.Middleware())
+	g.GET("/whoami", func(c *gin.Context) {
+		p := FromContext(c)
+		c.JSON(http.StatusOK, gin.H{"name": p.Name, "role": p.Role, "a": p.CanAccessAccount("a"), "b": p.CanAccessAccount("b")})
+	})
+	g.POST("/refresh", RequireAdmin(), func(c *gin.Context) { c.Status(http.StatusAccepted) })
+	// like the files of the website which combine all the accounts
+	g.GET("/website", func(c *gin.Context) {
+		if !FromContext(c).CanAccessAll([]string{"a", "b"}) {
+			c.Status(http.StatusForbidden)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	return r
+}
+
+func doRequest(r http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestMiddleware_Disabled(t *testing.T) {
+	r := newTestRouter(t, types.Auth{})
+	w := doRequest(r, httptest.NewRequest(http.MethodPost, "/refresh", nil))
+	assert.Equal(t, http.StatusAccepted, w.Code)
+}
+
+func TestMiddleware_Token(t *testing.T) {
+	r := newTestRouter(t, types.Auth{Tokens: []types.AuthToken{
+		{Name: "ci", Token: "admin-token", Grant: types.Grant{Role: types.RoleAdmin}},
+		{Name: "team-a", Token: "viewer-token", Grant: types.Grant{Role: types.RoleViewer, Accounts: []string{"a"}}},
+	}})
+	request := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		return doRequest(r, req)
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, "/whoami", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, "/whoami", "wrong").Code)
+	assert.Equal(t, http.StatusAccepted, request(http.MethodPost, "/refresh", "admin-token").Code)
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/website", "admin-token").Code)
+
+	w := request(http.MethodGet, "/whoami", "viewer-token")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"name":"team-a","role":"viewer","a":true,"b":false}`, w.Body.String())
+	assert.Equal(t, http.StatusForbidden, request(http.MethodPost, "/refresh", "viewer-token").Code)
+	assert.Equal(t, http.StatusForbidden, request(http.MethodGet, "/website", "viewer-token").Code)
+}
+
+func TestMiddleware_Basic(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	r := newTestRouter(t, types.Auth{BasicUsers: []types.BasicUser{
+		{Username: "alice", Password: "plain", Grant: types.Grant{Role: types.RoleViewer, Accounts: []string{"*"}}},
+		{Username: "bob", PasswordHash: string(hash), Grant: types.Grant{Role: types.RoleViewer}},
+	}})
+	request := func(username, password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/website", nil)
+		req.SetBasicAuth(username, password)
+		return doRequest(r, req)
+	}
+
+	w := doRequest(r, httptest.NewRequest(http.MethodGet, "/website", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Basic")
+
+	assert.Equal(t, http.StatusOK, request("alice", "plain").Code)
+	assert.Equal(t, http.StatusUnauthorized, request("alice", "secret").Code)
+	assert.Equal(t, http.StatusForbidden, request("bob", "secret").Code) // no account is granted
+	assert.Equal(t, http.StatusUnauthorized, request("carol", "secret").Code)
+}
+
+// newFakeIdP an openid provider issuing one code for the email
+func newFakeIdP(t *testing.T, email string) *httptest.Server {
+	var idp *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(oidcEndpoints{
+			AuthorizationEndpoint: idp.URL + "/authorize",
+			TokenEndpoint:         idp.URL + "/token",
+			UserinfoEndpoint:      idp.URL + "/userinfo",
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("code") != "the-code" || r.PostFormValue("client_secret") != "client-secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"access_token": "the-access-token", "token_type": "Bearer"})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer the-access-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"email": email, "email_verified": true})
+	})
+	idp = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+func TestMiddleware_OIDC(t *testing.T) {
+	idp := newFakeIdP(t, "Alice@example.com")
+	r := newTestRouter(t, types.Auth{
+		OIDC: &types.OIDC{
+			Issuer:       idp.URL,
+			ClientID:     "costpilot",
+			ClientSecret: "client-secret",
+			RedirectURL:  "http://localhost:8504/auth/callback",
+			Users:        []types.OIDCUser{{Email: "alice@example.com", Grant: types.Grant{Role: types.RoleViewer, Accounts: []string{"b"}}}},
+		},
+		SessionSecret: "session-secret",
+	})
+
+	// browsers are redirected to the login
+	req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+	req.Header.Set("Accept", "text/html")
+	w := doRequest(r, req)
+	require.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "/auth/login?next=%2Fwhoami", w.Header().Get("Location"))
+	assert.Equal(t, http.StatusUnauthorized, doRequest(r, httptest.NewRequest(http.MethodGet, "/whoami", nil)).Code)
+
+	w = doRequest(r, httptest.NewRequest(http.MethodGet, "/auth/login?next=/whoami", nil))
+	require.Equal(t, http.StatusFound, w.Code)
+	location, err := url.Parse(w.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, idp.URL+"/authorize", location.Scheme+"://"+location.Host+location.Path)
+	assert.Equal(t, "costpilot", location.Query().Get("client_id"))
+	assert.Equal(t, "openid email profile", location.Query().Get("scope"))
+	state := location.Query().Get("state")
+	stateCookie := w.Result().Cookies()[0]
+
+	// a forged state is rejected
+	req = httptest.NewRequest(http.MethodGet, "/auth/callback?code=the-code&state=forged", nil)
+	req.AddCookie(stateCookie)
+	assert.Equal(t, http.StatusBadRequest, doRequest(r, req).Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/auth/callback?code=the-code&state="+state, nil)
+	req.AddCookie(stateCookie)
+	w = doRequest(r, req)
+	require.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "/whoami", w.Header().Get("Location"))
+	var session *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == _sessionCookie {
+			session = c
+		}
+	}
+	require.NotNil(t, session)
+	assert.True(t, session.HttpOnly)
+
+	req = httptest.NewRequest(http.MethodGet, "/whoami", nil)
+	req.AddCookie(session)
+	w = doRequest(r, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"name":"Alice@example.com","role":"viewer","a":false,"b":true}`, w.Body.String())
+
+	// a tampered session is not accepted
+	req = httptest.NewRequest(http.MethodGet, "/whoami", nil)
+	req.AddCookie(&http.Cookie{Name: _sessionCookie, Value: "eyJlbWFpbCI6ImV2ZUBleGFtcGxlLmNvbSIsImV4cCI6OTk5OTk5OTk5OX0." + session.Value[len(session.Value)-10:]})
+	assert.Equal(t, http.StatusUnauthorized, doRequest(r, req).Code)
+}
+
+func TestOIDC_DeniedWithoutGrant(t *testing.T) {
+	idp := newFakeIdP(t, "eve@example.com")
+	o, err := newOIDCAuthenticator(types.OIDC{Issuer: idp.URL, ClientID: "costpilot", ClientSecret: "client-secret", RedirectURL: "https://costpilot.example.com/auth/callback"}, "s", 0)
+	require.NoError(t, err)
+	assert.True(t, o.secure)
+	email, err := o.exchange(httptest.NewRequest(http.MethodGet, "/", nil).Context(), "the-code")
+	require.NoError(t, err)
+	_, ok := o.grantOf(email)
+	assert.False(t, ok)
+
+	o.cfg.DefaultGrant = &types.Grant{Role: types.RoleViewer}
+	_, ok = o.grantOf(email)
+	assert.True(t, ok)
+}
+
+func TestSafeNext(t *testing.T) {
+	assert.Equal(t, "/website/", safeNext("/website/"))
+	assert.Equal(t, "", safeNext("//evil.example.com"))
+	assert.Equal(t, "", safeNext("https://evil.example.com"))
+	assert.Equal(t, "", safeNext("/\\evil.example.com"))
+}
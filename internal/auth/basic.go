@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"strings"
+
+	"github.com/galaxy-future/costpilot/internal/types"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var errInvalidCredentials = errors.New("invalid credentials")
+
+// tokenAuthenticator static api tokens, "Authorization: Bearer <token>"
+type tokenAuthenticator struct {
+	tokens []types.AuthToken
+}
+
+func newTokenAuthenticator(tokens []types.AuthToken) *tokenAuthenticator {
+	return &tokenAuthenticator{tokens: tokens}
+}
+
+func (a *tokenAuthenticator) Authenticate(c *gin.Context) (*Principal, error) {
+	h := c.GetHeader("Authorization")
+	if !strings.HasPrefix(h, "Bearer ") {
+		return nil, nil
+	}
+	token := strings.TrimSpace(strings.TrimPrefix(h, "Bearer "))
+	for _, t := range a.tokens {
+		if t.Token != "" && secureEqual(token, t.Token) {
+			return newPrincipal(t.Name, MethodToken, t.Grant), nil
+		}
+	}
+	return nil, errInvalidCredentials
+}
+
+// basicAuthenticator http basic auth of the users in config
+type basicAuthenticator struct {
+	users map[string]types.BasicUser
+}
+
+func newBasicAuthenticator(users []types.BasicUser) *basicAuthenticator {
+	a := &basicAuthenticator{users: make(map[string]types.BasicUser, len(users))}
+	for _, u := range users {
+		a.users[u.Username] = u
+	}
+	return a
+}
+
+func (a *basicAuthenticator) Authenticate(c *gin.Context) (*Principal, error) {
+	username, password, ok := c.Request.BasicAuth()
+	if !ok {
+		return nil, nil
+	}
+	u, ok := a.users[username]
+	if !ok || !checkPassword(u, password) {
+		return nil, errInvalidCredentials
+	}
+	return newPrincipal(username, MethodBasic, u.Grant), nil
+}
+
+func checkPassword(u types.BasicUser, password string) bool {
+	if u.PasswordHash != "" {
+		return bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) == nil
+	}
+	return u.Password != "" && secureEqual(password, u.Password)
+}
+
+// secureEqual constant time regardless of the lengths
+func secureEqual(a, b string) bool {
+	x, y := sha256.Sum256([]byte(a)), sha256.Sum256([]byte(b))
+	return subtle.ConstantTimeCompare(x[:], y[:]) == 1
+}
@@ -0,0 +1,314 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/types"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	_sessionCookie = "costpilot_session"
+	_stateCookie   = "costpilot_oidc_state"
+	_stateTTL      = 10 * time.Minute
+)
+
+type oidcEndpoints struct {
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+// session payload of the signed cookies, grants are resolved from config on each request
+type session struct {
+	Email  string `json:"email,omitempty"`
+	State  string `json:"state,omitempty"`
+	Next   string `json:"next,omitempty"`
+	Expiry int64  `json:"exp"`
+}
+
+// oidcAuthenticator authorization code flow against an openid provider, the login is kept in a signed cookie
+type oidcAuthenticator struct {
+	cfg    types.OIDC
+	secret []byte
+	ttl    time.Duration
+	secure bool
+	client *http.Client
+
+	mu        sync.Mutex
+	endpoints *oidcEndpoints
+}
+
+func newOIDCAuthenticator(cfg types.OIDC, secret string, ttl time.Duration) (*oidcAuthenticator, error) {
+	redirect, err := url.Parse(cfg.RedirectURL)
+	if err != nil {
+		return nil, fmt.Errorf("auth.oidc.redirect_url %q is invalid: %v", cfg.RedirectURL, err)
+	}
+	o := &oidcAuthenticator{
+		cfg:    cfg,
+		secret: []byte(secret),
+		ttl:    ttl,
+		secure: redirect.Scheme == "https",
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+	if secret == "" {
+		log.Println("W! auth.session_secret is empty, a random one is used and logins are lost on restart")
+		o.secret = make([]byte, 32)
+		if _, err := rand.Read(o.secret); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+func (o *oidcAuthenticator) Authenticate(c *gin.Context) (*Principal, error) {
+	var s session
+	if !o.readCookie(c, _sessionCookie, &s) || s.Email == "" {
+		return nil, nil // expired or tampered sessions log in again
+	}
+	grant, ok := o.grantOf(s.Email)
+	if !ok {
+		return nil, fmt.Errorf("%s is not granted", s.Email)
+	}
+	return newPrincipal(s.Email, MethodOIDC, grant), nil
+}
+
+func (o *oidcAuthenticator) grantOf(email string) (types.Grant, bool) {
+	for _, u := range o.cfg.Users {
+		if strings.EqualFold(u.Email, email) {
+			return u.Grant, true
+		}
+	}
+	if o.cfg.DefaultGrant != nil {
+		return *o.cfg.DefaultGrant, true
+	}
+	return types.Grant{}, false
+}
+
+// login redirect to the provider, next is where the callback returns to
+func (o *oidcAuthenticator) login(c *gin.Context) {
+	endpoints, err := o.discover(c.Request.Context())
+	if err != nil {
+		log.Printf("E! oidc discovery failed: %v", err)
+		c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{"error": "oidc provider is unavailable"})
+		return
+	}
+	state, err := randomString()
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	o.writeCookie(c, _stateCookie, session{State: state, Next: safeNext(c.Query("next")), Expiry: time.Now().Add(_stateTTL).Unix()}, _stateTTL)
+
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", o.cfg.ClientID)
+	q.Set("redirect_uri", o.cfg.RedirectURL)
+	q.Set("scope", strings.Join(o.cfg.GetScopes(), " "))
+	q.Set("state", state)
+	c.Redirect(http.StatusFound, endpoints.AuthorizationEndpoint+"?"+q.Encode())
+}
+
+// callback exchange the code, then log in by the email of userinfo
+func (o *oidcAuthenticator) callback(c *gin.Context) {
+	var state session
+	if !o.readCookie(c, _stateCookie, &state) || state.State == "" || !secureEqual(state.State, c.Query("state")) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid oidc state, please login again"})
+		return
+	}
+	o.clearCookie(c, _stateCookie)
+	if e := c.Query("error"); e != "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": e + ": " + c.Query("error_description")})
+		return
+	}
+	email, err := o.exchange(c.Request.Context(), c.Query("code"))
+	if err != nil {
+		log.Printf("W! oidc login failed: %v", err)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "oidc login failed"})
+		return
+	}
+	if _, ok := o.grantOf(email); !ok {
+		log.Printf("W! oidc login of %s is denied, no grant is configured", email)
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": email + " is not granted"})
+		return
+	}
+	o.writeCookie(c, _sessionCookie, session{Email: email, Expiry: time.Now().Add(o.ttl).Unix()}, o.ttl)
+	log.Printf("I! %s logged in by oidc", email)
+	next := state.Next
+	if next == "" {
+		next = "/website/"
+	}
+	c.Redirect(http.StatusFound, next)
+}
+
+func (o *oidcAuthenticator) logout(c *gin.Context) {
+	o.clearCookie(c, _sessionCookie)
+	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+}
+
+func (o *oidcAuthenticator) discover(ctx context.Context) (*oidcEndpoints, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.endpoints != nil {
+		return o.endpoints, nil
+	}
+	var e oidcEndpoints
+	if err := o.getJSON(ctx, strings.TrimSuffix(o.cfg.Issuer, "/")+"/.well-known/openid-configuration", "", &e); err != nil {
+		return nil, err
+	}
+	if e.AuthorizationEndpoint == "" || e.TokenEndpoint == "" || e.UserinfoEndpoint == "" {
+		return nil, errors.New("authorization, token or userinfo endpoint is not discovered")
+	}
+	o.endpoints = &e
+	return o.endpoints, nil
+}
+
+// exchange the authorization code for an access token, which reads the email from userinfo
+func (o *oidcAuthenticator) exchange(ctx context.Context, code string) (string, error) {
+	if code == "" {
+		return "", errors.New("code is empty")
+	}
+	endpoints, err := o.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", o.cfg.RedirectURL)
+	form.Set("client_id", o.cfg.ClientID)
+	form.Set("client_secret", o.cfg.ClientSecret)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoints.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := o.doJSON(req, &token); err != nil {
+		return "", fmt.Errorf("token: %v", err)
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("token: access_token is empty")
+	}
+
+	var userinfo struct {
+		Email         string `json:"email"`
+		EmailVerified *bool  `json:"email_verified"`
+	}
+	if err := o.getJSON(ctx, endpoints.UserinfoEndpoint, token.AccessToken, &userinfo); err != nil {
+		return "", fmt.Errorf("userinfo: %v", err)
+	}
+	if userinfo.Email == "" {
+		return "", errors.New("userinfo: email is empty, is the email scope granted?")
+	}
+	if userinfo.EmailVerified != nil && !*userinfo.EmailVerified {
+		return "", fmt.Errorf("userinfo: %s is not verified", userinfo.Email)
+	}
+	return userinfo.Email, nil
+}
+
+func (o *oidcAuthenticator) getJSON(ctx context.Context, u, accessToken string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	return o.doJSON(req, v)
+}
+
+func (o *oidcAuthenticator) doJSON(req *http.Request, v interface{}) error {
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: %s", req.Method, req.URL.Path, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (o *oidcAuthenticator) writeCookie(c *gin.Context, name string, s session, ttl time.Duration) {
+	b, _ := json.Marshal(s)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(name, o.sign(b), int(ttl.Seconds()), "/", "", o.secure, true)
+}
+
+func (o *oidcAuthenticator) clearCookie(c *gin.Context, name string) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(name, "", -1, "/", "", o.secure, true)
+}
+
+// readCookie false if the cookie is absent, tampered or expired
+func (o *oidcAuthenticator) readCookie(c *gin.Context, name string, s *session) bool {
+	value, err := c.Cookie(name)
+	if err != nil {
+		return false
+	}
+	b, ok := o.verify(value)
+	if !ok || json.Unmarshal(b, s) != nil {
+		return false
+	}
+	return time.Now().Unix() < s.Expiry
+}
+
+// sign base64(payload).base64(hmac-sha256(payload))
+func (o *oidcAuthenticator) sign(payload []byte) string {
+	mac := hmac.New(sha256.New, o.secret)
+	mac.Write(payload)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (o *oidcAuthenticator) verify(value string) ([]byte, bool) {
+	i := strings.IndexByte(value, '.')
+	if i < 0 {
+		return nil, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(value[:i])
+	if err != nil {
+		return nil, false
+	}
+	sum, err := base64.RawURLEncoding.DecodeString(value[i+1:])
+	if err != nil {
+		return nil, false
+	}
+	mac := hmac.New(sha256.New, o.secret)
+	mac.Write(payload)
+	return payload, hmac.Equal(sum, mac.Sum(nil))
+}
+
+// safeNext only local paths, no open redirects
+func safeNext(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return ""
+	}
+	return next
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"github.com/galaxy-future/costpilot/internal/types"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	MethodAnonymous = "anonymous"
+	MethodToken     = "token"
+	MethodBasic     = "basic"
+	MethodOIDC      = "oidc"
+
+	_principalKey = "costpilot.principal"
+)
+
+// Principal the authenticated user or token
+type Principal struct {
+	Name   string
+	Method string
+	Role   types.Role
+
+	accounts map[string]bool // nil grants all of them
+}
+
+func newPrincipal(name, method string, g types.Grant) *Principal {
+	p := &Principal{Name: name, Method: method, Role: g.Role}
+	if g.Role == types.RoleAdmin {
+		return p
+	}
+	p.Role = types.RoleViewer
+	p.accounts = make(map[string]bool, len(g.Accounts))
+	for _, a := range g.Accounts {
+		if a == types.AllAccounts {
+			p.accounts = nil
+			break
+		}
+		p.accounts[a] = true
+	}
+	return p
+}
+
+func (p *Principal) IsAdmin() bool {
+	return p.Role == types.RoleAdmin
+}
+
+func (p *Principal) CanAccessAccount(name string) bool {
+	return p.accounts == nil || p.accounts[name]
+}
+
+// CanAccessAll eg: the files of the website combine every account
+func (p *Principal) CanAccessAll(accounts []string) bool {
+	for _, a := range accounts {
+		if !p.CanAccessAccount(a) {
+			return false
+		}
+	}
+	return true
+}
+
+// FromContext nil if the request did not go through the middleware
+func FromContext(c *gin.Context) *Principal {
+	if v, ok := c.Get(_principalKey); ok {
+		return v.(*Principal)
+	}
+	return nil
+}
+
+func setPrincipal(c *gin.Context, p *Principal) {
+	c.Set(_principalKey, p)
+}
@@ -0,0 +1,139 @@
+package catalog
+
+import (
+	"embed"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	"github.com/galaxy-future/costpilot/internal/providers"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+//go:embed data/*.yaml
+var bundled embed.FS
+
+// HoursPerMonth converts hourly prices to monthly prices
+const HoursPerMonth = 730
+
+type InstanceType struct {
+	Type   string  `json:"type" yaml:"type"`
+	Family string  `json:"family" yaml:"family"`
+	VCPU   float64 `json:"vcpu" yaml:"vcpu"`
+	Memory float64 `json:"memory" yaml:"memory"` // GiB
+	Price  float64 `json:"price" yaml:"price"`   // on-demand hourly price
+}
+
+func (t InstanceType) MonthlyPrice() float64 {
+	return t.Price * HoursPerMonth
+}
+
+// Catalog instance types of one provider
+type Catalog struct {
+	Provider      cloud.Provider `json:"provider" yaml:"provider"`
+	Currency      string         `json:"currency" yaml:"currency"`
+	InstanceTypes []InstanceType `json:"instance_types" yaml:"instance_types"`
+
+	index map[string]InstanceType
+}
+
+// Lookup instance type by name, case insensitive
+func (c *Catalog) Lookup(instanceType string) (InstanceType, bool) {
+	t, ok := c.index[strings.ToLower(instanceType)]
+	return t, ok
+}
+
+// Family instance types of the same family, the smallest first
+func (c *Catalog) Family(family string) []InstanceType {
+	var ret []InstanceType
+	for _, t := range c.index {
+		if strings.EqualFold(t.Family, family) {
+			ret = append(ret, t)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Price != ret[j].Price {
+			return ret[i].Price < ret[j].Price
+		}
+		return ret[i].Type < ret[j].Type
+	})
+	return ret
+}
+
+func (c *Catalog) merge(o Catalog) {
+	if o.Currency != "" {
+		c.Currency = o.Currency
+	}
+	for _, t := range o.InstanceTypes {
+		c.index[strings.ToLower(t.Type)] = t
+	}
+	c.InstanceTypes = c.InstanceTypes[:0]
+	for _, t := range c.index {
+		c.InstanceTypes = append(c.InstanceTypes, t)
+	}
+	sort.Slice(c.InstanceTypes, func(i, j int) bool {
+		return c.InstanceTypes[i].Type < c.InstanceTypes[j].Type
+	})
+}
+
+// Catalogs key : provider
+type Catalogs map[cloud.Provider]*Catalog
+
+func (c Catalogs) Get(provider cloud.Provider) (*Catalog, bool) {
+	ret, ok := c[provider]
+	return ret, ok
+}
+
+// Load the bundled catalogs, then the *.yaml|*.yml files in dir which override instance types of the same name.
+// dir is optional
+func Load(dir string) (Catalogs, error) {
+	ret := make(Catalogs)
+	if err := load(ret, bundled, "data"); err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		return ret, nil
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return nil, errors.Wrapf(err, "catalog dir %s", dir)
+	}
+	if err := load(ret, os.DirFS(dir), "."); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func load(catalogs Catalogs, fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		ext := filepath.Ext(e.Name())
+		if e.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		b, err := fs.ReadFile(fsys, filepath.ToSlash(filepath.Join(dir, e.Name())))
+		if err != nil {
+			return err
+		}
+		var c Catalog
+		if err = yaml.Unmarshal(b, &c); err != nil {
+			return errors.Wrapf(err, "parse catalog %s", e.Name())
+		}
+		if _, ok := providers.Lookup(c.Provider); !ok {
+			return errors.Errorf("catalog %s: unknown provider %q", e.Name(), c.Provider)
+		}
+		if _, ok := catalogs[c.Provider]; !ok {
+			catalogs[c.Provider] = &Catalog{Provider: c.Provider, index: make(map[string]InstanceType)}
+		}
+		catalogs[c.Provider].merge(c)
+		log.Printf("I! load instance catalog %s, %d types", e.Name(), len(c.InstanceTypes))
+	}
+	return nil
+}
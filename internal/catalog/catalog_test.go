@@ -0,0 +1,42 @@
+package catalog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "custom.yaml"), []byte(`
+provider: AlibabaCloud
+instance_types:
+  - {type: ecs.g6.large, family: ecs.g6, vcpu: 2, memory: 8, price: 0.5}
+  - {type: ecs.custom.large, family: ecs.custom, vcpu: 2, memory: 2, price: 0.1}
+`), 0644))
+
+	catalogs, err := Load(dir)
+	assert.NoError(t, err)
+	for _, p := range []cloud.Provider{cloud.AlibabaCloud, cloud.AWSCloud, cloud.TencentCloud, cloud.HuaweiCloud} {
+		_, ok := catalogs.Get(p)
+		assert.True(t, ok, p)
+	}
+	c, _ := catalogs.Get(cloud.AlibabaCloud)
+	assert.Equal(t, "CNY", c.Currency)
+	g6, ok := c.Lookup("ECS.G6.LARGE")
+	assert.True(t, ok)
+	assert.Equal(t, 0.5, g6.Price)
+	assert.InDelta(t, 365, g6.MonthlyPrice(), 0.001)
+	_, ok = c.Lookup("ecs.custom.large")
+	assert.True(t, ok)
+
+	family := c.Family("ecs.c6")
+	assert.Equal(t, 5, len(family))
+	assert.Equal(t, "ecs.c6.large", family[0].Type)
+
+	_, err = Load(filepath.Join(dir, "absent"))
+	assert.Error(t, err)
+}
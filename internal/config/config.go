@@ -1,61 +1,107 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
+	pathpkg "path"
+	"strings"
 
-	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/export"
+	"github.com/galaxy-future/costpilot/internal/i18n"
+	"github.com/galaxy-future/costpilot/internal/notify"
+	"github.com/galaxy-future/costpilot/internal/providers"
+	"github.com/galaxy-future/costpilot/internal/providers/registry"
+	providerTypes "github.com/galaxy-future/costpilot/internal/providers/types"
+	"github.com/galaxy-future/costpilot/internal/scheduler"
+	"github.com/galaxy-future/costpilot/internal/secret"
 	"github.com/galaxy-future/costpilot/internal/types"
-	"github.com/pkg/errors"
 	"gopkg.in/yaml.v2"
 )
 
 type Config struct {
 	CloudAccounts []types.CloudAccount `json:"cloud_accounts" yaml:"cloud_accounts"`
+	Vault         types.Vault          `json:"vault" yaml:"vault"` // server of the vault secrets of the cloud accounts
+	Budgets       []types.Budget       `json:"budgets" yaml:"budgets"`
+
+	CostBasis providerTypes.CostBasis `json:"cost_basis" yaml:"cost_basis"` // list | discount | net | tax_inclusive, unset keeps the amounts of the earlier releases
+	Language  string                  `json:"language" yaml:"language"`     // en-US | zh-CN of the report labels and region names, default zh-CN
+
+	UtilizationAnalysis   types.UtilizationAnalysis   `json:"utilization_analysis" yaml:"utilization_analysis"`
+	AnomalyDetection      types.AnomalyDetection      `json:"anomaly_detection" yaml:"anomaly_detection"`
+	IdleInstanceDetection types.IdleInstanceDetection `json:"idle_instance_detection" yaml:"idle_instance_detection"`
+	Rightsizing           types.Rightsizing           `json:"rightsizing" yaml:"rightsizing"`
+	CommitmentAnalysis    types.CommitmentAnalysis    `json:"commitment_analysis" yaml:"commitment_analysis"`
+	Amortization          types.Amortization          `json:"amortization" yaml:"amortization"`
+
+	Daemon   types.Daemon   `json:"daemon" yaml:"daemon"`
+	Exporter types.Exporter `json:"exporter" yaml:"exporter"`
+	Auth     types.Auth     `json:"auth" yaml:"auth"`
+
+	Notification types.Notification `json:"notification" yaml:"notification"`
+	Export       types.Export       `json:"export" yaml:"export"`
 }
 
 var globalConfig *Config
 
 const (
-	COSTPILOT_PROVIDER  = "COSTPILOT_PROVIDER"
-	COSTPILOT_AK        = "COSTPILOT_AK"
-	COSTPILOT_SK        = "COSTPILOT_SK"
-	COSTPILOT_REGION_ID = "COSTPILOT_REGION_ID"
+	COSTPILOT_PROVIDER      = "COSTPILOT_PROVIDER"
+	COSTPILOT_AK            = "COSTPILOT_AK"
+	COSTPILOT_SK            = "COSTPILOT_SK"
+	COSTPILOT_REGION_ID     = "COSTPILOT_REGION_ID"
+	COSTPILOT_SK_FILE       = "COSTPILOT_SK_FILE"       // read sk from the file instead of COSTPILOT_SK
+	COSTPILOT_CHAIN         = "COSTPILOT_DEFAULT_CHAIN" // true to resolve the credential by the sdk instead of ak/sk
+	COSTPILOT_ACCOUNTS_JSON = "COSTPILOT_ACCOUNTS_JSON" // json array of the cloud accounts
+	COSTPILOT_ACCOUNTS      = "COSTPILOT_ACCOUNTS"      // COSTPILOT_ACCOUNTS_<index>_<FIELD>, eg: COSTPILOT_ACCOUNTS_0_REGION_ID
+	COSTPILOT_DAEMON        = "COSTPILOT_DAEMON"        // true to keep serving and rerun the analysis by schedule
+	COSTPILOT_EXPORTER      = "COSTPILOT_EXPORTER"      // true to serve /metrics only and rerun the analysis by schedule
+	COSTPILOT_SCHEDULE      = "COSTPILOT_SCHEDULE"      // cron of the daemon or the exporter
+	COSTPILOT_LANGUAGE      = "COSTPILOT_LANGUAGE"      // en-US | zh-CN
 )
 
+const _defaultConfigPath = "conf/config.yaml"
+
+// Init load the config file with the environment variables merged on top, the file may be left out if the environment
+// has the cloud accounts
 func Init() error {
-	if err := InitFromEnvConfig(); err == nil { // find variables in environment firstly
-		return nil
+	c, err := readConfig(_defaultConfigPath)
+	switch {
+	case os.IsNotExist(err):
+		log.Printf("I! no config file %s, load from environment", _defaultConfigPath)
+		c = &Config{}
+	case err != nil:
+		log.Printf("E! load from config file error, %v", err)
+		return err
 	}
-	if err := InitFileConfig(); err != nil {
+	applied, err := c.applyEnv()
+	if err != nil {
+		log.Printf("E! load from environment error, %v", err)
 		return err
 	}
+	if err = c.prepare(); err != nil {
+		log.Printf("E! load config error, %v", err)
+		return err
+	}
+	globalConfig = c
+	log.Printf("I! load config success, environment overrides: %v", applied)
 	return nil
 }
 
 // InitFromEnvConfig lood configuration from environment
 func InitFromEnvConfig() error {
-	p := os.Getenv(COSTPILOT_PROVIDER)
-	ak := os.Getenv(COSTPILOT_AK)
-	sk := os.Getenv(COSTPILOT_SK)
-	r := os.Getenv(COSTPILOT_REGION_ID)
-	globalConfig = &Config{
-		CloudAccounts: []types.CloudAccount{
-			{
-				Provider: cloud.Provider(p),
-				AK:       ak,
-				SK:       sk,
-				RegionID: r,
-				Name:     ak,
-			},
-		},
-	}
-	if err := globalConfig.verify(); err != nil {
+	c := &Config{}
+	if _, err := c.applyEnv(); err != nil {
+		log.Printf("W! load from environment error, %v", err)
+		return err
+	}
+	if err := c.prepare(); err != nil {
 		log.Printf("I! no valid environment variables, skip")
 		return err
 	}
+	globalConfig = c
 	log.Println("I! load env config success")
 	return nil
 }
@@ -71,50 +117,368 @@ func InitFileConfig(filePath ...string) (err error) {
 }
 
 func loadConfig(filePath ...string) (*Config, error) {
-	var confPath string
-	if len(filePath) == 0 {
-		confPath = "conf/config.yaml"
-	} else {
+	confPath := _defaultConfigPath
+	if len(filePath) > 0 {
 		confPath = filePath[0]
 	}
+	config, err := readConfig(confPath)
+	if err != nil {
+		return nil, err
+	}
+	if err = config.prepare(); err != nil {
+		return nil, err
+	}
+	log.Println("I! load file config success")
+	return config, nil
+}
 
+// readConfig the config file, not verified yet
+func readConfig(confPath string) (*Config, error) {
 	f, err := ioutil.ReadFile(confPath)
 	if err != nil {
 		return nil, err
 	}
+	if f, err = secret.ExpandEnv(f); err != nil {
+		return nil, err
+	}
+	if err = checkSchema(f); err != nil {
+		return nil, err
+	}
 	var config Config
 	if err = yaml.Unmarshal(f, &config); err != nil {
 		return nil, err
 	}
-	if err = config.verify(); err != nil {
-		return nil, err
+	return &config, nil
+}
+
+// prepare resolve the credentials, fill the default account names and regions and verify the config
+func (c *Config) prepare() error {
+	if err := c.resolveCredentials(); err != nil {
+		return err
 	}
-	for k, v := range config.CloudAccounts {
+	for k, v := range c.CloudAccounts {
 		if v.Name == "" {
-			config.CloudAccounts[k].Name = fmt.Sprintf("%s-%s", v.Provider.String(), v.AK)
+			c.CloudAccounts[k].Name = defaultAccountName(v)
+		}
+		if r, ok := providers.Lookup(v.Provider); ok && v.RegionID == "" {
+			c.CloudAccounts[k].RegionID = r.DefaultRegion
 		}
 	}
-	log.Println("I! load file config success")
-	return &config, nil
+	return c.verify()
 }
 
-// verify check the variables in the config
+// resolveCredentials read the ak/sk of the cloud accounts from the files and the vault
+func (c *Config) resolveCredentials() (err error) {
+	var vault *secret.Vault
+	for i := range c.CloudAccounts {
+		a, path := &c.CloudAccounts[i], index("cloud_accounts", i)
+		if a.AKFile != "" {
+			if a.AK, err = secret.ReadFile(a.AKFile); err != nil {
+				return FieldError{Path: path + ".ak_file", Message: err.Error()}
+			}
+		}
+		if a.SKFile != "" {
+			if a.SK, err = secret.ReadFile(a.SKFile); err != nil {
+				return FieldError{Path: path + ".sk_file", Message: err.Error()}
+			}
+		}
+		if a.Vault == nil {
+			continue
+		}
+		if vault == nil {
+			if vault, err = secret.NewVault(c.Vault); err != nil {
+				return FieldError{Path: "vault", Message: err.Error()}
+			}
+		}
+		var s map[string]string
+		if s, err = vault.Read(context.Background(), a.Vault.Path); err != nil {
+			return FieldError{Path: path + ".vault.path", Message: err.Error()}
+		}
+		if ak, ok := s[a.Vault.GetAKKey()]; ok {
+			a.AK = ak
+		} else if a.AK == "" { // the ak may be kept in the config
+			return FieldError{Path: path + ".vault.ak_key", Message: fmt.Sprintf("vault secret %s has no %s", a.Vault.Path, a.Vault.GetAKKey())}
+		}
+		sk, ok := s[a.Vault.GetSKKey()]
+		if !ok {
+			return FieldError{Path: path + ".vault.sk_key", Message: fmt.Sprintf("vault secret %s has no %s", a.Vault.Path, a.Vault.GetSKKey())}
+		}
+		a.SK = sk
+	}
+	return nil
+}
+
+// defaultAccountName the ak is not secret, the chain has none
+func defaultAccountName(a types.CloudAccount) string {
+	if a.DefaultChain {
+		profile := a.Profile
+		if profile == "" {
+			profile = "default"
+		}
+		return fmt.Sprintf("%s-%s", a.Provider.String(), profile)
+	}
+	return fmt.Sprintf("%s-%s", a.Provider.String(), a.AK)
+}
+
+// verify check the variables in the config, all the invalid ones are returned as a ValidationError
 func (c Config) verify() error {
+	v := &validator{}
 	if len(c.CloudAccounts) == 0 {
-		return errors.New("cloud_accounts config is required")
+		v.add("cloud_accounts", "at least one cloud account is required")
 	}
-	for _, account := range c.CloudAccounts {
-		if account.AK == "" || account.SK == "" || account.Provider == "" {
-			return errors.New("cloud_account ak/sk/provider/name config is required")
+	names := map[string]int{}
+	for i, account := range c.CloudAccounts {
+		path := index("cloud_accounts", i)
+		verifyAccount(v, path, account)
+		if account.Name == "" {
+			continue
 		}
-		if account.Provider.String() == cloud.Undefined {
-			return fmt.Errorf("invalid provider")
+		if first, ok := names[account.Name]; ok {
+			v.add(path+".name", "%q is already the name of cloud_accounts[%d]", account.Name, first)
+		} else {
+			names[account.Name] = i
 		}
-		if account.Name == "" {
-			account.Name = account.AK
+	}
+	if c.Daemon.Enabled {
+		if _, err := scheduler.ParseCron(c.Daemon.GetSchedule()); err != nil {
+			v.add("daemon.schedule", "%v", err)
 		}
 	}
-	return nil
+	if c.Exporter.Enabled {
+		if c.Daemon.Enabled {
+			v.add("exporter.enabled", "daemon and exporter can not be both enabled, the daemon serves /metrics too")
+		}
+		if _, err := scheduler.ParseCron(c.Exporter.GetSchedule()); err != nil {
+			v.add("exporter.schedule", "%v", err)
+		}
+	}
+	if !c.CostBasis.IsValid() {
+		v.add("cost_basis", "%q is not one of list, discount, net, tax_inclusive", c.CostBasis)
+	}
+	if _, err := i18n.ParseLanguage(c.Language); err != nil {
+		v.add("language", "%v", err)
+	}
+	c.verifyAuth(v)
+	c.verifyNotifiers(v)
+	for i, f := range c.Export.Formats {
+		if _, err := export.New(f); err != nil {
+			v.add(index("export.formats", i), "%v", err)
+		}
+	}
+	c.verifyBudgets(v)
+	return v.err()
+}
+
+// verifyAccount check the provider, the credential and the region of the account
+func verifyAccount(v *validator, path string, a types.CloudAccount) {
+	if a.Provider == "" {
+		v.add(path+".provider", "is required")
+		return
+	}
+	r, ok := providers.Lookup(a.Provider)
+	if !ok {
+		v.add(path+".provider", "%q is not one of %s", a.Provider, strings.Join(providerNames(), ", "))
+		return
+	}
+	if a.DefaultChain {
+		if !r.Capabilities.DefaultChain {
+			v.add(path+".default_chain", "provider[%s] has no default credential chain, ak/sk is required", a.Provider)
+		}
+	} else {
+		if a.AK == "" {
+			v.add(path+".ak", "is required, or set ak_file, vault or default_chain")
+		}
+		if a.SK == "" {
+			v.add(path+".sk", "is required, or set sk_file, vault or default_chain")
+		}
+	}
+	if a.RegionID != "" && !r.IsKnownRegion(a.RegionID) {
+		v.add(path+".region_id", "%q is not a known %s region", a.RegionID, a.Provider)
+	}
+	verifyRegionPatterns(v, path+".regions", r, a.Regions)
+	verifyRegionPatterns(v, path+".exclude_regions", r, a.ExcludeRegions)
+	if a.RegionConcurrency < 0 {
+		v.add(path+".region_concurrency", "must not be negative")
+	}
+	verifyAssumeRole(v, path, a)
+}
+
+// providerNames the registered providers as the values of cloud_accounts[].provider
+func providerNames() []string {
+	var names []string
+	for _, p := range providers.Names() {
+		names = append(names, string(p))
+	}
+	return names
+}
+
+// verifyRegionPatterns the regions without wildcards must be known, a typo would leave the region out silently
+func verifyRegionPatterns(v *validator, path string, r registry.Registration, patterns []string) {
+	for i, p := range patterns {
+		if _, err := pathpkg.Match(p, ""); err != nil {
+			v.add(index(path, i), "%q is not a valid pattern", p)
+		} else if !strings.ContainsAny(p, "*?[") && !r.IsKnownRegion(p) {
+			v.add(index(path, i), "%q is not a known %s region", p, r.Name)
+		}
+	}
+}
+
+// verifyAuth check the tokens, users and oidc of the web server
+func (c Config) verifyAuth(v *validator) {
+	for i, t := range c.Auth.Tokens {
+		path := index("auth.tokens", i)
+		if t.Name == "" {
+			v.add(path+".name", "is required")
+		}
+		if t.Token == "" {
+			v.add(path+".token", "is required")
+		}
+		verifyGrant(v, path, t.Grant)
+	}
+	for i, u := range c.Auth.BasicUsers {
+		path := index("auth.basic_users", i)
+		if u.Username == "" {
+			v.add(path+".username", "is required")
+		}
+		if u.Password == "" && u.PasswordHash == "" {
+			v.add(path+".password", "password or password_hash is required")
+		}
+		verifyGrant(v, path, u.Grant)
+	}
+	if o := c.Auth.OIDC; o != nil {
+		for _, f := range []struct{ name, value string }{{"issuer", o.Issuer}, {"client_id", o.ClientID}, {"redirect_url", o.RedirectURL}} {
+			if f.value == "" {
+				v.add("auth.oidc."+f.name, "is required")
+			}
+		}
+		for i, u := range o.Users {
+			path := index("auth.oidc.users", i)
+			if u.Email == "" {
+				v.add(path+".email", "is required")
+			}
+			verifyGrant(v, path, u.Grant)
+		}
+		if o.DefaultGrant != nil {
+			verifyGrant(v, "auth.oidc.default_grant", *o.DefaultGrant)
+		}
+	}
+}
+
+// verifyNotifiers check the channels and the templates of the notifiers
+func (c Config) verifyNotifiers(v *validator) {
+	for i, n := range c.Notification.Notifiers {
+		path := index("notification.notifiers", i)
+		if n.Name == "" {
+			v.add(path+".name", "is required")
+		}
+		switch n.Type {
+		case types.NotifierWebhook, types.NotifierSlack, types.NotifierDingTalk, types.NotifierFeishu, types.NotifierWeCom:
+			if n.URL == "" {
+				v.add(path+".url", "is required for %s", n.Type)
+			}
+		case types.NotifierEmail:
+			if n.SMTP.Host == "" || n.SMTP.From == "" || len(n.SMTP.To) == 0 {
+				v.add(path+".smtp", "host/from/to is required for email")
+			}
+		default:
+			v.add(path+".type", "%q is not one of webhook, slack, dingtalk, feishu, wecom, email", n.Type)
+		}
+		for j, e := range n.Events {
+			if e != types.NotifyEventDigest && e != types.NotifyEventAlert {
+				v.add(index(path+".events", j), "%q is not one of digest, alert", e)
+			}
+		}
+		switch data.AlertSeverity(n.MinSeverity) {
+		case "", data.AlertSeverityInfo, data.AlertSeverityWarning, data.AlertSeverityCritical:
+		default:
+			v.add(path+".min_severity", "%q is not one of info, warning, critical", n.MinSeverity)
+		}
+		if _, err := notify.NewTemplates(n.Templates.Digest, n.Templates.Alert); err != nil {
+			v.add(path+".templates", "%v", err)
+		}
+	}
+}
+
+// verifyAssumeRole check the role of the account and the roles of its members
+func verifyAssumeRole(v *validator, path string, a types.CloudAccount) {
+	reg, _ := providers.Lookup(a.Provider)
+	c := reg.Capabilities
+	if r := a.AssumeRole; r != nil {
+		path := path + ".assume_role"
+		switch {
+		case !c.AssumeRole:
+			v.add(path, "provider[%s] does not support assume_role", a.Provider)
+		case c.Agency && (r.DomainName == "" || r.AgencyName == ""):
+			v.add(path, "domain_name/agency_name is required for %s", a.Provider)
+		case !c.Agency && r.RoleArn == "":
+			v.add(path+".role_arn", "is required")
+		case !c.ExternalID && r.ExternalID != "":
+			v.add(path+".external_id", "is not supported by %s", a.Provider)
+		}
+	}
+	if d := a.DiscoverMembers; d != nil && d.Enabled {
+		path := path + ".discover_members"
+		switch {
+		case !c.MemberDiscovery:
+			v.add(path, "provider[%s] does not support discover_members", a.Provider)
+		case a.AssumeRole != nil:
+			v.add(path, "requires the credential of the management account, not assume_role")
+		case c.MemberRoleRequired && d.RoleName == "":
+			v.add(path+".role_name", "is required for %s", a.Provider)
+		case !c.ExternalID && d.ExternalID != "":
+			v.add(path+".external_id", "is not supported by %s", a.Provider)
+		}
+	}
+}
+
+func verifyGrant(v *validator, path string, g types.Grant) {
+	if g.Role != types.RoleAdmin && g.Role != types.RoleViewer {
+		v.add(path+".role", "%q is not one of admin, viewer", g.Role)
+	}
+}
+
+// verifyBudgets check the budgets in the config
+func (c Config) verifyBudgets(v *validator) {
+	for i, b := range c.Budgets {
+		path := index("budgets", i)
+		if b.Name == "" {
+			v.add(path+".name", "is required")
+		}
+		switch b.Scope {
+		case types.BudgetScopeAll:
+		case types.BudgetScopeAccount, types.BudgetScopeProvider, types.BudgetScopeProduct:
+			if b.Target == "" {
+				v.add(path+".target", "is required for scope %s", b.Scope)
+			}
+		default:
+			v.add(path+".scope", "%q is not one of all, account, provider, product", b.Scope)
+		}
+		if b.Period != types.BudgetPeriodMonthly && b.Period != types.BudgetPeriodQuarterly {
+			v.add(path+".period", "%q is not one of monthly, quarterly", b.Period)
+		}
+		if b.Amount <= 0 {
+			v.add(path+".amount", "must be greater than 0")
+		}
+		for j, t := range b.Thresholds {
+			if t <= 0 {
+				v.add(index(path+".thresholds", j), "must be greater than 0")
+			}
+		}
+	}
+}
+
+// GetCostBasis providerTypes.CostBasisLegacy if unset, so the amounts do not change on upgrade
+func (c Config) GetCostBasis() providerTypes.CostBasis {
+	return c.CostBasis
+}
+
+// GetLanguage
+func (c Config) GetLanguage() i18n.Language {
+	l, _ := i18n.ParseLanguage(c.Language)
+	if l == "" {
+		return i18n.Default
+	}
+	return l
 }
 
 func GetGlobalConfig() *Config {
@@ -1,7 +1,15 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	providerTypes "github.com/galaxy-future/costpilot/internal/providers/types"
+	"github.com/galaxy-future/costpilot/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestInitConfig(t *testing.T) {
@@ -48,3 +56,60 @@ func TestInitFromEnvConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadConfig_Credentials(t *testing.T) {
+	dir := t.TempDir()
+	skFile := filepath.Join(dir, "sk")
+	require.NoError(t, os.WriteFile(skFile, []byte("file-sk\n"), 0600))
+	t.Setenv("COSTPILOT_TEST_AK", "env-ak")
+	confFile := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(confFile, []byte(`cloud_accounts:
+  - provider: AlibabaCloud
+    ak: ${COSTPILOT_TEST_AK}
+    sk_file: `+skFile+`
+    region_id: cn-beijing
+  - provider: AWSCloud
+    default_chain: true
+    profile: billing
+    region_id: us-east-1
+`), 0600))
+
+	c, err := loadConfig(confFile)
+	require.NoError(t, err)
+	assert.Equal(t, providerTypes.Credential{AK: "env-ak", SK: "file-sk"}, c.CloudAccounts[0].Credential())
+	assert.Equal(t, "AlibabaCloud-env-ak", c.CloudAccounts[0].Name)
+	assert.Equal(t, providerTypes.Credential{DefaultChain: true, Profile: "billing"}, c.CloudAccounts[1].Credential())
+	assert.Equal(t, "AWSCloud-billing", c.CloudAccounts[1].Name)
+
+	require.NoError(t, os.WriteFile(confFile, []byte("cloud_accounts:\n  - provider: BaiduCloud\n    default_chain: true\n"), 0600))
+	_, err = loadConfig(confFile)
+	assert.EqualError(t, err, "cloud_accounts[0].default_chain: provider[BaiduCloud] has no default credential chain, ak/sk is required")
+}
+
+func TestVerifyAssumeRole(t *testing.T) {
+	tests := []struct {
+		account types.CloudAccount
+		err     string
+	}{
+		{account: types.CloudAccount{Provider: cloud.AWSCloud, AssumeRole: &providerTypes.AssumeRole{RoleArn: "arn:aws:iam::1:role/r", ExternalID: "x"}}},
+		{account: types.CloudAccount{Provider: cloud.HuaweiCloud, AssumeRole: &providerTypes.AssumeRole{DomainName: "d", AgencyName: "a"}}},
+		{account: types.CloudAccount{Provider: cloud.HuaweiCloud, AssumeRole: &providerTypes.AssumeRole{RoleArn: "r"}}, err: "a.assume_role: domain_name/agency_name is required for HuaweiCloud"},
+		{account: types.CloudAccount{Provider: cloud.AlibabaCloud, AssumeRole: &providerTypes.AssumeRole{RoleArn: "r", ExternalID: "x"}}, err: "a.assume_role.external_id: is not supported by AlibabaCloud"},
+		{account: types.CloudAccount{Provider: cloud.BaiduCloud, AssumeRole: &providerTypes.AssumeRole{RoleArn: "r"}}, err: "a.assume_role: provider[BaiduCloud] does not support assume_role"},
+		{account: types.CloudAccount{Provider: cloud.AlibabaCloud, DiscoverMembers: &types.DiscoverMembers{Enabled: true}}},
+		{account: types.CloudAccount{Provider: cloud.HuaweiCloud, DiscoverMembers: &types.DiscoverMembers{Enabled: true}}, err: "a.discover_members: provider[HuaweiCloud] does not support discover_members"},
+		{account: types.CloudAccount{Provider: cloud.TencentCloud, DiscoverMembers: &types.DiscoverMembers{Enabled: true}}, err: "a.discover_members.role_name: is required for TencentCloud"},
+		{account: types.CloudAccount{Provider: cloud.AWSCloud, AssumeRole: &providerTypes.AssumeRole{RoleArn: "r"}, DiscoverMembers: &types.DiscoverMembers{Enabled: true}},
+			err: "a.discover_members: requires the credential of the management account, not assume_role"},
+	}
+	for _, tt := range tests {
+		v := &validator{}
+		verifyAssumeRole(v, "a", tt.account)
+		err := v.err()
+		if tt.err == "" {
+			assert.NoError(t, err)
+		} else {
+			assert.EqualError(t, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	"github.com/galaxy-future/costpilot/internal/types"
+)
+
+const _maxEnvAccounts = 1000 // guards against a typo index allocating a huge list
+
+var _indexedAccountEnv = regexp.MustCompile(`^` + COSTPILOT_ACCOUNTS + `_(\d+)_(.+)$`)
+
+// applyEnv override the config by the environment variables, returns the names of the applied ones. The accounts are
+// replaced by COSTPILOT_PROVIDER or COSTPILOT_ACCOUNTS_JSON, then patched or appended by COSTPILOT_ACCOUNTS_<index>_<FIELD>
+func (c *Config) applyEnv() ([]string, error) {
+	var applied []string
+	lookup := func(name string) (string, bool) {
+		v := os.Getenv(name)
+		if v == "" {
+			return "", false
+		}
+		applied = append(applied, name)
+		return v, true
+	}
+	lookupBool := func(name string, b *bool) error {
+		v, ok := lookup(name)
+		if !ok {
+			return nil
+		}
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %s", name, v)
+		}
+		*b = parsed
+		return nil
+	}
+
+	if err := lookupBool(COSTPILOT_DAEMON, &c.Daemon.Enabled); err != nil {
+		return nil, err
+	}
+	if err := lookupBool(COSTPILOT_EXPORTER, &c.Exporter.Enabled); err != nil {
+		return nil, err
+	}
+	if v, ok := lookup(COSTPILOT_SCHEDULE); ok {
+		c.Daemon.Schedule = v
+		c.Exporter.Schedule = v
+	}
+	if v, ok := lookup(COSTPILOT_LANGUAGE); ok {
+		c.Language = v
+	}
+
+	if p, ok := lookup(COSTPILOT_PROVIDER); ok {
+		account := types.CloudAccount{Provider: cloud.Provider(p)}
+		account.AK, _ = lookup(COSTPILOT_AK)
+		account.SK, _ = lookup(COSTPILOT_SK)
+		account.SKFile, _ = lookup(COSTPILOT_SK_FILE)
+		account.RegionID, _ = lookup(COSTPILOT_REGION_ID)
+		if err := lookupBool(COSTPILOT_CHAIN, &account.DefaultChain); err != nil {
+			return nil, err
+		}
+		account.Name = account.AK
+		c.CloudAccounts = []types.CloudAccount{account}
+	}
+	if v, ok := lookup(COSTPILOT_ACCOUNTS_JSON); ok {
+		var accounts []types.CloudAccount
+		if err := json.Unmarshal([]byte(v), &accounts); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", COSTPILOT_ACCOUNTS_JSON, err)
+		}
+		c.CloudAccounts = accounts
+	}
+
+	accounts, indexed, err := applyIndexedAccountEnv(c.CloudAccounts, os.Environ())
+	if err != nil {
+		return nil, err
+	}
+	c.CloudAccounts = accounts
+	return append(applied, indexed...), nil
+}
+
+// applyIndexedAccountEnv set the fields of COSTPILOT_ACCOUNTS_<index>_<FIELD> to the account at the index, appended if
+// out of the list. FIELD is the json name in upper case, nested by '_', eg: COSTPILOT_ACCOUNTS_1_ASSUME_ROLE_ROLE_ARN
+func applyIndexedAccountEnv(accounts []types.CloudAccount, environ []string) ([]types.CloudAccount, []string, error) {
+	fields := map[int]map[string]string{}
+	var applied []string
+	for _, kv := range environ {
+		i := strings.IndexByte(kv, '=')
+		if i < 0 || kv[i+1:] == "" {
+			continue
+		}
+		m := _indexedAccountEnv.FindStringSubmatch(kv[:i])
+		if m == nil {
+			continue
+		}
+		index, err := strconv.Atoi(m[1])
+		if err != nil || index >= _maxEnvAccounts {
+			return nil, nil, fmt.Errorf("invalid account index of %s", kv[:i])
+		}
+		if fields[index] == nil {
+			fields[index] = map[string]string{}
+		}
+		fields[index][m[2]] = kv[i+1:]
+		applied = append(applied, kv[:i])
+	}
+	if len(fields) == 0 {
+		return accounts, nil, nil
+	}
+
+	indexes := make([]int, 0, len(fields))
+	for index := range fields {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	if last := indexes[len(indexes)-1]; last >= len(accounts) {
+		accounts = append(accounts, make([]types.CloudAccount, last+1-len(accounts))...)
+	}
+	for _, index := range indexes {
+		values := fields[index]
+		if err := setEnvFields(reflect.ValueOf(&accounts[index]).Elem(), "", values); err != nil {
+			return nil, nil, fmt.Errorf("%s_%d_%v", COSTPILOT_ACCOUNTS, index, err)
+		}
+		for name := range values {
+			return nil, nil, fmt.Errorf("unknown environment variable %s_%d_%s", COSTPILOT_ACCOUNTS, index, name)
+		}
+	}
+	sort.Strings(applied)
+	return accounts, applied, nil
+}
+
+// setEnvFields set the fields of the struct by their names, the names set are deleted from values
+func setEnvFields(v reflect.Value, prefix string, values map[string]string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if tag == "" || tag == "-" {
+			continue
+		}
+		name := prefix + strings.ToUpper(tag)
+		field := v.Field(i)
+
+		structType := field.Type()
+		if structType.Kind() == reflect.Ptr {
+			structType = structType.Elem()
+		}
+		if structType.Kind() == reflect.Struct {
+			if !hasEnvPrefix(values, name+"_") {
+				continue
+			}
+			if field.Kind() == reflect.Ptr {
+				if field.IsNil() {
+					field.Set(reflect.New(structType))
+				}
+				field = field.Elem()
+			}
+			if err := setEnvFields(field, name+"_", values); err != nil {
+				return err
+			}
+			continue
+		}
+
+		s, ok := values[name]
+		if !ok {
+			continue
+		}
+		delete(values, name)
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(s)
+		case reflect.Bool:
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return fmt.Errorf("%s: invalid bool %s", name, s)
+			}
+			field.SetBool(b)
+		case reflect.Int, reflect.Int32, reflect.Int64:
+			n, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return fmt.Errorf("%s: invalid integer %s", name, s)
+			}
+			field.SetInt(n)
+		case reflect.Slice:
+			if field.Type().Elem().Kind() != reflect.String {
+				return fmt.Errorf("%s: is not supported by the environment", name)
+			}
+			var items []string
+			for _, item := range strings.Split(s, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+			field.Set(reflect.ValueOf(items).Convert(field.Type()))
+		default:
+			return fmt.Errorf("%s: is not supported by the environment", name)
+		}
+	}
+	return nil
+}
+
+func hasEnvPrefix(values map[string]string, prefix string) bool {
+	for name := range values {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	providerTypes "github.com/galaxy-future/costpilot/internal/providers/types"
+	"github.com/galaxy-future/costpilot/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyEnv_Legacy(t *testing.T) {
+	t.Setenv(COSTPILOT_PROVIDER, "AlibabaCloud")
+	t.Setenv(COSTPILOT_AK, "ak")
+	t.Setenv(COSTPILOT_SK, "sk")
+	t.Setenv(COSTPILOT_REGION_ID, "cn-beijing")
+	t.Setenv(COSTPILOT_DAEMON, "true")
+	t.Setenv(COSTPILOT_SCHEDULE, "@daily")
+
+	c := &Config{CloudAccounts: []types.CloudAccount{{Provider: cloud.AWSCloud, AK: "file-ak", SK: "file-sk"}}}
+	applied, err := c.applyEnv()
+	require.NoError(t, err)
+	assert.Equal(t, []types.CloudAccount{{Provider: cloud.AlibabaCloud, AK: "ak", SK: "sk", RegionID: "cn-beijing", Name: "ak"}}, c.CloudAccounts)
+	assert.True(t, c.Daemon.Enabled)
+	assert.Equal(t, "@daily", c.Daemon.Schedule)
+	assert.Equal(t, "@daily", c.Exporter.Schedule)
+	assert.Contains(t, applied, COSTPILOT_SK)
+
+	t.Setenv(COSTPILOT_DAEMON, "yes")
+	_, err = (&Config{}).applyEnv()
+	assert.EqualError(t, err, "invalid COSTPILOT_DAEMON: yes")
+}
+
+func TestApplyEnv_Accounts(t *testing.T) {
+	t.Setenv(COSTPILOT_ACCOUNTS_JSON, `[{"provider":"AWSCloud","ak":"a1","sk":"s1","region_id":"us-east-1"},{"provider":"TencentCloud","default_chain":true}]`)
+	t.Setenv("COSTPILOT_ACCOUNTS_1_REGION_ID", "ap-guangzhou")
+	t.Setenv("COSTPILOT_ACCOUNTS_2_PROVIDER", "AWSCloud")
+	t.Setenv("COSTPILOT_ACCOUNTS_2_DEFAULT_CHAIN", "true")
+	t.Setenv("COSTPILOT_ACCOUNTS_2_ASSUME_ROLE_ROLE_ARN", "arn:aws:iam::123:role/billing")
+	t.Setenv("COSTPILOT_ACCOUNTS_2_ASSUME_ROLE_DURATION_SECONDS", "900")
+	t.Setenv("COSTPILOT_ACCOUNTS_2_DISCOVER_MEMBERS_EXCLUDE", "111, 222")
+
+	c := &Config{CloudAccounts: []types.CloudAccount{{Provider: cloud.BaiduCloud}}}
+	applied, err := c.applyEnv()
+	require.NoError(t, err)
+	require.Len(t, c.CloudAccounts, 3)
+	assert.Equal(t, types.CloudAccount{Provider: cloud.AWSCloud, AK: "a1", SK: "s1", RegionID: "us-east-1"}, c.CloudAccounts[0])
+	assert.Equal(t, types.CloudAccount{Provider: cloud.TencentCloud, DefaultChain: true, RegionID: "ap-guangzhou"}, c.CloudAccounts[1])
+	assert.Equal(t, types.CloudAccount{
+		Provider:        cloud.AWSCloud,
+		DefaultChain:    true,
+		AssumeRole:      &providerTypes.AssumeRole{RoleArn: "arn:aws:iam::123:role/billing", DurationSeconds: 900},
+		DiscoverMembers: &types.DiscoverMembers{Exclude: []string{"111", "222"}},
+	}, c.CloudAccounts[2])
+	assert.Len(t, applied, 7)
+}
+
+func TestApplyIndexedAccountEnv_Error(t *testing.T) {
+	tests := []struct {
+		name    string
+		environ []string
+		wantErr string
+	}{
+		{name: "unknown", environ: []string{"COSTPILOT_ACCOUNTS_0_SECRET=x"}, wantErr: "unknown environment variable COSTPILOT_ACCOUNTS_0_SECRET"},
+		{name: "bool", environ: []string{"COSTPILOT_ACCOUNTS_0_DEFAULT_CHAIN=on"}, wantErr: "COSTPILOT_ACCOUNTS_0_DEFAULT_CHAIN: invalid bool on"},
+		{name: "index", environ: []string{"COSTPILOT_ACCOUNTS_5000_AK=x"}, wantErr: "invalid account index of COSTPILOT_ACCOUNTS_5000_AK"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := applyIndexedAccountEnv(nil, tt.environ)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	providerTypes "github.com/galaxy-future/costpilot/internal/providers/types"
+	"github.com/galaxy-future/costpilot/internal/types"
+	"gopkg.in/yaml.v2"
+)
+
+// schema the json schema of a config value, built from the yaml tags of the go types
+type schema struct {
+	Type                 string             `json:"type"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Properties           map[string]*schema `json:"properties,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"` // false, or the schema of the map values
+	Items                *schema            `json:"items,omitempty"`
+}
+
+var (
+	// _typeEnums the values of the string types, an empty value means the default and is left to verify,
+	// the providers are the registered ones, see configSchema
+	_typeEnums = map[reflect.Type][]string{
+		reflect.TypeOf(providerTypes.CostBasis("")): {string(providerTypes.CostBasisList), string(providerTypes.CostBasisDiscount),
+			string(providerTypes.CostBasisNet), string(providerTypes.CostBasisTaxInclusive)},
+		reflect.TypeOf(providerTypes.MetricItem("")): providerTypes.MetricItemNames(),
+		reflect.TypeOf(types.Role("")):               {string(types.RoleAdmin), string(types.RoleViewer)},
+		reflect.TypeOf(types.NotifierType("")): {string(types.NotifierWebhook), string(types.NotifierSlack), string(types.NotifierDingTalk),
+			string(types.NotifierFeishu), string(types.NotifierWeCom), string(types.NotifierEmail)},
+		reflect.TypeOf(types.NotifyEvent("")):  {string(types.NotifyEventDigest), string(types.NotifyEventAlert)},
+		reflect.TypeOf(types.BudgetScope("")):  {string(types.BudgetScopeAll), string(types.BudgetScopeAccount), string(types.BudgetScopeProvider), string(types.BudgetScopeProduct)},
+		reflect.TypeOf(types.BudgetPeriod("")): {string(types.BudgetPeriodMonthly), string(types.BudgetPeriodQuarterly)},
+	}
+	// _pathEnums the values of the plain strings, by the path with the list indexes left out
+	_pathEnums = map[string][]string{
+		"notification.notifiers[].min_severity": {"info", "warning", "critical"},
+		"export.formats[]":                      {types.ExportJSON, types.ExportCSV, types.ExportXLSX, types.ExportParquet},
+	}
+)
+
+// configSchema built on use, as the out-of-tree providers may be registered after the package init
+func configSchema() *schema {
+	return buildSchema(reflect.TypeOf(Config{}), "")
+}
+
+func buildSchema(t reflect.Type, path string) *schema {
+	if t == reflect.TypeOf(time.Duration(0)) {
+		return &schema{Type: "string", Pattern: `^([0-9]+(\.[0-9]+)?(ns|us|ms|s|m|h))+$`}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return buildSchema(t.Elem(), path)
+	case reflect.String:
+		s := &schema{Type: "string", Enum: _typeEnums[t]}
+		if t == reflect.TypeOf(cloud.Provider("")) {
+			s.Enum = providerNames()
+		}
+		if enum, ok := _pathEnums[path]; ok {
+			s.Enum = enum
+		}
+		return s
+	case reflect.Bool:
+		return &schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &schema{Type: "array", Items: buildSchema(t.Elem(), path+"[]")}
+	case reflect.Map:
+		return &schema{Type: "object", AdditionalProperties: buildSchema(t.Elem(), path+".*")}
+	case reflect.Struct:
+		s := &schema{Type: "object", Properties: map[string]*schema{}, AdditionalProperties: false}
+		addProperties(s, t, path)
+		return s
+	}
+	panic(fmt.Sprintf("config schema: unsupported type %s of %s", t, path))
+}
+
+// addProperties the inline structs share the properties of their parent
+func addProperties(s *schema, t reflect.Type, path string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		tag := strings.Split(f.Tag.Get("yaml"), ",")
+		if len(tag) > 1 && tag[1] == "inline" {
+			addProperties(s, f.Type, path)
+			continue
+		}
+		name := tag[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fieldPath := name
+		if path != "" {
+			fieldPath = path + "." + name
+		}
+		s.Properties[name] = buildSchema(f.Type, fieldPath)
+	}
+}
+
+// JSONSchema the json schema of config.yaml for the editors
+func JSONSchema() ([]byte, error) {
+	doc := struct {
+		Schema string `json:"$schema"`
+		Title  string `json:"title"`
+		*schema
+	}{Schema: "http://json-schema.org/draft-07/schema#", Title: "CostPilot config", schema: configSchema()}
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// checkSchema the unknown keys, the type errors and the invalid enums of the yaml document
+func checkSchema(data []byte) error {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	v := &validator{}
+	configSchema().check(v, "", doc)
+	return v.err()
+}
+
+func (s *schema) check(v *validator, path string, value interface{}) {
+	if value == nil { // null is the zero value
+		return
+	}
+	switch s.Type {
+	case "object":
+		m, ok := value.(map[interface{}]interface{})
+		if !ok {
+			v.add(path, "expected a mapping, got %s", describe(value))
+			return
+		}
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, fmt.Sprint(k))
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := k
+			if path != "" {
+				child = path + "." + k
+			}
+			if prop, ok := s.Properties[k]; ok {
+				prop.check(v, child, lookupKey(m, k))
+			} else if values, ok := s.AdditionalProperties.(*schema); ok {
+				values.check(v, child, lookupKey(m, k))
+			} else if similar := s.similarProperty(k); similar != "" {
+				v.add(child, "unknown field, did you mean %q?", similar)
+			} else {
+				v.add(child, "unknown field")
+			}
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			v.add(path, "expected a list, got %s", describe(value))
+			return
+		}
+		for i, item := range items {
+			s.Items.check(v, index(path, i), item)
+		}
+	case "string":
+		switch value.(type) {
+		case map[interface{}]interface{}, []interface{}:
+			v.add(path, "expected a string, got %s", describe(value))
+			return
+		}
+		str := fmt.Sprint(value)
+		if s.Pattern != "" {
+			if _, err := time.ParseDuration(str); err != nil {
+				v.add(path, "%q is not a duration, eg: 30m or 12h", str)
+			}
+		}
+		if len(s.Enum) > 0 && str != "" && !contains(s.Enum, str) {
+			v.add(path, "%q is not one of %s", str, strings.Join(s.Enum, ", "))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			v.add(path, "expected true or false, got %s", describe(value))
+		}
+	case "integer":
+		switch value.(type) {
+		case int, int64, uint64:
+		default:
+			v.add(path, "expected an integer, got %s", describe(value))
+		}
+	case "number":
+		switch value.(type) {
+		case int, int64, uint64, float64:
+		default:
+			v.add(path, "expected a number, got %s", describe(value))
+		}
+	}
+}
+
+func lookupKey(m map[interface{}]interface{}, key string) interface{} {
+	for k, v := range m {
+		if fmt.Sprint(k) == key {
+			return v
+		}
+	}
+	return nil
+}
+
+// similarProperty a known property at most 2 edits away, as a hint of the typo
+func (s *schema) similarProperty(key string) string {
+	best, bestDistance := "", 3
+	for name := range s.Properties {
+		if d := editDistance(key, name); d < bestDistance || (d == bestDistance && name < best) {
+			best, bestDistance = name, d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, minInt(cur[j-1]+1, prev[j-1]+cost))
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func describe(value interface{}) string {
+	switch value.(type) {
+	case map[interface{}]interface{}:
+		return "a mapping"
+	case []interface{}:
+		return "a list"
+	case string:
+		return fmt.Sprintf("%q", value)
+	}
+	return fmt.Sprint(value)
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldError an invalid value of the config, at the path like cloud_accounts[2].region_id
+type FieldError struct {
+	Path    string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// ValidationError all the invalid values of the config, one per line
+type ValidationError []FieldError
+
+func (e ValidationError) Error() string {
+	lines := make([]string, 0, len(e))
+	for _, f := range e {
+		lines = append(lines, f.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// validator collects the field errors instead of returning the first one
+type validator struct {
+	errs ValidationError
+}
+
+func (v *validator) add(path, format string, args ...interface{}) {
+	v.errs = append(v.errs, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// err nil if nothing is collected, the nil ValidationError is not a nil error
+func (v *validator) err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+func index(path string, i int) string {
+	return fmt.Sprintf("%s[%d]", path, i)
+}
+
+// Validate the config file merged with the environment as Init does, the credentials are resolved too
+func Validate(confPath string) error {
+	c, err := readConfig(confPath)
+	if err != nil {
+		return err
+	}
+	if _, err = c.applyEnv(); err != nil {
+		return err
+	}
+	return c.prepare()
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	"github.com/galaxy-future/costpilot/internal/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckSchema(t *testing.T) {
+	err := checkSchema([]byte(`cloud_accounts:
+  - provider: AlibabaCloud
+    ak: 123
+    sk: abc
+    regoin_id: cn-beijing
+  - provider: GoogleCloud
+    default_chain: "yes"
+budgets:
+  - name: monthly
+    amount: lots
+    thresholds: 80
+auth:
+  session_ttl: 1d
+notification:
+  notifiers:
+    - name: ops
+      type: slack
+      headers:
+        X-Token: abc
+      events: [digest, weekly]
+utilization_analysis:
+  metrics: [disk.used.utilization, gpu.utilization]
+foo: bar
+`))
+	require.IsType(t, ValidationError{}, err)
+	assert.Equal(t, ValidationError{
+		{Path: "auth.session_ttl", Message: `"1d" is not a duration, eg: 30m or 12h`},
+		{Path: "budgets[0].amount", Message: `expected a number, got "lots"`},
+		{Path: "budgets[0].thresholds", Message: "expected a list, got 80"},
+		{Path: "cloud_accounts[0].regoin_id", Message: `unknown field, did you mean "region_id"?`},
+		{Path: "cloud_accounts[1].default_chain", Message: `expected true or false, got "yes"`},
+		{Path: "cloud_accounts[1].provider", Message: `"GoogleCloud" is not one of AWSCloud, AlibabaCloud, BaiduCloud, HuaweiCloud, TencentCloud`},
+		{Path: "foo", Message: "unknown field"},
+		{Path: "notification.notifiers[0].events[1]", Message: `"weekly" is not one of digest, alert`},
+		{Path: "utilization_analysis.metrics[1]", Message: `"gpu.utilization" is not one of cpu.utilization, memory.used.utilization, ` +
+			`disk.used.utilization, disk.read.iops, disk.write.iops, disk.read.throughput, disk.write.throughput, ` +
+			`network.in.bandwidth, network.out.bandwidth, load.average`},
+	}, err)
+
+	assert.NoError(t, checkSchema([]byte("cloud_accounts:\n  - provider: AWSCloud\n    assume_role:\n      duration_seconds: 900\n")))
+}
+
+func TestVerify_Paths(t *testing.T) {
+	dir := t.TempDir()
+	confFile := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(confFile, []byte(`cloud_accounts:
+  - provider: AlibabaCloud
+    ak: a1
+    sk: s1
+    region_id: cn-xx
+    name: prod
+  - provider: AWSCloud
+    ak: a2
+    region_id: us-east-1
+    name: prod
+  - provider: BaiduCloud
+    ak: a3
+    sk: s3
+    region_id: BJ
+budgets:
+  - name: q
+    scope: account
+    period: quarterly
+    amount: 100
+daemon:
+  enabled: true
+  schedule: every day
+`), 0600))
+
+	err := Validate(confFile)
+	assert.EqualError(t, err, `cloud_accounts[0].region_id: "cn-xx" is not a known AlibabaCloud region
+cloud_accounts[1].sk: is required, or set sk_file, vault or default_chain
+cloud_accounts[1].name: "prod" is already the name of cloud_accounts[0]
+daemon.schedule: invalid cron "every day", expect 5 fields
+budgets[0].target: is required for scope account`)
+}
+
+func TestJSONSchema(t *testing.T) {
+	b, err := JSONSchema()
+	require.NoError(t, err)
+	committed, err := os.ReadFile("../../conf/config.schema.json")
+	require.NoError(t, err)
+	assert.Equal(t, string(committed), string(b), "conf/config.schema.json is stale, run: go run . config schema > conf/config.schema.json")
+}
+
+func TestVerifyRegionPatterns(t *testing.T) {
+	aws, _ := providers.Lookup(cloud.AWSCloud)
+	baidu, _ := providers.Lookup(cloud.BaiduCloud)
+	v := &validator{}
+	verifyRegionPatterns(v, "regions", aws, []string{"us-*", "eu-west-1", "us-east-9", "ap-[", "BJ"})
+	verifyRegionPatterns(v, "exclude_regions", baidu, []string{"bj", "gz"})
+	assert.Equal(t, ValidationError{
+		{Path: "regions[2]", Message: `"us-east-9" is not a known AWSCloud region`},
+		{Path: "regions[3]", Message: `"ap-[" is not a valid pattern`},
+		{Path: "regions[4]", Message: `"BJ" is not a known AWSCloud region`},
+	}, v.errs)
+}
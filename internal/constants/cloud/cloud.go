@@ -11,28 +11,12 @@ const (
 	AWSCloud              = "AWSCloud"
 )
 
+// String the providers are registered by their packages, see providers.Lookup for the known ones
 func (p Provider) String() string {
-	switch p {
-	case AlibabaCloud, HuaweiCloud, TencentCloud, BaiduCloud, AWSCloud:
-		return string(p)
+	if p == "" {
+		return Undefined
 	}
-	return Undefined
-}
-
-func (p Provider) StringCN() string {
-	switch p {
-	case AlibabaCloud:
-		return "阿里云"
-	case HuaweiCloud:
-		return "华为"
-	case TencentCloud:
-		return "腾讯"
-	case BaiduCloud:
-		return "百度"
-	case AWSCloud:
-		return "AWS"
-	}
-	return Undefined
+	return string(p)
 }
 
 type SubscriptionType string
@@ -51,13 +35,3 @@ func (s SubscriptionType) String() string {
 	}
 	return Undefined
 }
-
-func (s SubscriptionType) StringCN() string {
-	switch s {
-	case PrePaid:
-		return "包年包月"
-	case PostPaid:
-		return "按量付费"
-	}
-	return Undefined
-}
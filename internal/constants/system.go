@@ -2,6 +2,24 @@ package constants
 
 const JsDataFile = "static/analysis/data-set.js"
 
+const AnomalyJsonFile = "static/analysis/anomalies.json"
+
+const IdleInstanceCsvFile = "static/analysis/idle-instances.csv"
+
 func GetJsDataPath() string {
 	return "website/" + JsDataFile
 }
+
+// GetJsDataStagingPath the data file is assembled here, then renamed to GetJsDataPath,
+// so the website never reads a partial one
+func GetJsDataStagingPath() string {
+	return GetJsDataPath() + ".tmp"
+}
+
+func GetAnomalyJsonPath() string {
+	return "website/" + AnomalyJsonFile
+}
+
+func GetIdleInstanceCsvPath() string {
+	return "website/" + IdleInstanceCsvFile
+}
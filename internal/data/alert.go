@@ -0,0 +1,30 @@
+package data
+
+import "time"
+
+type (
+	AlertKind     string
+	AlertSeverity string
+)
+
+const (
+	AlertKindBudget     AlertKind = "budget"
+	AlertKindAnomaly    AlertKind = "anomaly"
+	AlertKindCommitment AlertKind = "commitment"
+
+	AlertSeverityInfo     AlertSeverity = "info"
+	AlertSeverityWarning  AlertSeverity = "warning"
+	AlertSeverityCritical AlertSeverity = "critical"
+)
+
+// AlertEvent is raised by the analysis pipelines and delivered by notifiers
+type AlertEvent struct {
+	Kind     AlertKind         `json:"kind"`
+	Severity AlertSeverity     `json:"severity"`
+	Title    string            `json:"title"`
+	Message  string            `json:"message"`
+	Labels   map[string]string `json:"labels"` // eg: budget/account/provider/product
+	Time     time.Time         `json:"time"`
+	// Key the same for the event raised again by the next runs, which is delivered once, eg: budget/b1/2022-10-01/80
+	Key string `json:"key"`
+}
@@ -0,0 +1,35 @@
+package data
+
+import "github.com/galaxy-future/costpilot/internal/constants/cloud"
+
+type AnomalyDirection string
+
+const (
+	AnomalyDirectionSpike AnomalyDirection = "spike"
+	AnomalyDirectionDrop  AnomalyDirection = "drop"
+)
+
+type AnomalyContributor struct {
+	PipCode     string  `json:"pip_code"`
+	ProductName string  `json:"product_name"`
+	Amount      float64 `json:"amount"`
+	Baseline    float64 `json:"baseline"`
+	Deviation   float64 `json:"deviation"` // amount - baseline
+}
+
+// CostAnomaly an unexpected daily cost of an account, or of a product in the account when PipCode is not empty
+type CostAnomaly struct {
+	Day          string               `json:"day"` // 2022-10-01
+	AccountName  string               `json:"account_name"`
+	Provider     cloud.Provider       `json:"provider"`
+	PipCode      string               `json:"pip_code"`
+	ProductName  string               `json:"product_name"`
+	Amount       float64              `json:"amount"`
+	Baseline     float64              `json:"baseline"` // median of the trailing window
+	Deviation    float64              `json:"deviation"`
+	Score        float64              `json:"score"` // robust z-score
+	Direction    AnomalyDirection     `json:"direction"`
+	Severity     AlertSeverity        `json:"severity"`
+	Currency     string               `json:"currency"`
+	Contributors []AnomalyContributor `json:"contributors"` // top contributing products of an account anomaly
+}
@@ -0,0 +1,26 @@
+package data
+
+import "github.com/galaxy-future/costpilot/internal/types"
+
+type BudgetState string
+
+const (
+	BudgetStateOK       BudgetState = "ok"
+	BudgetStateWarning  BudgetState = "warning"  // some threshold reached, or projected to exceed
+	BudgetStateExceeded BudgetState = "exceeded" // spent >= amount
+)
+
+type BudgetStatus struct {
+	Budget           types.Budget `json:"budget"`
+	PeriodStart      string       `json:"period_start"` // 2022-10-01
+	PeriodEnd        string       `json:"period_end"`   // 2022-10-31
+	ElapsedDays      int          `json:"elapsed_days"`
+	TotalDays        int          `json:"total_days"`
+	Spent            float64      `json:"spent"`     // period to date
+	Projected        float64      `json:"projected"` // linear projection to the end of period
+	Percent          float64      `json:"percent"`
+	ProjectedPercent float64      `json:"projected_percent"`
+	ReachedThreshold float64      `json:"reached_threshold"` // highest threshold reached by spent, 0 if none
+	Currency         string       `json:"currency"`
+	State            BudgetState  `json:"state"`
+}
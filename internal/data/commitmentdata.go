@@ -0,0 +1,68 @@
+package data
+
+import (
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+)
+
+// CommitmentUsage coverage = CoveredUsage / EligibleUsage, utilization = UsedCommitment / TotalCommitment
+type CommitmentUsage struct {
+	Type            string // ReservedInstance | SavingsPlan | Subscription
+	Unit            string // Hours | currency of spend
+	CoveredUsage    float64
+	EligibleUsage   float64
+	UsedCommitment  float64
+	TotalCommitment float64 // zero when utilization does not apply
+}
+
+// Commitment a reservation, savings plan or subscription instance
+type Commitment struct {
+	CommitmentId string
+	Type         string
+	Description  string
+	RegionId     string
+	Count        int
+	StartTime    time.Time
+	EndTime      time.Time
+	AutoRenew    bool
+}
+
+type Commitments struct {
+	Usages []CommitmentUsage
+	List   []Commitment
+}
+
+type AccountCommitments struct {
+	AccountName string
+	Provider    cloud.Provider
+	Commitments
+}
+
+// CommitmentCoverage coverage and utilization of one commitment type of an account, in percent
+type CommitmentCoverage struct {
+	AccountName    string
+	Provider       cloud.Provider
+	Type           string
+	Unit           string
+	CoveredUsage   float64
+	EligibleUsage  float64
+	Coverage       float64
+	HasUtilization bool
+	Utilization    float64
+}
+
+// ExpiringCommitment a commitment ending within WithinDays
+type ExpiringCommitment struct {
+	AccountName string
+	Provider    cloud.Provider
+	Commitment
+	DaysLeft   int
+	WithinDays int // the smallest expiring bucket, eg: 30 | 60 | 90
+}
+
+type CommitmentAnalysis struct {
+	Coverages []CommitmentCoverage // per account
+	Providers []CommitmentCoverage // summed over the accounts of a provider, AccountName is empty
+	Expiring  []ExpiringCommitment
+}
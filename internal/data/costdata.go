@@ -1,6 +1,10 @@
 package data
 
-import "github.com/galaxy-future/costpilot/internal/constants/cloud"
+import (
+	"sync"
+
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+)
 
 type ItemInProductBilling struct {
 	PipCode          string                 `json:"pip_code"`
@@ -14,15 +18,28 @@ type ProductBilling struct {
 	TotalAmount float64                `json:"total_amount"`
 	Items       []ItemInProductBilling `json:"Items"`
 }
+
+// IsGroupedByProduct the bills are grouped by product, those fetched without grouping are summed under the empty pip code
+func IsGroupedByProduct(productsBilling map[string]ProductBilling) bool {
+	for pipCode := range productsBilling {
+		if pipCode != "" {
+			return true
+		}
+	}
+	return false
+}
+
 type DailyBilling struct {
 	Day             string                    `json:"day"`              // 20220101
 	ProductsBilling map[string]ProductBilling `json:"products_billing"` // map['pip_code'] key = ecs
 	TotalAmount     float64                   `json:"total_amount"`
+	Amortized       bool                      `json:"amortized"` // prepaid charges are spread over their service period
 }
 type MonthlyBilling struct {
 	Month           string                    `json:"month"`            // 202201
 	ProductsBilling map[string]ProductBilling `json:"products_billing"` // map['product_name']
 	TotalAmount     float64                   `json:"total_amount"`
+	Amortized       bool                      `json:"amortized"`
 }
 type YearlyBilling struct {
 	Year        string  `json:"year"` // 2022
@@ -33,3 +50,22 @@ type AccountBilling struct {
 	YearsBilling map[string][]YearlyBilling `json:"years_billing"`
 	Provider     cloud.Provider             `json:"provider"`
 }
+
+// AccountBillingMap billing collected from one cloud account
+type AccountBillingMap struct {
+	AccountName   string
+	Provider      cloud.Provider
+	MonthsBilling *sync.Map // key : month , val : MonthlyBilling
+	DaysBilling   *sync.Map // key : day , val : DailyBilling
+}
+
+// PrepaidCharge a subscription charge of an instance, the amortized view spreads it over [Day, EndDay)
+type PrepaidCharge struct {
+	InstanceId  string
+	PipCode     string
+	ProductName string
+	Currency    string
+	Amount      float64
+	Day         string // charged day, 2022-01-02
+	EndDay      string // exclusive
+}
@@ -0,0 +1,10 @@
+package data
+
+import "github.com/galaxy-future/costpilot/internal/constants/cloud"
+
+// DiscoveryFailure the member accounts of the account are not discovered in the run, the configured accounts are still analyzed
+type DiscoveryFailure struct {
+	AccountName string         `json:"account"`
+	Provider    cloud.Provider `json:"provider"`
+	Reason      string         `json:"reason"`
+}
@@ -0,0 +1,19 @@
+package data
+
+type ForecastPoint struct {
+	Period string  `json:"period"` // 2022-11 | 2022
+	Amount float64 `json:"amount"`
+	Lower  float64 `json:"lower"` // 95% confidence band
+	Upper  float64 `json:"upper"`
+}
+
+type CostForecast struct {
+	Model              string          `json:"model"` // linear trend | linear trend + seasonality | run rate
+	Trend              float64         `json:"trend"` // fitted change per month
+	HistoryMonths      int             `json:"history_months"`
+	CurrentMonthActual float64         `json:"current_month_actual"` // month to date
+	CurrentMonth       ForecastPoint   `json:"current_month"`
+	NextMonths         []ForecastPoint `json:"next_months"`
+	YearToDate         float64         `json:"year_to_date"`
+	YearEnd            ForecastPoint   `json:"year_end"`
+}
@@ -0,0 +1,46 @@
+package data
+
+import (
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+)
+
+type InstanceUsageLevel string
+
+const (
+	InstanceUsageIdle          InstanceUsageLevel = "idle"
+	InstanceUsageUnderutilized InstanceUsageLevel = "underutilized"
+)
+
+// InstanceUsage average and peak utilization of an instance over a window, in percent
+type InstanceUsage struct {
+	InstanceId string
+	Days       int // days with cpu metrics
+	CpuAvg     float64
+	CpuPeak    float64
+	HasMemory  bool
+	MemoryAvg  float64
+	MemoryPeak float64
+}
+
+// IdleInstance an instance whose utilization stays below the thresholds over the window
+type IdleInstance struct {
+	AccountName      string
+	Provider         cloud.Provider
+	InstanceId       string
+	RegionId         string
+	RegionName       string
+	InstanceSpec     string
+	SubscriptionType cloud.SubscriptionType
+	Level            InstanceUsageLevel
+
+	Days       int // days with cpu metrics in the window
+	CpuAvg     float64
+	CpuPeak    float64
+	HasMemory  bool
+	MemoryAvg  float64
+	MemoryPeak float64
+
+	HasBill     bool
+	MonthlyCost float64 // estimated by the month to date instance bill
+	Currency    string
+}
@@ -0,0 +1,31 @@
+package data
+
+import (
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+)
+
+type OptimizationKind string
+
+const (
+	OptimizationTerminate OptimizationKind = "terminate" // idle instance
+	OptimizationDownsize  OptimizationKind = "downsize"  // smaller type in the same family
+)
+
+// OptimizationOpportunity a concrete action on one instance and its projected monthly savings
+type OptimizationOpportunity struct {
+	AccountName     string
+	Provider        cloud.Provider
+	InstanceId      string
+	RegionName      string
+	Kind            OptimizationKind
+	CurrentType     string
+	RecommendedType string // empty for terminate
+
+	CpuPeak    float64
+	HasMemory  bool
+	MemoryPeak float64
+
+	MonthlyCost    float64 // estimated by the instance bill, or the catalog price
+	MonthlySavings float64
+	Currency       string
+}
@@ -0,0 +1,21 @@
+package data
+
+import "github.com/galaxy-future/costpilot/internal/constants/cloud"
+
+// the analyses an unsupported capability leaves data out of, see Unsupported
+const (
+	AnalysisCost         = "cost"
+	AnalysisAmortization = "amortization"
+	AnalysisUtilization  = "utilization"
+	AnalysisInstanceCost = "instance_cost" // the cost of the idle and the rightsizing instances
+	AnalysisCommitment   = "commitment"
+	AnalysisMetric       = "metric" // the capability is the catalog metric not mapped by the provider
+)
+
+// Unsupported the provider of the account lacks the capability, the data is left out of the analysis rather than shown as zeros
+type Unsupported struct {
+	AccountName string         `json:"account"`
+	Provider    cloud.Provider `json:"provider"`
+	Capability  string         `json:"capability"` // see providers.Capability, or the metric of AnalysisMetric
+	Analysis    string         `json:"analysis"`
+}
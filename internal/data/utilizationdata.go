@@ -1,17 +1,22 @@
 package data
 
 import (
+	"sync"
+
 	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	providerTypes "github.com/galaxy-future/costpilot/internal/providers/types"
 )
 
 type InstanceCpuUtilization struct {
 	InstanceId      string
 	UsedUtilization float64 // CPU使用率
+	MaxUtilization  float64 // CPU峰值使用率, 0 if the provider does not report it
 }
 
 type InstanceMemoryUtilization struct {
 	InstanceId      string
 	UsedUtilization float64 // 内存使用率
+	MaxUtilization  float64 // 内存峰值使用率, 0 if the provider does not report it
 }
 
 type DailyCpuUtilization struct {
@@ -26,10 +31,66 @@ type DailyMemoryUtilization struct {
 	Utilization []InstanceMemoryUtilization `json:"utilization"`
 }
 
+// InstanceMetric a catalog metric of an instance in a day, in the unit of the catalog
+type InstanceMetric struct {
+	InstanceId string
+	Average    float64
+	Max        float64 // 0 if the provider does not report it
+}
+
+// DailyMetric a catalog metric besides cpu and memory of the instances in a day
+type DailyMetric struct {
+	Provider cloud.Provider
+	Metric   providerTypes.MetricItem
+	Day      string           `json:"day"`
+	Samples  []InstanceMetric `json:"samples"`
+}
+
 type InstanceDetail struct {
 	Provider         cloud.Provider
 	InstanceId       string
 	RegionId         string
 	RegionName       string
+	InstanceSpec     string // empty unless the inventory of the provider reports it
+	SubscriptionType cloud.SubscriptionType
+}
+
+// InstanceBill cost of one instance in a billing cycle, summed over its billing items
+type InstanceBill struct {
+	InstanceId       string
+	BillingCycle     string // 2022-10
+	Region           string
+	InstanceSpec     string
 	SubscriptionType cloud.SubscriptionType
+	Currency         string
+	PretaxAmount     float64
+}
+
+// AccountUtilizationMap utilization and instance bills of one cloud account
+type AccountUtilizationMap struct {
+	AccountName     string
+	Provider        cloud.Provider
+	DailyCpu        *sync.Map                              // key : day , val : data.DailyCpuUtilization
+	DailyMemory     *sync.Map                              // key : day , val : data.DailyMemoryUtilization
+	RecentInstances *sync.Map                              // key : provider:instanceId , val : data.InstanceDetail
+	InstanceBills   *sync.Map                              // key : instanceId , val : data.InstanceBill
+	DailyMetrics    map[providerTypes.MetricItem]*sync.Map // key : day , val : data.DailyMetric
+	RegionScan      RegionScan
+}
+
+// RegionScan the regions scanned for the instances of an account, empty if the provider lists them in one call
+type RegionScan struct {
+	Scanned []string     `json:"scanned"`
+	Skipped []RegionSkip `json:"skipped"` // left out by regions/exclude_regions, or not available to the account
+	Failed  []RegionSkip `json:"failed"`  // the client or the api failed, the instances of the region are missing
+}
+
+type RegionSkip struct {
+	RegionId string `json:"region_id"`
+	Reason   string `json:"reason"`
+}
+
+// IsEmpty no region is scanned, skipped or failed
+func (r RegionScan) IsEmpty() bool {
+	return len(r.Scanned) == 0 && len(r.Skipped) == 0 && len(r.Failed) == 0
 }
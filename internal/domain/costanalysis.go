@@ -6,8 +6,15 @@ import (
 	"sync"
 	"time"
 
+	"github.com/galaxy-future/costpilot/internal/config"
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/providers"
+	"github.com/galaxy-future/costpilot/internal/services/amortization"
+	"github.com/galaxy-future/costpilot/internal/services/anomaly"
+	"github.com/galaxy-future/costpilot/internal/services/budget"
 	"github.com/galaxy-future/costpilot/internal/services/databean"
 	"github.com/galaxy-future/costpilot/internal/services/template"
+	tmpl "github.com/galaxy-future/costpilot/internal/template"
 
 	"github.com/galaxy-future/costpilot/internal/constants/cloud"
 
@@ -17,18 +24,27 @@ import (
 )
 
 type CostAnalysisDomain struct {
-	nowT              time.Time
-	monthsBillingList []*sync.Map
-	daysBillingList   []*sync.Map
+	nowT            time.Time
+	accountBillings []data.AccountBillingMap
+
+	// the other cost view, exported for toggling in the report
+	alternativeBillings []data.AccountBillingMap
+
+	budgetStatuses  []data.BudgetStatus
+	budgetAnalysis  tmpl.BudgetAnalysis
+	anomalies       []data.CostAnomaly
+	anomalyAnalysis tmpl.AnomalyAnalysis
+	alertEvents     []data.AlertEvent
+	forecast        data.CostForecast
+	analysisData    tmpl.AnalysisData
+	unsupported     []data.Unsupported
 
 	provider cloud.Provider // tmp solution for multiple cloud provider TODO delete
 }
 
 func NewCostAnalysisDomain() *CostAnalysisDomain {
 	return &CostAnalysisDomain{
-		nowT:              time.Now(),
-		monthsBillingList: []*sync.Map{},
-		daysBillingList:   []*sync.Map{},
+		nowT: time.Now(),
 	}
 }
 
@@ -42,20 +58,53 @@ func (s *CostAnalysisDomain) GetBillingList(ctx context.Context) error {
 	for _, a := range accounts {
 		s.provider = a.Provider
 		monthsBilling, daysBilling, err := s.GetBilling(ctx, a)
+		if providers.IsNotSupported(err) {
+			log.Printf("I! cloud-account[%s] is left out of the cost analysis, %v", a.Name, err)
+			continue
+		}
 		if err != nil {
 			log.Printf("E! get cloud-acount[%v] billing error", a.Name)
 			return err
 		}
-		s.monthsBillingList = append(s.monthsBillingList, monthsBilling)
-		s.daysBillingList = append(s.daysBillingList, daysBilling)
+		if cfg := config.GetGlobalConfig().Amortization; cfg.Enabled {
+			amortizedMonths, amortizedDays := s.GetAmortizedBilling(ctx, a, monthsBilling, daysBilling)
+			if cfg.GetDefaultView() == types.CostViewAmortized {
+				monthsBilling, amortizedMonths = amortizedMonths, monthsBilling
+				daysBilling, amortizedDays = amortizedDays, daysBilling
+			}
+			s.alternativeBillings = append(s.alternativeBillings, data.AccountBillingMap{
+				AccountName:   a.Name,
+				Provider:      a.Provider,
+				MonthsBilling: amortizedMonths,
+				DaysBilling:   amortizedDays,
+			})
+		}
+		s.accountBillings = append(s.accountBillings, data.AccountBillingMap{
+			AccountName:   a.Name,
+			Provider:      a.Provider,
+			MonthsBilling: monthsBilling,
+			DaysBilling:   daysBilling,
+		})
 	}
 	return nil
 }
 
 // GetBilling
 func (s *CostAnalysisDomain) GetBilling(ctx context.Context, a types.CloudAccount) (monthsBilling, daysBilling *sync.Map, err error) {
-	costDataBean := databean.NewCostDataBean(a, s.nowT)
+	costDataBean := databean.NewCostDataBean(a, s.nowT).SetCostBasis(config.GetGlobalConfig().GetCostBasis())
+	if ad := config.GetGlobalConfig().AnomalyDetection; ad.Enabled {
+		costDataBean.SetRecentDaysWithProduct(int32(ad.GetWindowDays() + ad.GetDetectDays()))
+	}
+	// the digest compares the month to date with the same days of last month
+	costDataBean.SetMonthToDate(len(config.GetGlobalConfig().Notification.Notifiers) > 0)
+	// the monthly product budgets use the recent month, which is always fetched with product detail
+	for _, b := range config.GetGlobalConfig().Budgets {
+		if b.Scope == types.BudgetScopeProduct && b.Period == types.BudgetPeriodQuarterly {
+			costDataBean.SetQuarterWithProduct(true)
+		}
+	}
 	err = costDataBean.RunPipeline(ctx)
+	s.unsupported = append(s.unsupported, costDataBean.GetUnsupported()...)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -64,26 +113,163 @@ func (s *CostAnalysisDomain) GetBilling(ctx context.Context, a types.CloudAccoun
 	return
 }
 
+// GetAmortizedBilling 分摊视图, 获取失败时保留现金账单
+func (s *CostAnalysisDomain) GetAmortizedBilling(ctx context.Context, a types.CloudAccount, cashMonthsBilling, cashDaysBilling *sync.Map) (monthsBilling, daysBilling *sync.Map) {
+	cfg := config.GetGlobalConfig().Amortization
+	costDataBean := databean.NewCostDataBean(a, s.nowT).SetCostBasis(config.GetGlobalConfig().GetCostBasis()).SetAmortized(int32(cfg.GetLookbackMonths()), cashMonthsBilling, cashDaysBilling)
+	err := costDataBean.RunPipeline(ctx)
+	s.unsupported = append(s.unsupported, costDataBean.GetUnsupported()...)
+	monthsBilling, daysBilling = costDataBean.GetBillingMap()
+	if err != nil {
+		log.Printf("W! get cloud-account[%s] amortized billing error = %v, cash billing is kept", a.Name, err)
+		return
+	}
+	charges, since := costDataBean.GetPrepaidCharges()
+	amortization.NewAmortizer(charges, since, s.nowT).Amortize(ctx, monthsBilling, daysBilling)
+	log.Printf("I! get cloud-account[%s] amortized billing success", a.Name)
+	return
+}
+
 // ExportStatisticData 导出到静态文件
 func (s *CostAnalysisDomain) ExportStatisticData(ctx context.Context) error {
-	costTemplate := template.NewCostTemplate(nil, nil, s.nowT)
-	costTemplate.SetProvider(s.provider)
-	err := costTemplate.CombineBilling(ctx, s.monthsBillingList, s.daysBillingList)
+	costTemplate, costForecast, err := newCostTemplate(ctx, s.nowT, s.provider, s.accountBillings, s.alternativeBillings)
 	if err != nil {
 		return err
 	}
+	s.forecast = costForecast
+	costTemplate.SetUnsupported(s.unsupported)
 	err = costTemplate.ExportCostAnalysis(ctx)
 	if err != nil {
 		log.Printf("E! export cost-analysis data failed: %v\n", err)
 		return err
 	}
+	s.analysisData = costTemplate.GetAnalysisData()
+	return nil
+}
+
+// EvaluateBudgets 计算预算执行情况并产生告警事件
+func (s *CostAnalysisDomain) EvaluateBudgets(ctx context.Context) error {
+	budgets := config.GetGlobalConfig().Budgets
+	if len(budgets) == 0 {
+		return nil
+	}
+	evaluator := budget.NewEvaluator(budgets, s.accountBillings, s.nowT)
+	s.budgetStatuses = evaluator.Evaluate(ctx)
+	events := evaluator.AlertEvents(s.budgetStatuses)
+	for _, e := range events {
+		log.Printf("W! [%s] %s: %s", e.Severity, e.Title, e.Message)
+	}
+	s.alertEvents = append(s.alertEvents, events...)
+	return nil
+}
+
+// ExportBudgetData 导出预算执行情况到静态文件
+func (s *CostAnalysisDomain) ExportBudgetData(ctx context.Context) error {
+	if len(s.budgetStatuses) == 0 {
+		return nil
+	}
+	budgetTemplate := template.NewBudgetTemplate(s.budgetStatuses, s.nowT)
+	s.budgetAnalysis = budgetTemplate.Assemble(ctx)
+	if err := budgetTemplate.Export(ctx, s.budgetAnalysis); err != nil {
+		log.Printf("E! export budget data failed: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// DetectAnomalies 检测每日成本异常
+func (s *CostAnalysisDomain) DetectAnomalies(ctx context.Context) error {
+	cfg := config.GetGlobalConfig().AnomalyDetection
+	if !cfg.Enabled {
+		return nil
+	}
+	detector := anomaly.NewDetector(cfg, s.accountBillings, s.nowT)
+	s.anomalies = detector.Detect(ctx)
+	events := detector.AlertEvents(s.anomalies)
+	for _, e := range events {
+		log.Printf("W! [%s] %s: %s", e.Severity, e.Title, e.Message)
+	}
+	s.alertEvents = append(s.alertEvents, events...)
 	return nil
 }
 
+// ExportAnomalyData 导出成本异常到静态文件
+func (s *CostAnalysisDomain) ExportAnomalyData(ctx context.Context) error {
+	if !config.GetGlobalConfig().AnomalyDetection.Enabled {
+		return nil
+	}
+	anomalyTemplate := template.NewAnomalyTemplate(s.anomalies, s.nowT)
+	s.anomalyAnalysis = anomalyTemplate.Assemble(ctx)
+	if err := anomalyTemplate.Export(ctx, s.anomalyAnalysis); err != nil {
+		log.Printf("E! export anomaly data failed: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// GetNowT time of the analysis
+func (s *CostAnalysisDomain) GetNowT() time.Time {
+	return s.nowT
+}
+
+// GetAccountBillings billing of each cloud account, in the default cost view
+func (s *CostAnalysisDomain) GetAccountBillings() []data.AccountBillingMap {
+	return s.accountBillings
+}
+
+// GetAlternativeBillings billing of each cloud account in the other cost view, empty if the amortization is disabled
+func (s *CostAnalysisDomain) GetAlternativeBillings() []data.AccountBillingMap {
+	return s.alternativeBillings
+}
+
+// GetAnomalies
+func (s *CostAnalysisDomain) GetAnomalies() []data.CostAnomaly {
+	return s.anomalies
+}
+
+// GetAnomalyAnalysis the anomalies shown by the website and the report
+func (s *CostAnalysisDomain) GetAnomalyAnalysis() tmpl.AnomalyAnalysis {
+	return s.anomalyAnalysis
+}
+
+// GetBudgetStatuses
+func (s *CostAnalysisDomain) GetBudgetStatuses() []data.BudgetStatus {
+	return s.budgetStatuses
+}
+
+// GetBudgetAnalysis the budgets shown by the website and the report
+func (s *CostAnalysisDomain) GetBudgetAnalysis() tmpl.BudgetAnalysis {
+	return s.budgetAnalysis
+}
+
+// GetForecast month-end and year-end spend forecast
+func (s *CostAnalysisDomain) GetForecast() data.CostForecast {
+	return s.forecast
+}
+
+// GetAnalysisData the statistics, ratios and trends shown by the website
+func (s *CostAnalysisDomain) GetAnalysisData() tmpl.AnalysisData {
+	return s.analysisData
+}
+
+// GetUnsupported the data of the accounts left out as their providers lack the capabilities
+func (s *CostAnalysisDomain) GetUnsupported() []data.Unsupported {
+	return s.unsupported
+}
+
+// GetAlertEvents alert events raised by the pipeline, to be delivered by notifiers
+func (s *CostAnalysisDomain) GetAlertEvents() []data.AlertEvent {
+	return s.alertEvents
+}
+
 func (s *CostAnalysisDomain) GetCostAnalysisPipeline() []func(context.Context) error {
 	return []func(context.Context) error{
 		s.GetBillingList,
 		s.ExportStatisticData,
+		s.EvaluateBudgets,
+		s.ExportBudgetData,
+		s.DetectAnomalies,
+		s.ExportAnomalyData,
 	}
 }
 
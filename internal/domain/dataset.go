@@ -0,0 +1,156 @@
+package domain
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/config"
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	"github.com/galaxy-future/costpilot/internal/data"
+	providerTypes "github.com/galaxy-future/costpilot/internal/providers/types"
+	"github.com/galaxy-future/costpilot/internal/services/commitment"
+	"github.com/galaxy-future/costpilot/internal/services/forecast"
+	"github.com/galaxy-future/costpilot/internal/services/template"
+	tmpl "github.com/galaxy-future/costpilot/internal/template"
+	"github.com/galaxy-future/costpilot/internal/types"
+)
+
+// DataSet the data of some of the cloud accounts, data-set.js of the website is rebuilt from it,
+// eg: for the viewers granted some of the accounts
+type DataSet struct {
+	NowT                time.Time
+	AccountBillings     []data.AccountBillingMap
+	AlternativeBillings []data.AccountBillingMap // empty if the amortization is disabled
+	AccountUtilizations []data.AccountUtilizationMap
+	AccountCommitments  []data.AccountCommitments
+	BudgetStatuses      []data.BudgetStatus
+	Anomalies           []data.CostAnomaly
+	IdleInstances       []data.IdleInstance
+	Opportunities       []data.OptimizationOpportunity
+	Unsupported         []data.Unsupported
+}
+
+// Render data-set.js of the accounts, the analyses are assembled as the pipelines do and the disabled ones are left out
+func (d DataSet) Render(ctx context.Context) (string, error) {
+	var costUnsupported, utilizationUnsupported []data.Unsupported
+	for _, u := range d.Unsupported {
+		if u.Analysis == data.AnalysisCost || u.Analysis == data.AnalysisAmortization {
+			costUnsupported = append(costUnsupported, u)
+		} else {
+			utilizationUnsupported = append(utilizationUnsupported, u)
+		}
+	}
+	var provider cloud.Provider
+	if n := len(d.AccountBillings); n > 0 {
+		provider = d.AccountBillings[n-1].Provider
+	}
+	costTemplate, _, err := newCostTemplate(ctx, d.NowT, provider, d.AccountBillings, d.AlternativeBillings)
+	if err != nil {
+		return "", err
+	}
+	costTemplate.SetUnsupported(costUnsupported)
+	ad, err := costTemplate.AssembleAnalysisData(ctx)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	write := func(c string, e error) {
+		if err == nil {
+			err = e
+			b.WriteString(c)
+		}
+	}
+	write(tmpl.ParseCostTemplate(ad))
+	if len(d.BudgetStatuses) > 0 {
+		write(tmpl.ParseBudgetTemplate(template.NewBudgetTemplate(d.BudgetStatuses, d.NowT).Assemble(ctx)))
+	}
+	if config.GetGlobalConfig().AnomalyDetection.Enabled {
+		write(tmpl.ParseAnomalyTemplate(template.NewAnomalyTemplate(d.Anomalies, d.NowT).Assemble(ctx)))
+	}
+	utilizationTemplate := newUtilizationTemplate(d.NowT, d.AccountUtilizations)
+	utilizationTemplate.SetUnsupported(utilizationUnsupported)
+	write(tmpl.ParseUtilizeAnalysisTemplate(utilizationTemplate.Assemble(ctx)))
+	if !config.GetGlobalConfig().IdleInstanceDetection.Disabled {
+		write(tmpl.ParseIdleInstanceTemplate(template.NewIdleInstanceTemplate(d.IdleInstances, d.NowT).Assemble(ctx)))
+	}
+	if !config.GetGlobalConfig().Rightsizing.Disabled {
+		write(tmpl.ParseOptimizationTemplate(template.NewOptimizationTemplate(d.Opportunities, d.NowT).Assemble(ctx)))
+	}
+	if cfg := config.GetGlobalConfig().CommitmentAnalysis; !cfg.Disabled {
+		analysis := commitment.NewAnalyzer(cfg, d.AccountCommitments, d.NowT).Analyze(ctx)
+		write(tmpl.ParseCommitmentTemplate(template.NewCommitmentTemplate(analysis, cfg.GetWindowDays(), d.NowT).Assemble(ctx)))
+	}
+	if err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// newCostTemplate the billing of the accounts combined with the forecast, and the other cost view if the amortization is enabled
+func newCostTemplate(ctx context.Context, nowT time.Time, provider cloud.Provider, billings, alternativeBillings []data.AccountBillingMap) (*template.CostTemplate, data.CostForecast, error) {
+	costTemplate, costForecast, err := combineBilling(ctx, nowT, provider, billings)
+	if err != nil {
+		return nil, data.CostForecast{}, err
+	}
+	if cfg := config.GetGlobalConfig().Amortization; cfg.Enabled {
+		costTemplate.SetCostView(cfg.GetDefaultView())
+		alternative, _, err := combineBilling(ctx, nowT, provider, alternativeBillings)
+		if err != nil {
+			return nil, data.CostForecast{}, err
+		}
+		alternative.SetCostView(types.CostViewAmortized)
+		if cfg.GetDefaultView() == types.CostViewAmortized {
+			alternative.SetCostView(types.CostViewCash)
+		}
+		costTemplate.SetAlternativeView(alternative)
+	}
+	return costTemplate, costForecast, nil
+}
+
+func combineBilling(ctx context.Context, nowT time.Time, provider cloud.Provider, billings []data.AccountBillingMap) (*template.CostTemplate, data.CostForecast, error) {
+	monthsBillingList := make([]*sync.Map, 0, len(billings))
+	daysBillingList := make([]*sync.Map, 0, len(billings))
+	for _, b := range billings {
+		monthsBillingList = append(monthsBillingList, b.MonthsBilling)
+		daysBillingList = append(daysBillingList, b.DaysBilling)
+	}
+	// the accounts are combined into empty maps, so an empty grant is rendered as zeros
+	costTemplate := template.NewCostTemplate(&sync.Map{}, &sync.Map{}, nowT)
+	costTemplate.SetProvider(provider)
+	if err := costTemplate.CombineBilling(ctx, monthsBillingList, daysBillingList); err != nil {
+		return nil, data.CostForecast{}, err
+	}
+	costForecast := forecast.NewForecaster(costTemplate.MonthsBilling, costTemplate.DaysBilling, nowT).Forecast(ctx)
+	costTemplate.SetForecast(&costForecast)
+	costTemplate.SetCostBasis(config.GetGlobalConfig().GetCostBasis())
+	return costTemplate, costForecast, nil
+}
+
+// newUtilizationTemplate the utilization of the accounts merged by day
+func newUtilizationTemplate(nowT time.Time, utilizations []data.AccountUtilizationMap) *template.UtilizationTemplate {
+	var (
+		dailyCpuProviders        = make([]*sync.Map, 0, len(utilizations))
+		dailyMemoryProviders     = make([]*sync.Map, 0, len(utilizations))
+		recentInstancesProviders = make([]*sync.Map, 0, len(utilizations))
+		dailyMetricProviders     = make([]map[providerTypes.MetricItem]*sync.Map, 0, len(utilizations))
+	)
+	// the maps of the data not collected are nil
+	for _, u := range utilizations {
+		if u.DailyCpu != nil {
+			dailyCpuProviders = append(dailyCpuProviders, u.DailyCpu)
+		}
+		if u.DailyMemory != nil {
+			dailyMemoryProviders = append(dailyMemoryProviders, u.DailyMemory)
+		}
+		if u.RecentInstances != nil {
+			recentInstancesProviders = append(recentInstancesProviders, u.RecentInstances)
+		}
+		dailyMetricProviders = append(dailyMetricProviders, u.DailyMetrics)
+	}
+	temp := template.NewUtilization(nowT)
+	temp.AssignData(dailyCpuProviders, dailyMemoryProviders, recentInstancesProviders)
+	temp.AssignMetrics(config.GetGlobalConfig().UtilizationAnalysis.Metrics, dailyMetricProviders)
+	return temp
+}
@@ -3,41 +3,86 @@ package domain
 import (
 	"context"
 	"log"
-	"sync"
+	"strings"
 	"time"
 
+	"github.com/galaxy-future/costpilot/internal/catalog"
+	"github.com/galaxy-future/costpilot/internal/config"
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/providers"
 	"github.com/galaxy-future/costpilot/internal/services"
+	"github.com/galaxy-future/costpilot/internal/services/commitment"
 	"github.com/galaxy-future/costpilot/internal/services/databean"
+	"github.com/galaxy-future/costpilot/internal/services/idle"
+	"github.com/galaxy-future/costpilot/internal/services/rightsizing"
 	"github.com/galaxy-future/costpilot/internal/services/template"
+	tmpl "github.com/galaxy-future/costpilot/internal/template"
 	"github.com/galaxy-future/costpilot/internal/types"
 	"github.com/pkg/errors"
 )
 
 type ResourceUtilizationDomain struct {
-	nowT                     time.Time
-	dailyCpuProviders        []*sync.Map
-	dailyMemoryProviders     []*sync.Map
-	recentInstancesProviders []*sync.Map
+	nowT                time.Time
+	accountUtilizations []data.AccountUtilizationMap
+
+	accountCommitments []data.AccountCommitments
+
+	idleInstances []data.IdleInstance
+	opportunities []data.OptimizationOpportunity
+	commitments   data.CommitmentAnalysis
+	alertEvents   []data.AlertEvent
+	unsupported   []data.Unsupported
+
+	utilizeAnalysis tmpl.UtilizeAnalysis
+	idleAnalysis    tmpl.IdleInstanceAnalysis
+	optimization    tmpl.OptimizationAnalysis
+	commitment      tmpl.CommitmentAnalysis
 }
 
 func NewResourceUtilizationDomain() *ResourceUtilizationDomain {
 	return &ResourceUtilizationDomain{
-		nowT:                     time.Now(),
-		dailyCpuProviders:        []*sync.Map{},
-		dailyMemoryProviders:     []*sync.Map{},
-		recentInstancesProviders: []*sync.Map{},
+		nowT: time.Now(),
 	}
 }
 
+// UtilizationResult the utilization, instance bills and commitments of one cloud account
+type UtilizationResult struct {
+	data.AccountUtilizationMap
+	Commitments data.Commitments
+}
+
 // GetUtilization 获取资源利用情况
-func (s *ResourceUtilizationDomain) GetUtilization(ctx context.Context, a types.CloudAccount) (dailyCpu, dailyMemory, dailyInstances *sync.Map, err error) {
+func (s *ResourceUtilizationDomain) GetUtilization(ctx context.Context, a types.CloudAccount) (UtilizationResult, error) {
 	dBean := databean.NewUtilization(a, s.nowT)
-	err = dBean.RunPipeline(ctx)
+	dBean.SetMetrics(config.GetGlobalConfig().UtilizationAnalysis.Metrics...)
+	if cfg := config.GetGlobalConfig().IdleInstanceDetection; !cfg.Disabled {
+		dBean.SetRecentDays(int32(cfg.GetWindowDays())).SetFetchInstanceBills(true)
+	}
+	if cfg := config.GetGlobalConfig().Rightsizing; !cfg.Disabled {
+		dBean.SetRecentDays(int32(cfg.GetWindowDays())).SetFetchInstanceBills(true)
+	}
+	if cfg := config.GetGlobalConfig().CommitmentAnalysis; !cfg.Disabled {
+		dBean.SetCommitmentDays(int32(cfg.GetWindowDays()))
+	}
+	err := dBean.RunPipeline(ctx)
+	s.unsupported = append(s.unsupported, dBean.GetUnsupported()...)
 	if err != nil {
-		return
+		return UtilizationResult{}, err
 	}
-	dailyCpu, dailyMemory, dailyInstances = dBean.GetUtilizationMap()
-	return
+	dailyCpu, dailyMemory, dailyInstances := dBean.GetUtilizationMap()
+	return UtilizationResult{
+		AccountUtilizationMap: data.AccountUtilizationMap{
+			AccountName:     a.Name,
+			Provider:        a.Provider,
+			DailyCpu:        dailyCpu,
+			DailyMemory:     dailyMemory,
+			RecentInstances: dailyInstances,
+			InstanceBills:   dBean.GetInstanceBillMap(),
+			DailyMetrics:    dBean.GetMetricMap(),
+			RegionScan:      dBean.GetRegionScan(),
+		},
+		Commitments: dBean.GetCommitments(),
+	}, nil
 }
 
 func (s *ResourceUtilizationDomain) GetUtilizationData(ctx context.Context) error {
@@ -48,36 +93,204 @@ func (s *ResourceUtilizationDomain) GetUtilizationData(ctx context.Context) erro
 	}
 	for _, a := range accounts {
 		log.Printf("I! start stat %s resouce utilization", a.Name)
-		dailyCpu, dailyMemory, dailyInstances, err := s.GetUtilization(ctx, a)
+		r, err := s.GetUtilization(ctx, a)
+		if providers.IsNotSupported(err) {
+			log.Printf("I! cloud-account[%s] is left out of the utilization analysis, %v", a.Name, err)
+			continue
+		}
 		if err != nil {
 			log.Printf("W! get cloud-acount[%v] utilization error = %v, utilization may not display correctly!", a.Name, err)
 			continue
 		}
 		log.Printf("I! end stat %s resouce utilization", a.Name)
-		s.dailyMemoryProviders = append(s.dailyMemoryProviders, dailyMemory)
-		s.dailyCpuProviders = append(s.dailyCpuProviders, dailyCpu)
-		s.recentInstancesProviders = append(s.recentInstancesProviders, dailyInstances)
+		logRegionScan(a.Name, r.RegionScan)
+		s.accountUtilizations = append(s.accountUtilizations, r.AccountUtilizationMap)
+		s.accountCommitments = append(s.accountCommitments, data.AccountCommitments{
+			AccountName: a.Name,
+			Provider:    a.Provider,
+			Commitments: r.Commitments,
+		})
 	}
 	return nil
 }
 
+// logRegionScan the instances of the failed regions are missing from the analysis
+func logRegionScan(account string, r data.RegionScan) {
+	for _, f := range r.Failed {
+		log.Printf("W! %s region %s failed, its instances are not analyzed: %s", account, f.RegionId, f.Reason)
+	}
+	if len(r.Skipped) > 0 {
+		var skipped []string
+		for _, v := range r.Skipped {
+			skipped = append(skipped, v.RegionId+" ("+v.Reason+")")
+		}
+		log.Printf("I! %s regions skipped: %s", account, strings.Join(skipped, ", "))
+	}
+}
+
 func (s *ResourceUtilizationDomain) ExportStatisticData(ctx context.Context) error {
-	temp := template.NewUtilization(s.nowT)
-	temp.AssignData(s.dailyCpuProviders, s.dailyMemoryProviders, s.recentInstancesProviders)
+	temp := newUtilizationTemplate(s.nowT, s.accountUtilizations)
+	temp.SetUnsupported(s.unsupported)
 	data := temp.Assemble(ctx)
 	err := temp.Export(ctx, data)
 	if err != nil {
 		log.Printf("E! export utilization-analysis data failed: %v\n", err)
 		return err
 	}
+	s.utilizeAnalysis = data
+
+	return nil
+}
+
+// DetectIdleInstances 找出闲置与低利用率实例
+func (s *ResourceUtilizationDomain) DetectIdleInstances(ctx context.Context) error {
+	cfg := config.GetGlobalConfig().IdleInstanceDetection
+	if cfg.Disabled {
+		return nil
+	}
+	s.idleInstances = idle.NewDetector(cfg, s.accountUtilizations, s.nowT).Detect(ctx)
+	return nil
+}
+
+// ExportIdleInstanceData 导出闲置与低利用率实例到静态文件与 csv
+func (s *ResourceUtilizationDomain) ExportIdleInstanceData(ctx context.Context) error {
+	if config.GetGlobalConfig().IdleInstanceDetection.Disabled {
+		return nil
+	}
+	temp := template.NewIdleInstanceTemplate(s.idleInstances, s.nowT)
+	s.idleAnalysis = temp.Assemble(ctx)
+	if err := temp.Export(ctx, s.idleAnalysis); err != nil {
+		log.Printf("E! export idle-instance data failed: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// AdviseRightsizing 结合实例规格目录给出停用与降配建议, 目录加载失败时跳过
+func (s *ResourceUtilizationDomain) AdviseRightsizing(ctx context.Context) error {
+	cfg := config.GetGlobalConfig().Rightsizing
+	if cfg.Disabled {
+		return nil
+	}
+	catalogs, err := catalog.Load(cfg.CatalogDir)
+	if err != nil {
+		log.Printf("W! load instance catalog failed: %v, rightsizing skipped", err)
+		return nil
+	}
+	s.opportunities = rightsizing.NewAdvisor(cfg, catalogs, s.accountUtilizations, s.idleInstances, s.nowT).Advise(ctx)
+	return nil
+}
+
+// ExportOptimizationData 导出优化机会到静态文件
+func (s *ResourceUtilizationDomain) ExportOptimizationData(ctx context.Context) error {
+	if config.GetGlobalConfig().Rightsizing.Disabled {
+		return nil
+	}
+	temp := template.NewOptimizationTemplate(s.opportunities, s.nowT)
+	s.optimization = temp.Assemble(ctx)
+	if err := temp.Export(ctx, s.optimization); err != nil {
+		log.Printf("E! export optimization data failed: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// AnalyzeCommitments 计算预留实例、节省计划与包年包月的覆盖率和使用率, 列出即将到期的承诺
+func (s *ResourceUtilizationDomain) AnalyzeCommitments(ctx context.Context) error {
+	cfg := config.GetGlobalConfig().CommitmentAnalysis
+	if cfg.Disabled {
+		return nil
+	}
+	analyzer := commitment.NewAnalyzer(cfg, s.accountCommitments, s.nowT)
+	s.commitments = analyzer.Analyze(ctx)
+	events := analyzer.AlertEvents(s.commitments)
+	for _, e := range events {
+		log.Printf("W! [%s] %s: %s", e.Severity, e.Title, e.Message)
+	}
+	s.alertEvents = append(s.alertEvents, events...)
+	return nil
+}
 
+// ExportCommitmentData 导出承诺使用分析到静态文件
+func (s *ResourceUtilizationDomain) ExportCommitmentData(ctx context.Context) error {
+	cfg := config.GetGlobalConfig().CommitmentAnalysis
+	if cfg.Disabled {
+		return nil
+	}
+	temp := template.NewCommitmentTemplate(s.commitments, cfg.GetWindowDays(), s.nowT)
+	s.commitment = temp.Assemble(ctx)
+	if err := temp.Export(ctx, s.commitment); err != nil {
+		log.Printf("E! export commitment data failed: %v\n", err)
+		return err
+	}
 	return nil
 }
 
+// GetAccountUtilizations utilization and instance bills of each cloud account
+func (s *ResourceUtilizationDomain) GetAccountUtilizations() []data.AccountUtilizationMap {
+	return s.accountUtilizations
+}
+
+// GetAccountCommitments commitments of each cloud account
+func (s *ResourceUtilizationDomain) GetAccountCommitments() []data.AccountCommitments {
+	return s.accountCommitments
+}
+
+// GetUtilizeAnalysis the statistics, ratios and trends shown by the website
+func (s *ResourceUtilizationDomain) GetUtilizeAnalysis() tmpl.UtilizeAnalysis {
+	return s.utilizeAnalysis
+}
+
+// GetIdleInstanceAnalysis the idle instances shown by the website and the report
+func (s *ResourceUtilizationDomain) GetIdleInstanceAnalysis() tmpl.IdleInstanceAnalysis {
+	return s.idleAnalysis
+}
+
+// GetOptimizationAnalysis the recommendations shown by the website and the report
+func (s *ResourceUtilizationDomain) GetOptimizationAnalysis() tmpl.OptimizationAnalysis {
+	return s.optimization
+}
+
+// GetCommitmentAnalysis the commitments shown by the website and the report
+func (s *ResourceUtilizationDomain) GetCommitmentAnalysis() tmpl.CommitmentAnalysis {
+	return s.commitment
+}
+
+// GetCommitments
+func (s *ResourceUtilizationDomain) GetCommitments() data.CommitmentAnalysis {
+	return s.commitments
+}
+
+// GetAlertEvents alert events raised by the pipeline, to be delivered by notifiers
+func (s *ResourceUtilizationDomain) GetAlertEvents() []data.AlertEvent {
+	return s.alertEvents
+}
+
+// GetOpportunities
+func (s *ResourceUtilizationDomain) GetOpportunities() []data.OptimizationOpportunity {
+	return s.opportunities
+}
+
+// GetIdleInstances
+func (s *ResourceUtilizationDomain) GetIdleInstances() []data.IdleInstance {
+	return s.idleInstances
+}
+
+// GetUnsupported the data of the accounts left out as their providers lack the capabilities
+func (s *ResourceUtilizationDomain) GetUnsupported() []data.Unsupported {
+	return s.unsupported
+}
+
 func (s *ResourceUtilizationDomain) GetCostAnalysisPipeline() []func(context.Context) error {
 	return []func(context.Context) error{
 		s.GetUtilizationData,
 		s.ExportStatisticData,
+		s.DetectIdleInstances,
+		s.ExportIdleInstanceData,
+		s.AdviseRightsizing,
+		s.ExportOptimizationData,
+		s.AnalyzeCommitments,
+		s.ExportCommitmentData,
 	}
 }
 
@@ -0,0 +1,68 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// csvExporter one file per view, with a header row
+type csvExporter struct{}
+
+func (csvExporter) Format() string { return "csv" }
+
+func (csvExporter) Export(dir string, ds Dataset) ([]string, error) {
+	var files []string
+	for _, t := range ds.Tables {
+		file := filepath.Join(dir, fmt.Sprintf("costpilot-%s.csv", t.Name))
+		if err := writeCSV(file, t); err != nil {
+			return files, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+func writeCSV(file string, t Table) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	header := make([]string, 0, len(t.Columns))
+	for _, c := range t.Columns {
+		header = append(header, c.Name)
+	}
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	record := make([]string, len(t.Columns))
+	for _, r := range t.Rows {
+		for i := range t.Columns {
+			record[i] = formatCell(r[i])
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func formatCell(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,99 @@
+package export
+
+import (
+	"sort"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/tools"
+)
+
+const (
+	TableDaily    = "daily"
+	TableMonthly  = "monthly"
+	TableProducts = "products"
+)
+
+var (
+	_dailyColumns = []Column{
+		{"day", String}, {"account", String}, {"provider", String}, {"currency", String}, {"amount", Float}, {"amortized", Bool},
+	}
+	_monthlyColumns = []Column{
+		{"month", String}, {"account", String}, {"provider", String}, {"currency", String}, {"amount", Float}, {"amortized", Bool},
+	}
+	_productsColumns = []Column{
+		{"day", String}, {"account", String}, {"provider", String}, {"pip_code", String}, {"product_name", String},
+		{"subscription_type", String}, {"currency", String}, {"amount", Float},
+	}
+)
+
+// BuildDataset daily, monthly and daily product cost of each account,
+// months which are not billed monthly yet are summed up by their days
+func BuildDataset(generatedAt time.Time, billings []data.AccountBillingMap) Dataset {
+	daily := Table{Name: TableDaily, Columns: _dailyColumns}
+	monthly := Table{Name: TableMonthly, Columns: _monthlyColumns}
+	products := Table{Name: TableProducts, Columns: _productsColumns}
+	for _, a := range billings {
+		account, provider := a.AccountName, a.Provider.String()
+		months := make(map[string]data.MonthlyBilling)
+		if a.MonthsBilling != nil {
+			a.MonthsBilling.Range(func(key, value interface{}) bool {
+				months[key.(string)] = value.(data.MonthlyBilling)
+				return true
+			})
+		}
+		summed := make(map[string]data.DailyBilling) // key : month, of the months without monthly bill
+		if a.DaysBilling != nil {
+			a.DaysBilling.Range(func(key, value interface{}) bool {
+				day, d := key.(string), value.(data.DailyBilling)
+				daily.Rows = append(daily.Rows, []interface{}{day, account, provider, tools.ExtractCurrency(d.ProductsBilling), d.TotalAmount, d.Amortized})
+				for pipCode, p := range d.ProductsBilling {
+					if len(p.Items) == 0 {
+						products.Rows = append(products.Rows, []interface{}{day, account, provider, pipCode, p.ProductName, "", "", p.TotalAmount})
+						continue
+					}
+					for _, item := range p.Items {
+						products.Rows = append(products.Rows, []interface{}{day, account, provider, pipCode, p.ProductName,
+							string(item.SubscriptionType), item.Currency, item.PretaxAmount})
+					}
+				}
+				month := day[:len("2006-01")]
+				if _, ok := months[month]; !ok {
+					sum, found := summed[month]
+					if !found {
+						sum.Amortized = d.Amortized
+					}
+					summed[month] = tools.AddDailyBilling(sum, d)
+				}
+				return true
+			})
+		}
+		for month, m := range months {
+			monthly.Rows = append(monthly.Rows, []interface{}{month, account, provider, tools.ExtractCurrency(m.ProductsBilling), m.TotalAmount, m.Amortized})
+		}
+		for month, d := range summed {
+			monthly.Rows = append(monthly.Rows, []interface{}{month, account, provider, tools.ExtractCurrency(d.ProductsBilling), d.TotalAmount, d.Amortized})
+		}
+	}
+	for _, t := range []Table{daily, monthly, products} {
+		sortRows(t.Rows)
+	}
+	return Dataset{GeneratedAt: generatedAt, Tables: []Table{daily, monthly, products}}
+}
+
+// sortRows by the leading string columns
+func sortRows(rows [][]interface{}) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		for k := range rows[i] {
+			x, ok1 := rows[i][k].(string)
+			y, ok2 := rows[j][k].(string)
+			if !ok1 || !ok2 {
+				return false
+			}
+			if x != y {
+				return x < y
+			}
+		}
+		return false
+	})
+}
@@ -0,0 +1,75 @@
+package export
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/types"
+)
+
+type ColumnType int
+
+const (
+	String ColumnType = iota
+	Float
+	Bool
+)
+
+type Column struct {
+	Name string
+	Type ColumnType
+}
+
+// Table a long-form view, the values of a row are string, float64 or bool by the columns
+type Table struct {
+	Name    string
+	Columns []Column
+	Rows    [][]interface{}
+}
+
+// Dataset the views to export
+type Dataset struct {
+	GeneratedAt time.Time
+	Tables      []Table
+}
+
+// Exporter writes the dataset into dir, returns the files written
+type Exporter interface {
+	Format() string
+	Export(dir string, ds Dataset) ([]string, error)
+}
+
+func New(format string) (Exporter, error) {
+	switch format {
+	case types.ExportJSON:
+		return jsonExporter{}, nil
+	case types.ExportCSV:
+		return csvExporter{}, nil
+	case types.ExportXLSX:
+		return xlsxExporter{}, nil
+	case types.ExportParquet:
+		return parquetExporter{}, nil
+	}
+	return nil, fmt.Errorf("invalid export format %q, expect json | csv | xlsx | parquet", format)
+}
+
+// Run export the dataset in each format
+func Run(cfg types.Export, ds Dataset) ([]string, error) {
+	if err := os.MkdirAll(cfg.GetDir(), 0755); err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, format := range cfg.Formats {
+		e, err := New(format)
+		if err != nil {
+			return files, err
+		}
+		written, err := e.Export(cfg.GetDir(), ds)
+		if err != nil {
+			return files, fmt.Errorf("export %s: %v", format, err)
+		}
+		files = append(files, written...)
+	}
+	return files, nil
+}
@@ -0,0 +1,287 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/common"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xuri/excelize/v2"
+)
+
+func newTestDataset() Dataset {
+	var days, months sync.Map
+	months.Store("2022-09", data.MonthlyBilling{Month: "2022-09", TotalAmount: 300, ProductsBilling: map[string]data.ProductBilling{
+		"ecs": {ProductName: "ECS", TotalAmount: 300, Items: []data.ItemInProductBilling{{PretaxAmount: 300, Currency: "CNY"}}},
+	}})
+	days.Store("2022-10-01", data.DailyBilling{Day: "2022-10-01", TotalAmount: 10, ProductsBilling: map[string]data.ProductBilling{
+		"ecs": {ProductName: "云服务器 ECS", TotalAmount: 7, Items: []data.ItemInProductBilling{
+			{PipCode: "ecs", PretaxAmount: 5, SubscriptionType: cloud.PostPaid, Currency: "CNY"},
+			{PipCode: "ecs", PretaxAmount: 2, SubscriptionType: cloud.PrePaid, Currency: "CNY"},
+		}},
+		"oss": {ProductName: "OSS", TotalAmount: 3, Items: []data.ItemInProductBilling{{PipCode: "oss", PretaxAmount: 3, SubscriptionType: cloud.PostPaid, Currency: "CNY"}}},
+	}})
+	days.Store("2022-10-02", data.DailyBilling{Day: "2022-10-02", TotalAmount: 20.5, Amortized: true, ProductsBilling: map[string]data.ProductBilling{
+		"": {TotalAmount: 20.5},
+	}})
+	return BuildDataset(time.Date(2022, 10, 3, 9, 0, 0, 0, time.UTC),
+		[]data.AccountBillingMap{{AccountName: "ali", Provider: cloud.AlibabaCloud, MonthsBilling: &months, DaysBilling: &days}})
+}
+
+func TestBuildDataset(t *testing.T) {
+	ds := newTestDataset()
+	require.Len(t, ds.Tables, 3)
+	daily, monthly, products := ds.Tables[0], ds.Tables[1], ds.Tables[2]
+	assert.Equal(t, [][]interface{}{
+		{"2022-10-01", "ali", "AlibabaCloud", "CNY", 10.0, false},
+		{"2022-10-02", "ali", "AlibabaCloud", "", 20.5, true},
+	}, daily.Rows)
+	// the recent month is summed up by its days
+	assert.Equal(t, [][]interface{}{
+		{"2022-09", "ali", "AlibabaCloud", "CNY", 300.0, false},
+		{"2022-10", "ali", "AlibabaCloud", "CNY", 30.5, false},
+	}, monthly.Rows)
+	assert.Equal(t, [][]interface{}{
+		{"2022-10-01", "ali", "AlibabaCloud", "ecs", "云服务器 ECS", "PostPaid", "CNY", 5.0},
+		{"2022-10-01", "ali", "AlibabaCloud", "ecs", "云服务器 ECS", "PrePaid", "CNY", 2.0},
+		{"2022-10-01", "ali", "AlibabaCloud", "oss", "OSS", "PostPaid", "CNY", 3.0},
+		{"2022-10-02", "ali", "AlibabaCloud", "", "", "", "", 20.5},
+	}, products.Rows)
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	files, err := Run(types.Export{Formats: []string{"json", "csv", "xlsx", "parquet"}, Dir: dir}, newTestDataset())
+	require.NoError(t, err)
+	assert.Len(t, files, 1+3+1+3)
+
+	_, err = Run(types.Export{Formats: []string{"pdf"}, Dir: dir}, newTestDataset())
+	assert.Error(t, err)
+}
+
+func TestJSONExporter(t *testing.T) {
+	dir := t.TempDir()
+	_, err := jsonExporter{}.Export(dir, newTestDataset())
+	require.NoError(t, err)
+	b, err := ioutil.ReadFile(filepath.Join(dir, "costpilot.json"))
+	require.NoError(t, err)
+	var out struct {
+		GeneratedAt string                   `json:"generated_at"`
+		Daily       []map[string]interface{} `json:"daily"`
+		Products    []map[string]interface{} `json:"products"`
+	}
+	require.NoError(t, json.Unmarshal(b, &out))
+	assert.Equal(t, "2022-10-03T09:00:00Z", out.GeneratedAt)
+	assert.Equal(t, map[string]interface{}{"day": "2022-10-02", "account": "ali", "provider": "AlibabaCloud", "currency": "", "amount": 20.5, "amortized": true}, out.Daily[1])
+	assert.Len(t, out.Products, 4)
+}
+
+func TestCSVExporter(t *testing.T) {
+	dir := t.TempDir()
+	files, err := csvExporter{}.Export(dir, newTestDataset())
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dir, "costpilot-products.csv"), files[2])
+	f, err := os.Open(files[2])
+	require.NoError(t, err)
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"day", "account", "provider", "pip_code", "product_name", "subscription_type", "currency", "amount"}, records[0])
+	assert.Equal(t, []string{"2022-10-01", "ali", "AlibabaCloud", "ecs", "云服务器 ECS", "PostPaid", "CNY", "5"}, records[1])
+	assert.Len(t, records, 5)
+}
+
+func TestXLSXExporter(t *testing.T) {
+	dir := t.TempDir()
+	files, err := xlsxExporter{}.Export(dir, newTestDataset())
+	require.NoError(t, err)
+	z, err := zip.OpenReader(files[0])
+	require.NoError(t, err)
+	defer z.Close()
+	parts := make(map[string]string)
+	for _, f := range z.File {
+		r, err := f.Open()
+		require.NoError(t, err)
+		b, _ := ioutil.ReadAll(r)
+		r.Close()
+		parts[f.Name] = string(b)
+	}
+	assert.Equal(t, "[Content_Types].xml", z.File[0].Name)
+	assert.Contains(t, parts["xl/workbook.xml"], `<sheet name="daily" sheetId="1" r:id="rId1"/><sheet name="monthly" sheetId="2" r:id="rId2"/><sheet name="products" sheetId="3" r:id="rId3"/>`)
+	assert.Contains(t, parts["[Content_Types].xml"], `PartName="/xl/worksheets/sheet3.xml"`)
+	assert.Contains(t, parts["xl/worksheets/sheet1.xml"], `<row r="3"><c r="A3" t="inlineStr"><is><t>2022-10-02</t></is></c>`)
+	assert.Contains(t, parts["xl/worksheets/sheet1.xml"], `<c r="E3"><v>20.5</v></c><c r="F3" t="b"><v>1</v></c></row>`)
+	assert.Contains(t, parts["xl/worksheets/sheet3.xml"], `<t>云服务器 ECS</t>`)
+	assert.Equal(t, "A", columnName(0))
+	assert.Equal(t, "Z", columnName(25))
+	assert.Equal(t, "AA", columnName(26))
+}
+
+// compactReader decodes the thrift compact structs into maps keyed by field id
+type compactReader struct {
+	b *bytes.Reader
+}
+
+func (r *compactReader) varint() uint64 {
+	v, _ := binary.ReadUvarint(r.b)
+	return v
+}
+
+func (r *compactReader) zigzag() int64 {
+	v := r.varint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *compactReader) value(typ byte) interface{} {
+	switch typ {
+	case compactI32, compactI64:
+		return r.zigzag()
+	case compactBinary:
+		b := make([]byte, r.varint())
+		_, _ = r.b.Read(b)
+		return string(b)
+	case compactList:
+		h, _ := r.b.ReadByte()
+		size, elemType := int(h>>4), h&0x0f
+		if size == 15 {
+			size = int(r.varint())
+		}
+		list := make([]interface{}, 0, size)
+		for i := 0; i < size; i++ {
+			list = append(list, r.value(elemType))
+		}
+		return list
+	case compactStruct:
+		return r.readStruct()
+	}
+	panic("unexpected type")
+}
+
+func (r *compactReader) readStruct() map[int]interface{} {
+	fields := make(map[int]interface{})
+	last := 0
+	for {
+		h, _ := r.b.ReadByte()
+		if h == 0 {
+			return fields
+		}
+		id := last + int(h>>4)
+		last = id
+		fields[id] = r.value(h & 0x0f)
+	}
+}
+
+func TestParquetExporter(t *testing.T) {
+	dir := t.TempDir()
+	files, err := parquetExporter{}.Export(dir, newTestDataset())
+	require.NoError(t, err)
+	b, err := ioutil.ReadFile(files[0]) // daily
+	require.NoError(t, err)
+	require.Equal(t, "PAR1", string(b[:4]))
+	require.Equal(t, "PAR1", string(b[len(b)-4:]))
+	size := binary.LittleEndian.Uint32(b[len(b)-8:])
+	meta := (&compactReader{bytes.NewReader(b[len(b)-8-int(size) : len(b)-8])}).readStruct()
+
+	assert.Equal(t, int64(1), meta[1])
+	assert.Equal(t, int64(2), meta[3])
+	schema := meta[2].([]interface{})
+	require.Len(t, schema, 7)
+	assert.Equal(t, int64(6), schema[0].(map[int]interface{})[5])
+	assert.Equal(t, map[int]interface{}{1: int64(_parquetByteArray), 3: int64(_parquetRequired), 4: "day", 6: int64(_parquetConvertUTF8)}, schema[1])
+	assert.Equal(t, map[int]interface{}{1: int64(_parquetDouble), 3: int64(_parquetRequired), 4: "amount"}, schema[5])
+
+	rowGroups := meta[4].([]interface{})
+	require.Len(t, rowGroups, 1)
+	columns := rowGroups[0].(map[int]interface{})[1].([]interface{})
+	require.Len(t, columns, 6)
+	readPage := func(col int) (map[int]interface{}, []byte) {
+		cm := columns[col].(map[int]interface{})[3].(map[int]interface{})
+		assert.Equal(t, []interface{}{"day", "account", "provider", "currency", "amount", "amortized"}[col], cm[3].([]interface{})[0])
+		offset := cm[9].(int64)
+		r := &compactReader{bytes.NewReader(b[offset:])}
+		header := r.readStruct()
+		start := len(b[offset:]) - r.b.Len()
+		values := b[offset+int64(start) : offset+int64(start)+header[3].(int64)]
+		assert.Equal(t, cm[6].(int64), int64(start)+header[3].(int64))
+		return header, values
+	}
+
+	header, values := readPage(0)
+	assert.Equal(t, int64(2), header[5].(map[int]interface{})[1])
+	assert.Equal(t, append(append([]byte{10, 0, 0, 0}, "2022-10-01"...), append([]byte{10, 0, 0, 0}, "2022-10-02"...)...), values)
+	_, values = readPage(4)
+	assert.Equal(t, 10.0, math.Float64frombits(binary.LittleEndian.Uint64(values[:8])))
+	assert.Equal(t, 20.5, math.Float64frombits(binary.LittleEndian.Uint64(values[8:])))
+	_, values = readPage(5)
+	assert.Equal(t, []byte{0b10}, values)
+}
+
+func TestParquetExporter_Empty(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, writeParquet(&b, Table{Name: TableDaily, Columns: _dailyColumns}))
+	size := binary.LittleEndian.Uint32(b.Bytes()[b.Len()-8:])
+	meta := (&compactReader{bytes.NewReader(b.Bytes()[4 : b.Len()-8])}).readStruct()
+	assert.Equal(t, int(size), b.Len()-12)
+	assert.Equal(t, int64(0), meta[3])
+	assert.Empty(t, meta[4])
+}
+
+// the output is read by the libraries the users read it with, not only by the readers above
+func TestXLSXExporter_Excelize(t *testing.T) {
+	files, err := xlsxExporter{}.Export(t.TempDir(), newTestDataset())
+	require.NoError(t, err)
+	f, err := excelize.OpenFile(files[0])
+	require.NoError(t, err)
+	defer f.Close()
+	assert.Equal(t, []string{"daily", "monthly", "products"}, f.GetSheetList())
+
+	rows, err := f.GetRows("daily")
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"day", "account", "provider", "currency", "amount", "amortized"},
+		{"2022-10-01", "ali", "AlibabaCloud", "CNY", "10", "FALSE"},
+		{"2022-10-02", "ali", "AlibabaCloud", "", "20.5", "TRUE"},
+	}, rows)
+	rows, err = f.GetRows("products")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2022-10-01", "ali", "AlibabaCloud", "ecs", "云服务器 ECS", "PostPaid", "CNY", "5"}, rows[1])
+}
+
+func TestParquetExporter_ParquetGo(t *testing.T) {
+	files, err := parquetExporter{}.Export(t.TempDir(), newTestDataset())
+	require.NoError(t, err)
+	file, err := local.NewLocalFileReader(files[0]) // daily
+	require.NoError(t, err)
+	defer file.Close()
+	pr, err := reader.NewParquetColumnReader(file, 1)
+	require.NoError(t, err)
+	defer pr.ReadStop()
+	assert.Equal(t, int64(2), pr.GetNumRows())
+
+	readColumn := func(name string) []interface{} {
+		values, _, _, err := pr.ReadColumnByPath(common.ReformPathStr(pr.SchemaHandler.GetRootExName()+"."+name), 2)
+		require.NoError(t, err)
+		return values
+	}
+	assert.Equal(t, []interface{}{"2022-10-01", "2022-10-02"}, readColumn("day"))
+	assert.Equal(t, []interface{}{"ali", "ali"}, readColumn("account"))
+	assert.Equal(t, []interface{}{"CNY", ""}, readColumn("currency"))
+	assert.Equal(t, []interface{}{10.0, 20.5}, readColumn("amount"))
+	assert.Equal(t, []interface{}{false, true}, readColumn("amortized"))
+}
@@ -0,0 +1,37 @@
+package export
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+)
+
+// jsonExporter one file, each view is an array of objects keyed by the columns
+type jsonExporter struct{}
+
+func (jsonExporter) Format() string { return "json" }
+
+func (jsonExporter) Export(dir string, ds Dataset) ([]string, error) {
+	out := map[string]interface{}{"generated_at": ds.GeneratedAt.Format(time.RFC3339)}
+	for _, t := range ds.Tables {
+		rows := make([]map[string]interface{}, 0, len(t.Rows))
+		for _, r := range t.Rows {
+			row := make(map[string]interface{}, len(t.Columns))
+			for i, c := range t.Columns {
+				row[c.Name] = r[i]
+			}
+			rows = append(rows, row)
+		}
+		out[t.Name] = rows
+	}
+	b, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	file := filepath.Join(dir, "costpilot.json")
+	if err := ioutil.WriteFile(file, b, 0644); err != nil {
+		return nil, err
+	}
+	return []string{file}, nil
+}
@@ -0,0 +1,279 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// parquetExporter one file per view, a single row group of required columns,
+// PLAIN encoded and uncompressed, which every parquet reader understands
+type parquetExporter struct{}
+
+func (parquetExporter) Format() string { return "parquet" }
+
+func (parquetExporter) Export(dir string, ds Dataset) ([]string, error) {
+	var files []string
+	for _, t := range ds.Tables {
+		file := filepath.Join(dir, fmt.Sprintf("costpilot-%s.parquet", t.Name))
+		f, err := os.Create(file)
+		if err != nil {
+			return files, err
+		}
+		err = writeParquet(f, t)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return files, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+const _parquetMagic = "PAR1"
+
+// parquet.thrift enums
+const (
+	_parquetBoolean   = 0
+	_parquetDouble    = 5
+	_parquetByteArray = 6
+
+	_parquetRequired     = 0
+	_parquetConvertUTF8  = 0
+	_parquetPlain        = 0
+	_parquetRLE          = 3
+	_parquetDataPage     = 0
+	_parquetUncompressed = 0
+)
+
+func parquetType(t ColumnType) int32 {
+	switch t {
+	case Float:
+		return _parquetDouble
+	case Bool:
+		return _parquetBoolean
+	}
+	return _parquetByteArray
+}
+
+type parquetChunk struct {
+	offset int64
+	size   int64
+}
+
+func writeParquet(w io.Writer, t Table) error {
+	var file bytes.Buffer
+	file.WriteString(_parquetMagic)
+	chunks := make([]parquetChunk, 0, len(t.Columns))
+	if len(t.Rows) > 0 {
+		for i, c := range t.Columns {
+			values := plainValues(t.Rows, i, c.Type)
+			header := newCompactWriter()
+			header.i32(1, _parquetDataPage)
+			header.i32(2, int32(len(values)))
+			header.i32(3, int32(len(values)))
+			header.beginStruct(5) // DataPageHeader
+			header.i32(1, int32(len(t.Rows)))
+			header.i32(2, _parquetPlain)
+			header.i32(3, _parquetRLE)
+			header.i32(4, _parquetRLE)
+			header.endStruct()
+			header.stop()
+
+			chunk := parquetChunk{offset: int64(file.Len()), size: int64(header.buf.Len() + len(values))}
+			file.Write(header.buf.Bytes())
+			file.Write(values)
+			chunks = append(chunks, chunk)
+		}
+	}
+
+	meta := newCompactWriter()
+	meta.i32(1, 1) // version
+	meta.beginList(2, compactStruct, len(t.Columns)+1)
+	meta.beginElem() // root of the schema
+	meta.binary(4, "schema")
+	meta.i32(5, int32(len(t.Columns)))
+	meta.endStruct()
+	for _, c := range t.Columns {
+		meta.beginElem()
+		meta.i32(1, parquetType(c.Type))
+		meta.i32(3, _parquetRequired)
+		meta.binary(4, c.Name)
+		if c.Type == String {
+			meta.i32(6, _parquetConvertUTF8)
+		}
+		meta.endStruct()
+	}
+	meta.i64(3, int64(len(t.Rows)))
+	var rowGroups int
+	if len(chunks) > 0 {
+		rowGroups = 1
+	}
+	meta.beginList(4, compactStruct, rowGroups)
+	if rowGroups > 0 {
+		var total int64
+		meta.beginElem() // RowGroup
+		meta.beginList(1, compactStruct, len(chunks))
+		for i, c := range t.Columns {
+			chunk := chunks[i]
+			total += chunk.size
+			meta.beginElem() // ColumnChunk
+			meta.i64(2, chunk.offset)
+			meta.beginStruct(3) // ColumnMetaData
+			meta.i32(1, parquetType(c.Type))
+			meta.beginList(2, compactI32, 2)
+			meta.elemI32(_parquetPlain)
+			meta.elemI32(_parquetRLE)
+			meta.beginList(3, compactBinary, 1)
+			meta.elemBinary(c.Name)
+			meta.i32(4, _parquetUncompressed)
+			meta.i64(5, int64(len(t.Rows)))
+			meta.i64(6, chunk.size)
+			meta.i64(7, chunk.size)
+			meta.i64(9, chunk.offset)
+			meta.endStruct()
+			meta.endStruct()
+		}
+		meta.i64(2, total)
+		meta.i64(3, int64(len(t.Rows)))
+		meta.endStruct()
+	}
+	meta.binary(6, "costpilot")
+	meta.stop()
+
+	file.Write(meta.buf.Bytes())
+	_ = binary.Write(&file, binary.LittleEndian, uint32(meta.buf.Len()))
+	file.WriteString(_parquetMagic)
+	_, err := w.Write(file.Bytes())
+	return err
+}
+
+// plainValues PLAIN encoding of the column, no levels are written for required columns
+func plainValues(rows [][]interface{}, col int, t ColumnType) []byte {
+	var b bytes.Buffer
+	switch t {
+	case Float:
+		for _, r := range rows {
+			v, _ := r[col].(float64)
+			_ = binary.Write(&b, binary.LittleEndian, math.Float64bits(v))
+		}
+	case Bool:
+		bits := make([]byte, (len(rows)+7)/8)
+		for i, r := range rows {
+			if v, _ := r[col].(bool); v {
+				bits[i/8] |= 1 << uint(i%8)
+			}
+		}
+		b.Write(bits)
+	default:
+		for _, r := range rows {
+			v := fmt.Sprint(r[col])
+			_ = binary.Write(&b, binary.LittleEndian, uint32(len(v)))
+			b.WriteString(v)
+		}
+	}
+	return b.Bytes()
+}
+
+// thrift compact protocol types
+const (
+	compactI32    = 5
+	compactI64    = 6
+	compactBinary = 8
+	compactList   = 9
+	compactStruct = 12
+)
+
+// compactWriter the subset of the thrift compact protocol used by the parquet metadata
+type compactWriter struct {
+	buf    bytes.Buffer
+	last   int16
+	frames []int16
+}
+
+func newCompactWriter() *compactWriter {
+	return &compactWriter{}
+}
+
+func (w *compactWriter) fieldHeader(id int16, typ byte) {
+	if delta := id - w.last; delta > 0 && delta <= 15 {
+		w.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		w.buf.WriteByte(typ)
+		w.varint(uint64(uint16(id)<<1 ^ uint16(id>>15)))
+	}
+	w.last = id
+}
+
+func (w *compactWriter) varint(v uint64) {
+	for v >= 0x80 {
+		w.buf.WriteByte(byte(v) | 0x80)
+		v >>= 7
+	}
+	w.buf.WriteByte(byte(v))
+}
+
+func (w *compactWriter) zigzag(v int64) {
+	w.varint(uint64(v<<1) ^ uint64(v>>63))
+}
+
+func (w *compactWriter) i32(id int16, v int32) {
+	w.fieldHeader(id, compactI32)
+	w.zigzag(int64(v))
+}
+
+func (w *compactWriter) i64(id int16, v int64) {
+	w.fieldHeader(id, compactI64)
+	w.zigzag(v)
+}
+
+func (w *compactWriter) binary(id int16, v string) {
+	w.fieldHeader(id, compactBinary)
+	w.elemBinary(v)
+}
+
+func (w *compactWriter) beginStruct(id int16) {
+	w.fieldHeader(id, compactStruct)
+	w.beginElem()
+}
+
+// beginElem a struct element of a list
+func (w *compactWriter) beginElem() {
+	w.frames = append(w.frames, w.last)
+	w.last = 0
+}
+
+func (w *compactWriter) endStruct() {
+	w.stop()
+	w.last = w.frames[len(w.frames)-1]
+	w.frames = w.frames[:len(w.frames)-1]
+}
+
+func (w *compactWriter) stop() {
+	w.buf.WriteByte(0)
+}
+
+func (w *compactWriter) beginList(id int16, elemType byte, size int) {
+	w.fieldHeader(id, compactList)
+	if size < 15 {
+		w.buf.WriteByte(byte(size)<<4 | elemType)
+		return
+	}
+	w.buf.WriteByte(0xf0 | elemType)
+	w.varint(uint64(size))
+}
+
+func (w *compactWriter) elemI32(v int32) {
+	w.zigzag(int64(v))
+}
+
+func (w *compactWriter) elemBinary(v string) {
+	w.varint(uint64(len(v)))
+	w.buf.WriteString(v)
+}
@@ -0,0 +1,108 @@
+package i18n
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+)
+
+type Language string
+
+const (
+	ZhCN Language = "zh-CN"
+	EnUS Language = "en-US"
+
+	Default = ZhCN
+)
+
+var (
+	_catalogsMu sync.RWMutex
+	_catalogs   = map[Language]map[string]string{
+		ZhCN: _zhCN,
+		EnUS: _enUS,
+	}
+)
+
+var _language atomic.Value // Language
+
+// ParseLanguage accepts the tags case-insensitively, with "_" or without the region, eg: en, en_us, zh-cn
+func ParseLanguage(s string) (Language, error) {
+	switch strings.ToLower(strings.ReplaceAll(strings.TrimSpace(s), "_", "-")) {
+	case "":
+		return Default, nil
+	case "zh", "zh-cn", "zh-hans":
+		return ZhCN, nil
+	case "en", "en-us":
+		return EnUS, nil
+	}
+	return "", fmt.Errorf("invalid language %q, expect en-US | zh-CN", s)
+}
+
+// SetLanguage the language of the labels rendered from now on
+func SetLanguage(l Language) {
+	_language.Store(l)
+}
+
+func GetLanguage() Language {
+	if l, ok := _language.Load().(Language); ok {
+		return l
+	}
+	return Default
+}
+
+// T the message of the key in the current language
+func T(key string, args ...interface{}) string {
+	return GetLanguage().T(key, args...)
+}
+
+// T the message of the key, formatted by args if any, falls back to the default language and then to the key
+func (l Language) T(key string, args ...interface{}) string {
+	_catalogsMu.RLock()
+	msg, ok := _catalogs[l][key]
+	if !ok {
+		if msg, ok = _catalogs[Default][key]; !ok {
+			msg = key
+		}
+	}
+	_catalogsMu.RUnlock()
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// AddMessages add or override the messages of the language, eg: the provider names of the registry
+func AddMessages(l Language, messages map[string]string) {
+	_catalogsMu.Lock()
+	defer _catalogsMu.Unlock()
+	if _catalogs[l] == nil {
+		_catalogs[l] = map[string]string{}
+	}
+	for k, v := range messages {
+		_catalogs[l][k] = v
+	}
+}
+
+// ProviderName the display name of the provider registered, else the name itself
+func ProviderName(p cloud.Provider) string {
+	if p == "" {
+		return cloud.Undefined
+	}
+	key := "provider." + string(p)
+	if msg := T(key); msg != key {
+		return msg
+	}
+	return string(p)
+}
+
+// SubscriptionTypeName the display name of the charge type
+func SubscriptionTypeName(s cloud.SubscriptionType) string {
+	key := "subscription." + string(s)
+	if msg := T(key); msg != key {
+		return msg
+	}
+	return cloud.Undefined
+}
@@ -0,0 +1,61 @@
+package i18n
+
+import (
+	"regexp"
+	"sort"
+	"testing"
+
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _verb = regexp.MustCompile(`%(\[\d+\])?[a-z]`)
+
+func TestCatalogs(t *testing.T) {
+	keys := func(m map[string]string) []string {
+		var ks []string
+		for k := range m {
+			ks = append(ks, k)
+		}
+		sort.Strings(ks)
+		return ks
+	}
+	require.Equal(t, keys(_zhCN), keys(_enUS))
+	for k, zh := range _zhCN {
+		assert.Equal(t, len(_verb.FindAllString(zh, -1)), len(_verb.FindAllString(_enUS[k], -1)), k)
+	}
+}
+
+func TestParseLanguage(t *testing.T) {
+	for s, want := range map[string]Language{"": ZhCN, "zh": ZhCN, "zh_CN": ZhCN, " en ": EnUS, "EN-us": EnUS} {
+		l, err := ParseLanguage(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, want, l, s)
+	}
+	_, err := ParseLanguage("fr")
+	assert.Error(t, err)
+}
+
+func TestT(t *testing.T) {
+	defer SetLanguage(GetLanguage())
+	AddMessages(EnUS, map[string]string{"provider.TestCloud": "Test Cloud"})
+	AddMessages(ZhCN, map[string]string{"provider.TestCloud": "测试云"})
+
+	SetLanguage(EnUS)
+	assert.Equal(t, "Q3 2022 to date", T("stat.quarter", 2022, 3))
+	assert.Equal(t, "Coverage (%)", T("col.coverage"))
+	assert.Equal(t, "AWS Savings plans coverage (%)", T("commitment.coverage", "AWS", T("commitment.savings")))
+	assert.Equal(t, "Test Cloud", ProviderName("TestCloud"))
+	assert.Equal(t, "Pay-as-you-go", SubscriptionTypeName(cloud.PostPaid))
+	assert.Equal(t, "Unknown", ProviderName("Unknown"))
+	assert.Equal(t, cloud.Undefined, ProviderName(""))
+	assert.Equal(t, "no.such.key", T("no.such.key"))
+
+	SetLanguage(ZhCN)
+	assert.Equal(t, "2022年第3季度累计", T("stat.quarter", 2022, 3))
+	assert.Equal(t, "测试云", ProviderName("TestCloud"))
+
+	// a language without a catalog falls back to the default
+	assert.Equal(t, "按量付费", Language("ja-JP").T("subscription.PostPaid"))
+}
@@ -0,0 +1,393 @@
+package i18n
+
+// the catalogs must have the same keys, the messages with args are fmt formats
+
+var _zhCN = map[string]string{
+	"layout.day":   "2006年01月02日",
+	"layout.month": "2006年01月",
+
+	"subscription.PrePaid":  "包年包月",
+	"subscription.PostPaid": "按量付费",
+	"region.unknown":        "未知",
+	"unit.server":           "台",
+	"unit.count":            "个",
+	"unit.hours":            "小时",
+	"yes":                   "是",
+	"no":                    "否",
+
+	"cost.view.cash":      "现金视图",
+	"cost.view.amortized": "分摊视图",
+	"cost.basis.list":     "原价",
+	"cost.basis.discount": "优惠后",
+	"cost.basis.net":      "净额",
+	"cost.basis.tax_incl": "含税",
+
+	"cost.ratio.product_day":    "日成本构成比例",
+	"cost.ratio.provider_day":   "日成本云厂商比例",
+	"cost.ratio.charge_day":     "云服务器日花费付费类型",
+	"cost.ratio.product_month":  "月成本构成比例",
+	"cost.ratio.provider_month": "月成本云厂商比例",
+	"cost.ratio.charge_month":   "云服务器月花费付费类型",
+	"cost.trend":                "成本走势",
+	"cost.trend.y_cost":         "成本 (%s)",
+	"cost.trend.y_ratio":        "变化率 (%)",
+	"cost.series.current":       "成本(本期)",
+	"cost.series.last_year":     "成本(上一年同期)",
+	"cost.series.day_over_day":  "环比上一天",
+	"cost.series.month_over":    "环比上一月",
+	"cost.series.year_over":     "同比上一年",
+	"cost.series.forecast":      "成本预测",
+	"cost.series.upper":         "预测上限",
+	"cost.series.lower":         "预测下限",
+
+	"stat.day":            "%s累计",
+	"stat.month":          "%s累计",
+	"stat.quarter":        "%d年第%d季度累计",
+	"stat.year":           "%d年累计",
+	"stat.year_forecast":  "%d年预计全年",
+	"stat.prev_day":       "前一天同期",
+	"stat.prev_month":     "上月同期",
+	"stat.prev_quarter":   "上季度同期",
+	"stat.prev_year":      "上年同期",
+	"stat.prev_full_year": "上年全年",
+
+	"util.cpu_avg":         "CPU 平均利用率",
+	"util.memory_avg":      "内存平均利用率",
+	"util.servers":         "云服务器",
+	"util.prev_day":        "前日数据",
+	"util.series.cpu":      "CPU",
+	"util.series.memory":   "内存",
+	"util.series.avg":      "平均值",
+	"util.cpu_above_80":    "80%以上",
+	"util.ratio.charge":    "服务器付费类型",
+	"util.ratio.provider":  "服务器云厂商分布",
+	"util.ratio.region":    "云服务器地域分布",
+	"util.cpu_trend":       "CPU 利用率分布",
+	"util.cpu_trend.y":     "机器数 (个)",
+	"util.utilize_trend":   "利用率走势",
+	"util.utilize_trend.y": "利用率（%）",
+
+	"anomaly.title":         "成本异常",
+	"anomaly.account_total": "账号合计",
+	"budget.title":          "预算执行情况",
+	"budget.state.ok":       "正常",
+	"budget.state.warning":  "预警",
+	"budget.state.exceeded": "超支",
+	"idle.title":            "闲置与低利用率实例",
+	"idle.level.idle":       "闲置",
+	"idle.level.low":        "低利用率",
+	"optimization.title":    "优化机会",
+	"optimization.savings":  "预计每月可节省(%s)",
+	"action.downsize":       "降配",
+	"action.terminate":      "停用",
+	"commitment.title":      "承诺使用分析",
+	"commitment.coverage":   "%s%s覆盖率(%%)",
+	"commitment.usage":      "%s%s使用率(%%)",
+	"commitment.bucket":     "%d天内到期",
+	"commitment.reserved":   "预留实例",
+	"commitment.savings":    "节省计划",
+	"commitment.subscribed": "包年包月",
+
+	"anomaly.severity.warning":  "警告",
+	"anomaly.severity.critical": "严重",
+	"commitment.coverages":      "承诺覆盖率",
+	"commitment.expiring":       "即将到期的承诺",
+
+	"col.account":          "账号",
+	"col.provider":         "云厂商",
+	"col.instance_id":      "实例ID",
+	"col.region":           "地域",
+	"col.instance_spec":    "规格",
+	"col.charge_type":      "付费类型",
+	"col.level":            "类型",
+	"col.cpu_avg":          "CPU 平均利用率(%)",
+	"col.cpu_peak":         "CPU 峰值利用率(%)",
+	"col.memory_avg":       "内存平均利用率(%)",
+	"col.memory_peak":      "内存峰值利用率(%)",
+	"col.monthly_cost":     "预计月成本",
+	"col.monthly_savings":  "预计每月节省",
+	"col.action":           "建议",
+	"col.current_type":     "当前规格",
+	"col.recommended_type": "推荐规格",
+	"col.currency":         "币种",
+	"col.commitment_type":  "承诺类型",
+	"col.covered":          "已覆盖用量",
+	"col.eligible":         "可覆盖用量",
+	"col.unit":             "单位",
+	"col.coverage":         "覆盖率(%)",
+	"col.utilization":      "使用率(%)",
+	"col.commitment_id":    "承诺ID",
+	"col.description":      "规格/产品",
+	"col.count":            "数量",
+	"col.end_time":         "到期时间",
+	"col.days_left":        "剩余天数",
+	"col.bucket":           "到期分组",
+	"col.auto_renew":       "自动续费",
+
+	"col.budget":            "预算",
+	"col.period":            "预算周期",
+	"col.budget_amount":     "预算金额",
+	"col.spent":             "已花费",
+	"col.projected":         "预计花费",
+	"col.percent":           "执行率(%)",
+	"col.projected_percent": "预计执行率(%)",
+	"col.state":             "状态",
+	"col.day":               "日期",
+	"col.product":           "产品",
+	"col.amount":            "金额",
+	"col.baseline":          "基线",
+	"col.severity":          "级别",
+	"col.contributors":      "主要变动产品",
+	"col.change":            "变化率(%)",
+
+	"report.title":       "CostPilot 成本分析",
+	"report.data_cycle":  "数据截至 %s",
+	"report.cost":        "成本统计",
+	"report.utilization": "资源利用统计",
+	"report.total":       "合计 %s %s",
+	"report.no_data":     "无数据",
+	"report.col.cycle":   "统计周期",
+	"report.col.cost":    "成本",
+	"report.col.prev":    "对比周期",
+	"report.col.prev_c":  "对比成本",
+	"report.col.change":  "变化率",
+	"report.col.metric":  "指标",
+	"report.col.value":   "数值",
+	"report.col.prev_v":  "对比数值",
+	"report.col.name":    "名称",
+	"report.col.share":   "占比",
+	"report.col.series":  "序列",
+	"report.col.trend":   "走势",
+	"report.col.min":     "最小",
+	"report.col.max":     "最大",
+	"report.col.latest":  "最新",
+
+	"report.unsupported":    "不支持的数据",
+	"report.col.capability": "缺少的能力",
+	"report.col.impact":     "影响",
+	"report.col.reason":     "原因",
+
+	"report.discovery_failed": "成员账号发现失败, 其成员账号未分析",
+	"report.region_failed":    "地域扫描失败, 其实例未分析",
+
+	"capability.billing":        "账单",
+	"capability.instance_bills": "实例账单",
+	"capability.inventory":      "地域实例列表",
+	"capability.subscriptions":  "实例列表",
+	"capability.commitments":    "预留实例与节省计划",
+	"capability.metrics":        "监控指标",
+	"capability.regions":        "地域列表",
+
+	"unsupported.cost":          "成本分析未包含该账号",
+	"unsupported.amortization":  "包年包月费用未分摊",
+	"unsupported.utilization":   "利用率分析未包含该账号",
+	"unsupported.instance_cost": "闲置与降配建议不含实例费用",
+	"unsupported.commitment":    "承诺使用分析未包含该账号",
+	"unsupported.metric":        "利用率走势不含该账号的此指标",
+
+	"metric.cpu.utilization":         "CPU 利用率",
+	"metric.memory.used.utilization": "内存利用率",
+	"metric.disk.used.utilization":   "磁盘使用率",
+	"metric.disk.read.iops":          "磁盘读 IOPS",
+	"metric.disk.write.iops":         "磁盘写 IOPS",
+	"metric.disk.read.throughput":    "磁盘读吞吐",
+	"metric.disk.write.throughput":   "磁盘写吞吐",
+	"metric.network.in.bandwidth":    "网络入带宽",
+	"metric.network.out.bandwidth":   "网络出带宽",
+	"metric.load.average":            "系统负载 (5 分钟)",
+}
+
+var _enUS = map[string]string{
+	"layout.day":   "Jan 2, 2006",
+	"layout.month": "Jan 2006",
+
+	"subscription.PrePaid":  "Subscription",
+	"subscription.PostPaid": "Pay-as-you-go",
+	"region.unknown":        "Unknown",
+	"unit.server":           "",
+	"unit.count":            "",
+	"unit.hours":            "Hours",
+	"yes":                   "Yes",
+	"no":                    "No",
+
+	"cost.view.cash":      "Cash view",
+	"cost.view.amortized": "Amortized view",
+	"cost.basis.list":     "List price",
+	"cost.basis.discount": "After discounts",
+	"cost.basis.net":      "Net",
+	"cost.basis.tax_incl": "Tax inclusive",
+
+	"cost.ratio.product_day":    "Daily cost by product",
+	"cost.ratio.provider_day":   "Daily cost by provider",
+	"cost.ratio.charge_day":     "Daily server cost by charge type",
+	"cost.ratio.product_month":  "Monthly cost by product",
+	"cost.ratio.provider_month": "Monthly cost by provider",
+	"cost.ratio.charge_month":   "Monthly server cost by charge type",
+	"cost.trend":                "Cost trend",
+	"cost.trend.y_cost":         "Cost (%s)",
+	"cost.trend.y_ratio":        "Change (%)",
+	"cost.series.current":       "Cost",
+	"cost.series.last_year":     "Cost (same period last year)",
+	"cost.series.day_over_day":  "Day over day",
+	"cost.series.month_over":    "Month over month",
+	"cost.series.year_over":     "Year over year",
+	"cost.series.forecast":      "Forecast",
+	"cost.series.upper":         "Forecast upper bound",
+	"cost.series.lower":         "Forecast lower bound",
+
+	"stat.day":            "Total of %s",
+	"stat.month":          "%s to date",
+	"stat.quarter":        "Q%[2]d %[1]d to date",
+	"stat.year":           "%d to date",
+	"stat.year_forecast":  "%d full year forecast",
+	"stat.prev_day":       "Previous day",
+	"stat.prev_month":     "Same period last month",
+	"stat.prev_quarter":   "Same period last quarter",
+	"stat.prev_year":      "Same period last year",
+	"stat.prev_full_year": "Last full year",
+
+	"util.cpu_avg":         "Average CPU utilization",
+	"util.memory_avg":      "Average memory utilization",
+	"util.servers":         "Servers",
+	"util.prev_day":        "Previous day",
+	"util.series.cpu":      "CPU",
+	"util.series.memory":   "Memory",
+	"util.series.avg":      "Average",
+	"util.cpu_above_80":    "80% and above",
+	"util.ratio.charge":    "Servers by charge type",
+	"util.ratio.provider":  "Servers by provider",
+	"util.ratio.region":    "Servers by region",
+	"util.cpu_trend":       "CPU utilization distribution",
+	"util.cpu_trend.y":     "Servers",
+	"util.utilize_trend":   "Utilization trend",
+	"util.utilize_trend.y": "Utilization (%)",
+
+	"anomaly.title":         "Cost anomalies",
+	"anomaly.account_total": "Account total",
+	"budget.title":          "Budgets",
+	"budget.state.ok":       "OK",
+	"budget.state.warning":  "Warning",
+	"budget.state.exceeded": "Exceeded",
+	"idle.title":            "Idle and underutilized instances",
+	"idle.level.idle":       "Idle",
+	"idle.level.low":        "Underutilized",
+	"optimization.title":    "Optimization opportunities",
+	"optimization.savings":  "Est. monthly savings (%s)",
+	"action.downsize":       "Downsize",
+	"action.terminate":      "Terminate",
+	"commitment.title":      "Commitment analysis",
+	"commitment.coverage":   "%s %s coverage (%%)",
+	"commitment.usage":      "%s %s utilization (%%)",
+	"commitment.bucket":     "Within %d days",
+	"commitment.reserved":   "Reserved instances",
+	"commitment.savings":    "Savings plans",
+	"commitment.subscribed": "Subscriptions",
+
+	"anomaly.severity.warning":  "Warning",
+	"anomaly.severity.critical": "Critical",
+	"commitment.coverages":      "Commitment coverage",
+	"commitment.expiring":       "Expiring commitments",
+
+	"col.account":          "Account",
+	"col.provider":         "Provider",
+	"col.instance_id":      "Instance ID",
+	"col.region":           "Region",
+	"col.instance_spec":    "Instance type",
+	"col.charge_type":      "Charge type",
+	"col.level":            "Level",
+	"col.cpu_avg":          "Avg CPU (%)",
+	"col.cpu_peak":         "Peak CPU (%)",
+	"col.memory_avg":       "Avg memory (%)",
+	"col.memory_peak":      "Peak memory (%)",
+	"col.monthly_cost":     "Est. monthly cost",
+	"col.monthly_savings":  "Est. monthly savings",
+	"col.action":           "Action",
+	"col.current_type":     "Current type",
+	"col.recommended_type": "Recommended type",
+	"col.currency":         "Currency",
+	"col.commitment_type":  "Commitment type",
+	"col.covered":          "Covered usage",
+	"col.eligible":         "Eligible usage",
+	"col.unit":             "Unit",
+	"col.coverage":         "Coverage (%)",
+	"col.utilization":      "Utilization (%)",
+	"col.commitment_id":    "Commitment ID",
+	"col.description":      "Type / product",
+	"col.count":            "Count",
+	"col.end_time":         "Expires at",
+	"col.days_left":        "Days left",
+	"col.bucket":           "Expires within",
+	"col.auto_renew":       "Auto renew",
+
+	"col.budget":            "Budget",
+	"col.period":            "Period",
+	"col.budget_amount":     "Amount",
+	"col.spent":             "Spent",
+	"col.projected":         "Projected",
+	"col.percent":           "Spent (%)",
+	"col.projected_percent": "Projected (%)",
+	"col.state":             "State",
+	"col.day":               "Day",
+	"col.product":           "Product",
+	"col.amount":            "Amount",
+	"col.baseline":          "Baseline",
+	"col.severity":          "Severity",
+	"col.contributors":      "Top contributors",
+	"col.change":            "Change (%)",
+
+	"report.title":       "CostPilot cost analysis",
+	"report.data_cycle":  "data as of %s",
+	"report.cost":        "Cost statistics",
+	"report.utilization": "Utilization statistics",
+	"report.total":       "total %s %s",
+	"report.no_data":     "No data",
+	"report.col.cycle":   "Period",
+	"report.col.cost":    "Cost",
+	"report.col.prev":    "Compared with",
+	"report.col.prev_c":  "Compared cost",
+	"report.col.change":  "Change",
+	"report.col.metric":  "Metric",
+	"report.col.value":   "Value",
+	"report.col.prev_v":  "Compared value",
+	"report.col.name":    "Name",
+	"report.col.share":   "Share",
+	"report.col.series":  "Series",
+	"report.col.trend":   "Trend",
+	"report.col.min":     "Min",
+	"report.col.max":     "Max",
+	"report.col.latest":  "Latest",
+
+	"report.unsupported":    "Unsupported data",
+	"report.col.capability": "Missing capability",
+	"report.col.impact":     "Impact",
+	"report.col.reason":     "Reason",
+
+	"report.discovery_failed": "Member discovery failed, the members are not analyzed",
+	"report.region_failed":    "Region scan failed, the instances of the regions are not analyzed",
+
+	"capability.billing":        "Bills",
+	"capability.instance_bills": "Instance bills",
+	"capability.inventory":      "Instances of the regions",
+	"capability.subscriptions":  "Instance list",
+	"capability.commitments":    "Reserved instances and savings plans",
+	"capability.metrics":        "Metrics",
+	"capability.regions":        "Region list",
+
+	"unsupported.cost":          "the account is left out of the cost analysis",
+	"unsupported.amortization":  "the prepaid charges are not amortized",
+	"unsupported.utilization":   "the account is left out of the utilization analysis",
+	"unsupported.instance_cost": "the idle and rightsizing findings have no instance cost",
+	"unsupported.commitment":    "the account is left out of the commitment analysis",
+	"unsupported.metric":        "the metric of the account is left out of the utilization trends",
+
+	"metric.cpu.utilization":         "CPU utilization",
+	"metric.memory.used.utilization": "Memory utilization",
+	"metric.disk.used.utilization":   "Disk usage",
+	"metric.disk.read.iops":          "Disk read IOPS",
+	"metric.disk.write.iops":         "Disk write IOPS",
+	"metric.disk.read.throughput":    "Disk read throughput",
+	"metric.disk.write.throughput":   "Disk write throughput",
+	"metric.network.in.bandwidth":    "Network in bandwidth",
+	"metric.network.out.bandwidth":   "Network out bandwidth",
+	"metric.load.average":            "Load average (5 min)",
+}
@@ -0,0 +1,216 @@
+package metrics
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/api"
+	"github.com/galaxy-future/costpilot/internal/auth"
+	"github.com/galaxy-future/costpilot/internal/data"
+	providerTypes "github.com/galaxy-future/costpilot/internal/providers/types"
+	"github.com/galaxy-future/costpilot/tools"
+	"github.com/gin-gonic/gin"
+)
+
+const _dayLayout = "2006-01-02"
+
+// Collector gauges of the latest analysis, served in the prometheus text format
+type Collector struct {
+	mu          sync.RWMutex
+	families    []*Family // of the last successful run
+	duration    time.Duration
+	success     bool
+	lastSuccess time.Time
+}
+
+func NewCollector() *Collector {
+	return &Collector{}
+}
+
+// Update after each pipeline run, a failed run keeps the data of the last successful one
+func (c *Collector) Update(snapshot *api.Snapshot, duration time.Duration, err error) {
+	var families []*Family
+	if err == nil && snapshot != nil {
+		families = build(snapshot)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.duration = duration
+	c.success = err == nil && snapshot != nil
+	if c.success {
+		c.families = families
+		c.lastSuccess = time.Now()
+	}
+}
+
+// Families the data gauges followed by the collection ones
+func (c *Collector) Families() []*Family {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	duration := &Family{Name: "costpilot_collection_duration_seconds", Help: "Duration of the last analysis run."}
+	duration.Add(c.duration.Seconds())
+	success := &Family{Name: "costpilot_collection_success", Help: "Whether the last analysis run succeeded."}
+	success.Add(boolValue(c.success))
+	lastSuccess := &Family{Name: "costpilot_collection_last_success_timestamp_seconds", Help: "Unix time of the last successful analysis run."}
+	if !c.lastSuccess.IsZero() {
+		lastSuccess.Add(float64(c.lastSuccess.Unix()))
+	}
+	return append(append([]*Family{}, c.families...), duration, success, lastSuccess)
+}
+
+func (c *Collector) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	_ = WriteText(w, c.Families())
+}
+
+// Serve behind the auth middleware, the samples of the accounts not granted to the principal are left out
+func (c *Collector) Serve(ctx *gin.Context) {
+	families := c.Families()
+	if p := auth.FromContext(ctx); p != nil {
+		families = Filter(families, p.CanAccessAccount)
+	}
+	ctx.Header("Content-Type", ContentType)
+	_ = WriteText(ctx.Writer, families)
+}
+
+func build(snapshot *api.Snapshot) []*Family {
+	yesterday := snapshot.GeneratedAt.AddDate(0, 0, -1).Format(_dayLayout)
+	month := yesterday[:len("2006-01")]
+
+	dailyCost := &Family{Name: "costpilot_daily_cost", Help: "Cost of the latest billed day, today is not included."}
+	monthToDate := &Family{Name: "costpilot_month_to_date_cost", Help: "Cost of the month of yesterday up to yesterday."}
+	for _, a := range snapshot.AccountBillings {
+		if a.DaysBilling == nil {
+			continue
+		}
+		account := []Label{{"provider", a.Provider.String()}, {"account", a.AccountName}}
+		latest := latestDay(a.DaysBilling, yesterday)
+		mtd := make(map[string]float64) // key : currency
+		a.DaysBilling.Range(func(key, value interface{}) bool {
+			day, d := key.(string), value.(data.DailyBilling)
+			if !strings.HasPrefix(day, month) || day > yesterday {
+				return true
+			}
+			for _, c := range productCosts(d) {
+				mtd[c.currency] = tools.Float64Add(mtd[c.currency], c.amount)
+				if day == latest {
+					dailyCost.Add(c.amount, append(account,
+						Label{"product", c.pipCode}, Label{"subscription_type", c.subscriptionType}, Label{"currency", c.currency})...)
+				}
+			}
+			return true
+		})
+		for currency, amount := range mtd {
+			monthToDate.Add(amount, append(account, Label{"currency", currency})...)
+		}
+	}
+
+	cpu := &Family{Name: "costpilot_instance_cpu_utilization", Help: "Average CPU utilization of the instance in the latest collected day, in percent."}
+	memory := &Family{Name: "costpilot_instance_memory_utilization", Help: "Average memory utilization of the instance in the latest collected day, in percent."}
+	metric := &Family{Name: "costpilot_instance_metric", Help: "Average of the metric of utilization_analysis.metrics of the instance in the latest collected day, in the unit label."}
+	regionFailed := &Family{Name: "costpilot_region_scan_failed", Help: "Regions whose instances could not be listed in the last run, their utilization is missing."}
+	for _, a := range snapshot.AccountUtilizations {
+		for _, f := range a.RegionScan.Failed {
+			regionFailed.Add(1, Label{"provider", a.Provider.String()}, Label{"account", a.AccountName}, Label{"region", f.RegionId})
+		}
+		region := func(instanceId string) string {
+			if a.RecentInstances == nil {
+				return ""
+			}
+			if val, ok := a.RecentInstances.Load(a.Provider.String() + ":" + instanceId); ok {
+				return val.(data.InstanceDetail).RegionId
+			}
+			return ""
+		}
+		instance := func(instanceId string) []Label {
+			return []Label{{"provider", a.Provider.String()}, {"account", a.AccountName}, {"instance", instanceId}, {"region", region(instanceId)}}
+		}
+		if a.DailyCpu != nil {
+			if val, ok := a.DailyCpu.Load(latestDay(a.DailyCpu, yesterday)); ok {
+				for _, u := range val.(data.DailyCpuUtilization).Utilization {
+					cpu.Add(u.UsedUtilization, instance(u.InstanceId)...)
+				}
+			}
+		}
+		if a.DailyMemory != nil {
+			if val, ok := a.DailyMemory.Load(latestDay(a.DailyMemory, yesterday)); ok {
+				for _, u := range val.(data.DailyMemoryUtilization).Utilization {
+					memory.Add(u.UsedUtilization, instance(u.InstanceId)...)
+				}
+			}
+		}
+		for _, d := range providerTypes.MetricCatalog() {
+			daily, ok := a.DailyMetrics[d.Item]
+			if !ok {
+				continue
+			}
+			if val, ok := daily.Load(latestDay(daily, yesterday)); ok {
+				for _, u := range val.(data.DailyMetric).Samples {
+					metric.Add(u.Average, append(instance(u.InstanceId), Label{"metric", string(d.Item)}, Label{"unit", string(d.Unit)})...)
+				}
+			}
+		}
+	}
+	discoveryFailed := &Family{Name: "costpilot_member_discovery_failed", Help: "Accounts whose member accounts could not be discovered in the last run, the members are missing."}
+	for _, f := range snapshot.DiscoveryFailures {
+		discoveryFailed.Add(1, Label{"provider", f.Provider.String()}, Label{"account", f.AccountName})
+	}
+	return []*Family{dailyCost, monthToDate, cpu, memory, metric, regionFailed, discoveryFailed}
+}
+
+type productCost struct {
+	pipCode          string
+	subscriptionType string
+	currency         string
+	amount           float64
+}
+
+// productCosts cost of the day by product, subscription type and currency, bills without product detail have empty labels
+func productCosts(d data.DailyBilling) []productCost {
+	sums := make(map[productCost]float64)
+	for pipCode, p := range d.ProductsBilling {
+		if len(p.Items) == 0 {
+			k := productCost{pipCode: pipCode}
+			sums[k] = tools.Float64Add(sums[k], p.TotalAmount)
+			continue
+		}
+		for _, item := range p.Items {
+			k := productCost{pipCode: pipCode, subscriptionType: string(item.SubscriptionType), currency: item.Currency}
+			sums[k] = tools.Float64Add(sums[k], item.PretaxAmount)
+		}
+	}
+	if len(d.ProductsBilling) == 0 && d.TotalAmount != 0 {
+		sums[productCost{}] = d.TotalAmount
+	}
+	ret := make([]productCost, 0, len(sums))
+	for k, v := range sums {
+		k.amount = v
+		ret = append(ret, k)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].pipCode+ret[i].subscriptionType+ret[i].currency < ret[j].pipCode+ret[j].subscriptionType+ret[j].currency
+	})
+	return ret
+}
+
+// latestDay the latest key not after the end day
+func latestDay(m *sync.Map, end string) string {
+	var latest string
+	m.Range(func(key, _ interface{}) bool {
+		if day := key.(string); day <= end && day > latest {
+			latest = day
+		}
+		return true
+	})
+	return latest
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
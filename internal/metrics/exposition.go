@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContentType of the prometheus text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+type Label struct {
+	Name, Value string
+}
+
+type Sample struct {
+	Labels []Label
+	Value  float64
+}
+
+// Family a gauge and its samples
+type Family struct {
+	Name    string
+	Help    string
+	Samples []Sample
+}
+
+func (f *Family) Add(value float64, labels ...Label) {
+	f.Samples = append(f.Samples, Sample{Labels: labels, Value: value})
+}
+
+// Filter the families with the samples of the accounts allowed, the samples without the account label are kept
+func Filter(families []*Family, allow func(account string) bool) []*Family {
+	ret := make([]*Family, 0, len(families))
+	for _, f := range families {
+		filtered := &Family{Name: f.Name, Help: f.Help, Samples: make([]Sample, 0, len(f.Samples))}
+		for _, s := range f.Samples {
+			if account, ok := s.label("account"); !ok || allow(account) {
+				filtered.Samples = append(filtered.Samples, s)
+			}
+		}
+		ret = append(ret, filtered)
+	}
+	return ret
+}
+
+func (s Sample) label(name string) (string, bool) {
+	for _, l := range s.Labels {
+		if l.Name == name {
+			return l.Value, true
+		}
+	}
+	return "", false
+}
+
+// WriteText write the families in the prometheus text format, samples are sorted by their labels
+func WriteText(w io.Writer, families []*Family) error {
+	b := bufio.NewWriter(w)
+	for _, f := range families {
+		b.WriteString("# HELP " + f.Name + " " + escapeHelp(f.Help) + "\n")
+		b.WriteString("# TYPE " + f.Name + " gauge\n")
+		lines := make([]string, 0, len(f.Samples))
+		for _, s := range f.Samples {
+			lines = append(lines, f.Name+formatLabels(s.Labels)+" "+formatValue(s.Value)+"\n")
+		}
+		sort.Strings(lines)
+		for _, l := range lines {
+			b.WriteString(l)
+		}
+	}
+	return b.Flush()
+}
+
+func formatLabels(labels []Label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, l := range labels {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(l.Name + `="` + escapeLabelValue(l.Value) + `"`)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	_helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	_labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return _helpEscaper.Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return _labelEscaper.Replace(s)
+}
@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/api"
+	"github.com/galaxy-future/costpilot/internal/auth"
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/types"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSnapshot() *api.Snapshot {
+	var days, cpu, memory, instances sync.Map
+	days.Store("2022-09-30", data.DailyBilling{Day: "2022-09-30", TotalAmount: 100, ProductsBilling: map[string]data.ProductBilling{
+		"ecs": {TotalAmount: 100, Items: []data.ItemInProductBilling{{PipCode: "ecs", PretaxAmount: 100, SubscriptionType: cloud.PostPaid, Currency: "CNY"}}},
+	}})
+	days.Store("2022-10-01", data.DailyBilling{Day: "2022-10-01", TotalAmount: 10, ProductsBilling: map[string]data.ProductBilling{
+		"ecs": {TotalAmount: 7, Items: []data.ItemInProductBilling{
+			{PipCode: "ecs", PretaxAmount: 5, SubscriptionType: cloud.PostPaid, Currency: "CNY"},
+			{PipCode: "ecs", PretaxAmount: 2, SubscriptionType: cloud.PrePaid, Currency: "CNY"},
+		}},
+		"oss": {TotalAmount: 3, Items: []data.ItemInProductBilling{{PipCode: "oss", PretaxAmount: 3, SubscriptionType: cloud.PostPaid, Currency: "CNY"}}},
+	}})
+	days.Store("2022-10-02", data.DailyBilling{Day: "2022-10-02", TotalAmount: 20, ProductsBilling: map[string]data.ProductBilling{
+		"ecs": {TotalAmount: 20, Items: []data.ItemInProductBilling{{PipCode: "ecs", PretaxAmount: 20, SubscriptionType: cloud.PostPaid, Currency: "CNY"}}},
+	}})
+	days.Store("2022-10-03", data.DailyBilling{Day: "2022-10-03", TotalAmount: 1}) // today is not complete
+	cpu.Store("2022-10-01", data.DailyCpuUtilization{Day: "2022-10-01", Utilization: []data.InstanceCpuUtilization{{InstanceId: "i-1", UsedUtilization: 10}}})
+	cpu.Store("2022-10-02", data.DailyCpuUtilization{Day: "2022-10-02", Utilization: []data.InstanceCpuUtilization{{InstanceId: "i-1", UsedUtilization: 12.5}}})
+	memory.Store("2022-10-02", data.DailyMemoryUtilization{Day: "2022-10-02", Utilization: []data.InstanceMemoryUtilization{{InstanceId: "i-1", UsedUtilization: 40}}})
+	instances.Store("AlibabaCloud:i-1", data.InstanceDetail{Provider: cloud.AlibabaCloud, InstanceId: "i-1", RegionId: "cn-hangzhou"})
+	return &api.Snapshot{
+		GeneratedAt:     time.Date(2022, 10, 3, 10, 0, 0, 0, time.Local),
+		AccountBillings: []data.AccountBillingMap{{AccountName: "ali", Provider: cloud.AlibabaCloud, DaysBilling: &days}},
+		AccountUtilizations: []data.AccountUtilizationMap{{AccountName: "ali", Provider: cloud.AlibabaCloud,
+			DailyCpu: &cpu, DailyMemory: &memory, RecentInstances: &instances,
+			RegionScan: data.RegionScan{Failed: []data.RegionSkip{{RegionId: "me-east-1", Reason: "timeout"}}}}},
+	}
+}
+
+func TestCollector(t *testing.T) {
+	c := NewCollector()
+	c.Update(newTestSnapshot(), 1500*time.Millisecond, nil)
+
+	w := httptest.NewRecorder()
+	c.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+	body := w.Body.String()
+	assert.Contains(t, body, "# TYPE costpilot_daily_cost gauge\n")
+	assert.Contains(t, body, `costpilot_daily_cost{provider="AlibabaCloud",account="ali",product="ecs",subscription_type="PostPaid",currency="CNY"} 20`+"\n")
+	assert.NotContains(t, body, `product="oss"`)
+	assert.Contains(t, body, `costpilot_month_to_date_cost{provider="AlibabaCloud",account="ali",currency="CNY"} 30`+"\n")
+	assert.Contains(t, body, `costpilot_instance_cpu_utilization{provider="AlibabaCloud",account="ali",instance="i-1",region="cn-hangzhou"} 12.5`+"\n")
+	assert.Contains(t, body, `costpilot_instance_memory_utilization{provider="AlibabaCloud",account="ali",instance="i-1",region="cn-hangzhou"} 40`+"\n")
+	assert.Contains(t, body, `costpilot_region_scan_failed{provider="AlibabaCloud",account="ali",region="me-east-1"} 1`+"\n")
+	assert.Contains(t, body, "costpilot_collection_duration_seconds 1.5\n")
+	assert.Contains(t, body, "costpilot_collection_success 1\n")
+
+	// a failed run keeps the data
+	c.Update(nil, time.Second, errors.New("timeout"))
+	w = httptest.NewRecorder()
+	c.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, w.Body.String(), "costpilot_collection_success 0\n")
+	assert.Contains(t, w.Body.String(), "costpilot_month_to_date_cost{")
+}
+
+func TestWriteText(t *testing.T) {
+	f := &Family{Name: "costpilot_test", Help: "a\\b\nc"}
+	f.Add(2, Label{"name", "b\"\n\\"})
+	f.Add(1, Label{"name", "a"})
+	var b bytes.Buffer
+	assert.NoError(t, WriteText(&b, []*Family{f}))
+	assert.Equal(t, "# HELP costpilot_test a\\\\b\\nc\n# TYPE costpilot_test gauge\n"+
+		"costpilot_test{name=\"a\"} 1\ncostpilot_test{name=\"b\\\"\\n\\\\\"} 2\n", b.String())
+}
+
+func TestCollector_Serve(t *testing.T) {
+	c := NewCollector()
+	c.Update(newTestSnapshot(), time.Second, nil)
+	gin.SetMode(gin.TestMode)
+	a, err := auth.New(types.Auth{Tokens: []types.AuthToken{
+		{Name: "ali-team", Token: "ali-token", Grant: types.Grant{Role: types.RoleViewer, Accounts: []string{"ali"}}},
+		{Name: "aws-team", Token: "aws-token", Grant: types.Grant{Role: types.RoleViewer, Accounts: []string{"aws"}}},
+	}})
+	require.NoError(t, err)
+	r := gin.New()
+	r.GET("/metrics", a.Middleware(), c.Serve)
+	request := func(token string) string {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+		return w.Body.String()
+	}
+
+	body := request("ali-token")
+	assert.Contains(t, body, `costpilot_month_to_date_cost{provider="AlibabaCloud",account="ali",currency="CNY"} 30`+"\n")
+	assert.Contains(t, body, "costpilot_collection_success 1\n")
+
+	// the samples of the accounts not granted are left out, the collection ones are kept
+	body = request("aws-token")
+	assert.NotContains(t, body, `account="ali"`)
+	assert.Contains(t, body, "# TYPE costpilot_month_to_date_cost gauge\n")
+	assert.Contains(t, body, "costpilot_collection_success 1\n")
+}
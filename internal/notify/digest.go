@@ -0,0 +1,168 @@
+package notify
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/tools"
+)
+
+const (
+	_dayLayout   = "2006-01-02"
+	_monthLayout = "2006-01"
+
+	_topProducts = 5
+)
+
+type DigestProduct struct {
+	PipCode     string  `json:"pip_code"`
+	ProductName string  `json:"product_name"`
+	Amount      float64 `json:"amount"`
+	Percent     float64 `json:"percent"` // of the day
+}
+
+// Digest spend of yesterday and of the month to date, over all accounts
+type Digest struct {
+	Day         string  `json:"day"` // yesterday, 2022-10-01
+	Currency    string  `json:"currency"`
+	Amount      float64 `json:"amount"`
+	PreviousDay float64 `json:"previous_day"`
+	Change      float64 `json:"change"` // Amount - PreviousDay
+	// ChangePercent is valid if HasChangePercent, which is false without the bill of the previous day
+	ChangePercent    float64         `json:"change_percent"`
+	HasChangePercent bool            `json:"has_change_percent"`
+	TopProducts      []DigestProduct `json:"top_products"` // top 5 of yesterday
+
+	Month           string  `json:"month"` // 2022-10
+	MonthToDate     float64 `json:"month_to_date"`
+	LastMonth       string  `json:"last_month"`
+	LastMonthToDate float64 `json:"last_month_to_date"` // same days of last month
+	LastMonthTotal  float64 `json:"last_month_total"`
+	// MonthChangePercent month to date vs the same days of last month, valid if HasMonthChangePercent
+	MonthChangePercent    float64 `json:"month_change_percent"`
+	HasMonthChangePercent bool    `json:"has_month_change_percent"`
+
+	// ByCurrency the digest of each currency if the accounts bill in different currencies,
+	// the amounts above are left zero then as they do not add up
+	ByCurrency []Digest `json:"by_currency,omitempty"`
+}
+
+// BuildDigest today is not included as its bills are not complete,
+// the days of last month are fetched by CostDataBean.SetMonthToDate as they are out of the recent days late in the month
+func BuildDigest(nowT time.Time, billings []data.AccountBillingMap) Digest {
+	byCurrency := make(map[string][]data.AccountBillingMap)
+	for _, a := range billings {
+		c := tools.AccountCurrency(a)
+		byCurrency[c] = append(byCurrency[c], a)
+	}
+	// the accounts without bills add nothing, they join the only currency
+	if unknown, ok := byCurrency[""]; ok && len(byCurrency) == 2 {
+		delete(byCurrency, "")
+		for c := range byCurrency {
+			byCurrency[c] = append(byCurrency[c], unknown...)
+		}
+	}
+	if len(byCurrency) <= 1 {
+		for c, accounts := range byCurrency {
+			return buildDigest(nowT, c, accounts)
+		}
+		return buildDigest(nowT, "", nil)
+	}
+
+	currencies := make([]string, 0, len(byCurrency))
+	for c := range byCurrency {
+		currencies = append(currencies, c)
+	}
+	sort.Strings(currencies)
+	d := buildDigest(nowT, "", nil)
+	for _, c := range currencies {
+		d.ByCurrency = append(d.ByCurrency, buildDigest(nowT, c, byCurrency[c]))
+	}
+	return d
+}
+
+// buildDigest of the accounts billing in the currency
+func buildDigest(nowT time.Time, currency string, billings []data.AccountBillingMap) Digest {
+	yesterday := nowT.AddDate(0, 0, -1)
+	monthStart := time.Date(yesterday.Year(), yesterday.Month(), 1, 0, 0, 0, 0, yesterday.Location())
+	lastMonthStart := monthStart.AddDate(0, -1, 0)
+	d := Digest{
+		Day:       yesterday.Format(_dayLayout),
+		Currency:  currency,
+		Month:     monthStart.Format(_monthLayout),
+		LastMonth: lastMonthStart.Format(_monthLayout),
+	}
+	previousDay := yesterday.AddDate(0, 0, -1).Format(_dayLayout)
+	// the same days of last month, capped by its length
+	lastMonthEnd := lastMonthStart.AddDate(0, 0, yesterday.Day()-1)
+	if lastMonthEnd.Month() != lastMonthStart.Month() {
+		lastMonthEnd = monthStart.AddDate(0, 0, -1)
+	}
+
+	products := make(map[string]*DigestProduct)
+	hasPreviousDay, hasLastMonthToDate := false, false
+	for _, a := range billings {
+		if a.DaysBilling != nil {
+			a.DaysBilling.Range(func(key, value interface{}) bool {
+				day, b := key.(string), value.(data.DailyBilling)
+				switch {
+				case day == d.Day:
+					d.Amount = tools.Float64Add(d.Amount, b.TotalAmount)
+					for pipCode, p := range b.ProductsBilling {
+						if pipCode == "" {
+							continue
+						}
+						item, ok := products[pipCode]
+						if !ok {
+							item = &DigestProduct{PipCode: pipCode, ProductName: p.ProductName}
+							products[pipCode] = item
+						}
+						item.Amount = tools.Float64Add(item.Amount, p.TotalAmount)
+					}
+				case day == previousDay:
+					d.PreviousDay = tools.Float64Add(d.PreviousDay, b.TotalAmount)
+					hasPreviousDay = true
+				}
+				if strings.HasPrefix(day, d.Month) && day <= d.Day {
+					d.MonthToDate = tools.Float64Add(d.MonthToDate, b.TotalAmount)
+				}
+				if strings.HasPrefix(day, d.LastMonth) && day <= lastMonthEnd.Format(_dayLayout) {
+					d.LastMonthToDate = tools.Float64Add(d.LastMonthToDate, b.TotalAmount)
+					hasLastMonthToDate = true
+				}
+				return true
+			})
+		}
+		if a.MonthsBilling != nil {
+			if val, ok := a.MonthsBilling.Load(d.LastMonth); ok {
+				d.LastMonthTotal = tools.Float64Add(d.LastMonthTotal, val.(data.MonthlyBilling).TotalAmount)
+			}
+		}
+	}
+	d.Change = tools.Float64Add(d.Amount, -d.PreviousDay)
+	if hasPreviousDay && d.PreviousDay != 0 {
+		d.ChangePercent, d.HasChangePercent = 100*d.Change/d.PreviousDay, true
+	}
+	if hasLastMonthToDate && d.LastMonthToDate != 0 {
+		d.MonthChangePercent, d.HasMonthChangePercent = 100*(d.MonthToDate-d.LastMonthToDate)/d.LastMonthToDate, true
+	}
+
+	for _, p := range products {
+		if d.Amount != 0 {
+			p.Percent = 100 * p.Amount / d.Amount
+		}
+		d.TopProducts = append(d.TopProducts, *p)
+	}
+	sort.Slice(d.TopProducts, func(i, j int) bool {
+		if d.TopProducts[i].Amount != d.TopProducts[j].Amount {
+			return d.TopProducts[i].Amount > d.TopProducts[j].Amount
+		}
+		return d.TopProducts[i].PipCode < d.TopProducts[j].PipCode
+	})
+	if len(d.TopProducts) > _topProducts {
+		d.TopProducts = d.TopProducts[:_topProducts]
+	}
+	return d
+}
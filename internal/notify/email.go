@@ -0,0 +1,113 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/types"
+)
+
+const _smtpTimeout = 30 * time.Second
+
+// emailNotifier plain text mail by smtp, STARTTLS is used if the server offers it, port 465 is implicit tls
+type emailNotifier struct {
+	cfg    types.Notifier
+	dryRun bool
+}
+
+func (n *emailNotifier) Name() string { return n.cfg.Name }
+
+func (n *emailNotifier) Send(ctx context.Context, msg Message) error {
+	s := n.cfg.SMTP
+	if s.Host == "" || s.From == "" || len(s.To) == 0 {
+		return errors.New("smtp host/from/to is required")
+	}
+	body, err := buildMail(s.From, s.To, msg.Title, msg.Text, _now())
+	if err != nil {
+		return err
+	}
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(s.GetPort()))
+	if n.dryRun {
+		log.Printf("I! [dry-run] notifier[%s] SMTP %s\n%s", n.cfg.Name, addr, body)
+		return nil
+	}
+	return sendMail(ctx, s, addr, body)
+}
+
+func buildMail(from string, to []string, subject, text string, t time.Time) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + strings.Join(to, ", ") + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	b.WriteString("Date: " + t.Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	w := quotedprintable.NewWriter(&b)
+	if _, err := w.Write([]byte(strings.ReplaceAll(text, "\n", "\r\n"))); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	b.WriteString("\r\n")
+	return b.Bytes(), nil
+}
+
+func sendMail(ctx context.Context, s types.SMTP, addr string, body []byte) error {
+	conn, err := (&net.Dialer{Timeout: _smtpTimeout}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	_ = conn.SetDeadline(time.Now().Add(_smtpTimeout))
+	tlsConfig := &tls.Config{ServerName: s.Host}
+	if s.GetPort() == 465 {
+		conn = tls.Client(conn, tlsConfig)
+	}
+	c, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("starttls: %v", err)
+		}
+	}
+	if s.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host)); err != nil {
+			return fmt.Errorf("auth: %v", err)
+		}
+	}
+	if err := c.Mail(s.From); err != nil {
+		return err
+	}
+	for _, to := range s.To {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("rcpt %s: %v", to, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -0,0 +1,152 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/types"
+)
+
+const (
+	KindDigest = "digest"
+	KindAlert  = "alert"
+)
+
+// Message rendered by the templates, Digest or Alert is set by Kind
+type Message struct {
+	Kind   string
+	Title  string
+	Text   string
+	Digest *Digest
+	Alert  *data.AlertEvent
+}
+
+// Notifier delivers messages to one channel
+type Notifier interface {
+	Name() string
+	Send(ctx context.Context, msg Message) error
+}
+
+// New build the notifier of the config, payloads are printed instead of sent in dry run
+func New(cfg types.Notifier, dryRun bool) (Notifier, error) {
+	var t transport = httpTransport{}
+	if dryRun {
+		t = dryRunTransport{}
+	}
+	switch cfg.Type {
+	case types.NotifierWebhook:
+		return &webhookNotifier{cfg: cfg, transport: t}, nil
+	case types.NotifierSlack:
+		return &slackNotifier{cfg: cfg, transport: t}, nil
+	case types.NotifierDingTalk:
+		return &dingTalkNotifier{cfg: cfg, transport: t}, nil
+	case types.NotifierFeishu:
+		return &feishuNotifier{cfg: cfg, transport: t}, nil
+	case types.NotifierWeCom:
+		return &weComNotifier{cfg: cfg, transport: t}, nil
+	case types.NotifierEmail:
+		return &emailNotifier{cfg: cfg, dryRun: dryRun}, nil
+	}
+	return nil, fmt.Errorf("notifier[%s] invalid type %q", cfg.Name, cfg.Type)
+}
+
+type subscriber struct {
+	cfg       types.Notifier
+	notifier  Notifier
+	templates *Templates
+}
+
+// Dispatcher renders the digest and the alerts for each notifier by its templates and subscriptions
+type Dispatcher struct {
+	subscribers []subscriber
+	state       *State // nil to send everything every time
+	dryRun      bool
+}
+
+func NewDispatcher(cfg types.Notification) (*Dispatcher, error) {
+	d := &Dispatcher{dryRun: cfg.DryRun}
+	for _, n := range cfg.Notifiers {
+		notifier, err := New(n, cfg.DryRun)
+		if err != nil {
+			return nil, err
+		}
+		templates, err := NewTemplates(n.Templates.Digest, n.Templates.Alert)
+		if err != nil {
+			return nil, fmt.Errorf("notifier[%s] %v", n.Name, err)
+		}
+		d.subscribers = append(d.subscribers, subscriber{cfg: n, notifier: notifier, templates: templates})
+	}
+	return d, nil
+}
+
+// SetState skip the digest of a day and the alerts already sent, the dry runs are not recorded
+func (d *Dispatcher) SetState(state *State) *Dispatcher {
+	d.state = state
+	return d
+}
+
+// Notify send the digest and the alerts, a failed notifier does not stop the others
+func (d *Dispatcher) Notify(ctx context.Context, digest Digest, events []data.AlertEvent) error {
+	var failed []string
+	for _, s := range d.subscribers {
+		var messages []Message
+		if s.cfg.Subscribes(types.NotifyEventDigest) && !d.state.digestSent(s.cfg.Name, digest.Day) {
+			msg, err := s.templates.Digest(digest)
+			if err != nil {
+				log.Printf("E! notifier[%s] %v", s.notifier.Name(), err)
+				failed = append(failed, s.notifier.Name())
+				continue
+			}
+			messages = append(messages, msg)
+		}
+		if s.cfg.Subscribes(types.NotifyEventAlert) {
+			for _, e := range events {
+				if severityRank(e.Severity) < severityRank(data.AlertSeverity(s.cfg.MinSeverity)) {
+					continue
+				}
+				msg, err := s.templates.Alert(e)
+				if err != nil {
+					log.Printf("E! notifier[%s] %v", s.notifier.Name(), err)
+					continue
+				}
+				if d.state.alertSent(s.cfg.Name, msg) {
+					continue
+				}
+				messages = append(messages, msg)
+			}
+		}
+		sent := 0
+		for _, msg := range messages {
+			if err := s.notifier.Send(ctx, msg); err != nil {
+				log.Printf("E! notifier[%s] send %s failed: %v", s.notifier.Name(), msg.Kind, err)
+				failed = append(failed, s.notifier.Name())
+				break
+			}
+			if !d.dryRun {
+				d.state.markSent(s.cfg.Name, msg)
+			}
+			sent++
+		}
+		if sent > 0 {
+			log.Printf("I! notifier[%s] sent %d messages", s.notifier.Name(), sent)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("notifiers failed: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// severityRank empty is the lowest, as is info
+func severityRank(s data.AlertSeverity) int {
+	switch s {
+	case data.AlertSeverityWarning:
+		return 1
+	case data.AlertSeverityCritical:
+		return 2
+	}
+	return 0
+}
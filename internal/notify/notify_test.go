@@ -0,0 +1,337 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBillings() []data.AccountBillingMap {
+	product := func(name string, amount float64) data.ProductBilling {
+		return data.ProductBilling{ProductName: name, TotalAmount: amount, Items: []data.ItemInProductBilling{{PretaxAmount: amount, Currency: "CNY"}}}
+	}
+	var days, months sync.Map
+	days.Store("2022-09-01", data.DailyBilling{TotalAmount: 50})
+	days.Store("2022-09-02", data.DailyBilling{TotalAmount: 50})
+	days.Store("2022-09-03", data.DailyBilling{TotalAmount: 50})
+	days.Store("2022-10-01", data.DailyBilling{TotalAmount: 80, ProductsBilling: map[string]data.ProductBilling{"ecs": product("ECS", 80)}})
+	days.Store("2022-10-02", data.DailyBilling{TotalAmount: 100, ProductsBilling: map[string]data.ProductBilling{
+		"ecs": product("ECS", 40), "oss": product("OSS", 20), "rds": product("RDS", 15), "slb": product("SLB", 10),
+		"cdn": product("CDN", 8), "eip": product("EIP", 7),
+	}})
+	months.Store("2022-09", data.MonthlyBilling{TotalAmount: 1500})
+	return []data.AccountBillingMap{{AccountName: "ali", Provider: cloud.AlibabaCloud, DaysBilling: &days, MonthsBilling: &months}}
+}
+
+func TestBuildDigest(t *testing.T) {
+	d := BuildDigest(time.Date(2022, 10, 3, 9, 0, 0, 0, time.Local), newTestBillings())
+	assert.Equal(t, "2022-10-02", d.Day)
+	assert.Equal(t, "CNY", d.Currency)
+	assert.Equal(t, 100.0, d.Amount)
+	assert.Equal(t, 20.0, d.Change)
+	assert.True(t, d.HasChangePercent)
+	assert.InDelta(t, 25, d.ChangePercent, 0.001)
+	assert.Equal(t, 180.0, d.MonthToDate)
+	assert.Equal(t, 100.0, d.LastMonthToDate) // 09-01 and 09-02
+	assert.InDelta(t, 80, d.MonthChangePercent, 0.001)
+	assert.Equal(t, 1500.0, d.LastMonthTotal)
+	require.Len(t, d.TopProducts, 5)
+	assert.Equal(t, DigestProduct{PipCode: "ecs", ProductName: "ECS", Amount: 40, Percent: 40}, d.TopProducts[0])
+	assert.Equal(t, "cdn", d.TopProducts[4].PipCode)
+}
+
+func TestBuildDigest_Currencies(t *testing.T) {
+	var days sync.Map
+	days.Store("2022-10-02", data.DailyBilling{TotalAmount: 30, ProductsBilling: map[string]data.ProductBilling{
+		"ec2": {ProductName: "EC2", TotalAmount: 30, Items: []data.ItemInProductBilling{{PretaxAmount: 30, Currency: "USD"}}},
+	}})
+	billings := append(newTestBillings(), data.AccountBillingMap{AccountName: "aws", Provider: cloud.AWSCloud, DaysBilling: &days})
+	d := BuildDigest(time.Date(2022, 10, 3, 9, 0, 0, 0, time.Local), billings)
+	assert.Equal(t, "2022-10-02", d.Day)
+	assert.Empty(t, d.Currency)
+	assert.Zero(t, d.Amount)
+	assert.Zero(t, d.MonthToDate)
+	assert.Empty(t, d.TopProducts)
+	require.Len(t, d.ByCurrency, 2)
+	assert.Equal(t, "CNY", d.ByCurrency[0].Currency)
+	assert.Equal(t, 100.0, d.ByCurrency[0].Amount)
+	assert.Equal(t, "USD", d.ByCurrency[1].Currency)
+	assert.Equal(t, 30.0, d.ByCurrency[1].Amount)
+	assert.Equal(t, "ec2", d.ByCurrency[1].TopProducts[0].PipCode)
+
+	templates, err := NewTemplates("", "")
+	require.NoError(t, err)
+	msg, err := templates.Digest(d)
+	require.NoError(t, err)
+	assert.Contains(t, msg.Text, "[CNY]\nYesterday: ¥100.00")
+	assert.Contains(t, msg.Text, "[USD]\nYesterday: $30.00")
+	assert.Contains(t, msg.Text, "1. EC2: $30.00 (100.0%)")
+
+	// an account without bills does not split the digest
+	billings = append(newTestBillings(), data.AccountBillingMap{AccountName: "empty", Provider: cloud.AWSCloud})
+	d = BuildDigest(time.Date(2022, 10, 3, 9, 0, 0, 0, time.Local), billings)
+	assert.Equal(t, "CNY", d.Currency)
+	assert.Empty(t, d.ByCurrency)
+}
+
+func TestTemplates(t *testing.T) {
+	templates, err := NewTemplates("", "")
+	require.NoError(t, err)
+	msg, err := templates.Digest(BuildDigest(time.Date(2022, 10, 3, 9, 0, 0, 0, time.Local), newTestBillings()))
+	require.NoError(t, err)
+	assert.Equal(t, KindDigest, msg.Kind)
+	assert.Contains(t, msg.Text, "Yesterday: ¥100.00 (+20.00, +25.0% vs ¥80.00 the day before)")
+	assert.Contains(t, msg.Text, "Month to date 2022-10: ¥180.00 (+80.0% vs the same days of 2022-09)")
+	assert.Contains(t, msg.Text, "1. ECS: ¥40.00 (40.0%)")
+	assert.NotContains(t, msg.Text, "EIP")
+
+	templates, err = NewTemplates("{{.Day}} {{money .Amount .Currency}}", "{{.Kind}}: {{.Title}}")
+	require.NoError(t, err)
+	msg, err = templates.Digest(Digest{Day: "2022-10-02", Amount: 1.5, Currency: "USD"})
+	require.NoError(t, err)
+	assert.Equal(t, "2022-10-02 $1.50", msg.Text)
+	msg, err = templates.Alert(data.AlertEvent{Kind: data.AlertKindBudget, Severity: data.AlertSeverityWarning, Title: "budget reached 80%"})
+	require.NoError(t, err)
+	assert.Equal(t, "budget: budget reached 80%", msg.Text)
+	assert.Equal(t, "[WARNING] budget reached 80%", msg.Title)
+
+	_, err = NewTemplates("{{.Day", "")
+	assert.Error(t, err)
+}
+
+type request struct {
+	path   string
+	query  string
+	header http.Header
+	body   []byte
+}
+
+// newStandIn records the posted requests and answers with the body
+func newStandIn(t *testing.T, answer string) (*httptest.Server, *[]request) {
+	var mu sync.Mutex
+	var requests []request
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, request{path: r.URL.Path, query: r.URL.RawQuery, header: r.Header, body: body})
+		mu.Unlock()
+		_, _ = w.Write([]byte(answer))
+	}))
+	t.Cleanup(s.Close)
+	return s, &requests
+}
+
+func TestDispatcher_Webhooks(t *testing.T) {
+	_now = func() time.Time { return time.Unix(1665000000, 0) }
+	defer func() { _now = time.Now }()
+	s, requests := newStandIn(t, `{"errcode":0,"code":0}`)
+	d, err := NewDispatcher(types.Notification{Notifiers: []types.Notifier{
+		{Name: "hook", Type: types.NotifierWebhook, URL: s.URL + "/hook", Secret: "k", Headers: map[string]string{"X-Team": "finops"}},
+		{Name: "slack", Type: types.NotifierSlack, URL: s.URL + "/slack", Events: []types.NotifyEvent{types.NotifyEventDigest}},
+		{Name: "ding", Type: types.NotifierDingTalk, URL: s.URL + "/ding?access_token=t", Secret: "ding-secret", MinSeverity: "critical"},
+		{Name: "feishu", Type: types.NotifierFeishu, URL: s.URL + "/feishu", Secret: "feishu-secret", Events: []types.NotifyEvent{types.NotifyEventAlert}},
+		{Name: "wecom", Type: types.NotifierWeCom, URL: s.URL + "/wecom", Events: []types.NotifyEvent{types.NotifyEventAlert}},
+	}})
+	require.NoError(t, err)
+	events := []data.AlertEvent{
+		{Kind: data.AlertKindAnomaly, Severity: data.AlertSeverityWarning, Title: "Cost spike of ali", Message: "cost 100"},
+		{Kind: data.AlertKindBudget, Severity: data.AlertSeverityCritical, Title: "Budget exceeded", Message: "spent 120%"},
+	}
+	digest := BuildDigest(time.Date(2022, 10, 3, 9, 0, 0, 0, time.Local), newTestBillings())
+	require.NoError(t, d.Notify(context.Background(), digest, events))
+
+	byPath := make(map[string][]request)
+	for _, r := range *requests {
+		byPath[r.path] = append(byPath[r.path], r)
+	}
+	require.Len(t, byPath["/hook"], 3)
+	hook := byPath["/hook"][0]
+	var payload webhookPayload
+	require.NoError(t, json.Unmarshal(hook.body, &payload))
+	assert.Equal(t, KindDigest, payload.Kind)
+	assert.Equal(t, 100.0, payload.Digest.Amount)
+	assert.Equal(t, "finops", hook.header.Get("X-Team"))
+	mac := hmac.New(sha256.New, []byte("k"))
+	mac.Write(hook.body)
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), hook.header.Get("X-Costpilot-Signature"))
+
+	require.Len(t, byPath["/slack"], 1)
+	assert.Contains(t, string(byPath["/slack"][0].body), `"text":"CostPilot daily digest 2022-10-02`)
+
+	// the warning is below min_severity
+	require.Len(t, byPath["/ding"], 2)
+	assert.Contains(t, string(byPath["/ding"][1].body), `"content":"[CRITICAL] Budget exceeded\nspent 120%"`)
+	mac = hmac.New(sha256.New, []byte("ding-secret"))
+	mac.Write([]byte("1665000000000\nding-secret"))
+	assert.Contains(t, byPath["/ding"][0].query, "access_token=t")
+	assert.Contains(t, byPath["/ding"][0].query, "timestamp=1665000000000")
+	assert.Contains(t, byPath["/ding"][0].query, "sign="+url.QueryEscape(base64.StdEncoding.EncodeToString(mac.Sum(nil))))
+
+	require.Len(t, byPath["/feishu"], 2)
+	var feishu map[string]interface{}
+	require.NoError(t, json.Unmarshal(byPath["/feishu"][0].body, &feishu))
+	mac = hmac.New(sha256.New, []byte("1665000000\nfeishu-secret"))
+	assert.Equal(t, "1665000000", feishu["timestamp"])
+	assert.Equal(t, base64.StdEncoding.EncodeToString(mac.Sum(nil)), feishu["sign"])
+	assert.Equal(t, "text", feishu["msg_type"])
+
+	require.Len(t, byPath["/wecom"], 2)
+	assert.Contains(t, string(byPath["/wecom"][0].body), `"msgtype":"text"`)
+}
+
+func TestDispatcher_Failures(t *testing.T) {
+	bad, _ := newStandIn(t, `{"errcode":310000,"errmsg":"sign not match"}`)
+	good, requests := newStandIn(t, `ok`)
+	d, err := NewDispatcher(types.Notification{Notifiers: []types.Notifier{
+		{Name: "ding", Type: types.NotifierDingTalk, URL: bad.URL},
+		{Name: "slack", Type: types.NotifierSlack, URL: good.URL},
+	}})
+	require.NoError(t, err)
+	err = d.Notify(context.Background(), Digest{Day: "2022-10-02"}, nil)
+	assert.EqualError(t, err, "notifiers failed: ding")
+	assert.Len(t, *requests, 1)
+}
+
+func TestDispatcher_DryRun(t *testing.T) {
+	s, requests := newStandIn(t, `ok`)
+	d, err := NewDispatcher(types.Notification{DryRun: true, Notifiers: []types.Notifier{
+		{Name: "slack", Type: types.NotifierSlack, URL: s.URL},
+		{Name: "mail", Type: types.NotifierEmail, SMTP: types.SMTP{Host: "127.0.0.1", Port: 1, From: "a@example.com", To: []string{"b@example.com"}}},
+	}})
+	require.NoError(t, err)
+	assert.NoError(t, d.Notify(context.Background(), Digest{Day: "2022-10-02"}, nil))
+	assert.Empty(t, *requests)
+}
+
+func TestDispatcher_State(t *testing.T) {
+	bad, _ := newStandIn(t, `{"errcode":310000,"errmsg":"sign not match"}`)
+	good, requests := newStandIn(t, `ok`)
+	file := filepath.Join(t.TempDir(), "state", "notify-state.json")
+	events := []data.AlertEvent{
+		{Kind: data.AlertKindBudget, Severity: data.AlertSeverityWarning, Title: "Budget reached 80%", Key: "budget/b1/2022-10-01/80"},
+		{Kind: data.AlertKindAnomaly, Severity: data.AlertSeverityWarning, Title: "Cost spike without key"},
+	}
+	notify := func(day string, events []data.AlertEvent) error {
+		state, err := LoadState(file)
+		require.NoError(t, err)
+		d, err := NewDispatcher(types.Notification{Notifiers: []types.Notifier{
+			{Name: "slack", Type: types.NotifierSlack, URL: good.URL},
+			{Name: "ding", Type: types.NotifierDingTalk, URL: bad.URL},
+		}})
+		require.NoError(t, err)
+		err = d.SetState(state).Notify(context.Background(), Digest{Day: day}, events)
+		require.NoError(t, state.Save())
+		return err
+	}
+
+	assert.Error(t, notify("2022-10-02", events))
+	assert.Len(t, *requests, 3)
+	// the same day again: the digest and the budget alert are not repeated
+	assert.Error(t, notify("2022-10-02", events))
+	assert.Len(t, *requests, 4)
+	// the next day: a new digest and the next threshold
+	events[0].Key = "budget/b1/2022-10-01/100"
+	assert.Error(t, notify("2022-10-03", events))
+	assert.Len(t, *requests, 7)
+
+	state, err := LoadState(file)
+	require.NoError(t, err)
+	assert.Equal(t, "2022-10-03", state.Notifiers["slack"].DigestDay)
+	assert.Len(t, state.Notifiers["slack"].Alerts, 2)
+	assert.NotContains(t, state.Notifiers, "ding") // failed, retried by the next run
+}
+
+// newSMTPStandIn accepts one mail and returns its data
+func newSMTPStandIn(t *testing.T) (string, int, <-chan string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+	mails := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r, w := bufio.NewReader(conn), bufio.NewWriter(conn)
+		reply := func(s string) {
+			_, _ = w.WriteString(s + "\r\n")
+			_ = w.Flush()
+		}
+		reply("220 localhost ESMTP stand-in")
+		var mail strings.Builder
+		inData := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if inData {
+				if line == ".\r\n" {
+					inData = false
+					mails <- mail.String()
+					reply("250 OK")
+					continue
+				}
+				mail.WriteString(line)
+				continue
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"):
+				reply("250-localhost\r\n250 AUTH PLAIN")
+			case strings.HasPrefix(cmd, "AUTH PLAIN"):
+				reply("235 Authentication successful")
+			case strings.HasPrefix(cmd, "DATA"):
+				inData = true
+				reply("354 End data with <CR><LF>.<CR><LF>")
+			case strings.HasPrefix(cmd, "QUIT"):
+				reply("221 Bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return host, p, mails
+}
+
+func TestEmailNotifier(t *testing.T) {
+	host, port, mails := newSMTPStandIn(t)
+	n, err := New(types.Notifier{Name: "mail", Type: types.NotifierEmail, SMTP: types.SMTP{
+		Host: host, Port: port, Username: "costpilot", Password: "secret", From: "costpilot@example.com", To: []string{"finops@example.com"},
+	}}, false)
+	require.NoError(t, err)
+	require.NoError(t, n.Send(context.Background(), Message{Kind: KindDigest, Title: "CostPilot 日报 2022-10-02", Text: "Yesterday: ¥100.00"}))
+	select {
+	case mail := <-mails:
+		assert.Contains(t, mail, "To: finops@example.com\r\n")
+		assert.Contains(t, mail, "Subject: =?utf-8?q?CostPilot_=E6=97=A5=E6=8A=A5_2022-10-02?=\r\n")
+		assert.Contains(t, mail, "Yesterday: =C2=A5100.00")
+	case <-time.After(5 * time.Second):
+		t.Fatal("no mail is received")
+	}
+}
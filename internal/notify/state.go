@@ -0,0 +1,115 @@
+package notify
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// _stateRetention alerts delivered before are forgotten, longer than the yearly budget periods
+const _stateRetention = 400 * 24 * time.Hour
+
+// State what each notifier delivered, so the runs of the same day do not repeat the digest and the alerts
+type State struct {
+	path      string
+	Notifiers map[string]*NotifierState `json:"notifiers"` // key : notifier name
+}
+
+type NotifierState struct {
+	DigestDay string               `json:"digest_day"` // Digest.Day of the last digest sent
+	Alerts    map[string]time.Time `json:"alerts"`     // key : AlertEvent.Key, value : when it was sent
+}
+
+// LoadState an empty state if the file does not exist yet
+func LoadState(path string) (*State, error) {
+	s := &State{path: path, Notifiers: make(map[string]*NotifierState)}
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, err
+	}
+	if s.Notifiers == nil {
+		s.Notifiers = make(map[string]*NotifierState)
+	}
+	return s, nil
+}
+
+// Save write the file by a rename, the alerts past the retention are dropped
+func (s *State) Save() error {
+	deadline := _now().Add(-_stateRetention)
+	for _, n := range s.Notifiers {
+		for key, sent := range n.Alerts {
+			if sent.Before(deadline) {
+				delete(n.Alerts, key)
+			}
+		}
+	}
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func (s *State) notifier(name string) *NotifierState {
+	n, ok := s.Notifiers[name]
+	if !ok {
+		n = &NotifierState{}
+		s.Notifiers[name] = n
+	}
+	if n.Alerts == nil {
+		n.Alerts = make(map[string]time.Time)
+	}
+	return n
+}
+
+// digestSent the digest of the day was sent by the notifier
+func (s *State) digestSent(notifier, day string) bool {
+	if s == nil {
+		return false
+	}
+	n, ok := s.Notifiers[notifier]
+	return ok && n.DigestDay == day
+}
+
+// alertSent the event was sent by the notifier, events without a key are always sent
+func (s *State) alertSent(notifier string, e Message) bool {
+	if s == nil || e.Alert == nil || e.Alert.Key == "" {
+		return false
+	}
+	n, ok := s.Notifiers[notifier]
+	if !ok {
+		return false
+	}
+	_, ok = n.Alerts[e.Alert.Key]
+	return ok
+}
+
+// markSent record the message sent by the notifier
+func (s *State) markSent(notifier string, msg Message) {
+	if s == nil {
+		return
+	}
+	n := s.notifier(notifier)
+	switch {
+	case msg.Digest != nil:
+		n.DigestDay = msg.Digest.Day
+	case msg.Alert != nil && msg.Alert.Key != "":
+		n.Alerts[msg.Alert.Key] = _now()
+	}
+}
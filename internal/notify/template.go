@@ -0,0 +1,104 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"text/template"
+
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/tools"
+)
+
+const _defaultDigestTemplate = `CostPilot daily digest {{.Day}}
+{{- range .ByCurrency}}
+
+[{{or .Currency "unknown currency"}}]
+{{- template "amounts" .}}
+{{- else}}
+{{- template "amounts" .}}
+{{- end}}
+{{- define "amounts"}}
+Yesterday: {{money .Amount .Currency}} ({{change .Change .ChangePercent .HasChangePercent}} vs {{money .PreviousDay .Currency}} the day before)
+Month to date {{.Month}}: {{money .MonthToDate .Currency}}{{if .HasMonthChangePercent}} ({{percent .MonthChangePercent}} vs the same days of {{.LastMonth}}){{end}}
+Last month {{.LastMonth}}: {{money .LastMonthTotal .Currency}}
+{{- if .TopProducts}}
+Top products of yesterday:
+{{- range $i, $p := .TopProducts}}
+{{inc $i}}. {{or $p.ProductName $p.PipCode}}: {{money $p.Amount $.Currency}} ({{printf "%.1f" $p.Percent}}%)
+{{- end}}
+{{- end}}
+{{- end}}
+`
+
+const _defaultAlertTemplate = `[{{upper .Severity}}] {{.Title}}
+{{.Message}}
+`
+
+var _funcs = template.FuncMap{
+	"money": func(amount float64, currency string) string {
+		if unit := tools.CurrencyUnit(currency); unit != "" {
+			return fmt.Sprintf("%s%.2f", unit, amount)
+		}
+		return strings.TrimSpace(fmt.Sprintf("%.2f %s", amount, currency))
+	},
+	"percent": func(v float64) string {
+		return fmt.Sprintf("%+.1f%%", v)
+	},
+	"change": func(change, percent float64, hasPercent bool) string {
+		sign := "+"
+		if change < 0 {
+			sign = "-"
+		}
+		s := fmt.Sprintf("%s%.2f", sign, math.Abs(change))
+		if hasPercent {
+			s += fmt.Sprintf(", %+.1f%%", percent)
+		}
+		return s
+	},
+	"inc":   func(i int) int { return i + 1 },
+	"upper": func(v interface{}) string { return strings.ToUpper(fmt.Sprint(v)) },
+}
+
+// Templates of the digest and the alerts of a notifier
+type Templates struct {
+	digest *template.Template
+	alert  *template.Template
+}
+
+// NewTemplates the defaults are used for the empty ones
+func NewTemplates(digest, alert string) (*Templates, error) {
+	if digest == "" {
+		digest = _defaultDigestTemplate
+	}
+	if alert == "" {
+		alert = _defaultAlertTemplate
+	}
+	t := &Templates{}
+	var err error
+	if t.digest, err = template.New("digest").Funcs(_funcs).Parse(digest); err != nil {
+		return nil, fmt.Errorf("digest template: %v", err)
+	}
+	if t.alert, err = template.New("alert").Funcs(_funcs).Parse(alert); err != nil {
+		return nil, fmt.Errorf("alert template: %v", err)
+	}
+	return t, nil
+}
+
+func (t *Templates) Digest(d Digest) (Message, error) {
+	var b bytes.Buffer
+	if err := t.digest.Execute(&b, d); err != nil {
+		return Message{}, fmt.Errorf("digest template: %v", err)
+	}
+	return Message{Kind: KindDigest, Title: "CostPilot daily digest " + d.Day, Text: strings.TrimSpace(b.String()), Digest: &d}, nil
+}
+
+func (t *Templates) Alert(e data.AlertEvent) (Message, error) {
+	var b bytes.Buffer
+	if err := t.alert.Execute(&b, e); err != nil {
+		return Message{}, fmt.Errorf("alert template: %v", err)
+	}
+	title := fmt.Sprintf("[%s] %s", strings.ToUpper(string(e.Severity)), e.Title)
+	return Message{Kind: KindAlert, Title: title, Text: strings.TrimSpace(b.String()), Alert: &e}, nil
+}
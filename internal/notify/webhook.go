@@ -0,0 +1,231 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/types"
+)
+
+var _now = time.Now
+
+// transport posts the json payloads of the webhook notifiers
+type transport interface {
+	post(ctx context.Context, name, u string, headers map[string]string, body []byte) ([]byte, error)
+}
+
+type httpTransport struct{}
+
+var _client = &http.Client{Timeout: 10 * time.Second}
+
+func (httpTransport) post(ctx context.Context, _, u string, headers map[string]string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := _client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s: %s", resp.Status, respBody)
+	}
+	return respBody, nil
+}
+
+// dryRunTransport prints the payload, the query of the url is left out as it may carry the token
+type dryRunTransport struct{}
+
+func (dryRunTransport) post(_ context.Context, name, u string, _ map[string]string, body []byte) ([]byte, error) {
+	if parsed, err := url.Parse(u); err == nil {
+		parsed.RawQuery = ""
+		u = parsed.String()
+	}
+	log.Printf("I! [dry-run] notifier[%s] POST %s\n%s", name, u, body)
+	return nil, nil
+}
+
+// checkErrCode the im robots answer 200 with an error code in the body, nil body is a dry run
+func checkErrCode(body []byte) error {
+	if body == nil {
+		return nil
+	}
+	var resp struct {
+		ErrCode *int   `json:"errcode"` // dingtalk, wecom
+		ErrMsg  string `json:"errmsg"`
+		Code    *int   `json:"code"` // feishu
+		Msg     string `json:"msg"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil
+	}
+	if resp.ErrCode != nil && *resp.ErrCode != 0 {
+		return fmt.Errorf("errcode %d: %s", *resp.ErrCode, resp.ErrMsg)
+	}
+	if resp.Code != nil && *resp.Code != 0 {
+		return fmt.Errorf("code %d: %s", *resp.Code, resp.Msg)
+	}
+	return nil
+}
+
+// webhookNotifier posts the message and its data, signed by the secret in X-Costpilot-Signature
+type webhookNotifier struct {
+	cfg       types.Notifier
+	transport transport
+}
+
+type webhookPayload struct {
+	Kind   string           `json:"kind"`
+	Title  string           `json:"title"`
+	Text   string           `json:"text"`
+	Digest *Digest          `json:"digest,omitempty"`
+	Alert  *data.AlertEvent `json:"alert,omitempty"`
+}
+
+func (n *webhookNotifier) Name() string { return n.cfg.Name }
+
+func (n *webhookNotifier) Send(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(webhookPayload{Kind: msg.Kind, Title: msg.Title, Text: msg.Text, Digest: msg.Digest, Alert: msg.Alert})
+	if err != nil {
+		return err
+	}
+	headers := make(map[string]string, len(n.cfg.Headers)+1)
+	for k, v := range n.cfg.Headers {
+		headers[k] = v
+	}
+	if n.cfg.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(n.cfg.Secret))
+		mac.Write(body)
+		headers["X-Costpilot-Signature"] = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	_, err = n.transport.post(ctx, n.cfg.Name, n.cfg.URL, headers, body)
+	return err
+}
+
+// slackNotifier incoming webhook
+type slackNotifier struct {
+	cfg       types.Notifier
+	transport transport
+}
+
+func (n *slackNotifier) Name() string { return n.cfg.Name }
+
+func (n *slackNotifier) Send(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(map[string]string{"text": msg.Text})
+	if err != nil {
+		return err
+	}
+	_, err = n.transport.post(ctx, n.cfg.Name, n.cfg.URL, nil, body)
+	return err
+}
+
+// dingTalkNotifier custom robot, signed by the secret if the robot requires it
+type dingTalkNotifier struct {
+	cfg       types.Notifier
+	transport transport
+}
+
+func (n *dingTalkNotifier) Name() string { return n.cfg.Name }
+
+func (n *dingTalkNotifier) Send(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"msgtype": "text",
+		"text":    map[string]string{"content": msg.Text},
+	})
+	if err != nil {
+		return err
+	}
+	u := n.cfg.URL
+	if n.cfg.Secret != "" {
+		timestamp := strconv.FormatInt(_now().UnixNano()/int64(time.Millisecond), 10)
+		mac := hmac.New(sha256.New, []byte(n.cfg.Secret))
+		mac.Write([]byte(timestamp + "\n" + n.cfg.Secret))
+		parsed, err := url.Parse(u)
+		if err != nil {
+			return err
+		}
+		q := parsed.Query()
+		q.Set("timestamp", timestamp)
+		q.Set("sign", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+		parsed.RawQuery = q.Encode()
+		u = parsed.String()
+	}
+	resp, err := n.transport.post(ctx, n.cfg.Name, u, nil, body)
+	if err != nil {
+		return err
+	}
+	return checkErrCode(resp)
+}
+
+// feishuNotifier custom bot of Feishu / Lark, signed by the secret if the bot requires it
+type feishuNotifier struct {
+	cfg       types.Notifier
+	transport transport
+}
+
+func (n *feishuNotifier) Name() string { return n.cfg.Name }
+
+func (n *feishuNotifier) Send(ctx context.Context, msg Message) error {
+	payload := map[string]interface{}{
+		"msg_type": "text",
+		"content":  map[string]string{"text": msg.Text},
+	}
+	if n.cfg.Secret != "" {
+		timestamp := strconv.FormatInt(_now().Unix(), 10)
+		// the string to sign is the key, the message is empty
+		mac := hmac.New(sha256.New, []byte(timestamp+"\n"+n.cfg.Secret))
+		payload["timestamp"] = timestamp
+		payload["sign"] = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := n.transport.post(ctx, n.cfg.Name, n.cfg.URL, nil, body)
+	if err != nil {
+		return err
+	}
+	return checkErrCode(resp)
+}
+
+// weComNotifier group robot
+type weComNotifier struct {
+	cfg       types.Notifier
+	transport transport
+}
+
+func (n *weComNotifier) Name() string { return n.cfg.Name }
+
+func (n *weComNotifier) Send(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"msgtype": "text",
+		"text":    map[string]string{"content": msg.Text},
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := n.transport.post(ctx, n.cfg.Name, n.cfg.URL, nil, body)
+	if err != nil {
+		return err
+	}
+	return checkErrCode(resp)
+}
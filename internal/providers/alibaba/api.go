@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,15 +17,21 @@ import (
 	util "github.com/alibabacloud-go/tea-utils/v2/service"
 	"github.com/alibabacloud-go/tea/tea"
 	"github.com/aliyun/alibaba-cloud-sdk-go/sdk"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/auth"
 	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/auth/credentials"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/auth/credentials/provider"
 	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
 	"github.com/aliyun/alibaba-cloud-sdk-go/services/bssopenapi"
+	credential "github.com/aliyun/credentials-go/credentials"
 	"github.com/galaxy-future/costpilot/internal/constants/cloud"
 	"github.com/galaxy-future/costpilot/internal/providers/types"
 	"github.com/pkg/errors"
 )
 
 type AlibabaCloud struct {
+	region     string
+	credential auth.Credential // of the clients of alibaba-cloud-sdk-go
+
 	bssClientOpt *bssopenapi.Client
 	bssClientNew *bssopenapiV3.Client
 	cmsClient    *cms.Client
@@ -32,9 +39,19 @@ type AlibabaCloud struct {
 }
 
 var (
-	metricNameMap = map[types.MetricItem]string{
-		types.MetricItemCPUUtilization:        "CPUUtilization",
-		types.MetricItemMemoryUsedUtilization: "memory_usedutilization",
+	// metricMappings of the namespace acs_ecs_dashboard, https://help.aliyun.com/document_detail/162844.html
+	// the memory, the disk usage, the network and the load are reported by the cloud monitor agent
+	metricMappings = map[types.MetricItem]types.MetricMapping{
+		types.MetricItemCPUUtilization:        {Name: "CPUUtilization", Unit: types.MetricUnitPercent},
+		types.MetricItemMemoryUsedUtilization: {Name: "memory_usedutilization", Unit: types.MetricUnitPercent},
+		types.MetricItemDiskUsedUtilization:   {Name: "diskusage_utilization", Unit: types.MetricUnitPercent},
+		types.MetricItemDiskReadIOPS:          {Name: "DiskReadIOPS", Unit: types.MetricUnitCountPerSecond},
+		types.MetricItemDiskWriteIOPS:         {Name: "DiskWriteIOPS", Unit: types.MetricUnitCountPerSecond},
+		types.MetricItemDiskReadThroughput:    {Name: "DiskReadBPS", Unit: types.MetricUnitBytesPerSecond},
+		types.MetricItemDiskWriteThroughput:   {Name: "DiskWriteBPS", Unit: types.MetricUnitBytesPerSecond},
+		types.MetricItemNetworkInBandwidth:    {Name: "networkin_rate", Unit: types.MetricUnitBitsPerSecond},
+		types.MetricItemNetworkOutBandwidth:   {Name: "networkout_rate", Unit: types.MetricUnitBitsPerSecond},
+		types.MetricItemLoadAverage:           {Name: "load_5m", Unit: types.MetricUnitNone},
 	}
 
 	resourceTypeMap = map[types.ResourceType]string{
@@ -49,39 +66,70 @@ var (
 )
 
 func New(AK, SK, region string) (*AlibabaCloud, error) {
-	bssClientNew, err := bssopenapiV3.NewClient(&openapi.Config{
-		AccessKeyId:     tea.String(AK),
-		AccessKeySecret: tea.String(SK),
-		Endpoint:        tea.String("business.aliyuncs.com"),
-	})
+	return NewWithCredential(types.Credential{AK: AK, SK: SK}, region)
+}
+
+// NewWithCredential the default chain covers the environment, the profile and the RAM role of the ECS instance,
+// the role is assumed by the ak/sk only
+func NewWithCredential(cred types.Credential, region string) (*AlibabaCloud, error) {
+	var (
+		optCredential auth.Credential = credentials.NewAccessKeyCredential(cred.AK, cred.SK)
+		newCredential credential.Credential
+		err           error
+	)
+	switch r := cred.AssumeRole; {
+	case r != nil && cred.DefaultChain:
+		return nil, errors.New("assume_role of AlibabaCloud requires ak/sk")
+	case r != nil:
+		duration := int(r.GetDuration().Seconds())
+		optCredential = credentials.NewRamRoleArnCredential(cred.AK, cred.SK, r.RoleArn, r.GetSessionName(), duration)
+		newCredential, err = credential.NewCredential(new(credential.Config).SetType("ram_role_arn").
+			SetAccessKeyId(cred.AK).SetAccessKeySecret(cred.SK).SetRoleArn(r.RoleArn).
+			SetRoleSessionName(r.GetSessionName()).SetRoleSessionExpiration(duration))
+		if err != nil {
+			return nil, err
+		}
+	case cred.DefaultChain:
+		if optCredential, err = provider.DefaultChain.Resolve(); err != nil {
+			return nil, err
+		}
+		if newCredential, err = credential.NewCredential(nil); err != nil {
+			return nil, err
+		}
+	}
+	config := func(endpoint string) *openapi.Config {
+		if newCredential != nil {
+			return &openapi.Config{Credential: newCredential, Endpoint: tea.String(endpoint)}
+		}
+		return &openapi.Config{
+			AccessKeyId:     tea.String(cred.AK),
+			AccessKeySecret: tea.String(cred.SK),
+			Endpoint:        tea.String(endpoint),
+		}
+	}
+
+	bssClientNew, err := bssopenapiV3.NewClient(config("business.aliyuncs.com"))
 	if err != nil {
 		return nil, err
 	}
 
-	bssClientOpt, err := bssopenapi.NewClientWithOptions(region, sdk.NewConfig().WithTimeout(10*time.Second), credentials.NewAccessKeyCredential(AK, SK))
+	bssClientOpt, err := bssopenapi.NewClientWithOptions(region, sdk.NewConfig().WithTimeout(10*time.Second), optCredential)
 	if err != nil {
 		return nil, err
 	}
 
-	config := &openapi.Config{
-		AccessKeyId:     tea.String(AK),
-		AccessKeySecret: tea.String(SK),
-	}
-	config.Endpoint = tea.String(_cmsEndPoint)
-	cmsClient, err := cms.NewClient(config)
+	cmsClient, err := cms.NewClient(config(_cmsEndPoint))
 	if err != nil {
 		return nil, err
 	}
 
-	ecsClient, err := ecs.NewClient(&openapi.Config{
-		AccessKeyId:     tea.String(AK),
-		AccessKeySecret: tea.String(SK),
-		Endpoint:        tea.String(_ecsEndPoint),
-	})
+	ecsClient, err := ecs.NewClient(config(_ecsEndPoint))
 	if err != nil {
 		return nil, err
 	}
 	return &AlibabaCloud{
+		region:       region,
+		credential:   optCredential,
 		bssClientOpt: bssClientOpt,
 		bssClientNew: bssClientNew,
 		cmsClient:    cmsClient,
@@ -117,7 +165,7 @@ func (p *AlibabaCloud) QueryAccountBill(ctx context.Context, param types.QueryAc
 		if len(billItems) == 0 {
 			billItems = make([]types.AccountBillItem, 0, totalCount)
 		}
-		billItems = append(billItems, convQueryAccountBill(response)...)
+		billItems = append(billItems, convQueryAccountBill(response, param.CostBasis)...)
 		if len(billItems) >= totalCount {
 			break
 		}
@@ -133,12 +181,127 @@ func (p *AlibabaCloud) QueryAccountBill(ctx context.Context, param types.QueryAc
 			Item: billItems,
 		},
 	}
+	// 分摊成本仅支持按月查询, 按日由调用方根据实例账单自行分摊
+	if param.IsAmortized && param.Granularity == types.Monthly && param.IsGroupByProduct {
+		gaapItems, err := p.queryGaapCost(param.BillingCycle, param.CostBasis)
+		if err != nil {
+			return types.DataInQueryAccountBill{}, err
+		}
+		result.Items.Item = replacePrePaidItems(billItems, gaapItems)
+		result.TotalCount = len(result.Items.Item)
+		result.IsAmortized = true
+	}
 
 	return result, nil
 }
 
+// queryGaapCost amortized cost of subscriptions in the billing cycle, summed by product
+func (p *AlibabaCloud) queryGaapCost(billingCycle string, basis types.CostBasis) ([]types.AccountBillItem, error) {
+	request := &bssopenapiV3.QueryInstanceGaapCostRequest{
+		BillingCycle:     tea.String(billingCycle),
+		SubscriptionType: tea.String(convSubscriptionTypeCloudToAliyun(cloud.PrePaid)),
+		PageNum:          tea.Int32(1),
+		PageSize:         tea.Int32(300),
+	}
+	amounts := make(map[string]*types.AccountBillItem) // key : product code
+	var productCodes []string
+	fetched := 0
+	for {
+		response, err := p.bssClientNew.QueryInstanceGaapCost(request)
+		if err != nil {
+			return nil, err
+		}
+		if !tea.BoolValue(response.Body.Success) {
+			return nil, fmt.Errorf("QueryInstanceGaapCost err: %v", tea.StringValue(response.Body.Message))
+		}
+		respData := response.Body.Data
+		if respData == nil || respData.Modules == nil || len(respData.Modules.Module) == 0 {
+			break
+		}
+		for _, m := range respData.Modules.Module {
+			productCode := tea.StringValue(m.ProductCode)
+			amount, err := convGaapAmount(m, basis)
+			if err != nil {
+				continue
+			}
+			item, ok := amounts[productCode]
+			if !ok {
+				item = &types.AccountBillItem{
+					PipCode:          convPipCode(productCode),
+					ProductName:      productCode,
+					SubscriptionType: cloud.PrePaid,
+					Currency:         tea.StringValue(m.Currency),
+				}
+				amounts[productCode] = item
+				productCodes = append(productCodes, productCode)
+			}
+			item.PretaxAmount += amount
+		}
+		fetched += len(respData.Modules.Module)
+		if fetched >= int(tea.Int32Value(respData.TotalCount)) {
+			break
+		}
+		request.PageNum = tea.Int32(tea.Int32Value(request.PageNum) + 1)
+	}
+	result := make([]types.AccountBillItem, 0, len(productCodes))
+	for _, productCode := range productCodes {
+		result = append(result, *amounts[productCode])
+	}
+	return result, nil
+}
+
+// replacePrePaidItems replace the cash subscription items with the amortized ones, product names are kept
+func replacePrePaidItems(billItems, gaapItems []types.AccountBillItem) []types.AccountBillItem {
+	names := make(map[types.PipCode]string)
+	result := make([]types.AccountBillItem, 0, len(billItems)+len(gaapItems))
+	for _, item := range billItems {
+		names[item.PipCode] = item.ProductName
+		if item.SubscriptionType != cloud.PrePaid {
+			result = append(result, item)
+		}
+	}
+	for _, item := range gaapItems {
+		if name, ok := names[item.PipCode]; ok {
+			item.ProductName = name
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+// convGaapAmount amortized amount of the month, tax is not amortized so tax_inclusive is the same as net
+func convGaapAmount(m *bssopenapiV3.QueryInstanceGaapCostResponseBodyDataModulesModule, basis types.CostBasis) (float64, error) {
+	if basis.OrDefault() == types.CostBasisList {
+		return strconv.ParseFloat(tea.StringValue(m.MonthGaapPretaxGrossAmount), 64)
+	}
+	amount, err := strconv.ParseFloat(tea.StringValue(m.MonthGaapPretaxAmount), 64)
+	if err != nil || basis.OrDefault() == types.CostBasisDiscount {
+		return amount, err
+	}
+	for _, deducted := range []*string{m.MonthGaapDeductedByCashCoupons, m.MonthGaapDeductedByPrepaidCard} {
+		if v, err := strconv.ParseFloat(tea.StringValue(deducted), 64); err == nil {
+			amount -= v
+		}
+	}
+	return amount, nil
+}
+
+// convBillAmount PretaxGrossAmount 原价, PretaxAmount 优惠后应付, cash coupons and prepaid cards are deducted from the net amount
+func convBillAmount(v bssopenapi.Item, basis types.CostBasis) float64 {
+	switch basis.OrDefault() {
+	case types.CostBasisList:
+		return v.PretaxGrossAmount
+	case types.CostBasisNet:
+		return v.PretaxAmount - v.DeductedByCashCoupons - v.DeductedByPrepaidCard
+	case types.CostBasisTaxInclusive:
+		return v.PretaxAmount - v.DeductedByCashCoupons - v.DeductedByPrepaidCard + v.Tax
+	default:
+		return v.PretaxAmount
+	}
+}
+
 // convQueryAccountBill
-func convQueryAccountBill(response *bssopenapi.QueryAccountBillResponse) []types.AccountBillItem {
+func convQueryAccountBill(response *bssopenapi.QueryAccountBillResponse, basis types.CostBasis) []types.AccountBillItem {
 	if response == nil {
 		return nil
 	}
@@ -151,7 +314,7 @@ func convQueryAccountBill(response *bssopenapi.QueryAccountBillResponse) []types
 			BillingDate:      v.BillingDate, // has date when Granularity=DAILY
 			SubscriptionType: convSubscriptionTypeAliyunToCloud(v.SubscriptionType),
 			Currency:         v.Currency,
-			PretaxAmount:     v.PretaxAmount,
+			PretaxAmount:     convBillAmount(v, basis),
 		}
 		result = append(result, item)
 	}
@@ -186,13 +349,13 @@ func (p *AlibabaCloud) DescribeMetricList(_ context.Context, param types.Describ
 		Maximum    float64 `json:"Maximum"`
 		Average    float64 `json:"Average"`
 	}
-	metricName, ok := metricNameMap[param.MetricName]
+	mapping, ok := metricMappings[param.MetricName]
 	if !ok {
 		return types.DescribeMetricList{}, errors.New("unknown metric name")
 	}
 	request := &cms.DescribeMetricListRequest{
 		Namespace:  tea.String("acs_ecs_dashboard"),
-		MetricName: tea.String(metricName),
+		MetricName: tea.String(mapping.Name),
 		Period:     tea.String(param.Period),
 		StartTime:  tea.String(param.StartTime.Format("2006-01-02T15:04:05Z")),
 		EndTime:    tea.String(param.EndTime.Format("2006-01-02T15:04:05Z")),
@@ -228,13 +391,14 @@ func (p *AlibabaCloud) DescribeMetricList(_ context.Context, param types.Describ
 		}
 	}
 
+	period, _ := strconv.Atoi(param.Period)
 	ret := types.DescribeMetricList{List: make([]types.MetricSample, 0, len(allDataList))}
 	for _, datapoint := range allDataList {
 		d := types.MetricSample{
 			InstanceId: datapoint.InstanceId,
-			Min:        datapoint.Minimum,
-			Max:        datapoint.Maximum,
-			Average:    datapoint.Average,
+			Min:        mapping.Convert(param.MetricName, datapoint.Minimum, period),
+			Max:        mapping.Convert(param.MetricName, datapoint.Maximum, period),
+			Average:    mapping.Convert(param.MetricName, datapoint.Average, period),
 			Timestamp:  datapoint.Timestamp,
 		}
 		ret.List = append(ret.List, d)
@@ -295,6 +459,9 @@ func (p *AlibabaCloud) DescribeInstanceBill(_ context.Context, param types.Descr
 		granularity := tea.ToString(param.Granularity)
 		request.Granularity = &granularity
 	}
+	if param.SubscriptionType != "" {
+		request.SubscriptionType = tea.String(convSubscriptionTypeCloudToAliyun(param.SubscriptionType))
+	}
 	var (
 		billItems []types.ItemsInInstanceBill
 		respData  *bssopenapiV3.DescribeInstanceBillResponseBodyData
@@ -352,11 +519,36 @@ func convInstanceBill(respData *bssopenapiV3.DescribeInstanceBillResponseBodyDat
 			ProductName:      *item.ProductName,
 			ProductDetail:    *item.ProductDetail,
 			ItemName:         *item.ItemName,
+			PretaxAmount:     float64(tea.Float32Value(item.PretaxAmount)),
+			PipCode:          convPipCode(tea.StringValue(item.PipCode)),
 		})
+		last := &result[len(result)-1]
+		last.ServicePeriod, last.ServicePeriodUnit = convServicePeriod(tea.StringValue(item.ServicePeriod), tea.StringValue(item.ServicePeriodUnit))
 	}
 	return result
 }
 
+// convServicePeriod 服务时长单位: 年 | 月 | 天 | 小时 | 秒, seconds are converted to hours
+func convServicePeriod(period, unit string) (int, types.ServicePeriodUnit) {
+	n, err := strconv.Atoi(period)
+	if err != nil || n <= 0 {
+		return 0, ""
+	}
+	switch unit {
+	case "年", "Year":
+		return n, types.ServicePeriodYear
+	case "月", "Month":
+		return n, types.ServicePeriodMonth
+	case "天", "日", "Day":
+		return n, types.ServicePeriodDay
+	case "小时", "Hour":
+		return n, types.ServicePeriodHour
+	case "秒", "Second":
+		return n / 3600, types.ServicePeriodHour
+	}
+	return 0, ""
+}
+
 func convSubscriptionTypeAliyunToCloud(subscriptionType string) cloud.SubscriptionType {
 	switch subscriptionType {
 	case "Subscription":
@@ -416,6 +608,9 @@ func (p *AlibabaCloud) queryAvailableInstancesByPage(_ context.Context, param ty
 		}
 		request.Region = tea.String(param.RegionId)
 	}
+	if len(param.ProductCode) > 0 {
+		request.ProductCode = tea.String(param.ProductCode)
+	}
 
 	if len(param.InstanceIdList) > 100 {
 		return types.QueryAvailableInstances{}, errors.Errorf("InstanceIDs in QueryAvailableInstancesRequest are max to 100, current: %d", len(param.InstanceIdList))
@@ -481,6 +676,8 @@ func convAvailableInstances(respData *bssopenapiV3.QueryAvailableInstancesRespon
 			Status:           *item.Status,
 			RenewStatus:      *item.RenewStatus,
 			SubscriptionType: convSubscriptionTypeAliyunToCloud(*item.SubscriptionType),
+			CreateTime:       convTime(item.CreateTime),
+			EndTime:          convTime(item.EndTime),
 			ProductCode:      *item.ProductCode,
 		}
 		result = append(result, i)
@@ -489,7 +686,20 @@ func convAvailableInstances(respData *bssopenapiV3.QueryAvailableInstancesRespon
 	return result
 }
 
-func (p *AlibabaCloud) DescribeInstances(ctx context.Context, param types.DescribeInstancesRequest) (types.DescribeInstances, error) {
-	// TODO implement me
-	return types.DescribeInstances{}, nil
+// convTime 2022-10-01T16:00:00Z, zero if absent
+func convTime(s *string) time.Time {
+	t, err := time.Parse(time.RFC3339, tea.StringValue(s))
+	if err != nil {
+		return time.Time{}
+	}
+	return t.Local()
+}
+
+// QueryCommitments coverage of subscription ecs instances and their expiry
+func (p *AlibabaCloud) QueryCommitments(ctx context.Context, param types.QueryCommitmentsRequest) (types.QueryCommitments, error) {
+	resp, err := p.QueryAvailableInstances(ctx, types.QueryAvailableInstancesRequest{ProductCode: "ecs"})
+	if err != nil {
+		return types.QueryCommitments{}, err
+	}
+	return types.ConvSubscriptionCommitments(resp.List, param), nil
 }
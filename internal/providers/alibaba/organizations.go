@@ -0,0 +1,60 @@
+package alibaba
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/resourcemanager"
+	"github.com/galaxy-future/costpilot/internal/providers/types"
+)
+
+const _memberRoleName = "ResourceDirectoryAccountAccessRole" // created in the accounts created by the resource directory
+
+// ListMemberAccounts the joined accounts of the resource directory except the management account itself
+func (p *AlibabaCloud) ListMemberAccounts(_ context.Context) ([]types.MemberAccount, error) {
+	client, err := resourcemanager.NewClientWithOptions(p.region, sdk.NewConfig().WithTimeout(10*time.Second), p.credential)
+	if err != nil {
+		return nil, err
+	}
+	dirRequest := resourcemanager.CreateGetResourceDirectoryRequest()
+	dirRequest.Scheme = "https"
+	dir, err := client.GetResourceDirectory(dirRequest)
+	if err != nil {
+		return nil, err
+	}
+	management := dir.ResourceDirectory.MasterAccountId
+
+	var members []types.MemberAccount
+	request := resourcemanager.CreateListAccountsRequest()
+	request.Scheme = "https"
+	request.PageSize = requests.NewInteger(100) // alibaba cloud max limit
+	for pageNum := 1; ; pageNum++ {
+		request.PageNumber = requests.NewInteger(pageNum)
+		response, err := client.ListAccounts(request)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range response.Accounts.Account {
+			if (a.Status != "CreateSuccess" && a.Status != "InviteSuccess") || a.AccountId == management {
+				continue
+			}
+			members = append(members, types.MemberAccount{ID: a.AccountId, Name: a.DisplayName})
+		}
+		if len(response.Accounts.Account) == 0 || pageNum*100 >= response.TotalCount {
+			break
+		}
+	}
+	return members, nil
+}
+
+// MemberRole the role in the member account, default ResourceDirectoryAccountAccessRole
+func (p *AlibabaCloud) MemberRole(memberID, roleName string) (types.AssumeRole, error) {
+	if roleName == "" {
+		roleName = _memberRoleName
+	}
+	return types.AssumeRole{RoleArn: fmt.Sprintf("acs:ram::%s:role/%s", memberID, strings.ToLower(roleName))}, nil
+}
@@ -0,0 +1,34 @@
+package alibaba
+
+import (
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	"github.com/galaxy-future/costpilot/internal/i18n"
+	"github.com/galaxy-future/costpilot/internal/providers/registry"
+	"github.com/galaxy-future/costpilot/internal/providers/types"
+)
+
+func init() {
+	registry.Register(registry.Registration{
+		Name:         cloud.AlibabaCloud,
+		DisplayNames: map[i18n.Language]string{i18n.ZhCN: "阿里云", i18n.EnUS: "Alibaba Cloud"},
+		New: func(cred types.Credential, regionID string) (registry.Provider, error) {
+			return NewWithCredential(cred, regionID)
+		},
+		Capabilities: registry.Capabilities{
+			AccountMetrics:  true,
+			DefaultChain:    true,
+			AssumeRole:      true,
+			MemberDiscovery: true,
+		},
+		Regions: []string{
+			"cn-qingdao", "cn-beijing", "cn-zhangjiakou", "cn-huhehaote", "cn-wulanchabu", "cn-hangzhou", "cn-shanghai",
+			"cn-nanjing", "cn-fuzhou", "cn-wuhan-lr", "cn-shenzhen", "cn-heyuan", "cn-guangzhou", "cn-chengdu", "cn-hongkong",
+			"cn-shanghai-finance-1", "cn-shenzhen-finance-1", "cn-beijing-finance-1", "cn-north-2-gov-1",
+			"ap-northeast-1", "ap-northeast-2", "ap-southeast-1", "ap-southeast-2", "ap-southeast-3", "ap-southeast-5",
+			"ap-southeast-6", "ap-southeast-7", "ap-south-1", "us-east-1", "us-west-1", "eu-west-1", "eu-central-1",
+			"me-east-1", "me-central-1",
+		},
+		DefaultRegion: "cn-hangzhou",
+		Metrics:       metricMappings,
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"strconv"
+	"strings"
 	"time"
 
 	cloudwatchType "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
@@ -16,28 +17,57 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/galaxy-future/costpilot/tools"
 
+	awsV2 "github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/costexplorer"
 	explorerTypes "github.com/aws/aws-sdk-go-v2/service/costexplorer/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/galaxy-future/costpilot/internal/constants/cloud"
 	"github.com/galaxy-future/costpilot/internal/providers/types"
 )
 
 type AWSCloud struct {
+	region      string
+	credentials awsV2.CredentialsProvider
+
 	client     *costexplorer.Client
 	ec2Client  *ec2.Client
 	cloudWatch *cloudwatch.Client
 }
 
 func New(AK, SK, regionId string) (*AWSCloud, error) {
-	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(regionId), config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(AK, SK, "")))
+	return NewWithCredential(types.Credential{AK: AK, SK: SK}, regionId)
+}
+
+// NewWithCredential the default chain covers the environment, the shared profiles, web identity, ECS and IMDS
+func NewWithCredential(cred types.Credential, regionId string) (*AWSCloud, error) {
+	opts := []func(*config.LoadOptions) error{config.WithRegion(regionId)}
+	if !cred.DefaultChain {
+		opts = append(opts, config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(cred.AK, cred.SK, "")))
+	} else if cred.Profile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(cred.Profile))
+	}
+	cfg, err := config.LoadDefaultConfig(context.TODO(), opts...)
 	if err != nil {
 		return nil, err
 	}
+	if r := cred.AssumeRole; r != nil {
+		cfg.Credentials = awsV2.NewCredentialsCache(stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), r.RoleArn, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = r.GetSessionName()
+			o.Duration = r.GetDuration()
+			if r.ExternalID != "" {
+				o.ExternalID = awsV2.String(r.ExternalID)
+			}
+		}))
+	}
 
 	return &AWSCloud{
+		region:      regionId,
+		credentials: cfg.Credentials,
+
 		client:     costexplorer.NewFromConfig(cfg),
 		ec2Client:  ec2.NewFromConfig(cfg),
 		cloudWatch: cloudwatch.NewFromConfig(cfg),
@@ -72,6 +102,7 @@ func (p *AWSCloud) QueryAccountBill(ctx context.Context, param types.QueryAccoun
 	result := types.DataInQueryAccountBill{
 		BillingCycle: param.BillingCycle,
 		TotalCount:   len(items),
+		IsAmortized:  param.IsAmortized,
 		Items: types.ItemsInQueryAccountBill{
 			Item: items,
 		},
@@ -94,6 +125,7 @@ func convGranularity(granularity types.Granularity) explorerTypes.Granularity {
 func convAccountBillItems(output *costexplorer.GetCostAndUsageOutput, param types.QueryAccountBillRequest, chargeType string) ([]types.AccountBillItem, error) {
 	var result []types.AccountBillItem
 	var err error
+	metric := convCostMetric(param)
 	resultBytime := output.ResultsByTime[0]
 	if param.IsGroupByProduct {
 		result = make([]types.AccountBillItem, 0, len(resultBytime.Groups))
@@ -101,10 +133,10 @@ func convAccountBillItems(output *costexplorer.GetCostAndUsageOutput, param type
 			newItem := types.AccountBillItem{
 				SubscriptionType: convChargeType(chargeType),
 				PipCode:          types.PipCode(group.Keys[0]),
-				Currency:         aws.StringValue(group.Metrics["BlendedCost"].Unit),
+				Currency:         aws.StringValue(group.Metrics[metric].Unit),
 				ProductName:      group.Keys[0],
 			}
-			newItem.PretaxAmount, err = convAmount(group.Metrics["BlendedCost"].Amount)
+			newItem.PretaxAmount, err = convAmount(group.Metrics[metric].Amount)
 			if err != nil {
 				return nil, err
 			}
@@ -117,9 +149,9 @@ func convAccountBillItems(output *costexplorer.GetCostAndUsageOutput, param type
 		result = make([]types.AccountBillItem, 0, 1)
 		newItem := types.AccountBillItem{
 			SubscriptionType: convChargeType(chargeType),
-			Currency:         aws.StringValue(resultBytime.Total["BlendedCost"].Unit),
+			Currency:         aws.StringValue(resultBytime.Total[metric].Unit),
 		}
-		newItem.PretaxAmount, err = convAmount(resultBytime.Total["BlendedCost"].Amount)
+		newItem.PretaxAmount, err = convAmount(resultBytime.Total[metric].Amount)
 		if err != nil {
 			return nil, err
 		}
@@ -131,6 +163,52 @@ func convAccountBillItems(output *costexplorer.GetCostAndUsageOutput, param type
 	return result, nil
 }
 
+// convCostMetric upfront fees of reservations and savings plans are spread by AmortizedCost,
+// the Net metrics have the discounts applied, see convRecordTypeFilter for the records left out of each basis,
+// CostBasisLegacy keeps BlendedCost with every record type
+func convCostMetric(param types.QueryAccountBillRequest) string {
+	switch param.CostBasis {
+	case types.CostBasisLegacy:
+		if param.IsAmortized {
+			return "AmortizedCost"
+		}
+		return "BlendedCost"
+	case types.CostBasisList:
+		if param.IsAmortized {
+			return "AmortizedCost"
+		}
+		return "UnblendedCost"
+	default:
+		if param.IsAmortized {
+			return "NetAmortizedCost"
+		}
+		return "NetUnblendedCost"
+	}
+}
+
+// convRecordTypeFilter exclude the record types which are not part of the cost basis
+func convRecordTypeFilter(basis types.CostBasis) *explorerTypes.Expression {
+	var excluded []string
+	switch basis {
+	case types.CostBasisList:
+		excluded = []string{"Credit", "Refund", "Tax", "Enterprise Discount Program Discount", "Bundled Discount", "Private Rate Card Discount"}
+	case types.CostBasisDiscount:
+		excluded = []string{"Credit", "Refund", "Tax"}
+	case types.CostBasisNet:
+		excluded = []string{"Tax"}
+	default:
+		return nil
+	}
+	return &explorerTypes.Expression{
+		Not: &explorerTypes.Expression{
+			Dimensions: &explorerTypes.DimensionValues{
+				Key:    explorerTypes.DimensionRecordType,
+				Values: excluded,
+			},
+		},
+	}
+}
+
 func convAmount(amountPtr *string) (float64, error) {
 	if amountPtr == nil {
 		return 0, errors.New("amountPtr is nil")
@@ -177,7 +255,7 @@ func (p *AWSCloud) QueryByFilter(param types.QueryAccountBillRequest, chargeType
 	}
 	input := &costexplorer.GetCostAndUsageInput{
 		Granularity: convGranularity(param.Granularity),
-		Metrics:     []string{"BlendedCost"},
+		Metrics:     []string{convCostMetric(param)},
 		TimePeriod: &explorerTypes.DateInterval{
 			End:   aws.String(end.Format(dateFormat)),
 			Start: aws.String(start.Format(dateFormat)),
@@ -192,13 +270,24 @@ func (p *AWSCloud) QueryByFilter(param types.QueryAccountBillRequest, chargeType
 			},
 		}
 	}
+	var filters []explorerTypes.Expression
 	if chargeType != "" {
-		input.Filter = &explorerTypes.Expression{
+		filters = append(filters, explorerTypes.Expression{
 			Dimensions: &explorerTypes.DimensionValues{
 				Key:    explorerTypes.DimensionPurchaseType,
 				Values: []string{chargeType},
 			},
-		}
+		})
+	}
+	if f := convRecordTypeFilter(param.CostBasis); f != nil {
+		filters = append(filters, *f)
+	}
+	switch len(filters) {
+	case 0:
+	case 1:
+		input.Filter = &filters[0]
+	default:
+		input.Filter = &explorerTypes.Expression{And: filters}
 	}
 	for {
 		output, err := p.client.GetCostAndUsage(context.Background(), input)
@@ -282,41 +371,53 @@ func (p *AWSCloud) DescribeInstances(ctx context.Context, param types.DescribeIn
 	return types.DescribeInstances{}, err
 }
 
+// reservedInstance active reservation, Count is consumed by matched instances
+type reservedInstance struct {
+	InstanceType     string
+	AvailabilityZone string // empty for regional reservations
+	Platform         string
+	Count            int
+}
+
 // Get Reserved Instances
-func (p *AWSCloud) describeReservedInstances(ctx context.Context) (map[string]string, error) {
-	reservedInstances := make(map[string]string, 0)
+func (p *AWSCloud) describeReservedInstances(ctx context.Context) ([]*reservedInstance, error) {
+	var reservedInstances []*reservedInstance
 	output, err := p.ec2Client.DescribeReservedInstances(ctx, &ec2.DescribeReservedInstancesInput{})
 	if err != nil {
 		log.Println(err.Error())
 		return reservedInstances, err
 	}
-	if output.ReservedInstances == nil || len(output.ReservedInstances) == 0 {
-		return reservedInstances, nil
-	}
-	if output.ReservedInstances != nil {
-		for _, reservation := range output.ReservedInstances {
-			if ec2Types.ReservedInstanceStateActive == reservation.State {
-				reservedInstances[aws.StringValue(reservation.ReservedInstancesId)] = string(reservation.InstanceType)
-			}
+	for _, reservation := range output.ReservedInstances {
+		if ec2Types.ReservedInstanceStateActive != reservation.State {
+			continue
 		}
+		ri := &reservedInstance{
+			InstanceType: string(reservation.InstanceType),
+			Platform:     string(reservation.ProductDescription),
+			Count:        int(tea.Int32Value(reservation.InstanceCount)),
+		}
+		if reservation.Scope == ec2Types.ScopeAvailabilityZone {
+			ri.AvailabilityZone = aws.StringValue(reservation.AvailabilityZone)
+		}
+		reservedInstances = append(reservedInstances, ri)
 	}
 	return reservedInstances, nil
 }
 
-func convDescribeInstances(reservations []ec2Types.Reservation, reservedInstances map[string]string) types.DescribeInstances {
+// convDescribeInstances an instance is PrePaid if an unconsumed reservation matches its type, zone and platform
+func convDescribeInstances(reservations []ec2Types.Reservation, reservedInstances []*reservedInstance) types.DescribeInstances {
 	awsInstances := make([]types.ItemDescribeInstance, 0)
 	for _, reservation := range reservations {
 		for _, instance := range reservation.Instances {
 			subscriptionType := cloud.PostPaid
-			for k, v := range reservedInstances {
-				if string(instance.InstanceType) == v {
-					subscriptionType = cloud.PrePaid
-					delete(reservedInstances, k)
-				}
+			if ri := matchReservedInstance(instance, reservedInstances); ri != nil {
+				subscriptionType = cloud.PrePaid
+				ri.Count--
 			}
 			newInstance := types.ItemDescribeInstance{
 				InstanceId:       aws.StringValue(instance.InstanceId),
 				InstanceName:     convInstanceName(instance.Tags), // maybe empty
+				InstanceType:     string(instance.InstanceType),
 				SubscriptionType: subscriptionType,
 				PublicIpAddress:  []string{aws.StringValue(instance.PublicIpAddress)},
 				InnerIpAddress:   []string{aws.StringValue(instance.PrivateIpAddress)},
@@ -330,6 +431,34 @@ func convDescribeInstances(reservations []ec2Types.Reservation, reservedInstance
 	}
 }
 
+// matchReservedInstance zonal reservations are matched before regional ones
+func matchReservedInstance(instance ec2Types.Instance, reservedInstances []*reservedInstance) *reservedInstance {
+	var zone string
+	if instance.Placement != nil {
+		zone = aws.StringValue(instance.Placement.AvailabilityZone)
+	}
+	platform := aws.StringValue(instance.PlatformDetails)
+	var regional *reservedInstance
+	for _, ri := range reservedInstances {
+		if ri.Count <= 0 || ri.InstanceType != string(instance.InstanceType) {
+			continue
+		}
+		if ri.Platform != "" && platform != "" && !strings.HasPrefix(ri.Platform, platform) {
+			continue
+		}
+		if ri.AvailabilityZone == "" {
+			if regional == nil {
+				regional = ri
+			}
+			continue
+		}
+		if ri.AvailabilityZone == zone {
+			return ri
+		}
+	}
+	return regional
+}
+
 func convInstanceName(tagSet []ec2Types.Tag) string {
 	if len(tagSet) == 0 {
 		return ""
@@ -342,19 +471,17 @@ func convInstanceName(tagSet []ec2Types.Tag) string {
 	return ""
 }
 
-func convDescribeMetricListRequest(param types.DescribeMetricListRequest) (*cloudwatch.GetMetricDataInput, map[string]string) {
+// convDescribeMetricListRequest the totals of the period are summed, the others are averaged
+func convDescribeMetricListRequest(param types.DescribeMetricListRequest, mapping types.MetricMapping) (*cloudwatch.GetMetricDataInput, map[string]string) {
 	ids := make(map[string]string)
 	metricDataQueries := []cloudwatchType.MetricDataQuery{}
-	var nameSpace, metricName, label string
-	if types.MetricItemCPUUtilization == param.MetricName {
-		nameSpace = _namespace_Cpu
-		metricName = _cpuUtilization
-		label = _cpuUtilization
+	nameSpace := mapping.Namespace
+	if nameSpace == "" {
+		nameSpace = _namespaceEC2
 	}
-	if types.MetricItemMemoryUsedUtilization == param.MetricName {
-		nameSpace = _namespace_Mem
-		metricName = _memoryUtilization
-		label = _memoryUtilization
+	stat := cloudwatchType.StatisticAverage
+	if mapping.Unit == types.MetricUnitCount || mapping.Unit == types.MetricUnitBytes {
+		stat = cloudwatchType.StatisticSum
 	}
 	period, _ := strconv.Atoi(param.Period)
 	for i, instanceId := range param.Filter.InstanceIds {
@@ -367,13 +494,13 @@ func convDescribeMetricListRequest(param types.DescribeMetricListRequest) (*clou
 			MetricStat: &cloudwatchType.MetricStat{
 				Metric: &cloudwatchType.Metric{
 					Namespace:  aws.String(nameSpace),
-					MetricName: aws.String(metricName),
+					MetricName: aws.String(mapping.Name),
 					Dimensions: []cloudwatchType.Dimension{dimension},
 				},
-				Stat:   aws.String(string(cloudwatchType.StatisticAverage)),
+				Stat:   aws.String(string(stat)),
 				Period: aws.Int32(int32(period)),
 			},
-			Label: aws.String(label),
+			Label: aws.String(mapping.Name),
 		}
 		metricDataQueries = append(metricDataQueries, metricDataQuery)
 		ids[aws.StringValue(metricDataQuery.Id)] = instanceId
@@ -389,7 +516,12 @@ func (p *AWSCloud) DescribeMetricList(ctx context.Context, param types.DescribeM
 	if param.Filter.InstanceIds == nil || len(param.Filter.InstanceIds) == 0 {
 		return types.DescribeMetricList{}, nil
 	}
-	request, ids := convDescribeMetricListRequest(param)
+	mapping, ok := _metrics[param.MetricName]
+	if !ok {
+		return types.DescribeMetricList{}, fmt.Errorf("collect metric %s not supported for aws", param.MetricName)
+	}
+	period, _ := strconv.Atoi(param.Period)
+	request, ids := convDescribeMetricListRequest(param, mapping)
 	output, err := p.cloudWatch.GetMetricData(ctx, request)
 	if err != nil {
 		log.Println(err.Error())
@@ -405,7 +537,7 @@ func (p *AWSCloud) DescribeMetricList(ctx context.Context, param types.DescribeM
 				for i, value := range metricDataResult.Values {
 					metricSample := types.MetricSample{
 						InstanceId: ids[aws.StringValue(metricDataResult.Id)],
-						Average:    value,
+						Average:    mapping.Convert(param.MetricName, value, period),
 						Timestamp:  aws.TimeUnixMilli(metricDataResult.Timestamps[i]),
 					}
 					list = append(list, metricSample)
@@ -420,10 +552,147 @@ func (p *AWSCloud) DescribeMetricList(ctx context.Context, param types.DescribeM
 	return types.DescribeMetricList{}, nil
 }
 
-func (p *AWSCloud) DescribeInstanceBill(ctx context.Context, param types.DescribeInstanceBillRequest, isAll bool) (types.DescribeInstanceBill, error) {
-	return types.DescribeInstanceBill{}, nil
+// QueryCommitments reserved instance and savings plans coverage/utilization from cost explorer
+func (p *AWSCloud) QueryCommitments(ctx context.Context, param types.QueryCommitmentsRequest) (types.QueryCommitments, error) {
+	period := &explorerTypes.DateInterval{Start: aws.String(param.StartDate), End: aws.String(param.EndDate)}
+	var ret types.QueryCommitments
+
+	riCoverage, err := p.client.GetReservationCoverage(ctx, &costexplorer.GetReservationCoverageInput{TimePeriod: period})
+	if err != nil {
+		return types.QueryCommitments{}, err
+	}
+	riUsage := types.CommitmentUsage{Type: types.CommitmentReservedInstance, Unit: types.CommitmentUnitHours}
+	if riCoverage.Total != nil && riCoverage.Total.CoverageHours != nil {
+		riUsage.CoveredUsage = convAmountOrZero(riCoverage.Total.CoverageHours.ReservedHours)
+		riUsage.EligibleUsage = convAmountOrZero(riCoverage.Total.CoverageHours.TotalRunningHours)
+	}
+	reservations, err := p.reservationUtilization(ctx, period, &riUsage)
+	if err != nil {
+		return types.QueryCommitments{}, err
+	}
+	ret.List = append(ret.List, reservations...)
+	if riUsage.EligibleUsage > 0 || riUsage.TotalCommitment > 0 {
+		ret.Usages = append(ret.Usages, riUsage)
+	}
+
+	// savings plans are optional, cost explorer reports an error when the account has never purchased one
+	spUsage := types.CommitmentUsage{Type: types.CommitmentSavingsPlan, Unit: "USD"}
+	var nextToken *string
+	for {
+		output, err := p.client.GetSavingsPlansCoverage(ctx, &costexplorer.GetSavingsPlansCoverageInput{TimePeriod: period, NextToken: nextToken})
+		if err != nil {
+			log.Printf("W! get savings plans coverage failed: %v", err)
+			return ret, nil
+		}
+		for _, c := range output.SavingsPlansCoverages {
+			if c.Coverage == nil {
+				continue
+			}
+			spUsage.CoveredUsage += convAmountOrZero(c.Coverage.SpendCoveredBySavingsPlans)
+			spUsage.EligibleUsage += convAmountOrZero(c.Coverage.TotalCost)
+		}
+		if nextToken = output.NextToken; aws.StringValue(nextToken) == "" {
+			break
+		}
+	}
+	spUtilization, err := p.client.GetSavingsPlansUtilization(ctx, &costexplorer.GetSavingsPlansUtilizationInput{TimePeriod: period})
+	if err != nil {
+		log.Printf("W! get savings plans utilization failed: %v", err)
+	} else if spUtilization.Total != nil && spUtilization.Total.Utilization != nil {
+		spUsage.UsedCommitment = convAmountOrZero(spUtilization.Total.Utilization.UsedCommitment)
+		spUsage.TotalCommitment = convAmountOrZero(spUtilization.Total.Utilization.TotalCommitment)
+	}
+	if spUsage.EligibleUsage > 0 || spUsage.TotalCommitment > 0 {
+		ret.Usages = append(ret.Usages, spUsage)
+	}
+	savingsPlans, err := p.savingsPlansDetails(ctx, period)
+	if err != nil {
+		log.Printf("W! get savings plans utilization details failed: %v", err)
+		return ret, nil
+	}
+	ret.List = append(ret.List, savingsPlans...)
+	return ret, nil
+}
+
+// reservationUtilization fills the utilization of usage and lists the reservations grouped by subscription
+func (p *AWSCloud) reservationUtilization(ctx context.Context, period *explorerTypes.DateInterval, usage *types.CommitmentUsage) ([]types.ItemCommitment, error) {
+	var list []types.ItemCommitment
+	var nextToken *string
+	for {
+		output, err := p.client.GetReservationUtilization(ctx, &costexplorer.GetReservationUtilizationInput{
+			TimePeriod:    period,
+			GroupBy:       []explorerTypes.GroupDefinition{{Type: explorerTypes.GroupDefinitionTypeDimension, Key: aws.String("SUBSCRIPTION_ID")}},
+			NextPageToken: nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if output.Total != nil {
+			usage.UsedCommitment = convAmountOrZero(output.Total.TotalActualHours)
+			usage.TotalCommitment = convAmountOrZero(output.Total.PurchasedHours)
+		}
+		for _, u := range output.UtilizationsByTime {
+			for _, g := range u.Groups {
+				if g.Attributes["subscriptionStatus"] == "Retired" {
+					continue
+				}
+				count, _ := strconv.Atoi(g.Attributes["numberOfInstances"])
+				list = append(list, types.ItemCommitment{
+					CommitmentId: aws.StringValue(g.Value),
+					Type:         types.CommitmentReservedInstance,
+					Description:  g.Attributes["instanceType"],
+					RegionId:     g.Attributes["region"],
+					Count:        count,
+					StartTime:    convRFC3339(g.Attributes["startDateTime"]),
+					EndTime:      convRFC3339(g.Attributes["endDateTime"]),
+				})
+			}
+		}
+		if nextToken = output.NextPageToken; aws.StringValue(nextToken) == "" {
+			break
+		}
+	}
+	return list, nil
+}
+
+func (p *AWSCloud) savingsPlansDetails(ctx context.Context, period *explorerTypes.DateInterval) ([]types.ItemCommitment, error) {
+	var list []types.ItemCommitment
+	var nextToken *string
+	for {
+		output, err := p.client.GetSavingsPlansUtilizationDetails(ctx, &costexplorer.GetSavingsPlansUtilizationDetailsInput{TimePeriod: period, NextToken: nextToken})
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range output.SavingsPlansUtilizationDetails {
+			list = append(list, types.ItemCommitment{
+				CommitmentId: aws.StringValue(d.SavingsPlanArn),
+				Type:         types.CommitmentSavingsPlan,
+				Description:  d.Attributes["SavingsPlansType"],
+				RegionId:     d.Attributes["Region"],
+				Count:        1,
+				StartTime:    convRFC3339(d.Attributes["StartDateTime"]),
+				EndTime:      convRFC3339(d.Attributes["EndDateTime"]),
+			})
+		}
+		if nextToken = output.NextToken; aws.StringValue(nextToken) == "" {
+			break
+		}
+	}
+	return list, nil
 }
 
-func (p *AWSCloud) QueryAvailableInstances(ctx context.Context, param types.QueryAvailableInstancesRequest) (types.QueryAvailableInstances, error) {
-	return types.QueryAvailableInstances{}, nil
+func convAmountOrZero(amountPtr *string) float64 {
+	amount, err := convAmount(amountPtr)
+	if err != nil {
+		return 0
+	}
+	return amount
+}
+
+func convRFC3339(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t.Local()
 }
@@ -154,3 +154,18 @@ func TestAWSCloud_DescribeMetricList(t *testing.T) {
 	t.Log(describeMetricList)
 	t.Log(err)
 }
+
+func Test_convCostMetric(t *testing.T) {
+	assert.Equal(t, "BlendedCost", convCostMetric(types.QueryAccountBillRequest{}))
+	assert.Equal(t, "NetUnblendedCost", convCostMetric(types.QueryAccountBillRequest{CostBasis: types.CostBasisDiscount}))
+	assert.Equal(t, "UnblendedCost", convCostMetric(types.QueryAccountBillRequest{CostBasis: types.CostBasisList}))
+	assert.Equal(t, "NetAmortizedCost", convCostMetric(types.QueryAccountBillRequest{CostBasis: types.CostBasisNet, IsAmortized: true}))
+
+	assert.Nil(t, convRecordTypeFilter(types.CostBasisTaxInclusive))
+	f := convRecordTypeFilter(types.CostBasisNet)
+	if assert.NotNil(t, f) && assert.NotNil(t, f.Not) {
+		assert.Equal(t, []string{"Tax"}, f.Not.Dimensions.Values)
+	}
+	assert.Contains(t, convRecordTypeFilter(types.CostBasisDiscount).Not.Dimensions.Values, "Credit")
+	assert.Nil(t, convRecordTypeFilter(types.CostBasisLegacy))
+}
@@ -1,9 +1,23 @@
 package aws
 
+import "github.com/galaxy-future/costpilot/internal/providers/types"
+
 const (
-	_cpuUtilization    string = "CPUUtilization"
-	_memoryUtilization string = "mem_used_percent"
-	_namespace_Cpu     string = "AWS/EC2"
-	_namespace_Mem     string = "CWAgent"
-	_instanceId        string = "InstanceId"
+	_namespaceEC2     string = "AWS/EC2"
+	_namespaceCWAgent string = "CWAgent"
+	_instanceId       string = "InstanceId"
 )
+
+// _metrics of the namespace AWS/EC2 by default, the memory usage is reported by the cloudwatch agent.
+// the agent reports the disk usage per path, device and fstype, which can not be queried by the instance alone,
+// and the load average is not reported by the agent of every system, both are left out
+var _metrics = map[types.MetricItem]types.MetricMapping{
+	types.MetricItemCPUUtilization:        {Name: "CPUUtilization", Unit: types.MetricUnitPercent},
+	types.MetricItemMemoryUsedUtilization: {Namespace: _namespaceCWAgent, Name: "mem_used_percent", Unit: types.MetricUnitPercent},
+	types.MetricItemDiskReadIOPS:          {Name: "EBSReadOps", Unit: types.MetricUnitCount},
+	types.MetricItemDiskWriteIOPS:         {Name: "EBSWriteOps", Unit: types.MetricUnitCount},
+	types.MetricItemDiskReadThroughput:    {Name: "EBSReadBytes", Unit: types.MetricUnitBytes},
+	types.MetricItemDiskWriteThroughput:   {Name: "EBSWriteBytes", Unit: types.MetricUnitBytes},
+	types.MetricItemNetworkInBandwidth:    {Name: "NetworkIn", Unit: types.MetricUnitBytes},
+	types.MetricItemNetworkOutBandwidth:   {Name: "NetworkOut", Unit: types.MetricUnitBytes},
+}
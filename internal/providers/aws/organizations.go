@@ -0,0 +1,62 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	awsV2 "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	orgTypes "github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	"github.com/galaxy-future/costpilot/internal/providers/types"
+)
+
+const _memberRoleName = "OrganizationAccountAccessRole" // created in the accounts created by the organization
+
+// ListMemberAccounts the active accounts of the organization except the management account itself
+func (p *AWSCloud) ListMemberAccounts(ctx context.Context) ([]types.MemberAccount, error) {
+	orgRegion := "us-east-1"
+	if p.partition() == "aws-cn" {
+		orgRegion = "cn-northwest-1"
+	}
+	client := organizations.New(organizations.Options{Region: orgRegion, Credentials: p.credentials})
+	org, err := client.DescribeOrganization(ctx, &organizations.DescribeOrganizationInput{})
+	if err != nil {
+		return nil, err
+	}
+	management := awsV2.ToString(org.Organization.MasterAccountId)
+
+	var members []types.MemberAccount
+	paginator := organizations.NewListAccountsPaginator(client, &organizations.ListAccountsInput{})
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range out.Accounts {
+			if a.Status != orgTypes.AccountStatusActive || awsV2.ToString(a.Id) == management {
+				continue
+			}
+			members = append(members, types.MemberAccount{ID: awsV2.ToString(a.Id), Name: awsV2.ToString(a.Name)})
+		}
+	}
+	return members, nil
+}
+
+// MemberRole the role in the member account, default OrganizationAccountAccessRole
+func (p *AWSCloud) MemberRole(memberID, roleName string) (types.AssumeRole, error) {
+	if roleName == "" {
+		roleName = _memberRoleName
+	}
+	return types.AssumeRole{RoleArn: fmt.Sprintf("arn:%s:iam::%s:role/%s", p.partition(), memberID, roleName)}, nil
+}
+
+func (p *AWSCloud) partition() string {
+	switch {
+	case strings.HasPrefix(p.region, "cn-"):
+		return "aws-cn"
+	case strings.HasPrefix(p.region, "us-gov-"):
+		return "aws-us-gov"
+	}
+	return "aws"
+}
@@ -0,0 +1,22 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAWSCloud_MemberRole(t *testing.T) {
+	for region, arn := range map[string]string{
+		"us-east-1":     "arn:aws:iam::123456789012:role/OrganizationAccountAccessRole",
+		"cn-north-1":    "arn:aws-cn:iam::123456789012:role/OrganizationAccountAccessRole",
+		"us-gov-west-1": "arn:aws-us-gov:iam::123456789012:role/OrganizationAccountAccessRole",
+	} {
+		r, err := (&AWSCloud{region: region}).MemberRole("123456789012", "")
+		require.NoError(t, err)
+		assert.Equal(t, arn, r.RoleArn, region)
+	}
+	r, _ := (&AWSCloud{region: "us-east-1"}).MemberRole("123456789012", "CostPilot")
+	assert.Equal(t, "arn:aws:iam::123456789012:role/CostPilot", r.RoleArn)
+}
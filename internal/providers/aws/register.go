@@ -0,0 +1,34 @@
+package aws
+
+import (
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	"github.com/galaxy-future/costpilot/internal/i18n"
+	"github.com/galaxy-future/costpilot/internal/providers/registry"
+	"github.com/galaxy-future/costpilot/internal/providers/types"
+)
+
+func init() {
+	var regions []string // from the endpoints of the sdk
+	for _, p := range endpoints.DefaultPartitions() {
+		for id := range p.Regions() {
+			regions = append(regions, id)
+		}
+	}
+	registry.Register(registry.Registration{
+		Name:         cloud.AWSCloud,
+		DisplayNames: map[i18n.Language]string{i18n.ZhCN: "AWS", i18n.EnUS: "AWS"},
+		New: func(cred types.Credential, regionID string) (registry.Provider, error) {
+			return NewWithCredential(cred, regionID)
+		},
+		Capabilities: registry.Capabilities{
+			DefaultChain:    true,
+			AssumeRole:      true,
+			ExternalID:      true,
+			MemberDiscovery: true,
+		},
+		Regions:       regions,
+		DefaultRegion: "us-east-1",
+		Metrics:       _metrics,
+	})
+}
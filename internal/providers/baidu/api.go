@@ -1,12 +1,10 @@
 package baidu
 
 import (
-	"context"
 	"strings"
 
 	"github.com/baidubce/bce-sdk-go/services/bcc"
 	"github.com/galaxy-future/costpilot/internal/constants/cloud"
-	"github.com/galaxy-future/costpilot/internal/providers/types"
 	"github.com/pkg/errors"
 )
 
@@ -43,32 +41,3 @@ func New(ak, sk, regionId string) (*BaiduCloud, error) {
 func (*BaiduCloud) ProviderType() cloud.Provider {
 	return cloud.BaiduCloud
 }
-
-// QueryAccountBill
-func (p *BaiduCloud) QueryAccountBill(ctx context.Context, param types.QueryAccountBillRequest) (types.DataInQueryAccountBill, error) {
-	return types.DataInQueryAccountBill{}, nil
-}
-
-func (p *BaiduCloud) DescribeMetricList(ctx context.Context, param types.DescribeMetricListRequest) (types.DescribeMetricList, error) {
-	// TODO implement me
-	return types.DescribeMetricList{}, nil
-}
-
-func (p *BaiduCloud) DescribeRegions(ctx context.Context, param types.DescribeRegionsRequest) (types.DescribeRegions, error) {
-	// TODO implement me
-	return types.DescribeRegions{}, nil
-}
-
-func (p *BaiduCloud) DescribeInstanceBill(ctx context.Context, param types.DescribeInstanceBillRequest, isAll bool) (types.DescribeInstanceBill, error) {
-	return types.DescribeInstanceBill{}, nil
-}
-
-func (p *BaiduCloud) QueryAvailableInstances(ctx context.Context, param types.QueryAvailableInstancesRequest) (types.QueryAvailableInstances, error) {
-	// TODO implement me
-	return types.QueryAvailableInstances{}, nil
-}
-
-func (p *BaiduCloud) DescribeInstances(ctx context.Context, param types.DescribeInstancesRequest) (types.DescribeInstances, error) {
-	// TODO implement me
-	return types.DescribeInstances{}, nil
-}
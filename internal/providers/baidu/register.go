@@ -0,0 +1,24 @@
+package baidu
+
+import (
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	"github.com/galaxy-future/costpilot/internal/i18n"
+	"github.com/galaxy-future/costpilot/internal/providers/registry"
+	"github.com/galaxy-future/costpilot/internal/providers/types"
+)
+
+func init() {
+	regions := make([]string, 0, len(EndPoints))
+	for id := range EndPoints {
+		regions = append(regions, id)
+	}
+	registry.Register(registry.Registration{
+		Name:         cloud.BaiduCloud,
+		DisplayNames: map[i18n.Language]string{i18n.ZhCN: "百度", i18n.EnUS: "Baidu Cloud"},
+		New: func(cred types.Credential, regionID string) (registry.Provider, error) {
+			return New(cred.AK, cred.SK, regionID)
+		},
+		Regions:       regions,
+		DefaultRegion: "bj",
+	})
+}
@@ -0,0 +1,119 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	"github.com/galaxy-future/costpilot/internal/providers/types"
+)
+
+// BillingProvider the bills of the account
+type BillingProvider interface {
+	Provider
+	QueryAccountBill(ctx context.Context, request types.QueryAccountBillRequest) (types.DataInQueryAccountBill, error)
+}
+
+// InstanceBillProvider the bills of the instances
+type InstanceBillProvider interface {
+	Provider
+	// DescribeInstanceBill query the consumption of all product instances or billing items for a certain account period
+	// in principle, we can get the basic info about specify InstantId, even through deleted or released.
+	DescribeInstanceBill(ctx context.Context, request types.DescribeInstanceBillRequest, isAll bool) (types.DescribeInstanceBill, error)
+}
+
+// InventoryProvider the instances of the region of the client
+type InventoryProvider interface {
+	Provider
+	// DescribeInstances Describes the specified instances or all instances.
+	DescribeInstances(context.Context, types.DescribeInstancesRequest) (types.DescribeInstances, error)
+}
+
+// SubscriptionProvider the available instances of the account with their charge types
+type SubscriptionProvider interface {
+	Provider
+	// QueryAvailableInstances list all available Instances by RegionId OR InstantIds.
+	QueryAvailableInstances(context.Context, types.QueryAvailableInstancesRequest) (types.QueryAvailableInstances, error)
+}
+
+// CommitmentProvider the reserved instances, savings plans and subscriptions
+type CommitmentProvider interface {
+	Provider
+	// QueryCommitments coverage and utilization of reserved instances, savings plans and subscriptions, with the active commitments.
+	QueryCommitments(context.Context, types.QueryCommitmentsRequest) (types.QueryCommitments, error)
+}
+
+// MetricsProvider the monitoring samples of the instances
+type MetricsProvider interface {
+	Provider
+	// DescribeMetricList get monitoring samples, eg: cpu/memory.
+	DescribeMetricList(context.Context, types.DescribeMetricListRequest) (types.DescribeMetricList, error)
+}
+
+// RegionProvider the regions of the account
+type RegionProvider interface {
+	Provider
+	// DescribeRegions list all regions as the RegionId and RegionName map.
+	DescribeRegions(context.Context, types.DescribeRegionsRequest) (types.DescribeRegions, error)
+}
+
+// Capability an optional interface of the providers, named in the reports
+type Capability string
+
+const (
+	CapabilityBilling       Capability = "billing"        // BillingProvider
+	CapabilityInstanceBills Capability = "instance_bills" // InstanceBillProvider
+	CapabilityInventory     Capability = "inventory"      // InventoryProvider
+	CapabilitySubscriptions Capability = "subscriptions"  // SubscriptionProvider
+	CapabilityCommitments   Capability = "commitments"    // CommitmentProvider
+	CapabilityMetrics       Capability = "metrics"        // MetricsProvider
+	CapabilityRegions       Capability = "regions"        // RegionProvider
+)
+
+// Supports whether the provider implements the interface of the capability
+func Supports(p Provider, c Capability) bool {
+	var ok bool
+	switch c {
+	case CapabilityBilling:
+		_, ok = p.(BillingProvider)
+	case CapabilityInstanceBills:
+		_, ok = p.(InstanceBillProvider)
+	case CapabilityInventory:
+		_, ok = p.(InventoryProvider)
+	case CapabilitySubscriptions:
+		_, ok = p.(SubscriptionProvider)
+	case CapabilityCommitments:
+		_, ok = p.(CommitmentProvider)
+	case CapabilityMetrics:
+		_, ok = p.(MetricsProvider)
+	case CapabilityRegions:
+		_, ok = p.(RegionProvider)
+	}
+	return ok
+}
+
+// NotSupportedError the provider does not implement the interface of the capability
+type NotSupportedError struct {
+	Provider   cloud.Provider
+	Capability Capability
+}
+
+func (e NotSupportedError) Error() string {
+	return fmt.Sprintf("provider[%s] does not support %s", e.Provider, e.Capability)
+}
+
+// NotSupported the error of a provider lacking the capability, the provider may be nil
+func NotSupported(p Provider, c Capability) error {
+	e := NotSupportedError{Capability: c}
+	if p != nil {
+		e.Provider = p.ProviderType()
+	}
+	return e
+}
+
+// IsNotSupported whether the step failed as the provider lacks a capability, rather than the data is missing
+func IsNotSupported(err error) bool {
+	var e NotSupportedError
+	return errors.As(err, &e)
+}
@@ -0,0 +1,36 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	"github.com/galaxy-future/costpilot/internal/providers/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type metricsOnly struct{}
+
+func (metricsOnly) ProviderType() cloud.Provider {
+	return "FakeCloud"
+}
+
+func (metricsOnly) DescribeMetricList(context.Context, types.DescribeMetricListRequest) (types.DescribeMetricList, error) {
+	return types.DescribeMetricList{}, nil
+}
+
+func TestSupports(t *testing.T) {
+	p := metricsOnly{}
+	assert.True(t, Supports(p, CapabilityMetrics))
+	for _, c := range []Capability{CapabilityBilling, CapabilityInstanceBills, CapabilityInventory, CapabilitySubscriptions,
+		CapabilityCommitments, CapabilityRegions} {
+		assert.False(t, Supports(p, c), c)
+	}
+
+	err := NotSupported(p, CapabilityBilling)
+	assert.EqualError(t, err, "provider[FakeCloud] does not support billing")
+	assert.True(t, IsNotSupported(fmt.Errorf("cost: %w", err)))
+	assert.False(t, IsNotSupported(fmt.Errorf("timeout")))
+	assert.False(t, IsNotSupported(nil))
+}
@@ -0,0 +1,71 @@
+package huawei
+
+import (
+	"sync"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/providers/types"
+	coreAuth "github.com/huaweicloud/huaweicloud-sdk-go-v3/core/auth"
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/auth/basic"
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/auth/global"
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/impl"
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/request"
+	iam "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/iam/v3"
+	iamModel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/iam/v3/model"
+)
+
+// agencyCredential the temporary access key of the agency delegated by another account, renewed before it expires
+type agencyCredential struct {
+	iamClient *iam.IamClient // authenticated as the delegated account
+	role      types.AssumeRole
+	project   bool // the basic credential of the project level services, or the global one
+
+	mu      sync.Mutex
+	region  string
+	current coreAuth.ICredential
+	expires time.Time
+}
+
+func newAgencyCredential(iamClient *iam.IamClient, role types.AssumeRole, project bool) *agencyCredential {
+	return &agencyCredential{iamClient: iamClient, role: role, project: project}
+}
+
+func (c *agencyCredential) ProcessAuthParams(_ *impl.DefaultHttpClient, region string) coreAuth.ICredential {
+	c.region = region
+	return c
+}
+
+func (c *agencyCredential) ProcessAuthRequest(client *impl.DefaultHttpClient, req *request.DefaultHttpRequest) (*request.DefaultHttpRequest, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.current == nil || time.Until(c.expires) < 5*time.Minute {
+		if err := c.renew(client); err != nil {
+			return nil, err
+		}
+	}
+	return c.current.ProcessAuthRequest(client, req)
+}
+
+func (c *agencyCredential) renew(client *impl.DefaultHttpClient) error {
+	domain, duration := c.role.DomainName, int32(c.role.GetDuration().Seconds())
+	resp, err := c.iamClient.CreateTemporaryAccessKeyByAgency(&iamModel.CreateTemporaryAccessKeyByAgencyRequest{
+		Body: &iamModel.CreateTemporaryAccessKeyByAgencyRequestBody{Auth: &iamModel.AgencyAuth{Identity: &iamModel.AgencyAuthIdentity{
+			Methods:    []iamModel.AgencyAuthIdentityMethods{iamModel.GetAgencyAuthIdentityMethodsEnum().ASSUME_ROLE},
+			AssumeRole: &iamModel.IdentityAssumerole{AgencyName: c.role.AgencyName, DomainName: &domain, DurationSeconds: &duration},
+		}}},
+	})
+	if err != nil {
+		return err
+	}
+	key := resp.Credential
+	if c.expires, err = time.Parse(time.RFC3339, key.ExpiresAt); err != nil {
+		c.expires = time.Now().Add(c.role.GetDuration())
+	}
+	if c.project {
+		c.current = basic.NewCredentialsBuilder().WithAk(key.Access).WithSk(key.Secret).WithSecurityToken(key.Securitytoken).Build().
+			ProcessAuthParams(client, c.region)
+	} else {
+		c.current = global.NewCredentialsBuilder().WithAk(key.Access).WithSk(key.Secret).WithSecurityToken(key.Securitytoken).Build()
+	}
+	return nil
+}
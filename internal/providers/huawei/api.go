@@ -5,13 +5,16 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/alibabacloud-go/tea/tea"
 	"github.com/galaxy-future/costpilot/internal/constants/cloud"
 	"github.com/galaxy-future/costpilot/internal/providers/types"
 	"github.com/galaxy-future/costpilot/tools/limiter"
+	coreAuth "github.com/huaweicloud/huaweicloud-sdk-go-v3/core/auth"
 	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/auth/basic"
 	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/auth/global"
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/auth/provider"
 	bss "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/bss/v2"
 	bssModel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/bss/v2/model"
 	regionHuawei "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/bss/v2/region"
@@ -34,14 +37,27 @@ const (
 	_average         = "average"
 	_namespaceSysECS = "SYS.ECS"
 	_namespaceAGTECS = "AGT.ECS"
+
+	_serviceTypeECS          = "hws.service.type.ec2"
+	_resourceStatusEffective = 2 // 1:初始化 2:已生效 3:已过期 4:已冻结 5:宽限期 6:保留期
+	_expirePolicyAutoRenew   = 3
 )
 
-var huaweiMetric = map[types.MetricItem]string{
-	types.MetricItemCPUUtilization:        "cpu_usage",
-	types.MetricItemMemoryUsedUtilization: "mem_usedPercent",
+// huaweiMetric of the agent namespace AGT.ECS by default, the disk usage of the agent is by mount point and is left out
+var huaweiMetric = map[types.MetricItem]types.MetricMapping{
+	types.MetricItemCPUUtilization:        {Name: "cpu_usage", Unit: types.MetricUnitPercent},
+	types.MetricItemMemoryUsedUtilization: {Name: "mem_usedPercent", Unit: types.MetricUnitPercent},
+	types.MetricItemDiskReadIOPS:          {Namespace: _namespaceSysECS, Name: "disk_read_requests_rate", Unit: types.MetricUnitCountPerSecond},
+	types.MetricItemDiskWriteIOPS:         {Namespace: _namespaceSysECS, Name: "disk_write_requests_rate", Unit: types.MetricUnitCountPerSecond},
+	types.MetricItemDiskReadThroughput:    {Namespace: _namespaceSysECS, Name: "disk_read_bytes_rate", Unit: types.MetricUnitBytesPerSecond},
+	types.MetricItemDiskWriteThroughput:   {Namespace: _namespaceSysECS, Name: "disk_write_bytes_rate", Unit: types.MetricUnitBytesPerSecond},
+	types.MetricItemNetworkInBandwidth:    {Namespace: _namespaceSysECS, Name: "network_incoming_bytes_aggregate_rate", Unit: types.MetricUnitBytesPerSecond},
+	types.MetricItemNetworkOutBandwidth:   {Namespace: _namespaceSysECS, Name: "network_outgoing_bytes_aggregate_rate", Unit: types.MetricUnitBytesPerSecond},
+	types.MetricItemLoadAverage:           {Name: "load_average5", Unit: types.MetricUnitNone},
 }
 
 type HuaweiCloud struct {
+	region       string
 	bssClientOpt *bss.BssClient
 	iamClient    *iam.IamClient
 	ecsClient    *ecs.EcsClient
@@ -49,15 +65,36 @@ type HuaweiCloud struct {
 }
 
 func New(AK, SK, region string) (*HuaweiCloud, error) {
-	auth := global.NewCredentialsBuilder().
-		WithAk(AK).
-		WithSk(SK).
-		Build()
+	return NewWithCredential(types.Credential{AK: AK, SK: SK}, region)
+}
+
+// NewWithCredential the default chain covers the environment, the profile and the ECS metadata,
+// the agency of AssumeRole is accessed by the temporary access keys of the credential
+func NewWithCredential(cred types.Credential, region string) (*HuaweiCloud, error) {
+	var auth, basicAuth coreAuth.ICredential
+	if cred.DefaultChain {
+		var err error
+		if auth, err = provider.GlobalCredentialProviderChain().GetCredentials(); err != nil {
+			return nil, err
+		}
+		if basicAuth, err = provider.BasicCredentialProviderChain().GetCredentials(); err != nil {
+			return nil, err
+		}
+	} else {
+		auth = global.NewCredentialsBuilder().
+			WithAk(cred.AK).
+			WithSk(cred.SK).
+			Build()
 
-	basicAuth := basic.NewCredentialsBuilder().
-		WithAk(AK).
-		WithSk(SK).
-		Build()
+		basicAuth = basic.NewCredentialsBuilder().
+			WithAk(cred.AK).
+			WithSk(cred.SK).
+			Build()
+	}
+	if r := cred.AssumeRole; r != nil {
+		delegated := iam.NewIamClient(iam.IamClientBuilder().WithRegion(iamRegion.ValueOf(region)).WithCredential(auth).Build())
+		auth, basicAuth = newAgencyCredential(delegated, *r, false), newAgencyCredential(delegated, *r, true)
+	}
 
 	bssClientOpt := bss.NewBssClient(bss.BssClientBuilder().WithRegion(regionHuawei.ValueOf(_bssRegion)).WithCredential(auth).Build())
 
@@ -80,6 +117,7 @@ func New(AK, SK, region string) (*HuaweiCloud, error) {
 			Build())
 
 	return &HuaweiCloud{
+		region:       region,
 		bssClientOpt: bssClientOpt,
 		iamClient:    iamClient,
 		ecsClient:    ecsClient,
@@ -117,14 +155,56 @@ func convQueryAccountBillByMonth(param types.QueryAccountBillRequest, response *
 			ProductName:      tea.StringValue(v.ServiceTypeName),
 			SubscriptionType: convSubscriptionType(strconv.Itoa(int(tea.Int32Value(v.ChargingMode)))),
 			Currency:         tea.StringValue(response.Currency),
-			PretaxAmount:     tea.Float64Value(v.CashAmount),
+			PretaxAmount:     convMonthlyBillAmount(v, param.CostBasis),
 		}
 		result = append(result, temp)
 	}
 	return result
 }
 
-func convQueryAccountBill(response *bssModel.ListCustomerselfResourceRecordsResponse) []types.AccountBillItem {
+// convMonthlyBillAmount OfficialAmount 官网价, ConsumeAmount 优惠后消费金额, CashAmount 现金支付金额 of CostBasisLegacy,
+// the net amount excludes coupons, flexipurchase coupons and stored value cards, prices are tax inclusive already
+func convMonthlyBillAmount(v bssModel.BillSumRecordInfoV2, basis types.CostBasis) float64 {
+	switch basis {
+	case types.CostBasisLegacy:
+		return tea.Float64Value(v.CashAmount)
+	case types.CostBasisList:
+		return tea.Float64Value(v.OfficialAmount)
+	case types.CostBasisNet, types.CostBasisTaxInclusive:
+		return tea.Float64Value(v.ConsumeAmount) - tea.Float64Value(v.CouponAmount) -
+			tea.Float64Value(v.FlexipurchaseCouponAmount) - tea.Float64Value(v.StoredValueCardAmount)
+	default:
+		return tea.Float64Value(v.ConsumeAmount)
+	}
+}
+
+// convMonthlySumAmount the sum has no official amount, see queryAccountBillByMonth for CostBasisList
+func convMonthlySumAmount(response *bssModel.ShowCustomerMonthlySumResponse, basis types.CostBasis) float64 {
+	switch basis {
+	case types.CostBasisLegacy:
+		return tea.Float64Value(response.CashAmount)
+	case types.CostBasisNet, types.CostBasisTaxInclusive:
+		return tea.Float64Value(response.ConsumeAmount) - tea.Float64Value(response.CouponAmount) -
+			tea.Float64Value(response.FlexipurchaseCouponAmount) - tea.Float64Value(response.StoredValueCardAmount)
+	default:
+		return tea.Float64Value(response.ConsumeAmount)
+	}
+}
+
+// convFeeRecordAmount same fields as convMonthlyBillAmount, Amount is the amount after discount and bonus is deducted as well
+func convFeeRecordAmount(v bssModel.ResFeeRecordV2, basis types.CostBasis) float64 {
+	switch basis.OrDefault() {
+	case types.CostBasisList:
+		return tea.Float64Value(v.OfficialAmount)
+	case types.CostBasisNet, types.CostBasisTaxInclusive:
+		return tea.Float64Value(v.Amount) - tea.Float64Value(v.CouponAmount) - tea.Float64Value(v.FlexipurchaseCouponAmount) -
+			tea.Float64Value(v.StoredCardAmount) - tea.Float64Value(v.BonusAmount)
+	default:
+		return tea.Float64Value(v.Amount)
+	}
+}
+
+func convQueryAccountBill(response *bssModel.ListCustomerselfResourceRecordsResponse, basis types.CostBasis) []types.AccountBillItem {
 	if response == nil {
 		return []types.AccountBillItem{}
 	}
@@ -139,7 +219,7 @@ func convQueryAccountBill(response *bssModel.ListCustomerselfResourceRecordsResp
 			BillingDate:      tea.StringValue(v.BillDate),
 			SubscriptionType: convSubscriptionType(*v.ChargeMode),
 			Currency:         tea.StringValue(response.Currency),
-			PretaxAmount:     tea.Float64Value(v.Amount),
+			PretaxAmount:     convFeeRecordAmount(v, basis),
 		}
 		result = append(result, item)
 	}
@@ -216,16 +296,20 @@ func (p *HuaweiCloud) DescribeMetricList(ctx context.Context, param types.Descri
 		return types.DescribeMetricList{}, fmt.Errorf("filter InstanceIds empty")
 	}
 
-	metricName, ok := huaweiMetric[param.MetricName]
+	mapping, ok := huaweiMetric[param.MetricName]
 	if !ok {
 		return types.DescribeMetricList{}, fmt.Errorf("collect metric %s not supported for huawei", param.MetricName)
 	}
+	namespace := mapping.Namespace
+	if namespace == "" {
+		namespace = _namespaceAGTECS
+	}
 
 	for _, i := range param.Filter.InstanceIds {
 		metrics = append(metrics, cesModel.MetricInfo{
-			Namespace:  _namespaceAGTECS,
+			Namespace:  namespace,
 			Dimensions: []cesModel.MetricsDimension{{Name: _instanceId, Value: i}},
-			MetricName: metricName,
+			MetricName: mapping.Name,
 		})
 	}
 
@@ -256,7 +340,7 @@ func (p *HuaweiCloud) DescribeMetricList(ctx context.Context, param types.Descri
 			ret.List = append(ret.List, types.MetricSample{
 				Timestamp:  datapoint.Timestamp,
 				InstanceId: id,
-				Average:    *datapoint.Average,
+				Average:    mapping.Convert(param.MetricName, *datapoint.Average, 0),
 			})
 		}
 	}
@@ -312,9 +396,14 @@ func (p *HuaweiCloud) DescribeInstances(ctx context.Context, param types.Describ
 		}
 		fixedIps, floatingIps := getIpInfoForECS(server)
 		chargeMode, _ := server.Metadata[_chargingMode]
+		var flavor string
+		if server.Flavor != nil {
+			flavor = server.Flavor.Id
+		}
 		ret.List = append(ret.List, types.ItemDescribeInstance{
 			InstanceId:       server.Id,
 			InstanceName:     server.Name,
+			InstanceType:     flavor,
 			SubscriptionType: convSubscriptionType(chargeMode),
 			InnerIpAddress:   fixedIps,
 			PublicIpAddress:  floatingIps,
@@ -324,12 +413,107 @@ func (p *HuaweiCloud) DescribeInstances(ctx context.Context, param types.Describ
 	return ret, nil
 }
 
-func (p *HuaweiCloud) DescribeInstanceBill(ctx context.Context, param types.DescribeInstanceBillRequest, isAll bool) (types.DescribeInstanceBill, error) {
-	return types.DescribeInstanceBill{}, nil
+// QueryAvailableInstances yearly/monthly resources of all regions from bss, and pay-per-use ecs servers of the client region
+func (p *HuaweiCloud) QueryAvailableInstances(ctx context.Context, param types.QueryAvailableInstancesRequest) (types.QueryAvailableInstances, error) {
+	var instanceList []types.ItemAvailableInstance
+	if param.SubscriptionType != cloud.PostPaid {
+		request := &bssModel.ListPayPerUseCustomerResourcesRequest{
+			Body: &bssModel.QueryResourcesReq{
+				OnlyMainResource: tea.Int32(1),
+				Offset:           tea.Int32(0),
+				Limit:            tea.Int32(100),
+			},
+		}
+		if len(param.InstanceIdList) > 0 {
+			request.Body.ResourceIds = &param.InstanceIdList
+		}
+		for {
+			limiter := limiter.Limiters.GetLimiter(p.ProviderType().String()+"-"+"ListPayPerUseCustomerResources", 9)
+			limiter.Take()
+			response, err := p.bssClientOpt.ListPayPerUseCustomerResources(request)
+			if err != nil {
+				return types.QueryAvailableInstances{}, err
+			}
+			if response.Data == nil || len(*response.Data) == 0 {
+				break
+			}
+			for _, r := range *response.Data {
+				if param.ProductCode != "" && tea.StringValue(r.ServiceTypeCode) != param.ProductCode {
+					continue
+				}
+				if param.RegionId != "" && tea.StringValue(r.RegionCode) != param.RegionId {
+					continue
+				}
+				instanceList = append(instanceList, convAvailableInstance(r))
+			}
+			offset := *request.Body.Offset + int32(len(*response.Data))
+			if offset >= tea.Int32Value(response.TotalCount) {
+				break
+			}
+			request.Body.Offset = &offset
+		}
+	}
+	if param.SubscriptionType != cloud.PrePaid && (param.ProductCode == "" || param.ProductCode == _serviceTypeECS) &&
+		(param.RegionId == "" || param.RegionId == p.region) {
+		servers, err := p.DescribeInstances(ctx, types.DescribeInstancesRequest{InstanceIds: param.InstanceIdList})
+		if err != nil {
+			return types.QueryAvailableInstances{}, err
+		}
+		for _, server := range servers.List {
+			if server.SubscriptionType == cloud.PrePaid { // listed by bss already
+				continue
+			}
+			instanceList = append(instanceList, types.ItemAvailableInstance{
+				InstanceId:       server.InstanceId,
+				RegionId:         p.region,
+				Status:           types.AvailableInstanceStatusNormal,
+				SubscriptionType: cloud.PostPaid,
+				ProductCode:      _serviceTypeECS,
+			})
+		}
+	}
+	return types.QueryAvailableInstances{TotalCount: len(instanceList), List: instanceList}, nil
 }
 
-func (p *HuaweiCloud) QueryAvailableInstances(ctx context.Context, param types.QueryAvailableInstancesRequest) (types.QueryAvailableInstances, error) {
-	return types.QueryAvailableInstances{}, nil
+func convAvailableInstance(r bssModel.OrderInstanceV2) types.ItemAvailableInstance {
+	i := types.ItemAvailableInstance{
+		InstanceId:       tea.StringValue(r.ResourceId),
+		RegionId:         tea.StringValue(r.RegionCode),
+		Status:           strconv.Itoa(int(tea.Int32Value(r.Status))),
+		RenewStatus:      "ManualRenewal",
+		SubscriptionType: cloud.PrePaid,
+		ProductCode:      tea.StringValue(r.ServiceTypeCode),
+	}
+	if tea.Int32Value(r.Status) == _resourceStatusEffective {
+		i.Status = types.AvailableInstanceStatusNormal
+	}
+	if tea.Int32Value(r.ExpirePolicy) == _expirePolicyAutoRenew {
+		i.RenewStatus = types.RenewStatusAutoRenewal
+	}
+	if t, err := time.Parse(time.RFC3339, tea.StringValue(r.EffectiveTime)); err == nil {
+		i.CreateTime = t.Local()
+	}
+	if t, err := time.Parse(time.RFC3339, tea.StringValue(r.ExpireTime)); err == nil {
+		i.EndTime = t.Local()
+	}
+	return i
+}
+
+// QueryCommitments coverage of yearly/monthly ecs servers in the client region, and the expiry of all yearly/monthly resources
+func (p *HuaweiCloud) QueryCommitments(ctx context.Context, param types.QueryCommitmentsRequest) (types.QueryCommitments, error) {
+	resp, err := p.QueryAvailableInstances(ctx, types.QueryAvailableInstancesRequest{})
+	if err != nil {
+		return types.QueryCommitments{}, err
+	}
+	var ecsInRegion []types.ItemAvailableInstance
+	for _, i := range resp.List {
+		if i.ProductCode == _serviceTypeECS && i.RegionId == p.region {
+			ecsInRegion = append(ecsInRegion, i)
+		}
+	}
+	ret := types.ConvSubscriptionCommitments(ecsInRegion, param)
+	ret.List = types.ConvSubscriptionCommitments(resp.List, param).List
+	return ret, nil
 }
 
 func (p *HuaweiCloud) queryAccountBillByMonth(ctx context.Context, param types.QueryAccountBillRequest) (result types.DataInQueryAccountBill, err error) {
@@ -366,6 +550,20 @@ func (p *HuaweiCloud) queryAccountBillByMonth(ctx context.Context, param types.Q
 			TotalCount:   int(tea.Int32Value(response.TotalCount)),
 			Items:        types.ItemsInQueryAccountBill{Item: billItems},
 		}
+	} else if param.CostBasis.OrDefault() == types.CostBasisList {
+		grouped := param
+		grouped.IsGroupByProduct = true
+		result, err = p.queryAccountBillByMonth(ctx, grouped)
+		if err != nil {
+			return types.DataInQueryAccountBill{}, err
+		}
+		total := types.AccountBillItem{}
+		for _, v := range result.Items.Item {
+			total.Currency = v.Currency
+			total.PretaxAmount += v.PretaxAmount
+		}
+		result.TotalCount = 1
+		result.Items.Item = []types.AccountBillItem{total}
 	} else {
 		limiter := limiter.Limiters.GetLimiter(p.ProviderType().String()+"-"+"ShowCustomerMonthlySum", 9)
 		limiter.Take()
@@ -380,7 +578,7 @@ func (p *HuaweiCloud) queryAccountBillByMonth(ctx context.Context, param types.Q
 				Item: []types.AccountBillItem{
 					{
 						Currency:     tea.StringValue(response.Currency),
-						PretaxAmount: tea.Float64Value(response.CashAmount),
+						PretaxAmount: convMonthlySumAmount(response, param.CostBasis),
 					},
 				},
 			},
@@ -413,7 +611,7 @@ func (p *HuaweiCloud) queryAccountBillByDate(ctx context.Context, param types.Qu
 		if len(billItems) == 0 {
 			billItems = make([]types.AccountBillItem, 0, *totalCount)
 		}
-		billItems = append(billItems, convQueryAccountBill(response)...)
+		billItems = append(billItems, convQueryAccountBill(response, param.CostBasis)...)
 		if len(billItems) >= int(*totalCount) {
 			break
 		}
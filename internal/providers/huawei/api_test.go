@@ -8,12 +8,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/alibabacloud-go/tea/tea"
 	"github.com/galaxy-future/costpilot/internal/constants/cloud"
 	"github.com/galaxy-future/costpilot/internal/providers/types"
 	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/auth/global"
 	bss "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/bss/v2"
 	"github.com/huaweicloud/huaweicloud-sdk-go-v3/services/bss/v2/model"
 	regionHuawei "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/bss/v2/region"
+	"github.com/stretchr/testify/assert"
 )
 
 var _AK = "AK"
@@ -243,3 +245,11 @@ func TestHuaweiCloud_DescribeMetricList(t *testing.T) {
 	}
 	t.Log(got)
 }
+
+func Test_convMonthlyBillAmount(t *testing.T) {
+	v := model.BillSumRecordInfoV2{OfficialAmount: tea.Float64(10), ConsumeAmount: tea.Float64(8), CashAmount: tea.Float64(6), CouponAmount: tea.Float64(2)}
+	assert.Equal(t, 6.0, convMonthlyBillAmount(v, types.CostBasisLegacy))
+	assert.Equal(t, 8.0, convMonthlyBillAmount(v, types.CostBasisDiscount))
+	assert.Equal(t, 10.0, convMonthlyBillAmount(v, types.CostBasisList))
+	assert.Equal(t, 6.0, convMonthlyBillAmount(v, types.CostBasisNet))
+}
@@ -0,0 +1,31 @@
+package huawei
+
+import (
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	"github.com/galaxy-future/costpilot/internal/i18n"
+	"github.com/galaxy-future/costpilot/internal/providers/registry"
+	"github.com/galaxy-future/costpilot/internal/providers/types"
+)
+
+func init() {
+	registry.Register(registry.Registration{
+		Name:         cloud.HuaweiCloud,
+		DisplayNames: map[i18n.Language]string{i18n.ZhCN: "华为", i18n.EnUS: "Huawei Cloud"},
+		New: func(cred types.Credential, regionID string) (registry.Provider, error) {
+			return NewWithCredential(cred, regionID)
+		},
+		Capabilities: registry.Capabilities{
+			DefaultChain: true,
+			AssumeRole:   true,
+			Agency:       true,
+		},
+		Regions: []string{
+			"cn-north-1", "cn-north-2", "cn-north-4", "cn-north-9", "cn-east-2", "cn-east-3", "cn-east-4", "cn-south-1",
+			"cn-south-2", "cn-south-4", "cn-southwest-2", "ap-southeast-1", "ap-southeast-2", "ap-southeast-3",
+			"ap-southeast-4", "af-south-1", "la-north-2", "la-south-2", "sa-brazil-1", "na-mexico-1", "tr-west-1",
+			"me-east-1", "ru-northwest-2", "eu-west-101", "ae-ad-1",
+		},
+		DefaultRegion: "cn-north-4",
+		Metrics:       huaweiMetric,
+	})
+}
@@ -5,62 +5,84 @@ import (
 	"fmt"
 	"sync"
 
-	"github.com/galaxy-future/costpilot/internal/providers/aws"
-	"github.com/galaxy-future/costpilot/internal/providers/baidu"
-	"github.com/galaxy-future/costpilot/internal/providers/huawei"
-	"github.com/galaxy-future/costpilot/internal/providers/tencent"
-
 	"github.com/galaxy-future/costpilot/internal/constants/cloud"
-	"github.com/galaxy-future/costpilot/internal/providers/alibaba"
+	"github.com/galaxy-future/costpilot/internal/providers/registry"
 	"github.com/galaxy-future/costpilot/internal/providers/types"
 	"github.com/spf13/cast"
+
+	// the builtin providers, the out-of-tree ones are registered the same way by importing their packages
+	"github.com/galaxy-future/costpilot/internal/providers/alibaba"
+	"github.com/galaxy-future/costpilot/internal/providers/aws"
+	_ "github.com/galaxy-future/costpilot/internal/providers/baidu"
+	"github.com/galaxy-future/costpilot/internal/providers/huawei"
+	"github.com/galaxy-future/costpilot/internal/providers/tencent"
 )
 
 var clientMap sync.Map
 
-type Provider interface {
-	ProviderType() cloud.Provider
+// the capabilities of the builtin providers
+var (
+	_ BillingProvider      = (*alibaba.AlibabaCloud)(nil)
+	_ InstanceBillProvider = (*alibaba.AlibabaCloud)(nil)
+	_ SubscriptionProvider = (*alibaba.AlibabaCloud)(nil)
+	_ CommitmentProvider   = (*alibaba.AlibabaCloud)(nil)
+	_ MetricsProvider      = (*alibaba.AlibabaCloud)(nil)
+	_ RegionProvider       = (*alibaba.AlibabaCloud)(nil)
+	_ MemberLister         = (*alibaba.AlibabaCloud)(nil)
 
-	QueryAccountBill(ctx context.Context, request types.QueryAccountBillRequest) (types.DataInQueryAccountBill, error)
-	// DescribeInstanceBill query the consumption of all product instances or billing items for a certain account period
-	// in principle, we can get the basic info about specify InstantId, even through deleted or released.
-	DescribeInstanceBill(ctx context.Context, request types.DescribeInstanceBillRequest, isAll bool) (types.DescribeInstanceBill, error)
-	// QueryAvailableInstances list all available Instances by RegionId OR InstantIds.
-	QueryAvailableInstances(context.Context, types.QueryAvailableInstancesRequest) (types.QueryAvailableInstances, error)
+	_ BillingProvider    = (*aws.AWSCloud)(nil)
+	_ InventoryProvider  = (*aws.AWSCloud)(nil)
+	_ CommitmentProvider = (*aws.AWSCloud)(nil)
+	_ MetricsProvider    = (*aws.AWSCloud)(nil)
+	_ RegionProvider     = (*aws.AWSCloud)(nil)
+	_ MemberLister       = (*aws.AWSCloud)(nil)
 
-	// DescribeRegions list all regions as the RegionId and RegionName map.
-	DescribeRegions(context.Context, types.DescribeRegionsRequest) (types.DescribeRegions, error)
-	// DescribeInstances Describes the specified instances or all instances.
-	DescribeInstances(context.Context, types.DescribeInstancesRequest) (types.DescribeInstances, error)
+	_ BillingProvider      = (*huawei.HuaweiCloud)(nil)
+	_ InventoryProvider    = (*huawei.HuaweiCloud)(nil)
+	_ SubscriptionProvider = (*huawei.HuaweiCloud)(nil)
+	_ CommitmentProvider   = (*huawei.HuaweiCloud)(nil)
+	_ MetricsProvider      = (*huawei.HuaweiCloud)(nil)
+	_ RegionProvider       = (*huawei.HuaweiCloud)(nil)
 
-	// DescribeMetricList get monitoring samples, eg: cpu/memory.
-	DescribeMetricList(context.Context, types.DescribeMetricListRequest) (types.DescribeMetricList, error)
+	_ BillingProvider = (*tencent.TencentCloud)(nil)
+	_ MemberLister    = (*tencent.TencentCloud)(nil)
+)
+
+type Provider = registry.Provider
+
+// MemberLister the management account of an organization, which lists the member accounts and the roles to access them
+type MemberLister interface {
+	// ListMemberAccounts the member accounts, the management account itself excluded
+	ListMemberAccounts(context.Context) ([]types.MemberAccount, error)
+	// MemberRole the role assumed in the member account, the default role of the organization if roleName is empty
+	MemberRole(memberID, roleName string) (types.AssumeRole, error)
+}
+
+// Lookup the registration of the provider, see registry.Register
+func Lookup(provider cloud.Provider) (registry.Registration, bool) {
+	return registry.Lookup(provider)
+}
+
+// Names the registered providers, sorted
+func Names() []cloud.Provider {
+	return registry.Names()
 }
 
 // GetProvider get provider
-func GetProvider(provider cloud.Provider, ak, sk, regionID string) (Provider, error) {
-	var client Provider
-	var err error
-	key := cast.ToString(provider) + ak + regionID
+func GetProvider(provider cloud.Provider, cred types.Credential, regionID string) (Provider, error) {
+	key := cast.ToString(provider) + cred.Key() + regionID
 	v, exist := clientMap.Load(key)
 	if exist {
 		return v.(Provider), nil
 	}
-
-	switch provider {
-	case cloud.AlibabaCloud:
-		client, err = alibaba.New(ak, sk, regionID)
-	case cloud.HuaweiCloud:
-		client, err = huawei.New(ak, sk, regionID)
-	case cloud.AWSCloud:
-		client, err = aws.New(ak, sk, regionID)
-	case cloud.TencentCloud:
-		client, err = tencent.New(ak, sk, regionID)
-	case cloud.BaiduCloud:
-		client, err = baidu.New(ak, sk, regionID)
-	default:
+	r, ok := registry.Lookup(provider)
+	if !ok {
 		return nil, fmt.Errorf("invalid provider[%s]", provider)
 	}
+	if cred.DefaultChain && !r.Capabilities.DefaultChain {
+		return nil, fmt.Errorf("provider[%s] has no default credential chain", provider)
+	}
+	client, err := r.New(cred, regionID)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,98 @@
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	"github.com/galaxy-future/costpilot/internal/i18n"
+	"github.com/galaxy-future/costpilot/internal/providers/types"
+)
+
+// Provider a cloud account, the data it provides are the optional interfaces of the providers package,
+// eg: providers.BillingProvider, detected by type assertion
+type Provider interface {
+	ProviderType() cloud.Provider
+}
+
+// Capabilities what the provider supports besides its interfaces, known before a client is created
+type Capabilities struct {
+	AccountMetrics bool // DescribeMetricList of all the instances of the account at once, the instances are not listed by region
+
+	DefaultChain       bool // the sdk resolves the credential by itself, see types.Credential.DefaultChain
+	AssumeRole         bool // by role_arn, or by domain_name/agency_name if Agency
+	Agency             bool
+	ExternalID         bool // external_id of the assumed roles
+	MemberDiscovery    bool // implements providers.MemberLister
+	MemberRoleRequired bool // the organization has no default role in the members
+}
+
+// Registration a cloud provider, registered by its package at init
+type Registration struct {
+	Name         cloud.Provider
+	DisplayNames map[i18n.Language]string // the name itself if missing
+	New          func(cred types.Credential, regionID string) (Provider, error)
+	Capabilities Capabilities
+
+	Regions       []string // the known region ids, any region is accepted if empty
+	DefaultRegion string   // region_id of the accounts leaving it out
+
+	Metrics map[types.MetricItem]types.MetricMapping // the catalog metrics of DescribeMetricList, the others are not supported
+}
+
+// IsKnownRegion the region id is matched case-insensitively
+func (r Registration) IsKnownRegion(regionID string) bool {
+	if len(r.Regions) == 0 {
+		return true
+	}
+	for _, id := range r.Regions {
+		if strings.EqualFold(id, regionID) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	mu            sync.RWMutex
+	registrations = map[cloud.Provider]Registration{}
+)
+
+// Register panics on a duplicated or an incomplete registration, as it is a programming error
+func Register(r Registration) {
+	if r.Name == "" || r.New == nil {
+		panic("registry: provider name and constructor are required")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := registrations[r.Name]; ok {
+		panic(fmt.Sprintf("registry: provider %s is registered twice", r.Name))
+	}
+	r.Regions = append([]string(nil), r.Regions...)
+	sort.Strings(r.Regions)
+	registrations[r.Name] = r
+	for l, name := range r.DisplayNames {
+		i18n.AddMessages(l, map[string]string{"provider." + string(r.Name): name})
+	}
+}
+
+func Lookup(name cloud.Provider) (Registration, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	r, ok := registrations[name]
+	return r, ok
+}
+
+// Names the registered providers, sorted
+func Names() []cloud.Provider {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]cloud.Provider, 0, len(registrations))
+	for name := range registrations {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
@@ -0,0 +1,45 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	"github.com/galaxy-future/costpilot/internal/i18n"
+	"github.com/galaxy-future/costpilot/internal/providers/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegister(t *testing.T) {
+	newFake := func(types.Credential, string) (Provider, error) { return nil, nil }
+	Register(Registration{
+		Name:          "FakeCloud",
+		DisplayNames:  map[i18n.Language]string{i18n.EnUS: "Fake Cloud"},
+		New:           newFake,
+		Capabilities:  Capabilities{AccountMetrics: true},
+		Regions:       []string{"south", "north"},
+		DefaultRegion: "north",
+	})
+	defer func() {
+		mu.Lock()
+		delete(registrations, "FakeCloud")
+		mu.Unlock()
+	}()
+
+	r, ok := Lookup("FakeCloud")
+	require.True(t, ok)
+	assert.Equal(t, []string{"north", "south"}, r.Regions)
+	assert.True(t, r.Capabilities.AccountMetrics)
+	assert.False(t, r.Capabilities.DefaultChain)
+	assert.True(t, r.IsKnownRegion("NORTH"))
+	assert.False(t, r.IsKnownRegion("east"))
+	assert.Contains(t, Names(), cloud.Provider("FakeCloud"))
+	assert.Equal(t, "Fake Cloud", i18n.EnUS.T("provider.FakeCloud"))
+
+	_, ok = Lookup("NoCloud")
+	assert.False(t, ok)
+	assert.True(t, Registration{}.IsKnownRegion("anywhere"))
+
+	assert.Panics(t, func() { Register(Registration{Name: "FakeCloud", New: newFake}) })
+	assert.Panics(t, func() { Register(Registration{Name: "OtherCloud"}) })
+}
@@ -20,13 +20,36 @@ import (
 )
 
 type TencentCloud struct {
+	credential common.CredentialIface
+
 	billingClient *billing.Client
 	cvmClient     *cvm.Client
 	monitorClient *monitor.Client
 }
 
 func New(ak, sk, regionId string) (*TencentCloud, error) {
-	credential := common.NewCredential(ak, sk)
+	return NewWithCredential(types.Credential{AK: ak, SK: sk}, regionId)
+}
+
+// NewWithCredential the default chain covers the environment, the profile and the CVM role, the role is assumed by the ak/sk only
+func NewWithCredential(cred types.Credential, regionId string) (*TencentCloud, error) {
+	var (
+		credential common.CredentialIface = common.NewCredential(cred.AK, cred.SK)
+		err        error
+	)
+	switch r := cred.AssumeRole; {
+	case r != nil && cred.DefaultChain:
+		return nil, errors.New("assume_role of TencentCloud requires ak/sk")
+	case r != nil:
+		p := common.NewRoleArnProvider(cred.AK, cred.SK, r.RoleArn, r.GetSessionName(), int64(r.GetDuration().Seconds()))
+		if credential, err = p.GetCredential(); err != nil {
+			return nil, err
+		}
+	case cred.DefaultChain:
+		if credential, err = common.DefaultProviderChain().GetCredential(); err != nil {
+			return nil, err
+		}
+	}
 	cpf := profile.NewClientProfile()
 	cpf.HttpProfile.Endpoint = _billingEndpoint
 	billingClient, err := billing.NewClient(credential, "", cpf)
@@ -49,6 +72,7 @@ func New(ak, sk, regionId string) (*TencentCloud, error) {
 	}
 
 	return &TencentCloud{
+		credential:    credential,
 		billingClient: billingClient,
 		cvmClient:     cvmClient,
 		monitorClient: monitorClient,
@@ -138,7 +162,7 @@ func convQueryAccountBill(param types.QueryAccountBillRequest, billList []*billi
 	var totalCost float64
 	currency := convCurrency(tea.StringValue(billList[0].ComponentSet[0].PriceUnit))
 	for _, item := range billList {
-		costMap[tea.StringValue(item.BusinessCodeName)+tea.StringValue(item.PayModeName)] += sumComponentSet(item.ComponentSet)
+		costMap[tea.StringValue(item.BusinessCodeName)+tea.StringValue(item.PayModeName)] += sumComponentSet(item.ComponentSet, param.CostBasis)
 	}
 	if param.IsGroupByProduct {
 		for _, item := range billList {
@@ -209,9 +233,19 @@ func parseDateStartEndTime(date string) (string, string, error) {
 
 	return dateFormatted + " 00:00:00", dateFormatted + " 23:59:59", nil
 }
-func sumComponentSet(componentSet []*billing.BillDetailComponent) (result float64) {
+
+// sumComponentSet Cost 原价, RealCost 优惠后总价, vouchers and incentive payments are deducted from the net amount,
+// prices are tax inclusive already
+func sumComponentSet(componentSet []*billing.BillDetailComponent, basis types.CostBasis) (result float64) {
 	for _, v := range componentSet {
-		result += convPretaxAmount(v.RealCost)
+		switch basis.OrDefault() {
+		case types.CostBasisList:
+			result += convPretaxAmount(v.Cost)
+		case types.CostBasisNet, types.CostBasisTaxInclusive:
+			result += convPretaxAmount(v.RealCost) - convPretaxAmount(v.VoucherPayAmount) - convPretaxAmount(v.IncentivePayAmount)
+		default:
+			result += convPretaxAmount(v.RealCost)
+		}
 	}
 	return
 }
@@ -237,25 +271,3 @@ func convCurrency(priceUnit string) (currency string) {
 	}
 	return
 }
-
-func (p *TencentCloud) DescribeMetricList(ctx context.Context, param types.DescribeMetricListRequest) (types.DescribeMetricList, error) {
-	// TODO implement me
-	return types.DescribeMetricList{}, nil
-}
-
-func (p *TencentCloud) DescribeRegions(ctx context.Context, param types.DescribeRegionsRequest) (types.DescribeRegions, error) {
-	// TODO implement me
-	return types.DescribeRegions{}, nil
-}
-
-func (p *TencentCloud) DescribeInstanceBill(ctx context.Context, param types.DescribeInstanceBillRequest, isAll bool) (types.DescribeInstanceBill, error) {
-	return types.DescribeInstanceBill{}, nil
-}
-
-func (p *TencentCloud) QueryAvailableInstances(ctx context.Context, param types.QueryAvailableInstancesRequest) (types.QueryAvailableInstances, error) {
-	return types.QueryAvailableInstances{}, nil
-}
-
-func (p *TencentCloud) DescribeInstances(ctx context.Context, param types.DescribeInstancesRequest) (types.DescribeInstances, error) {
-	return types.DescribeInstances{}, nil
-}
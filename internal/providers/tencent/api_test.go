@@ -150,6 +150,18 @@ func Test_convPretaxAmount1(t *testing.T) {
 	}
 }
 
+func Test_sumComponentSet(t *testing.T) {
+	components := []*billing.BillDetailComponent{
+		{Cost: common.StringPtr("18"), RealCost: common.StringPtr("17.46"), VoucherPayAmount: common.StringPtr("2"), IncentivePayAmount: common.StringPtr("0.46")},
+		{Cost: common.StringPtr("17.5"), RealCost: common.StringPtr("16.98"), VoucherPayAmount: common.StringPtr("0"), IncentivePayAmount: common.StringPtr("0")},
+	}
+	assert.InDelta(t, 35.5, sumComponentSet(components, types.CostBasisList), 0.001)
+	assert.InDelta(t, 34.44, sumComponentSet(components, ""), 0.001)
+	assert.InDelta(t, 34.44, sumComponentSet(components, types.CostBasisDiscount), 0.001)
+	assert.InDelta(t, 31.98, sumComponentSet(components, types.CostBasisNet), 0.001)
+	assert.InDelta(t, 31.98, sumComponentSet(components, types.CostBasisTaxInclusive), 0.001)
+}
+
 var p *TencentCloud
 
 func TestMain(m *testing.M) {
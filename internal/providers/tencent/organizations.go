@@ -0,0 +1,85 @@
+package tencent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	tchttp "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/http"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
+
+	"github.com/galaxy-future/costpilot/internal/providers/types"
+)
+
+const (
+	_organizationEndpoint = "organization.tencentcloudapi.com"
+	_organizationVersion  = "2021-03-31"
+	_organizationPageSize = 50 // tencent cloud max limit
+)
+
+// ListMemberAccounts the members of the organization except the management account itself
+func (p *TencentCloud) ListMemberAccounts(_ context.Context) ([]types.MemberAccount, error) {
+	cpf := profile.NewClientProfile()
+	cpf.HttpProfile.Endpoint = _organizationEndpoint
+	client := common.NewCommonClient(p.credential, "", cpf)
+
+	var org struct {
+		Response struct {
+			HostUin int64 `json:"HostUin"`
+		} `json:"Response"`
+	}
+	if err := sendOrganization(client, "DescribeOrganization", map[string]interface{}{}, &org); err != nil {
+		return nil, err
+	}
+
+	var members []types.MemberAccount
+	for offset := 0; ; offset += _organizationPageSize {
+		var page struct {
+			Response struct {
+				Items []struct {
+					MemberUin int64  `json:"MemberUin"`
+					Name      string `json:"Name"`
+				} `json:"Items"`
+				Total int `json:"Total"`
+			} `json:"Response"`
+		}
+		params := map[string]interface{}{"Offset": offset, "Limit": _organizationPageSize}
+		if err := sendOrganization(client, "DescribeOrganizationMembers", params, &page); err != nil {
+			return nil, err
+		}
+		for _, m := range page.Response.Items {
+			if m.MemberUin == org.Response.HostUin {
+				continue
+			}
+			members = append(members, types.MemberAccount{ID: strconv.FormatInt(m.MemberUin, 10), Name: m.Name})
+		}
+		if len(page.Response.Items) == 0 || offset+_organizationPageSize >= page.Response.Total {
+			break
+		}
+	}
+	return members, nil
+}
+
+// MemberRole the role in the member account, there is no default role of the organization
+func (p *TencentCloud) MemberRole(memberID, roleName string) (types.AssumeRole, error) {
+	if roleName == "" {
+		return types.AssumeRole{}, errors.New("role_name of the members is required for TencentCloud")
+	}
+	return types.AssumeRole{RoleArn: fmt.Sprintf("qcs::cam::uin/%s:roleName/%s", memberID, roleName)}, nil
+}
+
+// sendOrganization the organization api is called by the common client, its sdk is not a dependency
+func sendOrganization(client *common.Client, action string, params map[string]interface{}, v interface{}) error {
+	request := tchttp.NewCommonRequest("organization", _organizationVersion, action)
+	if err := request.SetActionParameters(params); err != nil {
+		return err
+	}
+	response := tchttp.NewCommonResponse()
+	if err := client.Send(request, response); err != nil {
+		return err
+	}
+	return json.Unmarshal(response.GetBody(), v)
+}
@@ -0,0 +1,31 @@
+package tencent
+
+import (
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	"github.com/galaxy-future/costpilot/internal/i18n"
+	"github.com/galaxy-future/costpilot/internal/providers/registry"
+	"github.com/galaxy-future/costpilot/internal/providers/types"
+)
+
+func init() {
+	registry.Register(registry.Registration{
+		Name:         cloud.TencentCloud,
+		DisplayNames: map[i18n.Language]string{i18n.ZhCN: "腾讯", i18n.EnUS: "Tencent Cloud"},
+		New: func(cred types.Credential, regionID string) (registry.Provider, error) {
+			return NewWithCredential(cred, regionID)
+		},
+		Capabilities: registry.Capabilities{
+			DefaultChain:       true,
+			AssumeRole:         true,
+			MemberDiscovery:    true,
+			MemberRoleRequired: true,
+		},
+		Regions: []string{
+			"ap-guangzhou", "ap-shanghai", "ap-nanjing", "ap-beijing", "ap-chengdu", "ap-chongqing", "ap-hongkong",
+			"ap-taipei", "ap-shanghai-fsi", "ap-shenzhen-fsi", "ap-beijing-fsi", "ap-guangzhou-open", "ap-singapore",
+			"ap-jakarta", "ap-seoul", "ap-tokyo", "ap-mumbai", "ap-bangkok", "na-siliconvalley", "na-ashburn", "na-toronto",
+			"sa-saopaulo", "eu-frankfurt", "eu-moscow",
+		},
+		DefaultRegion: "ap-guangzhou",
+	})
+}
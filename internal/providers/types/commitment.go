@@ -0,0 +1,99 @@
+package types
+
+import (
+	"math"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+)
+
+type CommitmentType string
+
+const (
+	CommitmentReservedInstance CommitmentType = "ReservedInstance"
+	CommitmentSavingsPlan      CommitmentType = "SavingsPlan"
+	CommitmentSubscription     CommitmentType = "Subscription"
+)
+
+const CommitmentUnitHours = "Hours"
+
+type QueryCommitmentsRequest struct {
+	// YYYY-MM-DD, [StartDate, EndDate)
+	StartDate string
+	EndDate   string
+}
+
+// CommitmentUsage coverage = CoveredUsage / EligibleUsage, utilization = UsedCommitment / TotalCommitment.
+// TotalCommitment is zero when utilization does not apply, eg: subscriptions are always used.
+type CommitmentUsage struct {
+	Type            CommitmentType
+	Unit            string // Hours | currency of spend, eg: USD
+	CoveredUsage    float64
+	EligibleUsage   float64
+	UsedCommitment  float64
+	TotalCommitment float64
+}
+
+type ItemCommitment struct {
+	CommitmentId string
+	Type         CommitmentType
+	Description  string // instance type | savings plans type | product code
+	RegionId     string
+	Count        int
+	StartTime    time.Time
+	EndTime      time.Time
+	AutoRenew    bool
+}
+
+type QueryCommitments struct {
+	Usages []CommitmentUsage
+	List   []ItemCommitment
+}
+
+const AvailableInstanceStatusNormal = "Normal"
+
+// ConvSubscriptionCommitments subscription coverage by instance hours, a normal instance is eligible from its creation
+// within the period, a prepaid one is covered until it expires
+func ConvSubscriptionCommitments(instances []ItemAvailableInstance, param QueryCommitmentsRequest) QueryCommitments {
+	start, _ := time.ParseInLocation("2006-01-02", param.StartDate, time.Local)
+	end, _ := time.ParseInLocation("2006-01-02", param.EndDate, time.Local)
+	usage := CommitmentUsage{Type: CommitmentSubscription, Unit: CommitmentUnitHours}
+	var list []ItemCommitment
+	for _, i := range instances {
+		if i.Status != AvailableInstanceStatusNormal {
+			continue
+		}
+		from := start
+		if i.CreateTime.After(from) {
+			from = i.CreateTime
+		}
+		eligible := end.Sub(from).Hours()
+		if eligible <= 0 {
+			continue
+		}
+		usage.EligibleUsage += eligible
+		if i.SubscriptionType != cloud.PrePaid {
+			continue
+		}
+		to := end
+		if !i.EndTime.IsZero() && i.EndTime.Before(to) {
+			to = i.EndTime
+		}
+		usage.CoveredUsage += math.Max(to.Sub(from).Hours(), 0)
+		list = append(list, ItemCommitment{
+			CommitmentId: i.InstanceId,
+			Type:         CommitmentSubscription,
+			Description:  i.ProductCode,
+			RegionId:     i.RegionId,
+			Count:        1,
+			StartTime:    i.CreateTime,
+			EndTime:      i.EndTime,
+			AutoRenew:    i.RenewStatus == RenewStatusAutoRenewal,
+		})
+	}
+	ret := QueryCommitments{List: list}
+	if usage.EligibleUsage > 0 {
+		ret.Usages = append(ret.Usages, usage)
+	}
+	return ret
+}
@@ -0,0 +1,29 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvSubscriptionCommitments(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2022, 10, d, 0, 0, 0, 0, time.Local)
+	}
+	instances := []ItemAvailableInstance{
+		{InstanceId: "i-prepaid", Status: AvailableInstanceStatusNormal, SubscriptionType: cloud.PrePaid, CreateTime: day(1).AddDate(0, -1, 0), EndTime: day(1).AddDate(1, 0, 0)},
+		{InstanceId: "i-new", Status: AvailableInstanceStatusNormal, SubscriptionType: cloud.PostPaid, CreateTime: day(8)},
+		{InstanceId: "i-expiring", Status: AvailableInstanceStatusNormal, SubscriptionType: cloud.PrePaid, CreateTime: day(1).AddDate(-1, 0, 0), EndTime: day(6)},
+		{InstanceId: "i-future", Status: AvailableInstanceStatusNormal, SubscriptionType: cloud.PostPaid, CreateTime: day(12)},
+		{InstanceId: "i-released", Status: "Released", SubscriptionType: cloud.PrePaid},
+	}
+	got := ConvSubscriptionCommitments(instances, QueryCommitmentsRequest{StartDate: "2022-10-01", EndDate: "2022-10-11"})
+	assert.Equal(t, 1, len(got.Usages))
+	assert.InDelta(t, (10+3+10)*24, got.Usages[0].EligibleUsage, 0.001)
+	assert.InDelta(t, (10+5)*24, got.Usages[0].CoveredUsage, 0.001)
+	assert.Equal(t, 2, len(got.List))
+	assert.Equal(t, "i-prepaid", got.List[0].CommitmentId)
+	assert.Equal(t, "i-expiring", got.List[1].CommitmentId)
+}
@@ -0,0 +1,58 @@
+package types
+
+import "time"
+
+// Credential how the clients of a provider authenticate
+type Credential struct {
+	AK string
+	SK string
+	// DefaultChain ignore AK/SK and resolve the credential by the chain of the sdk: environment, profile and instance role
+	DefaultChain bool
+	// Profile of the shared credentials file used by the chain, aws only, default the default profile
+	Profile string
+	// AssumeRole access another account by a role, authenticated by the credential above
+	AssumeRole *AssumeRole
+}
+
+// Key identifies the cached clients of the credential, without the secret
+func (c Credential) Key() string {
+	key := c.AK
+	if c.DefaultChain {
+		key = "chain:" + c.Profile
+	}
+	if r := c.AssumeRole; r != nil {
+		key += "|" + r.RoleArn + r.DomainName + "/" + r.AgencyName
+	}
+	return key
+}
+
+// AssumeRole the role of AWS, Alibaba Cloud and Tencent Cloud, or the agency of Huawei Cloud
+type AssumeRole struct {
+	// RoleArn aws arn:aws:iam::<account id>:role/<name>, alibaba acs:ram::<uid>:role/<name>, tencent qcs::cam::uin/<uin>:roleName/<name>
+	RoleArn         string `json:"role_arn" yaml:"role_arn"`
+	ExternalID      string `json:"external_id" yaml:"external_id"`           // aws only
+	SessionName     string `json:"session_name" yaml:"session_name"`         // default costpilot
+	DurationSeconds int    `json:"duration_seconds" yaml:"duration_seconds"` // default 3600
+	DomainName      string `json:"domain_name" yaml:"domain_name"`           // huawei, the account delegating the agency
+	AgencyName      string `json:"agency_name" yaml:"agency_name"`           // huawei
+}
+
+func (r AssumeRole) GetSessionName() string {
+	if r.SessionName == "" {
+		return "costpilot"
+	}
+	return r.SessionName
+}
+
+func (r AssumeRole) GetDuration() time.Duration {
+	if r.DurationSeconds <= 0 {
+		return time.Hour
+	}
+	return time.Duration(r.DurationSeconds) * time.Second
+}
+
+// MemberAccount an account of an organization, AWS Organizations, Alibaba Cloud resource directory or Tencent Cloud organization
+type MemberAccount struct {
+	ID   string
+	Name string
+}
@@ -0,0 +1,97 @@
+package types
+
+type (
+	MetricItem string
+
+	// MetricUnit the unit of the samples
+	MetricUnit string
+)
+
+const (
+	MetricItemCPUUtilization        MetricItem = "cpu.utilization"
+	MetricItemMemoryUsedUtilization MetricItem = "memory.used.utilization"
+	MetricItemDiskUsedUtilization   MetricItem = "disk.used.utilization"
+	MetricItemDiskReadIOPS          MetricItem = "disk.read.iops"
+	MetricItemDiskWriteIOPS         MetricItem = "disk.write.iops"
+	MetricItemDiskReadThroughput    MetricItem = "disk.read.throughput"
+	MetricItemDiskWriteThroughput   MetricItem = "disk.write.throughput"
+	MetricItemNetworkInBandwidth    MetricItem = "network.in.bandwidth"
+	MetricItemNetworkOutBandwidth   MetricItem = "network.out.bandwidth"
+	MetricItemLoadAverage           MetricItem = "load.average" // 5 minutes
+
+	MetricUnitNone           MetricUnit = ""
+	MetricUnitPercent        MetricUnit = "%"
+	MetricUnitCountPerSecond MetricUnit = "count/s"
+	MetricUnitBytesPerSecond MetricUnit = "B/s"
+	MetricUnitBitsPerSecond  MetricUnit = "bit/s"
+	MetricUnitCount          MetricUnit = "count" // the total of the period
+	MetricUnitBytes          MetricUnit = "B"     // the total of the period
+)
+
+// MetricDefinition a metric of the catalog, the samples of any provider are in its unit
+type MetricDefinition struct {
+	Item MetricItem
+	Unit MetricUnit
+}
+
+var _metricCatalog = []MetricDefinition{
+	{Item: MetricItemCPUUtilization, Unit: MetricUnitPercent},
+	{Item: MetricItemMemoryUsedUtilization, Unit: MetricUnitPercent},
+	{Item: MetricItemDiskUsedUtilization, Unit: MetricUnitPercent},
+	{Item: MetricItemDiskReadIOPS, Unit: MetricUnitCountPerSecond},
+	{Item: MetricItemDiskWriteIOPS, Unit: MetricUnitCountPerSecond},
+	{Item: MetricItemDiskReadThroughput, Unit: MetricUnitBytesPerSecond},
+	{Item: MetricItemDiskWriteThroughput, Unit: MetricUnitBytesPerSecond},
+	{Item: MetricItemNetworkInBandwidth, Unit: MetricUnitBitsPerSecond},
+	{Item: MetricItemNetworkOutBandwidth, Unit: MetricUnitBitsPerSecond},
+	{Item: MetricItemLoadAverage, Unit: MetricUnitNone},
+}
+
+// MetricCatalog the provider-neutral metrics in the order of the reports
+func MetricCatalog() []MetricDefinition {
+	return append([]MetricDefinition(nil), _metricCatalog...)
+}
+
+// LookupMetric
+func LookupMetric(item MetricItem) (MetricDefinition, bool) {
+	for _, d := range _metricCatalog {
+		if d.Item == item {
+			return d, true
+		}
+	}
+	return MetricDefinition{}, false
+}
+
+// MetricItemNames the names of the catalog metrics
+func MetricItemNames() []string {
+	names := make([]string, 0, len(_metricCatalog))
+	for _, d := range _metricCatalog {
+		names = append(names, string(d.Item))
+	}
+	return names
+}
+
+// MetricMapping the name and the unit of a catalog metric at a provider
+type MetricMapping struct {
+	Namespace string // the default namespace of the provider if empty
+	Name      string
+	Unit      MetricUnit // of the samples of the provider
+}
+
+// Convert a sample of the provider to the unit of the catalog metric, the totals are divided by the period in seconds
+func (m MetricMapping) Convert(item MetricItem, v float64, periodSeconds int) float64 {
+	d, ok := LookupMetric(item)
+	if !ok || d.Unit == m.Unit {
+		return v
+	}
+	if (m.Unit == MetricUnitCount || m.Unit == MetricUnitBytes) && periodSeconds > 0 {
+		v /= float64(periodSeconds)
+	}
+	switch {
+	case d.Unit == MetricUnitBitsPerSecond && (m.Unit == MetricUnitBytes || m.Unit == MetricUnitBytesPerSecond):
+		return v * 8
+	case d.Unit == MetricUnitBytesPerSecond && m.Unit == MetricUnitBitsPerSecond:
+		return v / 8
+	}
+	return v
+}
@@ -0,0 +1,21 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricMapping_Convert(t *testing.T) {
+	assert.Equal(t, 42.0, MetricMapping{Unit: MetricUnitPercent}.Convert(MetricItemDiskUsedUtilization, 42, 86400))
+	assert.Equal(t, 2.0, MetricMapping{Unit: MetricUnitCount}.Convert(MetricItemDiskReadIOPS, 172800, 86400))
+	assert.Equal(t, 16.0, MetricMapping{Unit: MetricUnitBytes}.Convert(MetricItemNetworkInBandwidth, 172800, 86400))
+	assert.Equal(t, 16.0, MetricMapping{Unit: MetricUnitBytesPerSecond}.Convert(MetricItemNetworkOutBandwidth, 2, 0))
+	assert.Equal(t, 2.0, MetricMapping{Unit: MetricUnitBytes}.Convert(MetricItemDiskWriteThroughput, 172800, 86400))
+	assert.Equal(t, 7.0, MetricMapping{Unit: MetricUnitBytes}.Convert("unknown", 7, 86400))
+
+	d, ok := LookupMetric(MetricItemNetworkInBandwidth)
+	assert.True(t, ok)
+	assert.Equal(t, MetricUnitBitsPerSecond, d.Unit)
+	assert.Len(t, MetricItemNames(), len(MetricCatalog()))
+}
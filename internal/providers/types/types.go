@@ -9,22 +9,52 @@ import (
 type (
 	Granularity string
 
-	MetricItem string
+	CostBasis string
 )
 
 const (
 	Monthly Granularity = "MONTHLY"
 	Daily   Granularity = "DAILY"
 
-	MetricItemCPUUtilization        MetricItem = "cpu.utilization"
-	MetricItemMemoryUsedUtilization MetricItem = "memory.used.utilization"
+	CostBasisList         CostBasis = "list"          // 原价, before any discount
+	CostBasisDiscount     CostBasis = "discount"      // 优惠后, after contract and promotion discounts, default
+	CostBasisNet          CostBasis = "net"           // 净额, after discounts, credits and vouchers
+	CostBasisTaxInclusive CostBasis = "tax_inclusive" // 含税, net plus tax
+	// CostBasisLegacy unset, the providers keep the amounts of the releases before the cost basis,
+	// which are after discount as well except AWS BlendedCost and Huawei CashAmount
+	CostBasisLegacy CostBasis = ""
 )
 
+// IsValid empty basis is valid, see CostBasisLegacy
+func (b CostBasis) IsValid() bool {
+	switch b {
+	case "", CostBasisList, CostBasisDiscount, CostBasisNet, CostBasisTaxInclusive:
+		return true
+	}
+	return false
+}
+
+// OrDefault CostBasisDiscount for CostBasisLegacy, the amounts of which are the closest
+func (b CostBasis) OrDefault() CostBasis {
+	if b == "" {
+		return CostBasisDiscount
+	}
+	return b
+}
+
+func (b CostBasis) String() string {
+	return string(b)
+}
+
 type QueryAccountBillRequest struct {
 	BillingCycle     string      `position:"Query" name:"BillingCycle"`
 	BillingDate      string      `position:"Query" name:"BillingDate"`
 	IsGroupByProduct bool        `position:"Query" name:"IsGroupByProduct"`
 	Granularity      Granularity `position:"Query" name:"Granularity"`
+	// IsAmortized spread prepaid charges over their service period, honored if DataInQueryAccountBill.IsAmortized is set
+	IsAmortized bool
+	// CostBasis which amount goes to AccountBillItem.PretaxAmount, default CostBasisLegacy
+	CostBasis CostBasis
 
 	// ProductCode      string           `position:"Query" name:"ProductCode"`
 	// PageNum          int `position:"Query" name:"PageNum"`
@@ -38,6 +68,7 @@ type DataInQueryAccountBill struct {
 	AccountID    string                  `json:"AccountID" xml:"AccountID"`
 	TotalCount   int                     `json:"TotalCount" xml:"TotalCount"`
 	AccountName  string                  `json:"AccountName" xml:"AccountName"`
+	IsAmortized  bool                    `json:"IsAmortized" xml:"IsAmortized"`
 	Items        ItemsInQueryAccountBill `json:"Items" xml:"Items"`
 }
 type ItemsInQueryAccountBill struct {
@@ -134,6 +165,7 @@ type ItemDescribeInstance struct {
 	RegionId           string
 	RegionName         string
 	HostName           string
+	InstanceType       string
 	SubscriptionType   cloud.SubscriptionType
 	InternetChargeType string
 	PublicIpAddress    []string
@@ -149,11 +181,20 @@ type DescribeInstanceBillRequest struct {
 	BillingCycle string
 	// ProductCode      string
 	// ProductType      string
-	// SubscriptionType string
-	Granularity Granularity
-	InstanceId  string
+	SubscriptionType cloud.SubscriptionType // optional
+	Granularity      Granularity
+	InstanceId       string
 }
 
+type ServicePeriodUnit string
+
+const (
+	ServicePeriodYear  ServicePeriodUnit = "Year"
+	ServicePeriodMonth ServicePeriodUnit = "Month"
+	ServicePeriodDay   ServicePeriodUnit = "Day"
+	ServicePeriodHour  ServicePeriodUnit = "Hour"
+)
+
 type ItemsInInstanceBill struct {
 	BillingDate      string
 	InstanceConfig   string
@@ -167,6 +208,11 @@ type ItemsInInstanceBill struct {
 	ProductName      string
 	ProductDetail    string
 	ItemName         string // 项目名称
+	PretaxAmount     float64
+
+	PipCode           PipCode
+	ServicePeriod     int // length of the prepaid service bought by this charge
+	ServicePeriodUnit ServicePeriodUnit
 }
 
 type DescribeInstanceBill struct {
@@ -185,12 +231,16 @@ type QueryAvailableInstancesRequest struct {
 	InstanceIdList   []string // max 100
 }
 
+const RenewStatusAutoRenewal = "AutoRenewal"
+
 type ItemAvailableInstance struct {
 	InstanceId       string
 	RegionId         string
-	Status           string
-	RenewStatus      string
+	Status           string // Normal if available
+	RenewStatus      string // AutoRenewal | ManualRenewal | NotRenewal
 	SubscriptionType cloud.SubscriptionType
+	CreateTime       time.Time
+	EndTime          time.Time // zero if PostPaid
 
 	ProductCode string
 }
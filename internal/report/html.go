@@ -0,0 +1,226 @@
+package report
+
+import (
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	_scriptTag = regexp.MustCompile(`<script\b([^>]*)>\s*</script>`)
+	_linkTag   = regexp.MustCompile(`<link\b([^>]*)>`)
+	_scriptEnd = regexp.MustCompile(`(?i)</script`)
+	_attr      = regexp.MustCompile(`([a-zA-Z_:-]+)(?:=("[^"]*"|'[^']*'|[^\s"'>]+))?`)
+)
+
+// inlineExts the assets referenced by the bundles which are embedded as data uris
+var inlineExts = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true, ".webp": true, ".ico": true,
+	".woff": true, ".woff2": true, ".ttf": true, ".eot": true,
+}
+
+// WriteHTML inline the scripts, styles, images and the data file of the website in siteDir into a single html file,
+// which is viewable without the website tree and a server
+func WriteHTML(siteDir, file string) error {
+	index, err := os.ReadFile(filepath.Join(siteDir, "index.html"))
+	if err != nil {
+		return err
+	}
+	s := &site{dir: siteDir}
+	if err := s.loadAssets(); err != nil {
+		return err
+	}
+	out, err := s.inline(string(index))
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(file); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, []byte(out), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+type site struct {
+	dir    string
+	assets map[string]string // key : path relative to the site, value : data uri
+}
+
+func (s *site) loadAssets() error {
+	s.assets = make(map[string]string)
+	return filepath.Walk(s.dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !inlineExts[strings.ToLower(filepath.Ext(p))] {
+			return err
+		}
+		rel, err := filepath.Rel(s.dir, p)
+		if err != nil {
+			return err
+		}
+		uri, err := s.dataURI(filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+		s.assets[filepath.ToSlash(rel)] = uri
+		return nil
+	})
+}
+
+func (s *site) read(ref string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(s.dir, filepath.FromSlash(cleanRef(ref))))
+}
+
+func (s *site) dataURI(ref string) (string, error) {
+	b, err := s.read(ref)
+	if err != nil {
+		return "", err
+	}
+	typ := mime.TypeByExtension(path.Ext(ref))
+	if typ == "" {
+		typ = http.DetectContentType(b)
+	}
+	return "data:" + typ + ";base64," + base64.StdEncoding.EncodeToString(b), nil
+}
+
+// inline the deferred scripts are moved to the end of the body, as inline scripts can not be deferred,
+// the chunks loaded on demand are placed ahead of them, so webpack finds them installed instead of requesting the files
+func (s *site) inline(index string) (string, error) {
+	var (
+		err      error
+		deferred []string
+		linked   = make(map[string]bool)
+	)
+	index = _scriptTag.ReplaceAllStringFunc(index, func(tag string) string {
+		attrs := parseAttrs(_scriptTag.FindStringSubmatch(tag)[1])
+		src, ok := attrs["src"]
+		if !ok || err != nil {
+			return tag
+		}
+		var js []byte
+		if js, err = s.read(src); err != nil {
+			return tag
+		}
+		linked[cleanRef(src)] = true
+		script := "<script>" + escapeScript(s.embedAssets(string(js))) + "</script>"
+		if _, ok := attrs["defer"]; ok {
+			deferred = append(deferred, script)
+			return ""
+		}
+		return script
+	})
+	if err != nil {
+		return "", err
+	}
+	index = _linkTag.ReplaceAllStringFunc(index, func(tag string) string {
+		attrs := parseAttrs(_linkTag.FindStringSubmatch(tag)[1])
+		href, ok := attrs["href"]
+		if !ok || err != nil || strings.HasPrefix(href, "data:") {
+			return tag
+		}
+		switch strings.ToLower(attrs["rel"]) {
+		case "stylesheet":
+			var css []byte
+			if css, err = s.read(href); err != nil {
+				return tag
+			}
+			linked[cleanRef(href)] = true
+			return "<style>" + s.embedAssets(string(css)) + "</style>"
+		case "icon", "shortcut icon":
+			var uri string
+			if uri, err = s.dataURI(href); err != nil {
+				return tag
+			}
+			return strings.Replace(tag, href, uri, 1)
+		}
+		return tag
+	})
+	if err != nil {
+		return "", err
+	}
+
+	lazyStyles, lazyScripts, err := s.lazyChunks(linked)
+	if err != nil {
+		return "", err
+	}
+	index = strings.Replace(index, "</head>", strings.Join(lazyStyles, "")+"</head>", 1)
+	tail := strings.Join(lazyScripts, "") + strings.Join(deferred, "")
+	if i := strings.LastIndex(index, "</body>"); i >= 0 {
+		return index[:i] + tail + index[i:], nil
+	}
+	return index + tail, nil
+}
+
+// lazyChunks the bundles under js and css which are not linked by index.html,
+// the styles keep their path in data-href, by which the css chunk loader looks for loaded ones
+func (s *site) lazyChunks(linked map[string]bool) (styles, scripts []string, err error) {
+	for _, dir := range []string{"css", "js"} {
+		files, err := filepath.Glob(filepath.Join(s.dir, dir, "*."+dir))
+		if err != nil {
+			return nil, nil, err
+		}
+		sort.Strings(files)
+		for _, f := range files {
+			ref := dir + "/" + filepath.Base(f)
+			if linked[ref] {
+				continue
+			}
+			b, err := os.ReadFile(f)
+			if err != nil {
+				return nil, nil, err
+			}
+			if dir == "css" {
+				styles = append(styles, fmt.Sprintf(`<style data-href="%s">%s</style>`, ref, s.embedAssets(string(b))))
+				continue
+			}
+			scripts = append(scripts, "<script>"+escapeScript(s.embedAssets(string(b)))+"</script>")
+		}
+	}
+	return styles, scripts, nil
+}
+
+// embedAssets replace the asset paths in a bundle with their data uris,
+// the paths are relative to the site, or to css for the styles
+func (s *site) embedAssets(content string) string {
+	refs := make([]string, 0, len(s.assets))
+	for ref := range s.assets {
+		refs = append(refs, ref)
+	}
+	// the longer first, so a path is never replaced by a shorter one it ends with
+	sort.Slice(refs, func(i, j int) bool { return len(refs[i]) > len(refs[j]) })
+	pairs := make([]string, 0, len(refs)*4)
+	for _, ref := range refs {
+		pairs = append(pairs, "../"+ref, s.assets[ref], ref, s.assets[ref])
+	}
+	return strings.NewReplacer(pairs...).Replace(content)
+}
+
+func parseAttrs(s string) map[string]string {
+	attrs := make(map[string]string)
+	for _, m := range _attr.FindAllStringSubmatch(s, -1) {
+		attrs[strings.ToLower(m[1])] = strings.Trim(m[2], `"'`)
+	}
+	return attrs
+}
+
+// cleanRef the path relative to the site, without the query
+func cleanRef(ref string) string {
+	return strings.TrimPrefix(path.Clean("/"+strings.SplitN(ref, "?", 2)[0]), "/")
+}
+
+// escapeScript a script must not close its own tag
+func escapeScript(js string) string {
+	return _scriptEnd.ReplaceAllStringFunc(js, func(m string) string {
+		return `<\/` + m[2:]
+	})
+}
@@ -0,0 +1,89 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeSite(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0644))
+	}
+	return dir
+}
+
+func TestWriteHTML(t *testing.T) {
+	dir := writeSite(t, map[string]string{
+		"index.html": `<!doctype html><html><head><link rel="icon" href="favicon.ico"><title>Cost pilot</title>` +
+			`<script src="./static/analysis/data-set.js"></script><script defer="defer" src="js/app.js"></script>` +
+			`<link href="css/app.css" rel="stylesheet"></head><body><div id="app"></div></body></html>`,
+		"favicon.ico":                  "\x00\x00\x01\x00",
+		"static/analysis/data-set.js":  `var costAnalysis = {"note": "</script>"};`,
+		"static/imgs/rise.png":         "\x89PNG\r\n\x1a\n",
+		"js/app.js":                    `console.log("app")`,
+		"js/app.js.map":                `{}`,
+		"js/CostAnalysis.js":           "img.src=`${publicPath}static/imgs/rise.png`",
+		"css/app.css":                  `body{margin:0}`,
+		"css/CostAnalysis.css":         `.rise{background:url(../static/imgs/rise.png)}`,
+		"static/analysis/anomaly.json": `[]`,
+	})
+	file := filepath.Join(t.TempDir(), "2022-10", "costpilot.html")
+	require.NoError(t, WriteHTML(dir, file))
+	b, err := os.ReadFile(file)
+	require.NoError(t, err)
+	out := string(b)
+
+	png := "data:image/png;base64,iVBORw0KGgo="
+	assert.Regexp(t, `<link rel="icon" href="data:image/[a-z.-]+;base64,AAABAA==">`, out)
+	assert.Contains(t, out, `<script>var costAnalysis = {"note": "<\/script>"};</script>`)
+	assert.Contains(t, out, `<style>body{margin:0}</style>`)
+	assert.Contains(t, out, `<style data-href="css/CostAnalysis.css">.rise{background:url(`+png+`)}</style></head>`)
+	// the lazy chunk is installed before the app starts, both after the mount point
+	assert.Contains(t, out, `<div id="app"></div><script>img.src=`+"`${publicPath}"+png+"`"+`</script><script>console.log("app")</script></body>`)
+	assert.NotContains(t, out, " src=")
+	assert.NotContains(t, out, "{}")
+
+	_, err = os.Stat(file + ".tmp")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestWriteHTML_MissingBundle(t *testing.T) {
+	dir := writeSite(t, map[string]string{
+		"index.html": `<html><head><script defer src="js/app.js"></script></head><body></body></html>`,
+	})
+	assert.Error(t, WriteHTML(dir, filepath.Join(t.TempDir(), "costpilot.html")))
+}
+
+// the panels of extras.js follow the bundles of the website, so they find the dashboard mounted,
+// view.js stays ahead of them
+func TestWriteHTML_Website(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "costpilot.html")
+	require.NoError(t, WriteHTML(filepath.Join("..", "..", "website"), file))
+	b, err := os.ReadFile(file)
+	require.NoError(t, err)
+	out := string(b)
+
+	last := out[strings.LastIndex(out, "<script>"):]
+	assert.Contains(t, last, "costpilot-extras")
+	assert.Contains(t, last, "metricTrends")
+	assert.Contains(t, last, "unsupportedColumns")
+	assert.Contains(t, last, "window.budgetAnalysis")
+	assert.Contains(t, last, "window.anomalyAnalysis")
+	assert.Contains(t, last, "window.idleInstanceAnalysis")
+	assert.Contains(t, last, "window.optimizationAnalysis")
+	assert.Contains(t, last, "window.commitmentAnalysis")
+	assert.NotContains(t, out, "extras.js")
+	// the cost view is swapped before the dashboard reads it
+	view := strings.Index(out, "cost.alternativeView.costView")
+	require.NotEqual(t, -1, view)
+	assert.Less(t, view, strings.Index(out, "costpilot-extras"))
+	assert.NotContains(t, out, `src="js/view.js"`)
+}
@@ -0,0 +1,456 @@
+package report
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/i18n"
+	"github.com/galaxy-future/costpilot/internal/template"
+	"github.com/spf13/cast"
+)
+
+const (
+	FormatText     = "text"
+	FormatMarkdown = "markdown"
+)
+
+// Content the data of the website to render in the terminal, the labels follow i18n.GetLanguage
+type Content struct {
+	Cost              template.AnalysisData
+	Utilization       template.UtilizeAnalysis
+	Budgets           template.BudgetAnalysis
+	Anomalies         template.AnomalyAnalysis
+	IdleInstances     template.IdleInstanceAnalysis
+	Optimization      template.OptimizationAnalysis
+	Commitment        template.CommitmentAnalysis
+	DiscoveryFailures []data.DiscoveryFailure
+	RegionScans       []data.AccountUtilizationMap // the failed regions of the accounts are listed
+}
+
+func CheckFormat(format string) error {
+	if format != FormatText && format != FormatMarkdown {
+		return fmt.Errorf("invalid report format %q, expect text | markdown", format)
+	}
+	return nil
+}
+
+// Render the statistics, ratios and trends of the website as aligned tables or github flavored markdown
+func Render(w io.Writer, format string, c Content) error {
+	if err := CheckFormat(format); err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	title, sections := buildSections(c)
+	if format == FormatMarkdown {
+		renderMarkdown(bw, title, sections)
+	} else {
+		renderText(bw, title, sections)
+	}
+	return bw.Flush()
+}
+
+type table struct {
+	headers []string
+	right   []bool // right aligned columns
+	rows    [][]string
+}
+
+type section struct {
+	title string
+	table table
+}
+
+func buildSections(c Content) (string, []section) {
+	cost, day := c.Cost, c.Cost.CostAnalysisByDay
+	title := i18n.T("report.title")
+	if views := strings.Trim(cost.CostViewName+" · "+cost.CostBasisName, " ·"); views != "" {
+		title += " (" + views + ")"
+	}
+	if day.DataCycle != "" {
+		title += " " + i18n.T("report.data_cycle", day.DataCycle)
+	}
+
+	sections := costSections(cost, "")
+	if alt := cost.AlternativeView; alt != nil {
+		sections = append(sections, costSections(*alt, " · "+alt.CostViewName)...)
+	}
+
+	u := c.Utilization.AnalysisByDay
+	utilization := table{
+		headers: []string{i18n.T("report.col.metric"), i18n.T("report.col.value"), i18n.T("report.col.prev"), i18n.T("report.col.prev_v"), i18n.T("report.col.change")},
+		right:   []bool{false, true, false, true, true},
+	}
+	for _, s := range u.Statistics {
+		utilization.rows = append(utilization.rows, []string{s.SCycle, s.SAmount + s.SUnit, s.SPreCycle, s.SPreAmount + s.SPreUnit, percent(s.SRatio)})
+	}
+	sections = append(sections, section{title: i18n.T("report.utilization"), table: utilization})
+	for _, r := range u.Ratios {
+		sections = append(sections, ratioSection(r.Chart, ""))
+	}
+	if u.UtilizeTrend != nil {
+		chart := u.UtilizeTrend.Chart
+		var rows [][]string
+		for _, s := range chart.Series {
+			rows = append(rows, trendRow(s.Name, s.Data))
+		}
+		sections = append(sections, trendSection(chart.Title, chart.XData, rows))
+	}
+	if u.CpuTrend != nil {
+		chart := u.CpuTrend.Chart
+		var rows [][]string
+		for _, s := range chart.Series {
+			rows = append(rows, trendRow(s.Name, s.Data))
+		}
+		sections = append(sections, trendSection(chart.Title, chart.XData, rows))
+	}
+	for _, t := range u.MetricTrends {
+		chart := t.Chart
+		var rows [][]string
+		for _, s := range chart.Series {
+			rows = append(rows, trendRow(s.Name, s.Data))
+		}
+		sections = append(sections, trendSection(strings.Join(chart.YTitle, " "), chart.XData, rows))
+	}
+	if len(c.Budgets.Budgets) > 0 {
+		t := table{
+			headers: []string{i18n.T("col.budget"), i18n.T("col.period"), i18n.T("col.budget_amount"), i18n.T("col.spent"), i18n.T("col.projected"), i18n.T("col.percent"), i18n.T("col.projected_percent"), i18n.T("col.state")},
+			right:   []bool{false, false, true, true, true, true, true, false},
+		}
+		for _, b := range c.Budgets.Budgets {
+			t.rows = append(t.rows, []string{b.Name, b.PeriodRange, b.Amount + b.Unit, b.Spent + b.Unit, b.Projected + b.Unit, b.Percent, b.ProjectedPercent, i18n.T("budget.state." + b.State)})
+		}
+		sections = append(sections, section{title: c.Budgets.Title, table: t})
+	}
+	if len(c.Anomalies.Anomalies) > 0 {
+		t := table{
+			headers: []string{i18n.T("col.day"), i18n.T("col.account"), i18n.T("col.provider"), i18n.T("col.product"), i18n.T("col.amount"), i18n.T("col.baseline"), i18n.T("report.col.change"), i18n.T("col.severity"), i18n.T("col.contributors")},
+			right:   []bool{false, false, false, false, true, true, true, false, false},
+		}
+		for _, a := range c.Anomalies.Anomalies {
+			product := a.Product
+			if product == "" {
+				product = i18n.T("anomaly.account_total")
+			}
+			t.rows = append(t.rows, []string{a.Day, a.Account, a.Provider, product, a.Amount + a.Unit, a.Baseline + a.Unit, percent(a.Ratio), i18n.T("anomaly.severity." + a.Severity), strings.Join(a.Contributors, ", ")})
+		}
+		sections = append(sections, section{title: c.Anomalies.Title, table: t})
+	}
+	if len(c.IdleInstances.Instances) > 0 {
+		sections = append(sections, section{title: c.IdleInstances.Title, table: columnTable(c.IdleInstances.Columns, c.IdleInstances.Instances, "monthlyCost")})
+	}
+	if o := c.Optimization; len(o.Opportunities) > 0 {
+		title := o.Title
+		for _, s := range o.Statistics {
+			title += fmt.Sprintf(" · %s %s", s.SCycle, s.SAmount)
+		}
+		sections = append(sections, section{title: title, table: columnTable(o.Columns, o.Opportunities, "monthlyCost", "monthlySavings")})
+	}
+	if cm := c.Commitment; len(cm.Coverages) > 0 || len(cm.Expiring) > 0 {
+		statistics := table{headers: []string{i18n.T("report.col.metric"), i18n.T("report.col.value")}, right: []bool{false, true}}
+		for _, s := range cm.Statistics {
+			statistics.rows = append(statistics.rows, []string{s.SCycle, s.SAmount})
+		}
+		title := cm.Title
+		if cm.DataCycle != "" {
+			title += " (" + cm.DataCycle + ")"
+		}
+		sections = append(sections,
+			section{title: title, table: statistics},
+			section{title: i18n.T("commitment.coverages"), table: columnTable(cm.CoverageColumns, cm.Coverages)},
+			section{title: i18n.T("commitment.expiring"), table: columnTable(cm.ExpiringColumns, cm.Expiring)},
+		)
+	}
+	if unsupported := append(append([]template.ItemInUnsupported{}, c.Cost.Unsupported...), c.Utilization.Unsupported...); len(unsupported) > 0 {
+		t := table{
+			headers: []string{i18n.T("col.account"), i18n.T("col.provider"), i18n.T("report.col.capability"), i18n.T("report.col.impact")},
+			right:   []bool{false, false, false, false},
+		}
+		for _, u := range unsupported {
+			t.rows = append(t.rows, []string{u.Account, u.Provider, u.Capability, u.Impact})
+		}
+		sections = append(sections, section{title: i18n.T("report.unsupported"), table: t})
+	}
+	regionFailed := table{
+		headers: []string{i18n.T("col.account"), i18n.T("col.provider"), i18n.T("col.region"), i18n.T("report.col.reason")},
+		right:   []bool{false, false, false, false},
+	}
+	for _, u := range c.RegionScans {
+		for _, f := range u.RegionScan.Failed {
+			regionFailed.rows = append(regionFailed.rows, []string{u.AccountName, i18n.ProviderName(u.Provider), f.RegionId, f.Reason})
+		}
+	}
+	if len(regionFailed.rows) > 0 {
+		sections = append(sections, section{title: i18n.T("report.region_failed"), table: regionFailed})
+	}
+	if len(c.DiscoveryFailures) > 0 {
+		t := table{
+			headers: []string{i18n.T("col.account"), i18n.T("col.provider"), i18n.T("report.col.reason")},
+			right:   []bool{false, false, false},
+		}
+		for _, f := range c.DiscoveryFailures {
+			t.rows = append(t.rows, []string{f.AccountName, i18n.ProviderName(f.Provider), f.Reason})
+		}
+		sections = append(sections, section{title: i18n.T("report.discovery_failed"), table: t})
+	}
+	return title, sections
+}
+
+// costSections the statistics, ratios and trends of a cost view, suffix follows the titles of the sections
+func costSections(cost template.AnalysisData, suffix string) []section {
+	day, month := cost.CostAnalysisByDay, cost.CostAnalysisByMonth
+	var sections []section
+	statistics := table{
+		headers: []string{i18n.T("report.col.cycle"), i18n.T("report.col.cost"), i18n.T("report.col.prev"), i18n.T("report.col.prev_c"), i18n.T("report.col.change")},
+		right:   []bool{false, true, false, true, true},
+	}
+	for _, s := range day.Statistics {
+		statistics.rows = append(statistics.rows, []string{s.SCycle, s.SAmount, s.SPreCycle, s.SPreAmount, percent(s.SRatio)})
+	}
+	sections = append(sections, section{title: i18n.T("report.cost") + suffix, table: statistics})
+	for _, r := range append(append([]template.ItemInRatios{}, day.Ratios...), month.Ratios...) {
+		sections = append(sections, ratioSection(r.Chart, suffix))
+	}
+	for _, a := range []template.CostAnalysis{day, month} {
+		if a.CostTrend == nil {
+			continue
+		}
+		chart := a.CostTrend.Chart
+		var rows [][]string
+		for _, s := range chart.Series {
+			rows = append(rows, trendRow(s.Name, s.Data))
+		}
+		sections = append(sections, trendSection(chart.Title+suffix, chart.XData, rows))
+	}
+	return sections
+}
+
+// columnTable the columns of a website table, the cells are the json fields of the rows named by the column keys.
+// the unit of the row follows the cells of unitKeys
+func columnTable(columns []template.ItemInTableColumns, rows interface{}, unitKeys ...string) table {
+	t := table{}
+	for _, c := range columns {
+		t.headers = append(t.headers, c.Title)
+		t.right = append(t.right, c.Sortable)
+	}
+	var fields []map[string]interface{}
+	b, _ := json.Marshal(rows)
+	_ = json.Unmarshal(b, &fields)
+	for _, f := range fields {
+		row := make([]string, 0, len(columns))
+		for _, c := range columns {
+			cell := cast.ToString(f[c.Key])
+			if _, ok := parseValue(cell); ok && contains(unitKeys, c.Key) {
+				cell += cast.ToString(f["unit"])
+			}
+			row = append(row, cell)
+		}
+		t.rows = append(t.rows, row)
+	}
+	return t
+}
+
+func contains(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// ratioSection the items of a ratio chart with their share of the total, suffix follows the title
+func ratioSection(chart template.ChartInRatios, suffix string) section {
+	t := table{headers: []string{i18n.T("report.col.name"), i18n.T("report.col.value"), i18n.T("report.col.share")}, right: []bool{false, true, true}}
+	total := cast.ToFloat64(chart.MidValue)
+	for _, d := range chart.Data {
+		share := "--"
+		if total != 0 {
+			share = fmt.Sprintf("%.2f%%", 100*cast.ToFloat64(d.Value)/total)
+		}
+		t.rows = append(t.rows, []string{d.Name, d.Value, share})
+	}
+	title := chart.Title + suffix
+	if chart.MidValue != "" && chart.MidValue != "-" {
+		title += " (" + strings.TrimSpace(i18n.T("report.total", chart.MidValue, chart.MidUnit)) + ")"
+	}
+	return section{title: title, table: t}
+}
+
+// trendSection a sparkline of each series of a trend chart over the period of its x axis
+func trendSection(title string, xData []string, rows [][]string) section {
+	if len(xData) > 0 {
+		title += fmt.Sprintf(" (%s ~ %s)", xData[0], xData[len(xData)-1])
+	}
+	return section{title: title, table: table{
+		headers: []string{i18n.T("report.col.series"), i18n.T("report.col.trend"), i18n.T("report.col.min"), i18n.T("report.col.max"), i18n.T("report.col.latest")},
+		right:   []bool{false, false, true, true, true},
+		rows:    rows,
+	}}
+}
+
+func trendRow(name string, values []string) []string {
+	var (
+		latest   = "--"
+		min, max = math.Inf(1), math.Inf(-1)
+	)
+	for _, v := range values {
+		f, ok := parseValue(v)
+		if !ok {
+			continue
+		}
+		latest = v
+		min, max = math.Min(min, f), math.Max(max, f)
+	}
+	if math.IsInf(min, 1) {
+		return []string{name, sparkline(values), "--", "--", latest}
+	}
+	return []string{name, sparkline(values), fmt.Sprintf("%.2f", min), fmt.Sprintf("%.2f", max), latest}
+}
+
+var _sparks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline one bar per value scaled between the min and the max, the missing values are left blank
+func sparkline(values []string) string {
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		if f, ok := parseValue(v); ok {
+			min, max = math.Min(min, f), math.Max(max, f)
+		}
+	}
+	var b strings.Builder
+	for _, v := range values {
+		f, ok := parseValue(v)
+		switch {
+		case !ok:
+			b.WriteRune(' ')
+		case max == min:
+			b.WriteRune(_sparks[len(_sparks)/2])
+		default:
+			b.WriteRune(_sparks[int(math.Round((f-min)/(max-min)*float64(len(_sparks)-1)))])
+		}
+	}
+	return b.String()
+}
+
+// parseValue the charts mark the missing values by "--", "-" or ""
+func parseValue(v string) (float64, bool) {
+	f, err := cast.ToFloat64E(strings.TrimSpace(v))
+	if err != nil || strings.Trim(v, "- ") == "" {
+		return 0, false
+	}
+	return f, true
+}
+
+func percent(ratio string) string {
+	if _, ok := parseValue(ratio); !ok {
+		return "--"
+	}
+	if !strings.HasPrefix(ratio, "-") {
+		ratio = "+" + ratio
+	}
+	return ratio + "%"
+}
+
+func renderText(w io.Writer, title string, sections []section) {
+	fmt.Fprintln(w, title)
+	for _, s := range sections {
+		fmt.Fprintf(w, "\n%s\n", s.title)
+		t := s.table
+		widths := make([]int, len(t.headers))
+		for _, row := range append([][]string{t.headers}, t.rows...) {
+			for i, cell := range row {
+				if n := displayWidth(cell); n > widths[i] {
+					widths[i] = n
+				}
+			}
+		}
+		border := "+"
+		for _, n := range widths {
+			border += strings.Repeat("-", n+2) + "+"
+		}
+		writeRow := func(row []string) {
+			fmt.Fprint(w, "|")
+			for i, cell := range row {
+				pad := strings.Repeat(" ", widths[i]-displayWidth(cell))
+				if t.right[i] {
+					fmt.Fprintf(w, " %s%s |", pad, cell)
+				} else {
+					fmt.Fprintf(w, " %s%s |", cell, pad)
+				}
+			}
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, border)
+		writeRow(t.headers)
+		fmt.Fprintln(w, border)
+		for _, row := range t.rows {
+			writeRow(row)
+		}
+		if len(t.rows) == 0 {
+			fmt.Fprintln(w, "| "+padRight(i18n.T("report.no_data"), len(border)-4)+" |")
+		}
+		fmt.Fprintln(w, border)
+	}
+}
+
+func renderMarkdown(w io.Writer, title string, sections []section) {
+	fmt.Fprintf(w, "# %s\n", escapeMarkdown(title))
+	for _, s := range sections {
+		fmt.Fprintf(w, "\n## %s\n\n", escapeMarkdown(s.title))
+		t := s.table
+		if len(t.rows) == 0 {
+			fmt.Fprintln(w, i18n.T("report.no_data"))
+			continue
+		}
+		cells := make([]string, len(t.headers))
+		for i, h := range t.headers {
+			cells[i] = escapeMarkdown(h)
+		}
+		fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+		for i := range t.headers {
+			cells[i] = "---"
+			if t.right[i] {
+				cells[i] = "---:"
+			}
+		}
+		fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+		for _, row := range t.rows {
+			for i, cell := range row {
+				cells[i] = escapeMarkdown(cell)
+			}
+			fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+		}
+	}
+}
+
+func escapeMarkdown(s string) string {
+	return strings.NewReplacer("|", `\|`, "*", `\*`, "_", `\_`, "`", "\\`").Replace(s)
+}
+
+func padRight(s string, width int) string {
+	if n := displayWidth(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+// displayWidth the columns taken in a terminal, east asian wide characters take two
+func displayWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		switch {
+		case r >= 0x1100 && r <= 0x115f, r >= 0x2e80 && r <= 0xa4cf, r >= 0xac00 && r <= 0xd7a3,
+			r >= 0xf900 && r <= 0xfaff, r >= 0xfe30 && r <= 0xfe4f, r >= 0xff00 && r <= 0xff60,
+			r >= 0xffe0 && r <= 0xffe6, r >= 0x20000 && r <= 0x3fffd:
+			n += 2
+		default:
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,248 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/i18n"
+	"github.com/galaxy-future/costpilot/internal/template"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestContent() Content {
+	ratios := []template.ItemInRatios{{Chart: template.ChartInRatios{
+		Title: "日成本构成比例", MidUnit: "元", MidValue: "100",
+		Data: []template.ItemInRatioData{{Name: "云服务器 ECS", Value: "75.00"}, {Name: "OSS|存储", Value: "25.00"}},
+	}}}
+	return Content{
+		Cost: template.AnalysisData{
+			CostViewName:  "现金视图",
+			CostBasisName: "优惠后",
+			CostAnalysisByDay: template.CostAnalysis{
+				DataCycle: "2022-10-01 23:59:59",
+				Statistics: []template.ItemInStatistics{
+					{SCycle: "2022年10月01日累计", SAmount: "100.00", SPreCycle: "前一天同期", SPreAmount: "80.00", SRatio: "25.00"},
+					{SCycle: "2022年累计", SAmount: "900.00", SPreCycle: "上年同期", SPreAmount: "0.00", SRatio: "--"},
+				},
+				Ratios: ratios,
+				CostTrend: &template.CostTrend{Chart: template.ChartInCostTrend{
+					Title: "成本走势", XData: []string{"2022-09-29", "2022-09-30", "2022-10-01"},
+					Series: []template.ItemInSeries{{Name: "成本(本期)", Data: []string{"10.00", "--", "80.00"}}},
+				}},
+			},
+		},
+		Utilization: template.UtilizeAnalysis{AnalysisByDay: template.UtilizeAnalysisByDay{
+			Statistics: []template.UtilizeAnalysisStatisticsItem{
+				{SCycle: "云服务器", SAmount: "3", SUnit: "台", SPreCycle: "前日数据", SPreAmount: "4", SPreUnit: "台", SRatio: "-25.00"},
+			},
+		}},
+	}
+}
+
+func TestRender_Text(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, Render(&b, FormatText, newTestContent()))
+	out := b.String()
+	assert.True(t, strings.HasPrefix(out, "CostPilot 成本分析 (现金视图 · 优惠后) 数据截至 2022-10-01 23:59:59\n"))
+	assert.Contains(t, out, `成本统计
++--------------------+--------+------------+----------+---------+
+| 统计周期           |   成本 | 对比周期   | 对比成本 |  变化率 |
++--------------------+--------+------------+----------+---------+
+| 2022年10月01日累计 | 100.00 | 前一天同期 |    80.00 | +25.00% |
+| 2022年累计         | 900.00 | 上年同期   |     0.00 |      -- |
++--------------------+--------+------------+----------+---------+`)
+	assert.Contains(t, out, "日成本构成比例 (合计 100 元)\n")
+	assert.Contains(t, out, "| 云服务器 ECS | 75.00 | 75.00% |")
+	assert.Contains(t, out, "成本走势 (2022-09-29 ~ 2022-10-01)\n")
+	assert.Contains(t, out, "| 成本(本期) | ▁ █  | 10.00 | 80.00 | 80.00 |")
+	assert.Contains(t, out, "| 云服务器 |  3台 | 前日数据 |      4台 | -25.00% |")
+}
+
+func TestRender_Markdown(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, Render(&b, FormatMarkdown, newTestContent()))
+	out := b.String()
+	assert.True(t, strings.HasPrefix(out, "# CostPilot 成本分析 (现金视图 · 优惠后) 数据截至 2022-10-01 23:59:59\n"))
+	assert.Contains(t, out, `## 成本统计
+
+| 统计周期 | 成本 | 对比周期 | 对比成本 | 变化率 |
+| --- | ---: | --- | ---: | ---: |
+| 2022年10月01日累计 | 100.00 | 前一天同期 | 80.00 | +25.00% |
+`)
+	assert.Contains(t, out, `| OSS\|存储 | 25.00 | 25.00% |`)
+	assert.Contains(t, out, "## 资源利用统计\n\n| 指标 |")
+	assert.NotContains(t, out, "+---")
+	assert.NotContains(t, out, "## 不支持的数据")
+
+	c := newTestContent()
+	c.Cost.Unsupported = []template.ItemInUnsupported{{Account: "bd", Provider: "百度", Capability: "账单", Impact: "成本分析未包含该账号"}}
+	c.Utilization.Unsupported = []template.ItemInUnsupported{{Account: "bd", Provider: "百度", Capability: "监控指标", Impact: "利用率分析未包含该账号"}}
+	b.Reset()
+	require.NoError(t, Render(&b, FormatMarkdown, c))
+	assert.Contains(t, b.String(), `## 不支持的数据
+
+| 账号 | 云厂商 | 缺少的能力 | 影响 |
+| --- | --- | --- | --- |
+| bd | 百度 | 账单 | 成本分析未包含该账号 |
+| bd | 百度 | 监控指标 | 利用率分析未包含该账号 |
+`)
+
+	c = newTestContent()
+	c.DiscoveryFailures = []data.DiscoveryFailure{{AccountName: "org", Provider: cloud.AWSCloud, Reason: "AccessDenied"}}
+	b.Reset()
+	require.NoError(t, Render(&b, FormatMarkdown, c))
+	assert.Contains(t, b.String(), "| org | "+i18n.ProviderName(cloud.AWSCloud)+" | AccessDenied |\n")
+	assert.NotContains(t, b.String(), "## 地域扫描失败")
+
+	c = newTestContent()
+	c.RegionScans = []data.AccountUtilizationMap{{AccountName: "ali", Provider: cloud.AlibabaCloud, RegionScan: data.RegionScan{
+		Scanned: []string{"cn-beijing"},
+		Failed:  []data.RegionSkip{{RegionId: "cn-hangzhou", Reason: "Throttling"}},
+	}}}
+	b.Reset()
+	require.NoError(t, Render(&b, FormatMarkdown, c))
+	assert.Contains(t, b.String(), "## 地域扫描失败, 其实例未分析\n\n| 账号 | 云厂商 | 地域 | 原因 |\n| --- | --- | --- | --- |\n| ali | "+i18n.ProviderName(cloud.AlibabaCloud)+" | cn-hangzhou | Throttling |\n")
+
+	c = newTestContent()
+	c.Budgets = template.BudgetAnalysis{Title: "预算执行情况", Budgets: []template.ItemInBudgets{{
+		Name: "月度总预算", PeriodRange: "2022-10-01 ~ 2022-10-31", Amount: "1000.00", Spent: "900.00", Projected: "2790.00",
+		Percent: "90.00", ProjectedPercent: "279.00", Unit: "元", State: "warning",
+	}}}
+	b.Reset()
+	require.NoError(t, Render(&b, FormatMarkdown, c))
+	assert.Contains(t, b.String(), `## 预算执行情况
+
+| 预算 | 预算周期 | 预算金额 | 已花费 | 预计花费 | 执行率(%) | 预计执行率(%) | 状态 |
+| --- | --- | ---: | ---: | ---: | ---: | ---: | --- |
+| 月度总预算 | 2022-10-01 ~ 2022-10-31 | 1000.00元 | 900.00元 | 2790.00元 | 90.00 | 279.00 | 预警 |
+`)
+
+	c = newTestContent()
+	c.Anomalies = template.AnomalyAnalysis{Title: "成本异常", Anomalies: []template.ItemInAnomalies{
+		{Day: "2022-10-01", Account: "ali", Provider: "阿里云", Amount: "300.00", Baseline: "100.00", Ratio: "200.00", Severity: "critical", Unit: "元",
+			Contributors: []string{"云服务器 ECS +180.00", "OSS +20.00"}},
+		{Day: "2022-09-30", Account: "ali", Provider: "阿里云", Product: "OSS", Amount: "0.00", Baseline: "20.00", Ratio: "-100.00", Severity: "warning", Unit: "元"},
+	}}
+	b.Reset()
+	require.NoError(t, Render(&b, FormatMarkdown, c))
+	assert.Contains(t, b.String(), `## 成本异常
+
+| 日期 | 账号 | 云厂商 | 产品 | 金额 | 基线 | 变化率 | 级别 | 主要变动产品 |
+| --- | --- | --- | --- | ---: | ---: | ---: | --- | --- |
+| 2022-10-01 | ali | 阿里云 | 账号合计 | 300.00元 | 100.00元 | +200.00% | 严重 | 云服务器 ECS +180.00, OSS +20.00 |
+| 2022-09-30 | ali | 阿里云 | OSS | 0.00元 | 20.00元 | -100.00% | 警告 |  |
+`)
+
+	c = newTestContent()
+	c.IdleInstances = template.IdleInstanceAnalysis{
+		Title: "闲置与低利用率实例",
+		Columns: []template.ItemInTableColumns{
+			{Key: "instanceId", Title: "实例ID"}, {Key: "level", Title: "类型"},
+			{Key: "cpuAvg", Title: "CPU 平均利用率(%)", Sortable: true}, {Key: "monthlyCost", Title: "预计月成本", Sortable: true},
+		},
+		Instances: []template.ItemInIdleInstances{
+			{InstanceId: "i-idle", Level: "闲置", CpuAvg: "1.00", MonthlyCost: "310.00", Unit: "元"},
+			{InstanceId: "i-low", Level: "低利用率", CpuAvg: "10.00", MonthlyCost: "--", Unit: "元"},
+		},
+	}
+	b.Reset()
+	require.NoError(t, Render(&b, FormatMarkdown, c))
+	assert.Contains(t, b.String(), `## 闲置与低利用率实例
+
+| 实例ID | 类型 | CPU 平均利用率(%) | 预计月成本 |
+| --- | --- | ---: | ---: |
+| i-idle | 闲置 | 1.00 | 310.00元 |
+| i-low | 低利用率 | 10.00 | -- |
+`)
+
+	c = newTestContent()
+	c.Optimization = template.OptimizationAnalysis{
+		Title:      "优化机会",
+		Statistics: []template.ItemInStatistics{{SCycle: "预计每月可节省(元)", SAmount: "1550.00"}},
+		Columns: []template.ItemInTableColumns{
+			{Key: "instanceId", Title: "实例ID"}, {Key: "action", Title: "建议"}, {Key: "recommendedType", Title: "推荐规格"},
+			{Key: "monthlySavings", Title: "预计每月节省", Sortable: true},
+		},
+		Opportunities: []template.ItemInOptimizations{{InstanceId: "i-big", Action: "降配", RecommendedType: "ecs.g6.2xlarge", MonthlySavings: "1550.00", Unit: "元"}},
+	}
+	b.Reset()
+	require.NoError(t, Render(&b, FormatMarkdown, c))
+	assert.Contains(t, b.String(), `## 优化机会 · 预计每月可节省(元) 1550.00
+
+| 实例ID | 建议 | 推荐规格 | 预计每月节省 |
+| --- | --- | --- | ---: |
+| i-big | 降配 | ecs.g6.2xlarge | 1550.00元 |
+`)
+
+	c = newTestContent()
+	c.Commitment = template.CommitmentAnalysis{
+		Title:           "承诺使用分析",
+		DataCycle:       "2022-09-04 ~ 2022-10-01",
+		Statistics:      []template.ItemInStatistics{{SCycle: "阿里云包年包月覆盖率(%)", SAmount: "60.00"}},
+		CoverageColumns: []template.ItemInTableColumns{{Key: "account", Title: "账号"}, {Key: "coverage", Title: "覆盖率(%)", Sortable: true}},
+		Coverages:       []template.ItemInCoverages{{Account: "ali", Coverage: "60.00"}},
+		ExpiringColumns: []template.ItemInTableColumns{{Key: "commitmentId", Title: "承诺ID"}, {Key: "daysLeft", Title: "剩余天数", Sortable: true}},
+		Expiring:        []template.ItemInExpiringCommits{{CommitmentId: "i-prepaid", DaysLeft: 5}},
+	}
+	b.Reset()
+	require.NoError(t, Render(&b, FormatMarkdown, c))
+	assert.Contains(t, b.String(), `## 承诺使用分析 (2022-09-04 ~ 2022-10-01)
+
+| 指标 | 数值 |
+| --- | ---: |
+| 阿里云包年包月覆盖率(%) | 60.00 |
+
+## 承诺覆盖率
+
+| 账号 | 覆盖率(%) |
+| --- | ---: |
+| ali | 60.00 |
+
+## 即将到期的承诺
+
+| 承诺ID | 剩余天数 |
+| --- | ---: |
+| i-prepaid | 5 |
+`)
+
+	assert.Error(t, Render(&b, "html", newTestContent()))
+}
+
+func TestRender_AlternativeView(t *testing.T) {
+	c := newTestContent()
+	alternative := newTestContent().Cost
+	alternative.CostViewName = "分摊视图"
+	alternative.CostAnalysisByDay.Statistics[0].SAmount = "60.00"
+	c.Cost.AlternativeView = &alternative
+
+	var b bytes.Buffer
+	require.NoError(t, Render(&b, FormatMarkdown, c))
+	out := b.String()
+	assert.True(t, strings.HasPrefix(out, "# CostPilot 成本分析 (现金视图 · 优惠后) 数据截至 2022-10-01 23:59:59\n"))
+	assert.Contains(t, out, "## 成本统计\n\n| 统计周期 | 成本 | 对比周期 | 对比成本 | 变化率 |\n| --- | ---: | --- | ---: | ---: |\n| 2022年10月01日累计 | 100.00 |")
+	assert.Contains(t, out, "## 成本统计 · 分摊视图\n\n| 统计周期 | 成本 | 对比周期 | 对比成本 | 变化率 |\n| --- | ---: | --- | ---: | ---: |\n| 2022年10月01日累计 | 60.00 |")
+	assert.Contains(t, out, "## 日成本构成比例 · 分摊视图 (合计 100 元)\n")
+	assert.Contains(t, out, "## 成本走势 · 分摊视图 (2022-09-29 ~ 2022-10-01)\n")
+}
+
+func TestSparkline(t *testing.T) {
+	assert.Equal(t, "▁▅█", sparkline([]string{"0", "5", "10"}))
+	assert.Equal(t, "▅ ▅", sparkline([]string{"3", "-", "3"}))
+	assert.Equal(t, "  ", sparkline([]string{"--", ""}))
+	assert.Equal(t, 6, displayWidth("成本ab"))
+}
+
+func TestRender_English(t *testing.T) {
+	defer i18n.SetLanguage(i18n.GetLanguage())
+	i18n.SetLanguage(i18n.EnUS)
+
+	var b bytes.Buffer
+	require.NoError(t, Render(&b, FormatMarkdown, Content{}))
+	out := b.String()
+	assert.True(t, strings.HasPrefix(out, "# CostPilot cost analysis\n"))
+	assert.Contains(t, out, "## Cost statistics\n\nNo data\n")
+}
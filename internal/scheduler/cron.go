@@ -0,0 +1,152 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule standard 5 fields cron: minute hour day-of-month month day-of-week,
+// supports * , - / and the descriptors @hourly @daily @weekly @monthly
+type Schedule struct {
+	spec                          string
+	minute, hour, dom, month, dow uint64 // bit n is set if value n matches
+	domRestricted, dowRestricted  bool
+}
+
+var _descriptors = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+type bounds struct {
+	name     string
+	min, max int
+}
+
+var (
+	_minute = bounds{"minute", 0, 59}
+	_hour   = bounds{"hour", 0, 23}
+	_dom    = bounds{"day of month", 1, 31}
+	_month  = bounds{"month", 1, 12}
+	_dow    = bounds{"day of week", 0, 7} // 0 and 7 are sunday
+)
+
+// ParseCron eg: "0 9 * * *" daily at 09:00, "*/30 8-20 * * 1-5" every 30 minutes in working hours
+func ParseCron(spec string) (*Schedule, error) {
+	s := &Schedule{spec: spec}
+	expr := strings.TrimSpace(spec)
+	if d, ok := _descriptors[expr]; ok {
+		expr = d
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron %q, expect 5 fields", spec)
+	}
+	var err error
+	if s.minute, err = parseField(fields[0], _minute); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseField(fields[1], _hour); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseField(fields[2], _dom); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseField(fields[3], _month); err != nil {
+		return nil, err
+	}
+	if s.dow, err = parseField(fields[4], _dow); err != nil {
+		return nil, err
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domRestricted, s.dowRestricted = fields[2] != "*", fields[4] != "*"
+	return s, nil
+}
+
+func parseField(field string, b bounds) (uint64, error) {
+	var ret uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid %s step %q", b.name, part)
+			}
+			rangePart, step = part[:i], n
+		}
+		lo, hi := b.min, b.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			ends := strings.SplitN(rangePart, "-", 2)
+			var err1, err2 error
+			lo, err1 = strconv.Atoi(ends[0])
+			hi, err2 = strconv.Atoi(ends[1])
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("invalid %s range %q", b.name, part)
+			}
+		default:
+			n, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, fmt.Errorf("invalid %s %q", b.name, part)
+			}
+			lo = n
+			if step == 1 {
+				hi = n
+			}
+		}
+		if lo < b.min || hi > b.max || lo > hi {
+			return 0, fmt.Errorf("%s %q out of range [%d, %d]", b.name, part, b.min, b.max)
+		}
+		for v := lo; v <= hi; v += step {
+			ret |= 1 << uint(v)
+		}
+	}
+	return ret, nil
+}
+
+func (s *Schedule) String() string {
+	return s.spec
+}
+
+// Next the first time after t which matches the schedule, zero if there is none within 5 years, eg: 0 0 30 2 *
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// matchDay either day of month or day of week matches if both are restricted, as cron does
+func (s *Schedule) matchDay(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domRestricted && s.dowRestricted {
+		return dom || dow
+	}
+	return dom && dow
+}
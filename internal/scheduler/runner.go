@@ -0,0 +1,133 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	TriggerStartup  = "startup"
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+var ErrRunning = errors.New("a run is in progress")
+
+// Status of the current or last run
+type Status struct {
+	Schedule    string    `json:"schedule"`
+	Running     bool      `json:"running"`
+	Trigger     string    `json:"trigger"` // startup | schedule | manual
+	LastStart   time.Time `json:"last_start"`
+	LastEnd     time.Time `json:"last_end"`
+	LastError   string    `json:"last_error"`
+	LastSuccess time.Time `json:"last_success"`
+	NextRun     time.Time `json:"next_run"`
+	Runs        int       `json:"runs"`
+	Failures    int       `json:"failures"`
+}
+
+// Runner runs the job by the schedule or on demand, one run at a time
+type Runner struct {
+	schedule *Schedule
+	job      func(context.Context) error
+
+	mu     sync.Mutex
+	status Status
+}
+
+func NewRunner(schedule *Schedule, job func(context.Context) error) *Runner {
+	return &Runner{
+		schedule: schedule,
+		job:      job,
+		status:   Status{Schedule: schedule.String()},
+	}
+}
+
+// Start run by the schedule until ctx is done, a scheduled run is skipped if the previous one is still running
+func (r *Runner) Start(ctx context.Context) {
+	for {
+		next := r.schedule.Next(time.Now())
+		if next.IsZero() {
+			log.Printf("W! schedule[%s] never fires", r.schedule)
+			return
+		}
+		r.mu.Lock()
+		r.status.NextRun = next
+		r.mu.Unlock()
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			if !r.Trigger(ctx, TriggerSchedule) {
+				log.Printf("W! scheduled run skipped, the previous run is still in progress")
+			}
+		}
+	}
+}
+
+// Trigger run in background, false if a run is in progress
+func (r *Runner) Trigger(ctx context.Context, trigger string) bool {
+	if !r.begin(trigger) {
+		return false
+	}
+	go r.run(ctx)
+	return true
+}
+
+// Run run and wait for it
+func (r *Runner) Run(ctx context.Context, trigger string) error {
+	if !r.begin(trigger) {
+		return ErrRunning
+	}
+	return r.run(ctx)
+}
+
+// Status
+func (r *Runner) Status() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status
+}
+
+func (r *Runner) begin(trigger string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.status.Running {
+		return false
+	}
+	r.status.Running = true
+	r.status.Trigger = trigger
+	r.status.LastStart = time.Now()
+	return true
+}
+
+func (r *Runner) run(ctx context.Context) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.status.Running = false
+		r.status.LastEnd = time.Now()
+		r.status.Runs++
+		r.status.LastError = ""
+		if err != nil {
+			r.status.Failures++
+			r.status.LastError = err.Error()
+			log.Printf("E! %s run failed: %v", r.status.Trigger, err)
+			return
+		}
+		r.status.LastSuccess = r.status.LastEnd
+		log.Printf("I! %s run done in %v", r.status.Trigger, r.status.LastEnd.Sub(r.status.LastStart).Round(time.Second))
+	}()
+	log.Printf("I! %s run started", r.Status().Trigger)
+	return r.job(ctx)
+}
@@ -0,0 +1,81 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	base := time.Date(2022, 10, 11, 9, 0, 30, 0, time.Local) // tuesday
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"0 9 * * *", time.Date(2022, 10, 12, 9, 0, 0, 0, time.Local)},
+		{"@daily", time.Date(2022, 10, 12, 0, 0, 0, 0, time.Local)},
+		{"*/15 * * * *", time.Date(2022, 10, 11, 9, 15, 0, 0, time.Local)},
+		{"30 8-20/6 * * *", time.Date(2022, 10, 11, 14, 30, 0, 0, time.Local)},
+		{"0 9 * * 0,6", time.Date(2022, 10, 15, 9, 0, 0, 0, time.Local)},
+		{"0 9 * * 7", time.Date(2022, 10, 16, 9, 0, 0, 0, time.Local)},
+		{"0 0 1 * *", time.Date(2022, 11, 1, 0, 0, 0, 0, time.Local)},
+		{"0 0 13 * 5", time.Date(2022, 10, 13, 0, 0, 0, 0, time.Local)}, // day of month or day of week
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.Local)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		s, err := ParseCron(tt.spec)
+		if assert.NoError(t, err, tt.spec) {
+			assert.Equal(t, tt.want, s.Next(base), tt.spec)
+		}
+	}
+}
+
+func TestParseCron_Invalid(t *testing.T) {
+	for _, spec := range []string{"", "0 9 * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, err := ParseCron(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestRunner(t *testing.T) {
+	s, _ := ParseCron("@daily")
+	started, release := make(chan struct{}), make(chan struct{})
+	fail := false
+	r := NewRunner(s, func(ctx context.Context) error {
+		started <- struct{}{}
+		<-release
+		if fail {
+			return errors.New("boom")
+		}
+		return nil
+	})
+
+	assert.True(t, r.Trigger(context.Background(), TriggerStartup))
+	<-started
+	assert.False(t, r.Trigger(context.Background(), TriggerManual))
+	assert.Equal(t, ErrRunning, r.Run(context.Background(), TriggerManual))
+	assert.True(t, r.Status().Running)
+	release <- struct{}{}
+	assert.Eventually(t, func() bool { return !r.Status().Running }, time.Second, time.Millisecond)
+	status := r.Status()
+	assert.Equal(t, 1, status.Runs)
+	assert.Equal(t, TriggerStartup, status.Trigger)
+	assert.False(t, status.LastSuccess.IsZero())
+	assert.Equal(t, "@daily", status.Schedule)
+
+	fail = true
+	go func() {
+		<-started
+		release <- struct{}{}
+	}()
+	assert.EqualError(t, r.Run(context.Background(), TriggerManual), "boom")
+	status = r.Status()
+	assert.Equal(t, 2, status.Runs)
+	assert.Equal(t, 1, status.Failures)
+	assert.Equal(t, "boom", status.LastError)
+	assert.True(t, status.LastSuccess.Before(status.LastEnd))
+}
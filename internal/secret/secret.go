@@ -0,0 +1,84 @@
+package secret
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var _envRef = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ExpandEnv replace the ${NAME} references by the environment variables in a yaml document, $${NAME} is kept as ${NAME}.
+// Unlike os.ExpandEnv, the bare $NAME is not a reference, so the bcrypt hashes and the templates are left alone,
+// and the commented examples are skipped
+func ExpandEnv(b []byte) ([]byte, error) {
+	unset := map[string]bool{}
+	expand := func(ref []byte) []byte {
+		if ref[1] == '$' {
+			return ref[1:]
+		}
+		name := string(ref[2 : len(ref)-1])
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			unset[name] = true
+		}
+		return []byte(v)
+	}
+	lines := bytes.SplitAfter(b, []byte("\n"))
+	for i, line := range lines {
+		n := commentAt(line)
+		lines[i] = append(_envRef.ReplaceAllFunc(line[:n:n], expand), line[n:]...)
+	}
+	if len(unset) > 0 {
+		var names []string
+		for name := range unset {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("environment variable %s is not set", strings.Join(names, ", "))
+	}
+	return bytes.Join(lines, nil), nil
+}
+
+// commentAt the offset of the yaml comment in the line, a # at the start or after a space out of the quotes
+func commentAt(line []byte) int {
+	var quote byte
+	for i, c := range line {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return i
+		}
+	}
+	return len(line)
+}
+
+// ReadFile the secret in the file without the trailing new line, ~ is the home directory
+func ReadFile(path string) (string, error) {
+	if strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(home, path[2:])
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	s := strings.TrimRight(string(b), "\r\n")
+	if s == "" {
+		return "", fmt.Errorf("secret file %s is empty", path)
+	}
+	return s, nil
+}
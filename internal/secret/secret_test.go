@@ -0,0 +1,51 @@
+package secret
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("COSTPILOT_TEST_SK", "s3cr#t")
+	t.Setenv("COSTPILOT_TEST_EMPTY", "")
+
+	out, err := ExpandEnv([]byte(`cloud_accounts:
+  - sk: ${COSTPILOT_TEST_SK}  # or ${COSTPILOT_TEST_UNSET}
+    ak: "${COSTPILOT_TEST_EMPTY}"
+#   sk: ${COSTPILOT_TEST_UNSET}
+password_hash: $2a$10$abc
+url: http://example.com/#${COSTPILOT_TEST_SK}
+literal: $${COSTPILOT_TEST_UNSET}
+`))
+	require.NoError(t, err)
+	assert.Equal(t, `cloud_accounts:
+  - sk: s3cr#t  # or ${COSTPILOT_TEST_UNSET}
+    ak: ""
+#   sk: ${COSTPILOT_TEST_UNSET}
+password_hash: $2a$10$abc
+url: http://example.com/#s3cr#t
+literal: ${COSTPILOT_TEST_UNSET}
+`, string(out))
+
+	_, err = ExpandEnv([]byte("ak: ${COSTPILOT_TEST_UNSET_B}\nsk: ${COSTPILOT_TEST_UNSET_A} ${COSTPILOT_TEST_UNSET_B}\n"))
+	assert.EqualError(t, err, "environment variable COSTPILOT_TEST_UNSET_A, COSTPILOT_TEST_UNSET_B is not set")
+}
+
+func TestReadFile(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "sk")
+	require.NoError(t, os.WriteFile(p, []byte("abc \n"), 0600))
+	s, err := ReadFile(p)
+	require.NoError(t, err)
+	assert.Equal(t, "abc ", s)
+
+	require.NoError(t, os.WriteFile(p, []byte("\n"), 0600))
+	_, err = ReadFile(p)
+	assert.Error(t, err)
+	_, err = ReadFile(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,106 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/types"
+	"github.com/spf13/cast"
+)
+
+// Vault reads the kv secrets of a HashiCorp Vault server by the http api, both kv v1 and v2 are supported
+type Vault struct {
+	address   string
+	token     string
+	namespace string
+	client    *http.Client
+
+	mu      sync.Mutex
+	secrets map[string]map[string]string // path => the secret
+}
+
+// NewVault the token is the configured one, the token file, $VAULT_TOKEN or ~/.vault-token in order
+func NewVault(cfg types.Vault) (*Vault, error) {
+	address := strings.TrimRight(cfg.GetAddress(), "/")
+	if address == "" {
+		return nil, errors.New("vault address is required, set vault.address or VAULT_ADDR")
+	}
+	token := cfg.Token
+	if token == "" && cfg.TokenFile != "" {
+		var err error
+		if token, err = ReadFile(cfg.TokenFile); err != nil {
+			return nil, fmt.Errorf("vault token_file: %v", err)
+		}
+	}
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+	if token == "" {
+		token, _ = ReadFile("~/.vault-token")
+	}
+	if token == "" {
+		return nil, errors.New("vault token is required, set vault.token, vault.token_file or VAULT_TOKEN")
+	}
+	return &Vault{
+		address:   address,
+		token:     token,
+		namespace: cfg.GetNamespace(),
+		client:    &http.Client{Timeout: 10 * time.Second},
+		secrets:   map[string]map[string]string{},
+	}, nil
+}
+
+// Read the key/values of the secret at the path, eg: secret/data/costpilot of kv v2, the secrets are read once
+func (v *Vault) Read(ctx context.Context, path string) (map[string]string, error) {
+	path = strings.Trim(path, "/")
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if s, ok := v.secrets[path]; ok {
+		return s, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.address+"/v1/"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var body struct {
+		Data   map[string]interface{} `json:"data"`
+		Errors []string               `json:"errors"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("vault secret %s: %v", path, err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("vault secret %s not found", path)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("vault secret %s: %s %s", path, resp.Status, strings.Join(body.Errors, "; "))
+	}
+
+	data := body.Data
+	if inner, ok := data["data"].(map[string]interface{}); ok && data["metadata"] != nil { // kv v2
+		data = inner
+	}
+	s := make(map[string]string, len(data))
+	for k, val := range data {
+		s[k] = cast.ToString(val)
+	}
+	v.secrets[path] = s
+	return s, nil
+}
@@ -0,0 +1,76 @@
+package secret
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/galaxy-future/costpilot/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newVaultServer the responses of `vault server -dev`, kv v2 mounted at secret/ and kv v1 at kv/
+func newVaultServer(t *testing.T) (*httptest.Server, *int) {
+	calls := new(int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		assert.Equal(t, "team-a", r.Header.Get("X-Vault-Namespace"))
+		switch r.URL.Path {
+		case "/v1/secret/data/costpilot/aliyun":
+			_, _ = w.Write([]byte(`{"data":{"data":{"ak":"LTAI","sk":"s3cret","port":8200},"metadata":{"version":1}}}`))
+		case "/v1/kv/costpilot/aws":
+			_, _ = w.Write([]byte(`{"data":{"access_key":"AKIA","secret_key":"abc"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, calls
+}
+
+func TestVault_Read(t *testing.T) {
+	srv, calls := newVaultServer(t)
+	v, err := NewVault(types.Vault{Address: srv.URL + "/", Token: "root", Namespace: "team-a"})
+	require.NoError(t, err)
+
+	s, err := v.Read(context.Background(), "/secret/data/costpilot/aliyun")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"ak": "LTAI", "sk": "s3cret", "port": "8200"}, s)
+	_, err = v.Read(context.Background(), "secret/data/costpilot/aliyun")
+	require.NoError(t, err)
+	assert.Equal(t, 1, *calls)
+
+	s, err = v.Read(context.Background(), "kv/costpilot/aws")
+	require.NoError(t, err)
+	assert.Equal(t, "AKIA", s["access_key"])
+
+	_, err = v.Read(context.Background(), "secret/data/missing")
+	assert.EqualError(t, err, "vault secret secret/data/missing not found")
+}
+
+func TestVault_Token(t *testing.T) {
+	t.Setenv("VAULT_ADDR", "")
+	t.Setenv("VAULT_TOKEN", "")
+	t.Setenv("HOME", t.TempDir())
+	_, err := NewVault(types.Vault{Token: "root"})
+	assert.Error(t, err)
+	_, err = NewVault(types.Vault{Address: "http://127.0.0.1:8200"})
+	assert.Error(t, err)
+
+	srv, _ := newVaultServer(t)
+	t.Setenv("VAULT_ADDR", srv.URL)
+	t.Setenv("VAULT_TOKEN", "wrong")
+	t.Setenv("VAULT_NAMESPACE", "team-a")
+	v, err := NewVault(types.Vault{})
+	require.NoError(t, err)
+	_, err = v.Read(context.Background(), "kv/costpilot/aws")
+	assert.EqualError(t, err, "vault secret kv/costpilot/aws: 403 Forbidden permission denied")
+}
@@ -1,12 +1,22 @@
 package services
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"sync/atomic"
 
 	"github.com/galaxy-future/costpilot/internal/config"
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/providers"
 	"github.com/galaxy-future/costpilot/internal/types"
 )
 
+var (
+	_discovered  atomic.Value // []types.CloudAccount
+	_getProvider = providers.GetProvider
+)
+
 type AccountService struct {
 	cloudAccount []types.CloudAccount
 }
@@ -23,7 +33,7 @@ func (s *AccountService) GetAccounts() []types.CloudAccount {
 
 // InitCloudAccounts
 func (s *AccountService) InitCloudAccounts() {
-	s.cloudAccount = config.GetGlobalConfig().CloudAccounts
+	s.cloudAccount = GetCloudAccounts()
 	var a []string
 	for _, v := range s.cloudAccount {
 		a = append(a, v.Name)
@@ -31,3 +41,82 @@ func (s *AccountService) InitCloudAccounts() {
 	log.Printf("I! get cloud account ready: %v\n", a)
 	return
 }
+
+// GetCloudAccounts the configured accounts with the members discovered by the last DiscoverCloudAccounts
+func GetCloudAccounts() []types.CloudAccount {
+	if accounts, ok := _discovered.Load().([]types.CloudAccount); ok {
+		return accounts
+	}
+	return config.GetGlobalConfig().CloudAccounts
+}
+
+// DiscoverCloudAccounts expand the accounts with discover_members into their member accounts, at the start of each run,
+// a failed discovery leaves out the members of the account only
+func DiscoverCloudAccounts(ctx context.Context) []data.DiscoveryFailure {
+	accounts, failures := discoverAccounts(ctx, config.GetGlobalConfig().CloudAccounts)
+	_discovered.Store(accounts)
+	return failures
+}
+
+func discoverAccounts(ctx context.Context, configured []types.CloudAccount) ([]types.CloudAccount, []data.DiscoveryFailure) {
+	var (
+		accounts []types.CloudAccount
+		failures []data.DiscoveryFailure
+	)
+	for _, a := range configured {
+		if a.DiscoverMembers == nil || !a.DiscoverMembers.Enabled {
+			accounts = append(accounts, a)
+			continue
+		}
+		if !a.DiscoverMembers.SkipSelf {
+			accounts = append(accounts, a)
+		}
+		members, err := discoverMembers(ctx, a)
+		if err != nil {
+			log.Printf("E! discover member accounts of %s failed, its members are not analyzed: %v", a.Name, err)
+			failures = append(failures, data.DiscoveryFailure{AccountName: a.Name, Provider: a.Provider, Reason: err.Error()})
+			continue
+		}
+		log.Printf("I! discovered %d member accounts of %s", len(members), a.Name)
+		accounts = append(accounts, members...)
+	}
+	return accounts, failures
+}
+
+// discoverMembers the members are accessed by the credential of the management account, named <management>/<member>
+func discoverMembers(ctx context.Context, a types.CloudAccount) ([]types.CloudAccount, error) {
+	p, err := _getProvider(a.Provider, a.Credential(), a.RegionID)
+	if err != nil {
+		return nil, err
+	}
+	lister, ok := p.(providers.MemberLister)
+	if !ok {
+		return nil, fmt.Errorf("provider[%s] can not discover member accounts", a.Provider)
+	}
+	members, err := lister.ListMemberAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	d := a.DiscoverMembers
+	var accounts []types.CloudAccount
+	for _, m := range members {
+		if !d.IsMemberIncluded(m.ID) {
+			continue
+		}
+		role, err := lister.MemberRole(m.ID, d.RoleName)
+		if err != nil {
+			return nil, err
+		}
+		role.ExternalID = d.ExternalID
+		name := m.Name
+		if name == "" {
+			name = m.ID
+		}
+		member := a
+		member.Name = a.Name + "/" + name
+		member.AssumeRole = &role
+		member.DiscoverMembers = nil
+		accounts = append(accounts, member)
+	}
+	return accounts, nil
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/providers"
+	providerTypes "github.com/galaxy-future/costpilot/internal/providers/types"
+	"github.com/galaxy-future/costpilot/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeOrganization struct {
+	providers.Provider
+	members []providerTypes.MemberAccount
+	err     error
+}
+
+func (f fakeOrganization) ListMemberAccounts(context.Context) ([]providerTypes.MemberAccount, error) {
+	return f.members, f.err
+}
+
+func (f fakeOrganization) MemberRole(memberID, roleName string) (providerTypes.AssumeRole, error) {
+	return providerTypes.AssumeRole{RoleArn: "arn:aws:iam::" + memberID + ":role/" + roleName}, nil
+}
+
+func TestDiscoverMembers(t *testing.T) {
+	defer func() { _getProvider = providers.GetProvider }()
+	_getProvider = func(p cloud.Provider, cred providerTypes.Credential, _ string) (providers.Provider, error) {
+		assert.EqualValues(t, cloud.AWSCloud, p)
+		assert.Equal(t, "AKIA", cred.AK)
+		return fakeOrganization{members: []providerTypes.MemberAccount{{ID: "111", Name: "prod"}, {ID: "222"}, {ID: "333", Name: "sandbox"}}}, nil
+	}
+
+	management := types.CloudAccount{
+		Provider: cloud.AWSCloud, AK: "AKIA", SK: "secret", RegionID: "us-east-1", Name: "org",
+		DiscoverMembers: &types.DiscoverMembers{Enabled: true, RoleName: "CostPilot", ExternalID: "x-1", Exclude: []string{"333"}},
+	}
+	members, err := discoverMembers(context.Background(), management)
+	require.NoError(t, err)
+	require.Len(t, members, 2)
+	assert.Equal(t, "org/prod", members[0].Name)
+	assert.Equal(t, "org/222", members[1].Name)
+	assert.Nil(t, members[0].DiscoverMembers)
+	assert.Equal(t, providerTypes.Credential{AK: "AKIA", SK: "secret", AssumeRole: &providerTypes.AssumeRole{
+		RoleArn: "arn:aws:iam::111:role/CostPilot", ExternalID: "x-1",
+	}}, members[0].Credential())
+	assert.NotEqual(t, members[0].Credential().Key(), members[1].Credential().Key())
+
+	management.DiscoverMembers.Include = []string{"222"}
+	members, err = discoverMembers(context.Background(), management)
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+	assert.Equal(t, "org/222", members[0].Name)
+}
+
+func TestDiscoverAccounts_Failed(t *testing.T) {
+	defer func() { _getProvider = providers.GetProvider }()
+	_getProvider = func(_ cloud.Provider, cred providerTypes.Credential, _ string) (providers.Provider, error) {
+		if cred.AK == "denied" {
+			return fakeOrganization{err: errors.New("AccessDeniedException")}, nil
+		}
+		return fakeOrganization{members: []providerTypes.MemberAccount{{ID: "111", Name: "prod"}}}, nil
+	}
+
+	configured := []types.CloudAccount{
+		{Provider: cloud.AlibabaCloud, AK: "ak", Name: "ali"},
+		{Provider: cloud.AWSCloud, AK: "denied", Name: "org1", DiscoverMembers: &types.DiscoverMembers{Enabled: true}},
+		{Provider: cloud.AWSCloud, AK: "AKIA", Name: "org2", DiscoverMembers: &types.DiscoverMembers{Enabled: true, SkipSelf: true}},
+	}
+	accounts, failures := discoverAccounts(context.Background(), configured)
+	var names []string
+	for _, a := range accounts {
+		names = append(names, a.Name)
+	}
+	assert.Equal(t, []string{"ali", "org1", "org2/prod"}, names)
+	assert.Equal(t, []data.DiscoveryFailure{{AccountName: "org1", Provider: cloud.AWSCloud, Reason: "AccessDeniedException"}}, failures)
+}
@@ -0,0 +1,159 @@
+package amortization
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/tools"
+)
+
+// Amortizer spreads prepaid charges evenly over the days of their service period. Bills already amortized
+// by the provider, and bills before the first month of the charges, are kept as they are.
+type Amortizer struct {
+	charges    []data.PrepaidCharge
+	firstMonth string // charges are loaded since this month, 2022-01
+
+	bp *tools.BillingDatePilot
+}
+
+func NewAmortizer(charges []data.PrepaidCharge, firstMonth string, t time.Time) *Amortizer {
+	return &Amortizer{
+		charges:    charges,
+		firstMonth: firstMonth,
+		bp:         tools.NewBillDatePilot().SetNowT(t),
+	}
+}
+
+// Amortize updates the bills in place
+func (s *Amortizer) Amortize(_ context.Context, monthsBilling, daysBilling *sync.Map) {
+	nowT := s.bp.GetNowT()
+	today := time.Date(nowT.Year(), nowT.Month(), nowT.Day(), 0, 0, 0, 0, nowT.Location())
+	var cnt int
+	monthsBilling.Range(func(key, value interface{}) bool {
+		b := value.(data.MonthlyBilling)
+		if b.Amortized || b.Month < s.firstMonth {
+			return true
+		}
+		start, err := time.ParseInLocation("2006-01", b.Month, time.Local)
+		if err != nil {
+			return true
+		}
+		end := start.AddDate(0, 1, 0)
+		if end.After(today) {
+			end = today
+		}
+		b.ProductsBilling = cloneProductsBilling(b.ProductsBilling)
+		b.TotalAmount = s.spread(b.ProductsBilling, b.TotalAmount, start, end)
+		b.Amortized = true
+		monthsBilling.Store(key, b)
+		cnt++
+		return true
+	})
+	daysBilling.Range(func(key, value interface{}) bool {
+		b := value.(data.DailyBilling)
+		if b.Amortized || tools.Date2Month(b.Day) < s.firstMonth {
+			return true
+		}
+		start, err := time.ParseInLocation("2006-01-02", b.Day, time.Local)
+		if err != nil {
+			return true
+		}
+		b.ProductsBilling = cloneProductsBilling(b.ProductsBilling)
+		b.TotalAmount = s.spread(b.ProductsBilling, b.TotalAmount, start, start.AddDate(0, 0, 1))
+		b.Amortized = true
+		daysBilling.Store(key, b)
+		cnt++
+		return true
+	})
+	log.Printf("I! amortize %d prepaid charges over %d bills done", len(s.charges), cnt)
+}
+
+// spread moves the charges out of [start, end) and adds back their shares of the days in it, returns the new total amount
+func (s *Amortizer) spread(productsBilling map[string]data.ProductBilling, total float64, start, end time.Time) float64 {
+	grouped := data.IsGroupedByProduct(productsBilling)
+	for _, c := range s.charges {
+		chargeStart, err := time.ParseInLocation("2006-01-02", c.Day, time.Local)
+		if err != nil {
+			continue
+		}
+		chargeEnd, err := time.ParseInLocation("2006-01-02", c.EndDay, time.Local)
+		if err != nil || !chargeEnd.After(chargeStart) {
+			continue
+		}
+		var delta float64
+		if !chargeStart.Before(start) && chargeStart.Before(end) {
+			delta -= c.Amount
+		}
+		if overlap := overlapDays(start, end, chargeStart, chargeEnd); overlap > 0 {
+			delta += c.Amount * float64(overlap) / float64(overlapDays(chargeStart, chargeEnd, chargeStart, chargeEnd))
+		}
+		if delta == 0 {
+			continue
+		}
+		pipCode := ""
+		if grouped {
+			pipCode = c.PipCode
+		}
+		addPrePaid(productsBilling, pipCode, c, delta)
+		total = tools.Float64Add(total, delta)
+	}
+	return total
+}
+
+// addPrePaid adds delta to the PrePaid item of the product
+func addPrePaid(productsBilling map[string]data.ProductBilling, pipCode string, c data.PrepaidCharge, delta float64) {
+	p, ok := productsBilling[pipCode]
+	if !ok {
+		p = data.ProductBilling{ProductName: c.ProductName}
+	}
+	p.TotalAmount = tools.Float64Add(p.TotalAmount, delta)
+	found := false
+	for i := range p.Items {
+		if p.Items[i].SubscriptionType == cloud.PrePaid {
+			p.Items[i].PretaxAmount = tools.Float64Add(p.Items[i].PretaxAmount, delta)
+			found = true
+			break
+		}
+	}
+	if !found {
+		p.Items = append(p.Items, data.ItemInProductBilling{
+			PipCode:          pipCode,
+			ProductName:      p.ProductName,
+			PretaxAmount:     delta,
+			SubscriptionType: cloud.PrePaid,
+			Currency:         c.Currency,
+		})
+	}
+	productsBilling[pipCode] = p
+}
+
+// cloneProductsBilling the bills are shared with the cash view
+func cloneProductsBilling(productsBilling map[string]data.ProductBilling) map[string]data.ProductBilling {
+	ret := make(map[string]data.ProductBilling, len(productsBilling))
+	for pipCode, p := range productsBilling {
+		items := make([]data.ItemInProductBilling, len(p.Items))
+		copy(items, p.Items)
+		p.Items = items
+		ret[pipCode] = p
+	}
+	return ret
+}
+
+// overlapDays days in both [s1, e1) and [s2, e2)
+func overlapDays(s1, e1, s2, e2 time.Time) int {
+	start, end := s1, e1
+	if s2.After(start) {
+		start = s2
+	}
+	if e2.Before(end) {
+		end = e2
+	}
+	if !end.After(start) {
+		return 0
+	}
+	return int(end.Sub(start).Hours()/24 + 0.5) // tolerate daylight saving time
+}
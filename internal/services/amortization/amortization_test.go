@@ -0,0 +1,57 @@
+package amortization
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAmortizer_Amortize(t *testing.T) {
+	nowT := time.Date(2022, 10, 11, 8, 0, 0, 0, time.Local)
+	charges := []data.PrepaidCharge{
+		// 30 days from 09-16, 15 days in september and 15 days in october
+		{InstanceId: "i-1", PipCode: "ecs", ProductName: "云服务器 ECS", Currency: "CNY", Amount: 300, Day: "2022-09-16", EndDay: "2022-10-16"},
+	}
+	var monthsBilling, daysBilling sync.Map
+	monthsBilling.Store("2022-08", data.MonthlyBilling{Month: "2022-08", TotalAmount: 50})
+	monthsBilling.Store("2022-09", data.MonthlyBilling{Month: "2022-09", TotalAmount: 400, ProductsBilling: map[string]data.ProductBilling{
+		"ecs": {ProductName: "云服务器 ECS", TotalAmount: 400, Items: []data.ItemInProductBilling{
+			{PipCode: "ecs", SubscriptionType: cloud.PostPaid, PretaxAmount: 100},
+			{PipCode: "ecs", SubscriptionType: cloud.PrePaid, PretaxAmount: 300},
+		}},
+	}})
+	purchaseDay := data.DailyBilling{Day: "2022-09-16", TotalAmount: 310, ProductsBilling: map[string]data.ProductBilling{
+		"": {TotalAmount: 310, Items: []data.ItemInProductBilling{{PretaxAmount: 310}}},
+	}}
+	daysBilling.Store("2022-09-16", purchaseDay)
+	daysBilling.Store("2022-10-10", data.DailyBilling{Day: "2022-10-10", TotalAmount: 10})
+	daysBilling.Store("2022-10-09", data.DailyBilling{Day: "2022-10-09", TotalAmount: 20, Amortized: true})
+
+	NewAmortizer(charges, "2022-09", nowT).Amortize(context.Background(), &monthsBilling, &daysBilling)
+
+	val, _ := monthsBilling.Load("2022-08")
+	assert.False(t, val.(data.MonthlyBilling).Amortized) // before the first month of charges
+	assert.InDelta(t, 50, val.(data.MonthlyBilling).TotalAmount, 0.001)
+
+	val, _ = monthsBilling.Load("2022-09")
+	september := val.(data.MonthlyBilling)
+	assert.True(t, september.Amortized)
+	assert.InDelta(t, 250, september.TotalAmount, 0.001)
+	assert.InDelta(t, 250, september.ProductsBilling["ecs"].TotalAmount, 0.001)
+	assert.InDelta(t, 150, september.ProductsBilling["ecs"].Items[1].PretaxAmount, 0.001)
+
+	val, _ = daysBilling.Load("2022-09-16")
+	assert.InDelta(t, 20, val.(data.DailyBilling).TotalAmount, 0.001)
+	assert.InDelta(t, 20, val.(data.DailyBilling).ProductsBilling[""].TotalAmount, 0.001)
+	assert.InDelta(t, 310, purchaseDay.ProductsBilling[""].TotalAmount, 0.001) // the cash view is untouched
+
+	val, _ = daysBilling.Load("2022-10-10")
+	assert.InDelta(t, 20, val.(data.DailyBilling).TotalAmount, 0.001)
+	val, _ = daysBilling.Load("2022-10-09")
+	assert.InDelta(t, 20, val.(data.DailyBilling).TotalAmount, 0.001) // amortized by the provider
+}
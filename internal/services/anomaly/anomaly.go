@@ -0,0 +1,242 @@
+package anomaly
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/types"
+	"github.com/galaxy-future/costpilot/tools"
+)
+
+const (
+	_madScale        = 1.4826 // makes MAD a consistent estimator of the standard deviation
+	_maxContributors = 3
+)
+
+// Detector flags spikes and drops on the per-account and per-product daily cost series,
+// by comparing every day with the median/MAD of its trailing window.
+type Detector struct {
+	cfg             types.AnomalyDetection
+	accountBillings []data.AccountBillingMap
+
+	bp *tools.BillingDatePilot
+}
+
+func NewDetector(cfg types.AnomalyDetection, accountBillings []data.AccountBillingMap, t time.Time) *Detector {
+	return &Detector{
+		cfg:             cfg,
+		accountBillings: accountBillings,
+		bp:              tools.NewBillDatePilot().SetNowT(t),
+	}
+}
+
+// series daily values, valid is false if the bill of that day is absent
+type series struct {
+	values []float64
+	valid  []bool
+}
+
+func newSeries(n int) *series {
+	return &series{values: make([]float64, n), valid: make([]bool, n)}
+}
+
+// baseline median and scale of the valid values in [i-window, i)
+func (s *series) baseline(i, window, minHistory int, minAmount float64) (median, scale float64, ok bool) {
+	history := make([]float64, 0, window)
+	for j := i - window; j < i; j++ {
+		if j >= 0 && s.valid[j] {
+			history = append(history, s.values[j])
+		}
+	}
+	if len(history) < minHistory {
+		return 0, 0, false
+	}
+	median = tools.Median(history)
+	scale = _madScale * tools.MedianAbsoluteDeviation(history)
+	if scale < 1e-9 { // flat history
+		scale = math.Max(0.1*math.Abs(median), minAmount)
+	}
+	return median, scale, true
+}
+
+// Detect returns anomalies of the recent DetectDays, the latest and the most significant first
+func (s *Detector) Detect(_ context.Context) []data.CostAnomaly {
+	var ret []data.CostAnomaly
+	window, detectDays := s.cfg.GetWindowDays(), s.cfg.GetDetectDays()
+	days := s.bp.GetRecentXDaysBillingDate(int32(window + detectDays)).Days
+	for _, a := range s.accountBillings {
+		ret = append(ret, s.detectAccount(a, days, window, detectDays)...)
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		if ret[i].Day != ret[j].Day {
+			return ret[i].Day > ret[j].Day
+		}
+		return math.Abs(ret[i].Score) > math.Abs(ret[j].Score)
+	})
+	log.Printf("I! detect cost anomalies done, found %d", len(ret))
+	return ret
+}
+
+func (s *Detector) detectAccount(a data.AccountBillingMap, days []string, window, detectDays int) []data.CostAnomaly {
+	var (
+		ret          []data.CostAnomaly
+		currency     string
+		total        = newSeries(len(days))
+		products     = make(map[string]*series)
+		productNames = make(map[string]string)
+		hasProducts  = make([]bool, len(days))
+	)
+	for i, d := range days {
+		val, ok := a.DaysBilling.Load(d)
+		if !ok {
+			continue
+		}
+		bill := val.(data.DailyBilling)
+		total.values[i], total.valid[i] = bill.TotalAmount, true
+		if currency == "" {
+			currency = tools.ExtractCurrency(bill.ProductsBilling)
+		}
+		for pipCode, p := range bill.ProductsBilling {
+			if pipCode == "" { // not grouped by product
+				continue
+			}
+			hasProducts[i] = true
+			if _, ok := products[pipCode]; !ok {
+				products[pipCode] = newSeries(len(days))
+			}
+			products[pipCode].values[i] = p.TotalAmount
+			productNames[pipCode] = p.ProductName
+		}
+	}
+	for _, p := range products { // absent product on a day with product detail costs nothing
+		for i := range days {
+			p.valid[i] = hasProducts[i]
+		}
+	}
+
+	minHistory := (window + 1) / 2
+	threshold, minAmount := s.cfg.GetThreshold(), s.cfg.GetMinAmount()
+	for i := len(days) - detectDays; i < len(days); i++ {
+		if i < 0 {
+			continue
+		}
+		if total.valid[i] {
+			if median, scale, ok := total.baseline(i, window, minHistory, minAmount); ok {
+				if anomaly, yes := s.judge(total.values[i], median, scale, threshold, minAmount); yes {
+					anomaly.Day, anomaly.AccountName, anomaly.Provider, anomaly.Currency = days[i], a.AccountName, a.Provider, currency
+					if hasProducts[i] {
+						anomaly.Contributors = topContributors(anomaly.Direction, i, window, minHistory, minAmount, products, productNames)
+					}
+					ret = append(ret, anomaly)
+				}
+			}
+		}
+		for pipCode, p := range products {
+			if !p.valid[i] {
+				continue
+			}
+			median, scale, ok := p.baseline(i, window, minHistory, minAmount)
+			if !ok {
+				continue
+			}
+			if anomaly, yes := s.judge(p.values[i], median, scale, threshold, minAmount); yes {
+				anomaly.Day, anomaly.AccountName, anomaly.Provider, anomaly.Currency = days[i], a.AccountName, a.Provider, currency
+				anomaly.PipCode, anomaly.ProductName = pipCode, productNames[pipCode]
+				ret = append(ret, anomaly)
+			}
+		}
+	}
+	return ret
+}
+
+func (s *Detector) judge(amount, median, scale, threshold, minAmount float64) (data.CostAnomaly, bool) {
+	deviation := amount - median
+	score := deviation / scale
+	if math.Abs(score) < threshold || math.Abs(deviation) < minAmount {
+		return data.CostAnomaly{}, false
+	}
+	anomaly := data.CostAnomaly{
+		Amount:    amount,
+		Baseline:  median,
+		Deviation: deviation,
+		Score:     score,
+		Direction: data.AnomalyDirectionSpike,
+		Severity:  data.AlertSeverityWarning,
+	}
+	if deviation < 0 {
+		anomaly.Direction = data.AnomalyDirectionDrop
+	}
+	if math.Abs(score) >= 2*threshold {
+		anomaly.Severity = data.AlertSeverityCritical
+	}
+	return anomaly, true
+}
+
+// topContributors products whose deviation on day i goes the same direction as the anomaly, the largest first
+func topContributors(direction data.AnomalyDirection, i, window, minHistory int, minAmount float64, products map[string]*series, productNames map[string]string) []data.AnomalyContributor {
+	var ret []data.AnomalyContributor
+	for pipCode, p := range products {
+		median, _, ok := p.baseline(i, window, minHistory, minAmount)
+		if !ok {
+			median = 0 // new product
+		}
+		deviation := p.values[i] - median
+		if (direction == data.AnomalyDirectionSpike && deviation <= 0) || (direction == data.AnomalyDirectionDrop && deviation >= 0) {
+			continue
+		}
+		ret = append(ret, data.AnomalyContributor{
+			PipCode:     pipCode,
+			ProductName: productNames[pipCode],
+			Amount:      p.values[i],
+			Baseline:    median,
+			Deviation:   deviation,
+		})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return math.Abs(ret[i].Deviation) > math.Abs(ret[j].Deviation)
+	})
+	if len(ret) > _maxContributors {
+		ret = ret[:_maxContributors]
+	}
+	return ret
+}
+
+// AlertEvents convert anomalies of the recent day to alert events
+func (s *Detector) AlertEvents(anomalies []data.CostAnomaly) []data.AlertEvent {
+	var ret []data.AlertEvent
+	recentDay := s.bp.GetRecentDayBillingDate().Days[0]
+	for _, a := range anomalies {
+		if a.Day != recentDay {
+			continue
+		}
+		subject := a.AccountName
+		if a.PipCode != "" {
+			subject = fmt.Sprintf("%s/%s", a.AccountName, a.ProductName)
+		}
+		message := fmt.Sprintf("cost %.2f %s on %s, baseline %.2f, deviation %+.2f (score %.1f)",
+			a.Amount, a.Currency, a.Day, a.Baseline, a.Deviation, a.Score)
+		for _, c := range a.Contributors {
+			message += fmt.Sprintf("; %s %+.2f", c.ProductName, c.Deviation)
+		}
+		ret = append(ret, data.AlertEvent{
+			Kind:     data.AlertKindAnomaly,
+			Severity: a.Severity,
+			Title:    fmt.Sprintf("Cost %s of %s", a.Direction, subject),
+			Message:  message,
+			Labels: map[string]string{
+				"account":  a.AccountName,
+				"provider": string(a.Provider),
+				"product":  a.PipCode,
+				"day":      a.Day,
+			},
+			Time: s.bp.GetNowT(),
+			Key:  fmt.Sprintf("anomaly/%s/%s/%s", a.AccountName, a.PipCode, a.Day),
+		})
+	}
+	return ret
+}
@@ -0,0 +1,87 @@
+package anomaly
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// fillDays ecs costs around 100 per day and oss 20 per day, day offsets in spikes override the ecs cost
+func fillDays(nowT time.Time, n int, ecs map[int]float64) *sync.Map {
+	var daysBilling sync.Map
+	for i := 1; i <= n; i++ {
+		day := nowT.AddDate(0, 0, -i).Format("2006-01-02")
+		ecsAmount := 100 + float64(i%3) // 100 ~ 102
+		if v, ok := ecs[i]; ok {
+			ecsAmount = v
+		}
+		daysBilling.Store(day, data.DailyBilling{
+			Day: day,
+			ProductsBilling: map[string]data.ProductBilling{
+				"ecs": {ProductName: "云服务器 ECS", TotalAmount: ecsAmount, Items: []data.ItemInProductBilling{{Currency: "CNY"}}},
+				"oss": {ProductName: "对象存储 OSS", TotalAmount: 20},
+			},
+			TotalAmount: ecsAmount + 20,
+		})
+	}
+	return &daysBilling
+}
+
+func TestDetector_Detect(t *testing.T) {
+	nowT := time.Date(2022, 10, 20, 8, 0, 0, 0, time.Local)
+	accounts := []data.AccountBillingMap{
+		{
+			AccountName:   "ali-prod",
+			Provider:      cloud.AlibabaCloud,
+			DaysBilling:   fillDays(nowT, 28, map[int]float64{1: 400, 5: 10}),
+			MonthsBilling: &sync.Map{},
+		},
+	}
+	s := NewDetector(types.AnomalyDetection{}, accounts, nowT)
+	got := s.Detect(context.Background())
+
+	var accountAnomalies, productAnomalies []data.CostAnomaly
+	for _, a := range got {
+		if a.PipCode == "" {
+			accountAnomalies = append(accountAnomalies, a)
+		} else {
+			productAnomalies = append(productAnomalies, a)
+		}
+	}
+	assert.Equal(t, 2, len(accountAnomalies))
+	assert.Equal(t, 2, len(productAnomalies))
+
+	spike := accountAnomalies[0]
+	assert.Equal(t, "2022-10-19", spike.Day)
+	assert.Equal(t, data.AnomalyDirectionSpike, spike.Direction)
+	assert.Equal(t, data.AlertSeverityCritical, spike.Severity)
+	assert.Equal(t, "CNY", spike.Currency)
+	assert.Equal(t, 1, len(spike.Contributors))
+	assert.Equal(t, "ecs", spike.Contributors[0].PipCode)
+
+	drop := accountAnomalies[1]
+	assert.Equal(t, "2022-10-15", drop.Day)
+	assert.Equal(t, data.AnomalyDirectionDrop, drop.Direction)
+
+	events := s.AlertEvents(got)
+	assert.Equal(t, 2, len(events)) // account and product anomalies of 2022-10-19
+	assert.Equal(t, data.AlertKindAnomaly, events[0].Kind)
+}
+
+func TestDetector_DetectFlat(t *testing.T) {
+	nowT := time.Date(2022, 10, 20, 8, 0, 0, 0, time.Local)
+	var daysBilling sync.Map
+	for i := 1; i <= 28; i++ {
+		day := nowT.AddDate(0, 0, -i).Format("2006-01-02")
+		daysBilling.Store(day, data.DailyBilling{Day: day, TotalAmount: 50})
+	}
+	accounts := []data.AccountBillingMap{{AccountName: "aws", Provider: cloud.AWSCloud, DaysBilling: &daysBilling}}
+	got := NewDetector(types.AnomalyDetection{}, accounts, nowT).Detect(context.Background())
+	assert.Empty(t, got)
+}
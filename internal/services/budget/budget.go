@@ -0,0 +1,180 @@
+package budget
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/types"
+	"github.com/galaxy-future/costpilot/tools"
+)
+
+type Evaluator struct {
+	budgets         []types.Budget
+	accountBillings []data.AccountBillingMap
+
+	bp *tools.BillingDatePilot
+}
+
+func NewEvaluator(budgets []types.Budget, accountBillings []data.AccountBillingMap, t time.Time) *Evaluator {
+	return &Evaluator{
+		budgets:         budgets,
+		accountBillings: accountBillings,
+		bp:              tools.NewBillDatePilot().SetNowT(t),
+	}
+}
+
+// Evaluate compute period-to-date spend and projected period-end spend of every budget
+func (s *Evaluator) Evaluate(_ context.Context) []data.BudgetStatus {
+	ret := make([]data.BudgetStatus, 0, len(s.budgets))
+	for _, b := range s.budgets {
+		ret = append(ret, s.evaluate(b))
+	}
+	log.Printf("I! evaluate %d budgets done", len(ret))
+	return ret
+}
+
+func (s *Evaluator) evaluate(b types.Budget) data.BudgetStatus {
+	var billingDate tools.BillingDate
+	if b.Period == types.BudgetPeriodQuarterly {
+		billingDate = s.bp.GetRecentQuarterBillingDate(true)
+	} else {
+		billingDate = s.bp.GetRecentMonthBillingDate(true)
+	}
+	start, end, yesterday := s.periodRange(b.Period)
+	status := data.BudgetStatus{
+		Budget:      b,
+		PeriodStart: start.Format("2006-01-02"),
+		PeriodEnd:   end.AddDate(0, 0, -1).Format("2006-01-02"),
+		ElapsedDays: daysBetween(start, yesterday) + 1,
+		TotalDays:   daysBetween(start, end),
+		State:       data.BudgetStateOK,
+	}
+	for _, a := range s.accountBillings {
+		for _, m := range billingDate.Months {
+			if val, ok := a.MonthsBilling.Load(m); ok {
+				bill := val.(data.MonthlyBilling)
+				status.Spent = tools.Float64Add(status.Spent, amountInScope(b, a, bill.TotalAmount, bill.ProductsBilling))
+				if status.Currency == "" {
+					status.Currency = tools.ExtractCurrency(bill.ProductsBilling)
+				}
+			}
+		}
+		for _, d := range billingDate.Days {
+			if val, ok := a.DaysBilling.Load(d); ok {
+				bill := val.(data.DailyBilling)
+				status.Spent = tools.Float64Add(status.Spent, amountInScope(b, a, bill.TotalAmount, bill.ProductsBilling))
+				if status.Currency == "" {
+					status.Currency = tools.ExtractCurrency(bill.ProductsBilling)
+				}
+			}
+		}
+	}
+	status.Projected = status.Spent
+	if status.ElapsedDays > 0 && status.ElapsedDays < status.TotalDays {
+		status.Projected = status.Spent / float64(status.ElapsedDays) * float64(status.TotalDays)
+	}
+	status.Percent = 100 * status.Spent / b.Amount
+	status.ProjectedPercent = 100 * status.Projected / b.Amount
+	for _, t := range b.GetThresholds() {
+		if status.Percent >= t && t > status.ReachedThreshold {
+			status.ReachedThreshold = t
+		}
+	}
+	switch {
+	case status.Percent >= 100:
+		status.State = data.BudgetStateExceeded
+	case status.ReachedThreshold > 0 || status.ProjectedPercent >= 100:
+		status.State = data.BudgetStateWarning
+	}
+	return status
+}
+
+// periodRange returns [start, end) of the period which yesterday belongs to, and yesterday itself
+func (s *Evaluator) periodRange(period types.BudgetPeriod) (start, end, yesterday time.Time) {
+	t := s.bp.GetNowT().AddDate(0, 0, -1)
+	yesterday = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	if period == types.BudgetPeriodQuarterly {
+		firstMonth := time.Month((int(yesterday.Month())-1)/3*3 + 1)
+		start = time.Date(yesterday.Year(), firstMonth, 1, 0, 0, 0, 0, yesterday.Location())
+		return start, start.AddDate(0, 3, 0), yesterday
+	}
+	start = time.Date(yesterday.Year(), yesterday.Month(), 1, 0, 0, 0, 0, yesterday.Location())
+	return start, start.AddDate(0, 1, 0), yesterday
+}
+
+func daysBetween(from, to time.Time) int {
+	return int(math.Round(to.Sub(from).Hours() / 24))
+}
+
+// AlertEvents convert budget status to alert events, at most one event per budget
+func (s *Evaluator) AlertEvents(statuses []data.BudgetStatus) []data.AlertEvent {
+	var ret []data.AlertEvent
+	for _, st := range statuses {
+		labels := map[string]string{
+			"budget": st.Budget.Name,
+			"scope":  string(st.Budget.Scope),
+			"target": st.Budget.Target,
+			"period": string(st.Budget.Period),
+		}
+		if st.ReachedThreshold > 0 {
+			severity := data.AlertSeverityWarning
+			if st.ReachedThreshold >= 100 {
+				severity = data.AlertSeverityCritical
+			}
+			ret = append(ret, data.AlertEvent{
+				Kind:     data.AlertKindBudget,
+				Severity: severity,
+				Title:    fmt.Sprintf("Budget %s reached %.0f%%", st.Budget.Name, st.ReachedThreshold),
+				Message: fmt.Sprintf("spent %.2f %s of %.2f (%.2f%%) from %s, projected %.2f by %s",
+					st.Spent, st.Currency, st.Budget.Amount, st.Percent, st.PeriodStart, st.Projected, st.PeriodEnd),
+				Labels: labels,
+				Time:   s.bp.GetNowT(),
+				Key:    fmt.Sprintf("budget/%s/%s/%.0f", st.Budget.Name, st.PeriodStart, st.ReachedThreshold),
+			})
+			continue
+		}
+		if st.ProjectedPercent >= 100 {
+			ret = append(ret, data.AlertEvent{
+				Kind:     data.AlertKindBudget,
+				Severity: data.AlertSeverityWarning,
+				Title:    fmt.Sprintf("Budget %s is projected to exceed", st.Budget.Name),
+				Message: fmt.Sprintf("projected %.2f %s of %.2f (%.2f%%) by %s, spent %.2f so far",
+					st.Projected, st.Currency, st.Budget.Amount, st.ProjectedPercent, st.PeriodEnd, st.Spent),
+				Labels: labels,
+				Time:   s.bp.GetNowT(),
+				Key:    fmt.Sprintf("budget/%s/%s/projected", st.Budget.Name, st.PeriodStart),
+			})
+		}
+	}
+	return ret
+}
+
+// amountInScope returns the part of one bill that the budget covers
+func amountInScope(b types.Budget, a data.AccountBillingMap, total float64, products map[string]data.ProductBilling) float64 {
+	switch b.Scope {
+	case types.BudgetScopeAll:
+		return total
+	case types.BudgetScopeAccount:
+		if a.AccountName == b.Target {
+			return total
+		}
+	case types.BudgetScopeProvider:
+		if strings.EqualFold(string(a.Provider), b.Target) {
+			return total
+		}
+	case types.BudgetScopeProduct:
+		var sum float64
+		for pipCode, p := range products {
+			if pipCode == b.Target || p.ProductName == b.Target {
+				sum = tools.Float64Add(sum, p.TotalAmount)
+			}
+		}
+		return sum
+	}
+	return 0
+}
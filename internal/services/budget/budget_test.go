@@ -0,0 +1,128 @@
+package budget
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/providers/types"
+	cfgTypes "github.com/galaxy-future/costpilot/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAccountBilling(name string, provider cloud.Provider, ecsDaily, s3Daily float64) data.AccountBillingMap {
+	var monthsBilling, daysBilling sync.Map
+	for day := 1; day <= 31; day++ {
+		d := fmt.Sprintf("2022-10-%02d", day)
+		daysBilling.Store(d, data.DailyBilling{
+			Day: d,
+			ProductsBilling: map[string]data.ProductBilling{
+				types.ECS.String(): {
+					ProductName: "云服务器 ECS",
+					TotalAmount: ecsDaily,
+					Items:       []data.ItemInProductBilling{{Currency: "CNY", PretaxAmount: ecsDaily}},
+				},
+				types.S3.String(): {
+					ProductName: "对象存储 OSS",
+					TotalAmount: s3Daily,
+				},
+			},
+			TotalAmount: ecsDaily + s3Daily,
+		})
+	}
+	for _, m := range []string{"2022-07", "2022-08", "2022-09"} {
+		monthsBilling.Store(m, data.MonthlyBilling{Month: m, TotalAmount: 30 * (ecsDaily + s3Daily)})
+	}
+	return data.AccountBillingMap{
+		AccountName:   name,
+		Provider:      provider,
+		MonthsBilling: &monthsBilling,
+		DaysBilling:   &daysBilling,
+	}
+}
+
+func TestEvaluator_Evaluate(t *testing.T) {
+	nowT := time.Date(2022, 10, 11, 10, 0, 0, 0, time.Local) // 10 days of october elapsed
+	accounts := []data.AccountBillingMap{
+		newAccountBilling("ali-prod", cloud.AlibabaCloud, 10, 5),
+		newAccountBilling("aws-prod", cloud.AWSCloud, 20, 0),
+	}
+	tests := []struct {
+		name          string
+		budget        cfgTypes.Budget
+		wantSpent     float64
+		wantProjected float64
+		wantReached   float64
+		wantState     data.BudgetState
+	}{
+		{
+			name:          "all",
+			budget:        cfgTypes.Budget{Name: "all", Scope: cfgTypes.BudgetScopeAll, Period: cfgTypes.BudgetPeriodMonthly, Amount: 2000},
+			wantSpent:     350,
+			wantProjected: 1085,
+			wantReached:   0,
+			wantState:     data.BudgetStateOK,
+		},
+		{
+			name:          "account",
+			budget:        cfgTypes.Budget{Name: "account", Scope: cfgTypes.BudgetScopeAccount, Target: "aws-prod", Period: cfgTypes.BudgetPeriodMonthly, Amount: 400},
+			wantSpent:     200,
+			wantProjected: 620,
+			wantReached:   50,
+			wantState:     data.BudgetStateWarning,
+		},
+		{
+			name:          "provider",
+			budget:        cfgTypes.Budget{Name: "provider", Scope: cfgTypes.BudgetScopeProvider, Target: "AlibabaCloud", Period: cfgTypes.BudgetPeriodMonthly, Amount: 100},
+			wantSpent:     150,
+			wantProjected: 465,
+			wantReached:   100,
+			wantState:     data.BudgetStateExceeded,
+		},
+		{
+			name:          "product",
+			budget:        cfgTypes.Budget{Name: "product", Scope: cfgTypes.BudgetScopeProduct, Target: types.ECS.String(), Period: cfgTypes.BudgetPeriodMonthly, Amount: 1000, Thresholds: []float64{20, 90}},
+			wantSpent:     300,
+			wantProjected: 930,
+			wantReached:   20,
+			wantState:     data.BudgetStateWarning,
+		},
+		{
+			name:          "quarterly",
+			budget:        cfgTypes.Budget{Name: "quarterly", Scope: cfgTypes.BudgetScopeAll, Period: cfgTypes.BudgetPeriodQuarterly, Amount: 10000},
+			wantSpent:     350,
+			wantProjected: 3220,
+			wantReached:   0,
+			wantState:     data.BudgetStateOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewEvaluator([]cfgTypes.Budget{tt.budget}, accounts, nowT)
+			got := s.Evaluate(context.Background())
+			assert.Equal(t, 1, len(got))
+			assert.InDelta(t, tt.wantSpent, got[0].Spent, 0.001)
+			assert.InDelta(t, tt.wantProjected, got[0].Projected, 0.001)
+			assert.Equal(t, tt.wantReached, got[0].ReachedThreshold)
+			assert.Equal(t, tt.wantState, got[0].State)
+		})
+	}
+}
+
+func TestEvaluator_AlertEvents(t *testing.T) {
+	s := NewEvaluator(nil, nil, time.Now())
+	got := s.AlertEvents([]data.BudgetStatus{
+		{Budget: cfgTypes.Budget{Name: "a", Amount: 100}, Percent: 40, ProjectedPercent: 90},
+		{Budget: cfgTypes.Budget{Name: "b", Amount: 100}, Percent: 60, ProjectedPercent: 120, ReachedThreshold: 50},
+		{Budget: cfgTypes.Budget{Name: "c", Amount: 100}, Percent: 110, ProjectedPercent: 200, ReachedThreshold: 100},
+		{Budget: cfgTypes.Budget{Name: "d", Amount: 100}, Percent: 30, ProjectedPercent: 101},
+	})
+	assert.Equal(t, 3, len(got))
+	assert.Equal(t, data.AlertSeverityWarning, got[0].Severity)
+	assert.Equal(t, data.AlertSeverityCritical, got[1].Severity)
+	assert.Equal(t, "d", got[2].Labels["budget"])
+}
@@ -0,0 +1,154 @@
+package commitment
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/types"
+	"github.com/galaxy-future/costpilot/tools"
+)
+
+// Analyzer computes the coverage and utilization of reserved instances, savings plans and subscriptions
+// per account and provider, and lists the commitments expiring within the configured days.
+type Analyzer struct {
+	cfg         types.CommitmentAnalysis
+	commitments []data.AccountCommitments
+
+	bp *tools.BillingDatePilot
+}
+
+func NewAnalyzer(cfg types.CommitmentAnalysis, commitments []data.AccountCommitments, t time.Time) *Analyzer {
+	return &Analyzer{
+		cfg:         cfg,
+		commitments: commitments,
+		bp:          tools.NewBillDatePilot().SetNowT(t),
+	}
+}
+
+func (s *Analyzer) Analyze(_ context.Context) data.CommitmentAnalysis {
+	var ret data.CommitmentAnalysis
+	providers := make(map[string]*providerUsage) // key : provider + type + unit
+	var providerKeys []string
+	for _, a := range s.commitments {
+		for _, u := range a.Usages {
+			ret.Coverages = append(ret.Coverages, newCoverage(a.AccountName, a.Provider, u))
+
+			key := strings.Join([]string{string(a.Provider), u.Type, u.Unit}, "|")
+			p, ok := providers[key]
+			if !ok {
+				p = &providerUsage{provider: a.Provider, usage: data.CommitmentUsage{Type: u.Type, Unit: u.Unit}}
+				providers[key] = p
+				providerKeys = append(providerKeys, key)
+			}
+			p.usage.CoveredUsage = tools.Float64Add(p.usage.CoveredUsage, u.CoveredUsage)
+			p.usage.EligibleUsage = tools.Float64Add(p.usage.EligibleUsage, u.EligibleUsage)
+			p.usage.UsedCommitment = tools.Float64Add(p.usage.UsedCommitment, u.UsedCommitment)
+			p.usage.TotalCommitment = tools.Float64Add(p.usage.TotalCommitment, u.TotalCommitment)
+		}
+	}
+	for _, key := range providerKeys {
+		p := providers[key]
+		ret.Providers = append(ret.Providers, newCoverage("", p.provider, p.usage))
+	}
+
+	ret.Expiring = s.expiring()
+	log.Printf("I! commitment analysis done, expiring %d", len(ret.Expiring))
+	return ret
+}
+
+type providerUsage struct {
+	provider cloud.Provider
+	usage    data.CommitmentUsage
+}
+
+// expiring commitments ending within the largest bucket, the earliest first
+func (s *Analyzer) expiring() []data.ExpiringCommitment {
+	var ret []data.ExpiringCommitment
+	buckets := s.cfg.GetExpiringDays()
+	nowT := s.bp.GetNowT()
+	for _, a := range s.commitments {
+		for _, c := range a.List {
+			if c.EndTime.IsZero() || c.EndTime.Before(nowT) {
+				continue
+			}
+			daysLeft := int(math.Ceil(c.EndTime.Sub(nowT).Hours() / 24))
+			for _, b := range buckets {
+				if daysLeft <= b {
+					ret = append(ret, data.ExpiringCommitment{
+						AccountName: a.AccountName,
+						Provider:    a.Provider,
+						Commitment:  c,
+						DaysLeft:    daysLeft,
+						WithinDays:  b,
+					})
+					break
+				}
+			}
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].EndTime.Before(ret[j].EndTime)
+	})
+	return ret
+}
+
+// AlertEvents one event per account for the commitments in the first expiring bucket that are not renewed automatically
+func (s *Analyzer) AlertEvents(ca data.CommitmentAnalysis) []data.AlertEvent {
+	var ret []data.AlertEvent
+	within := s.cfg.GetExpiringDays()[0]
+	ids := make(map[string][]string)       // key : account name
+	var accounts []data.ExpiringCommitment // first expiring commitment of every account
+	for _, e := range ca.Expiring {
+		if e.WithinDays != within || e.AutoRenew {
+			continue
+		}
+		if _, ok := ids[e.AccountName]; !ok {
+			accounts = append(accounts, e)
+		}
+		ids[e.AccountName] = append(ids[e.AccountName], e.CommitmentId)
+	}
+	for _, a := range accounts {
+		ret = append(ret, data.AlertEvent{
+			Kind:     data.AlertKindCommitment,
+			Severity: data.AlertSeverityWarning,
+			Title:    fmt.Sprintf("%d commitments of %s expire within %d days", len(ids[a.AccountName]), a.AccountName, within),
+			Message: fmt.Sprintf("the first ends in %d days, not renewed automatically: %s",
+				a.DaysLeft, strings.Join(ids[a.AccountName], ", ")),
+			Labels: map[string]string{
+				"account":  a.AccountName,
+				"provider": string(a.Provider),
+			},
+			Time: s.bp.GetNowT(),
+			Key:  fmt.Sprintf("commitment/%s/%s/%d", a.AccountName, strings.Join(ids[a.AccountName], ","), within),
+		})
+	}
+	return ret
+}
+
+func newCoverage(accountName string, provider cloud.Provider, u data.CommitmentUsage) data.CommitmentCoverage {
+	return data.CommitmentCoverage{
+		AccountName:    accountName,
+		Provider:       provider,
+		Type:           u.Type,
+		Unit:           u.Unit,
+		CoveredUsage:   u.CoveredUsage,
+		EligibleUsage:  u.EligibleUsage,
+		Coverage:       percent(u.CoveredUsage, u.EligibleUsage),
+		HasUtilization: u.TotalCommitment > 0,
+		Utilization:    percent(u.UsedCommitment, u.TotalCommitment),
+	}
+}
+
+func percent(part, total float64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return part / total * 100
+}
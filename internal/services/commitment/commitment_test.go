@@ -0,0 +1,86 @@
+package commitment
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyzer_Analyze(t *testing.T) {
+	nowT := time.Date(2022, 10, 11, 8, 0, 0, 0, time.Local)
+	accounts := []data.AccountCommitments{
+		{
+			AccountName: "aws-prod",
+			Provider:    cloud.AWSCloud,
+			Commitments: data.Commitments{
+				Usages: []data.CommitmentUsage{
+					{Type: "ReservedInstance", Unit: "Hours", CoveredUsage: 600, EligibleUsage: 1000, UsedCommitment: 600, TotalCommitment: 720},
+					{Type: "SavingsPlan", Unit: "USD", CoveredUsage: 30, EligibleUsage: 120, UsedCommitment: 30, TotalCommitment: 40},
+				},
+				List: []data.Commitment{
+					{CommitmentId: "ri-1", Type: "ReservedInstance", EndTime: nowT.Add(20 * 24 * time.Hour)},
+					{CommitmentId: "ri-2", Type: "ReservedInstance", EndTime: nowT.Add(75 * 24 * time.Hour)},
+					{CommitmentId: "ri-3", Type: "ReservedInstance", EndTime: nowT.Add(200 * 24 * time.Hour)},
+					{CommitmentId: "ri-expired", Type: "ReservedInstance", EndTime: nowT.Add(-24 * time.Hour)},
+				},
+			},
+		},
+		{
+			AccountName: "aws-dev",
+			Provider:    cloud.AWSCloud,
+			Commitments: data.Commitments{
+				Usages: []data.CommitmentUsage{
+					{Type: "ReservedInstance", Unit: "Hours", CoveredUsage: 0, EligibleUsage: 1000, UsedCommitment: 0, TotalCommitment: 0},
+				},
+			},
+		},
+		{
+			AccountName: "ali-prod",
+			Provider:    cloud.AlibabaCloud,
+			Commitments: data.Commitments{
+				Usages: []data.CommitmentUsage{
+					{Type: "Subscription", Unit: "Hours", CoveredUsage: 720, EligibleUsage: 2160},
+				},
+				List: []data.Commitment{
+					{CommitmentId: "i-renew", Type: "Subscription", EndTime: nowT.Add(5 * 24 * time.Hour), AutoRenew: true},
+					{CommitmentId: "i-monthly", Type: "Subscription", EndTime: nowT.Add(40 * 24 * time.Hour)},
+				},
+			},
+		},
+	}
+	analyzer := NewAnalyzer(types.CommitmentAnalysis{}, accounts, nowT)
+	got := analyzer.Analyze(context.Background())
+
+	assert.Equal(t, 4, len(got.Coverages))
+	assert.InDelta(t, 60, got.Coverages[0].Coverage, 0.001)
+	assert.True(t, got.Coverages[0].HasUtilization)
+	assert.InDelta(t, 83.333, got.Coverages[0].Utilization, 0.001)
+	assert.InDelta(t, 25, got.Coverages[1].Coverage, 0.001)
+	assert.False(t, got.Coverages[2].HasUtilization)
+
+	assert.Equal(t, 3, len(got.Providers))
+	assert.Equal(t, "", got.Providers[0].AccountName)
+	assert.Equal(t, "ReservedInstance", got.Providers[0].Type)
+	assert.InDelta(t, 30, got.Providers[0].Coverage, 0.001) // 600 / 2000
+	assert.InDelta(t, 83.333, got.Providers[0].Utilization, 0.001)
+	assert.InDelta(t, 33.333, got.Providers[2].Coverage, 0.001)
+
+	assert.Equal(t, 4, len(got.Expiring))
+	assert.Equal(t, "i-renew", got.Expiring[0].CommitmentId)
+	assert.Equal(t, 30, got.Expiring[0].WithinDays)
+	assert.Equal(t, "ri-1", got.Expiring[1].CommitmentId)
+	assert.Equal(t, 20, got.Expiring[1].DaysLeft)
+	assert.Equal(t, 60, got.Expiring[2].WithinDays)
+	assert.Equal(t, "ri-2", got.Expiring[3].CommitmentId)
+	assert.Equal(t, 90, got.Expiring[3].WithinDays)
+
+	events := analyzer.AlertEvents(got)
+	assert.Equal(t, 1, len(events)) // i-renew is renewed automatically
+	assert.Equal(t, data.AlertKindCommitment, events[0].Kind)
+	assert.Equal(t, "aws-prod", events[0].Labels["account"])
+}
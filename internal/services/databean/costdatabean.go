@@ -2,19 +2,23 @@ package databean
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"sort"
 	"sync"
 	"time"
 
+	"github.com/galaxy-future/costpilot/internal/data"
 	"github.com/galaxy-future/costpilot/internal/services/datareader"
 
 	"github.com/galaxy-future/costpilot/internal/providers"
+	providerTypes "github.com/galaxy-future/costpilot/internal/providers/types"
 	"github.com/galaxy-future/costpilot/internal/types"
 	"github.com/galaxy-future/costpilot/tools"
 )
 
 type CostDataBean struct {
+	cloudAccount  types.CloudAccount
 	billingDate   tools.BillingDate
 	daysBilling   sync.Map
 	monthsBilling sync.Map
@@ -22,13 +26,26 @@ type CostDataBean struct {
 	bp       *tools.BillingDatePilot
 	provider providers.Provider
 
+	recentDaysWithProduct int32 // recent days to fetch with product detail, eg: for anomaly detection
+	monthToDate           bool  // fetch the days of the month to date and the same days of last month, eg: for the digest
+	quarterWithProduct    bool  // fetch the months of the recent quarter with product detail, eg: for the quarterly product budgets
+	costBasis             providerTypes.CostBasis
+
+	amortized      bool
+	lookbackMonths int32 // months of prepaid charges to load if the provider does not amortize natively
+	prepaidCharges []data.PrepaidCharge
+	chargesSince   string // first month of the prepaid charges
+
+	unsupported []data.Unsupported
+
 	pipeLineFunc []func(context.Context) error
 }
 
 func NewCostDataBean(a types.CloudAccount, t time.Time) *CostDataBean {
 	s := &CostDataBean{
-		billingDate: tools.BillingDate{},
-		bp:          tools.NewBillDatePilot().SetNowT(t),
+		cloudAccount: a,
+		billingDate:  tools.BillingDate{},
+		bp:           tools.NewBillDatePilot().SetNowT(t),
 	}
 	s.initProvider(a)
 
@@ -38,18 +55,67 @@ func NewCostDataBean(a types.CloudAccount, t time.Time) *CostDataBean {
 // initProvider
 func (s *CostDataBean) initProvider(a types.CloudAccount) *CostDataBean {
 	var err error
-	s.provider, err = providers.GetProvider(a.Provider, a.AK, a.SK, a.RegionID)
+	s.provider, err = providers.GetProvider(a.Provider, a.Credential(), a.RegionID)
 	if err != nil {
 		log.Printf("E! init provider failed: %v\n", err)
 	}
 	return s
 }
 
+// SetRecentDaysWithProduct
+func (s *CostDataBean) SetRecentDaysWithProduct(n int32) *CostDataBean {
+	s.recentDaysWithProduct = n
+	return s
+}
+
+// SetMonthToDate
+func (s *CostDataBean) SetMonthToDate(monthToDate bool) *CostDataBean {
+	s.monthToDate = monthToDate
+	return s
+}
+
+// SetQuarterWithProduct
+func (s *CostDataBean) SetQuarterWithProduct(quarterWithProduct bool) *CostDataBean {
+	s.quarterWithProduct = quarterWithProduct
+	return s
+}
+
+// SetCostBasis
+func (s *CostDataBean) SetCostBasis(basis providerTypes.CostBasis) *CostDataBean {
+	s.costBasis = basis
+	return s
+}
+
+// SetAmortized build the amortized view from a copy of the cash billing,
+// the bills are fetched again only if the provider amortizes them natively
+func (s *CostDataBean) SetAmortized(lookbackMonths int32, monthsBilling, daysBilling *sync.Map) *CostDataBean {
+	s.amortized = true
+	s.lookbackMonths = lookbackMonths
+	monthsBilling.Range(func(key, value interface{}) bool {
+		s.monthsBilling.Store(key, value)
+		return true
+	})
+	daysBilling.Range(func(key, value interface{}) bool {
+		s.daysBilling.Store(key, value)
+		return true
+	})
+	return s
+}
+
 // GetBillingMap
 func (s *CostDataBean) GetBillingMap() (*sync.Map, *sync.Map) {
 	return &s.monthsBilling, &s.daysBilling
 }
 
+// GetPrepaidCharges charges since the month, to spread over the bills which are not amortized by the provider
+func (s *CostDataBean) GetPrepaidCharges() (charges []data.PrepaidCharge, since string) {
+	return s.prepaidCharges, s.chargesSince
+}
+
+func (s *CostDataBean) newCostDataReader() *datareader.CostDataReader {
+	return datareader.NewCostDataReader(s.provider).SetCostBasis(s.costBasis)
+}
+
 // getRecent15DaysBilling today is not included
 func (s *CostDataBean) getRecent15DaysBilling(ctx context.Context) error {
 	billingDate := s.bp.GetRecentXDaysBillingDate(15)
@@ -130,7 +196,7 @@ func (s *CostDataBean) getPreviousDayDayBilling(ctx context.Context) error {
 func (s *CostDataBean) getRecentDayBillingWithProduct(ctx context.Context) error {
 	billingDate := s.bp.GetRecentDayBillingDate()
 	day := billingDate.Days[0]
-	costDataReader := datareader.NewCostDataReader(s.provider)
+	costDataReader := s.newCostDataReader()
 	dayBilling, err := costDataReader.GetDailyCost(ctx, day, true)
 	if err != nil {
 		return err
@@ -143,7 +209,7 @@ func (s *CostDataBean) getRecentDayBillingWithProduct(ctx context.Context) error
 // getRecentMonthBillingWithProduct
 func (s *CostDataBean) getRecentMonthBillingWithProduct(ctx context.Context) error {
 	monthBillingDate := s.bp.GetRecentMonthBillingDate(true)
-	costDataReader := datareader.NewCostDataReader(s.provider)
+	costDataReader := s.newCostDataReader()
 	if len(monthBillingDate.Months) != 0 {
 		monthsBilling, err := costDataReader.GetMonthsCost(ctx, true, monthBillingDate.Months...)
 		if err != nil {
@@ -169,6 +235,51 @@ func (s *CostDataBean) getRecentMonthBillingWithProduct(ctx context.Context) err
 	return nil
 }
 
+// getRecentQuarterBillingWithProduct product detail of the months in recent quarter, used by quarterly product budgets
+func (s *CostDataBean) getRecentQuarterBillingWithProduct(ctx context.Context) error {
+	if !s.quarterWithProduct {
+		return nil
+	}
+	quarterBillingDate := s.bp.GetRecentQuarterBillingDate(true)
+	if len(quarterBillingDate.Months) == 0 {
+		return nil
+	}
+	costDataReader := s.newCostDataReader()
+	monthsBilling, err := costDataReader.GetMonthsCost(ctx, true, quarterBillingDate.Months...)
+	if err != nil {
+		return err
+	}
+	for _, v := range monthsBilling {
+		s.monthsBilling.Store(v.Month, v) // cover old data
+	}
+	log.Printf("I! getRecentQuarterBillingWithProduct done")
+	return nil
+}
+
+// getRecentXDaysBillingWithProduct skip the days which are already grouped by product
+func (s *CostDataBean) getRecentXDaysBillingWithProduct(ctx context.Context) error {
+	if s.recentDaysWithProduct <= 0 {
+		return nil
+	}
+	var days []string
+	for _, d := range s.bp.GetRecentXDaysBillingDate(s.recentDaysWithProduct).Days {
+		if val, ok := s.daysBilling.Load(d); ok && data.IsGroupedByProduct(val.(data.DailyBilling).ProductsBilling) {
+			continue
+		}
+		days = append(days, d)
+	}
+	costDataReader := s.newCostDataReader()
+	daysBilling, err := costDataReader.GetDaysCost(ctx, true, days...)
+	if err != nil {
+		return err
+	}
+	for _, v := range daysBilling {
+		s.daysBilling.Store(v.Day, v) // cover old data
+	}
+	log.Printf("I! getRecentXDaysBillingWithProduct done")
+	return nil
+}
+
 // getRecentQuarterBilling
 func (s *CostDataBean) getRecentQuarterBilling(ctx context.Context) error {
 	billingDate := s.bp.GetRecentQuarterBillingDate(true)
@@ -201,6 +312,14 @@ func (s *CostDataBean) getPreviousMouthBilling(ctx context.Context) error {
 	return nil
 }
 
+// getMonthToDateBilling the days of last month are out of the recent days late in the month
+func (s *CostDataBean) getMonthToDateBilling(ctx context.Context) error {
+	if !s.monthToDate {
+		return nil
+	}
+	return s.AddBillingDate(ctx, s.bp.GetMonthToDateBillingDate())
+}
+
 // AddBillingDate
 func (s *CostDataBean) AddBillingDate(ctx context.Context, billingDate tools.BillingDate) error {
 	s.billingDate.Months = tools.Union(s.billingDate.Months, billingDate.Months)
@@ -211,7 +330,7 @@ func (s *CostDataBean) AddBillingDate(ctx context.Context, billingDate tools.Bil
 // FillBillings
 func (s *CostDataBean) FillBillings(ctx context.Context) error {
 	b := s.billingDate
-	costDataReader := datareader.NewCostDataReader(s.provider)
+	costDataReader := s.newCostDataReader()
 	var months, days []string
 	for _, v := range b.Months {
 		if _, ok := s.monthsBilling.Load(v); !ok { // skip if key exist
@@ -247,8 +366,101 @@ func (s *CostDataBean) FillBillings(ctx context.Context) error {
 	return nil
 }
 
+// fetchAmortizedBillings probe the provider with the recent month and day, re-fetch all of them grouped by product if supported
+func (s *CostDataBean) fetchAmortizedBillings(ctx context.Context) error {
+	costDataReader := s.newCostDataReader().SetAmortized(true)
+	var months, days []string
+	s.monthsBilling.Range(func(key, value interface{}) bool {
+		months = append(months, key.(string))
+		return true
+	})
+	s.daysBilling.Range(func(key, value interface{}) bool {
+		days = append(days, key.(string))
+		return true
+	})
+	sort.Strings(months)
+	sort.Strings(days)
+
+	if len(months) != 0 {
+		probe, err := costDataReader.GetMonthlyCost(ctx, months[len(months)-1], true)
+		if err != nil {
+			return err
+		}
+		if probe.Amortized {
+			monthsBilling, err := costDataReader.GetMonthsCost(ctx, true, months...)
+			if err != nil {
+				return err
+			}
+			for _, v := range monthsBilling {
+				s.monthsBilling.Store(v.Month, v) // cover cash data
+			}
+		}
+	}
+	if len(days) != 0 {
+		probe, err := costDataReader.GetDailyCost(ctx, days[len(days)-1], true)
+		if err != nil {
+			return err
+		}
+		if probe.Amortized {
+			daysBilling, err := costDataReader.GetDaysCost(ctx, true, days...)
+			if err != nil {
+				return err
+			}
+			for _, v := range daysBilling {
+				s.daysBilling.Store(v.Day, v) // cover cash data
+			}
+		}
+	}
+	log.Printf("I! fetchAmortizedBillings done")
+	return nil
+}
+
+// loadPrepaidCharges charges of the recent lookback months, skipped if the provider amortizes all bills
+func (s *CostDataBean) loadPrepaidCharges(ctx context.Context) error {
+	amortized := true
+	check := func(key, value interface{}) bool {
+		switch v := value.(type) {
+		case data.MonthlyBilling:
+			amortized = v.Amortized
+		case data.DailyBilling:
+			amortized = v.Amortized
+		}
+		return amortized
+	}
+	s.monthsBilling.Range(check)
+	if amortized {
+		s.daysBilling.Range(check)
+	}
+	if amortized {
+		return nil
+	}
+	if !providers.Supports(s.provider, providers.CapabilityInstanceBills) {
+		s.addUnsupported(providers.CapabilityInstanceBills, data.AnalysisAmortization)
+		log.Printf("I! loadPrepaidCharges skipped, provider[%s] does not support instance bills", s.cloudAccount.Provider)
+		return nil
+	}
+	recentMonth, _ := time.ParseInLocation("2006-01", s.bp.GetRecentMonth(), time.Local)
+	months := make([]string, 0, s.lookbackMonths)
+	for i := int(s.lookbackMonths) - 1; i >= 0; i-- {
+		months = append(months, tools.AddDate(recentMonth, 0, -i, 0).Format("2006-01"))
+	}
+	charges, err := s.newCostDataReader().GetPrepaidCharges(ctx, months...)
+	if err != nil {
+		return err
+	}
+	s.prepaidCharges, s.chargesSince = charges, months[0]
+	log.Printf("I! loadPrepaidCharges done")
+	return nil
+}
+
 // GetCostAnalysisPipeLine
 func (s *CostDataBean) GetCostAnalysisPipeLine() []func(context.Context) error {
+	if s.amortized {
+		return []func(context.Context) error{
+			s.fetchAmortizedBillings,
+			s.loadPrepaidCharges,
+		}
+	}
 	return []func(context.Context) error{
 		s.getRecent24MonthsBilling,
 		s.getRecentYearMonthsBilling,
@@ -260,15 +472,39 @@ func (s *CostDataBean) GetCostAnalysisPipeLine() []func(context.Context) error {
 		s.getPreviousMouthBilling,
 		s.getRecentDayBilling,
 		s.getPreviousDayDayBilling,
+		s.getMonthToDateBilling,
 		s.FillBillings,
 		//
 		s.getRecentDayBillingWithProduct,
 		s.getRecentMonthBillingWithProduct,
+		s.getRecentQuarterBillingWithProduct,
+		s.getRecentXDaysBillingWithProduct,
 	}
 }
 
-// RunPipeline
+// GetUnsupported the data left out as the provider lacks the capabilities
+func (s *CostDataBean) GetUnsupported() []data.Unsupported {
+	return s.unsupported
+}
+
+func (s *CostDataBean) addUnsupported(c providers.Capability, analysis string) {
+	s.unsupported = append(s.unsupported, data.Unsupported{
+		AccountName: s.cloudAccount.Name,
+		Provider:    s.cloudAccount.Provider,
+		Capability:  string(c),
+		Analysis:    analysis,
+	})
+}
+
+// RunPipeline a providers.NotSupportedError if the provider has no bills
 func (s *CostDataBean) RunPipeline(ctx context.Context) error {
+	if s.provider == nil {
+		return fmt.Errorf("provider[%s] of %s is not initialized", s.cloudAccount.Provider, s.cloudAccount.Name)
+	}
+	if !providers.Supports(s.provider, providers.CapabilityBilling) {
+		s.addUnsupported(providers.CapabilityBilling, data.AnalysisCost)
+		return providers.NotSupported(s.provider, providers.CapabilityBilling)
+	}
 	var err error
 	for _, f := range s.GetCostAnalysisPipeLine() {
 		err = f(ctx)
@@ -5,18 +5,22 @@ import (
 	"fmt"
 	"log"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/galaxy-future/costpilot/internal/constants/cloud"
 	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/i18n"
 	"github.com/galaxy-future/costpilot/internal/providers"
+	providerTypes "github.com/galaxy-future/costpilot/internal/providers/types"
 	"github.com/galaxy-future/costpilot/internal/services/datareader"
 	"github.com/galaxy-future/costpilot/internal/types"
 	"github.com/galaxy-future/costpilot/tools"
 	"github.com/pkg/errors"
 )
 
+var _getRegionProvider = providers.GetProvider
+
 type UtilizationDataBean struct {
 	cloudAccount types.CloudAccount
 	provider     providers.Provider
@@ -27,10 +31,20 @@ type UtilizationDataBean struct {
 	regionInstancesMap map[string][]data.InstanceDetail // k->v: regionId->[]data.InstanceDetail
 	allInstancesMap    map[string]data.InstanceDetail   // k->v: instanceId->[]data.InstanceDetail
 
-	dailyCpu    sync.Map // 2022-01-02 -> data.DailyCpuUtilization
-	dailyMemory sync.Map // 2022-01-02 -> data.DailyCpuUtilization
+	dailyCpu     sync.Map                               // 2022-01-02 -> data.DailyCpuUtilization
+	dailyMemory  sync.Map                               // 2022-01-02 -> data.DailyCpuUtilization
+	dailyMetrics map[providerTypes.MetricItem]*sync.Map // metric -> 2022-01-02 -> data.DailyMetric
 
 	recentInstancesMap sync.Map // providerType+instanceId -> data.InstanceDetail
+	instanceBills      sync.Map // instanceId -> data.InstanceBill
+	commitments        data.Commitments
+	regionScan         data.RegionScan
+	unsupported        []data.Unsupported
+
+	recentDays         int32 // extra days of utilization, 0 means none
+	fetchInstanceBills bool
+	commitmentDays     int32                      // days of commitment coverage, 0 means none
+	metrics            []providerTypes.MetricItem // the catalog metrics besides cpu and memory
 
 	bp *tools.BillingDatePilot
 
@@ -44,6 +58,7 @@ func NewUtilization(a types.CloudAccount, t time.Time) *UtilizationDataBean {
 		bp:                 tools.NewBillDatePilot().SetNowT(t),
 		regionInstancesMap: make(map[string][]data.InstanceDetail),
 		allInstancesMap:    make(map[string]data.InstanceDetail),
+		dailyMetrics:       make(map[providerTypes.MetricItem]*sync.Map),
 	}
 	s.initProvider(a)
 	s.initDataReader()
@@ -53,26 +68,21 @@ func NewUtilization(a types.CloudAccount, t time.Time) *UtilizationDataBean {
 // initProvider
 func (s *UtilizationDataBean) initProvider(a types.CloudAccount) *UtilizationDataBean {
 	var err error
-	s.provider, err = providers.GetProvider(a.Provider, a.AK, a.SK, a.RegionID)
+	s.provider, err = providers.GetProvider(a.Provider, a.Credential(), a.RegionID)
 	if err != nil {
 		log.Printf("E! init provider failed: %v\n", err)
 	}
 	return s
 }
 
-// newRegionProvider create provider by new region
+// newRegionProvider create provider by new region, some sdks panic on the regions they do not support
 func (s *UtilizationDataBean) newRegionProvider(regionId string) (p providers.Provider, err error) {
 	defer func() {
 		if r := recover(); r != nil {
-			ep := &err
-			*ep = errors.New(fmt.Sprintf("panic occur, regionid[%s] not support. skip", regionId))
+			err = fmt.Errorf("region %s is not supported by the sdk: %v", regionId, r)
 		}
 	}()
-	p, err = providers.GetProvider(s.cloudAccount.Provider, s.cloudAccount.AK, s.cloudAccount.SK, regionId)
-	if err != nil {
-		log.Printf("E! newRegionProvider failed: %v\n", err)
-	}
-	return p, err
+	return _getRegionProvider(s.cloudAccount.Provider, s.cloudAccount.Credential(), regionId)
 }
 
 func (s *UtilizationDataBean) initDataReader() {
@@ -90,31 +100,86 @@ func (s *UtilizationDataBean) loadRegionMap(ctx context.Context) error {
 	return nil
 }
 
-func (s *UtilizationDataBean) getAllInstances(ctx context.Context) error {
-	for regionId, _ := range s.regionMap {
-		p, err := s.newRegionProvider(regionId)
-		if err != nil {
-			log.Printf("W! newRegionProvider for %s failed, %v", regionId, err)
-			continue
+// scanRegions the regions of the region map allowed by the account, the others are skipped
+func (s *UtilizationDataBean) scanRegions() []string {
+	var regions []string
+	for regionId := range s.regionMap {
+		if s.cloudAccount.IsRegionIncluded(regionId) {
+			regions = append(regions, regionId)
+		} else {
+			s.regionScan.Skipped = append(s.regionScan.Skipped, data.RegionSkip{RegionId: regionId, Reason: "excluded by regions/exclude_regions"})
 		}
-		instanceList, err := s.dataReader.GetInstanceByRegionProvider(ctx, p, regionId)
-		if err != nil {
-			log.Printf("E! getAllInstances.GetInstanceByZones:%v", err)
+	}
+	for _, r := range s.cloudAccount.Regions {
+		if _, ok := s.regionMap[r]; !ok && !strings.ContainsAny(r, "*?[") {
+			s.regionScan.Skipped = append(s.regionScan.Skipped, data.RegionSkip{RegionId: r, Reason: "not available to the account"})
 		}
-		if len(instanceList) == 0 {
-			continue
+	}
+	sort.Strings(regions)
+	return regions
+}
+
+// scanRegion the instances of one region
+func (s *UtilizationDataBean) scanRegion(ctx context.Context, regionId string) (instanceList []data.InstanceDetail, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
 		}
-		s.regionInstancesMap[regionId] = instanceList
-		for _, detail := range instanceList {
-			regionName, yes := s.regionMap[detail.RegionId]
-			if !yes {
-				regionName = "未知"
+	}()
+	p, err := s.newRegionProvider(regionId)
+	if err != nil {
+		return nil, err
+	}
+	return s.dataReader.GetInstanceByRegionProvider(ctx, p, regionId)
+}
+
+// getAllInstances scan the allowed regions concurrently, up to region_concurrency at a time
+func (s *UtilizationDataBean) getAllInstances(ctx context.Context) error {
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, s.cloudAccount.GetRegionConcurrency())
+	)
+	for _, regionId := range s.scanRegions() {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(regionId string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			instanceList, err := s.scanRegion(ctx, regionId)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				log.Printf("W! scan region %s of %s failed, %v", regionId, s.cloudAccount.Name, err)
+				s.regionScan.Failed = append(s.regionScan.Failed, data.RegionSkip{RegionId: regionId, Reason: err.Error()})
+				return
 			}
-			detail.RegionName = regionName
-			s.allInstancesMap[detail.InstanceId] = detail
-		}
+			s.regionScan.Scanned = append(s.regionScan.Scanned, regionId)
+			if len(instanceList) == 0 {
+				return
+			}
+			s.regionInstancesMap[regionId] = instanceList
+			for _, detail := range instanceList {
+				regionName, yes := s.regionMap[detail.RegionId]
+				if !yes {
+					regionName = i18n.T("region.unknown")
+				}
+				detail.RegionName = regionName
+				s.allInstancesMap[detail.InstanceId] = detail
+			}
+		}(regionId)
+	}
+	wg.Wait()
+
+	r := &s.regionScan
+	sort.Strings(r.Scanned)
+	for _, skips := range [][]data.RegionSkip{r.Skipped, r.Failed} {
+		sort.Slice(skips, func(i, j int) bool { return skips[i].RegionId < skips[j].RegionId })
 	}
-	log.Printf("I! getAllInstances success,len=%d", len(s.allInstancesMap))
+	log.Printf("I! getAllInstances success,len=%d, regions scanned=%d skipped=%d failed=%d",
+		len(s.allInstancesMap), len(r.Scanned), len(r.Skipped), len(r.Failed))
 	return nil
 }
 
@@ -143,6 +208,59 @@ func (s *UtilizationDataBean) getRecent14DaysDate(ctx context.Context) error {
 	return nil
 }
 
+// getRecentXDaysDate today is not included
+func (s *UtilizationDataBean) getRecentXDaysDate(ctx context.Context) error {
+	dateList := s.bp.GetRecentXDaysBillingDate(s.recentDays)
+	if err := s.AddDate(ctx, dateList); err != nil {
+		return err
+	}
+	return nil
+}
+
+// SetRecentDays fetch utilization of the recent n days besides the recent 14 days, the largest n wins
+func (s *UtilizationDataBean) SetRecentDays(n int32) *UtilizationDataBean {
+	if n > s.recentDays {
+		s.recentDays = n
+	}
+	return s
+}
+
+// SetFetchInstanceBills fetch the instance bills of the recent month
+func (s *UtilizationDataBean) SetFetchInstanceBills(yes bool) *UtilizationDataBean {
+	s.fetchInstanceBills = yes
+	return s
+}
+
+// SetCommitmentDays fetch the commitment coverage and utilization of the recent n days
+func (s *UtilizationDataBean) SetCommitmentDays(n int32) *UtilizationDataBean {
+	s.commitmentDays = n
+	return s
+}
+
+// SetMetrics fetch the catalog metrics of the recent 14 days besides cpu and memory, which are always fetched
+func (s *UtilizationDataBean) SetMetrics(metrics ...providerTypes.MetricItem) *UtilizationDataBean {
+	s.metrics = nil
+	for _, m := range metrics {
+		if m == providerTypes.MetricItemCPUUtilization || m == providerTypes.MetricItemMemoryUsedUtilization {
+			continue
+		}
+		if _, ok := providerTypes.LookupMetric(m); !ok || containsMetric(s.metrics, m) {
+			continue
+		}
+		s.metrics = append(s.metrics, m)
+	}
+	return s
+}
+
+func containsMetric(metrics []providerTypes.MetricItem, m providerTypes.MetricItem) bool {
+	for _, v := range metrics {
+		if v == m {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *UtilizationDataBean) AddDate(_ context.Context, dateList tools.BillingDate) error {
 	s.dateRange.Days = tools.Union(s.dateRange.Days, dateList.Days)
 	return nil
@@ -288,6 +406,72 @@ func (s *UtilizationDataBean) fetchMemoryUtilizationByInstanceIds(ctx context.Co
 	return nil
 }
 
+// fetchMetrics 监控指标获取失败不影响利用率分析, the metrics the provider does not map are recorded
+func (s *UtilizationDataBean) fetchMetrics(ctx context.Context) error {
+	r, _ := providers.Lookup(s.cloudAccount.Provider)
+	var metrics []providerTypes.MetricItem
+	for _, m := range s.metrics {
+		if _, ok := r.Metrics[m]; !ok {
+			s.unsupported = append(s.unsupported, data.Unsupported{
+				AccountName: s.cloudAccount.Name,
+				Provider:    s.cloudAccount.Provider,
+				Capability:  string(m),
+				Analysis:    data.AnalysisMetric,
+			})
+			continue
+		}
+		s.dailyMetrics[m] = &sync.Map{}
+		metrics = append(metrics, m)
+	}
+	days := s.bp.GetRecentXDaysBillingDate(14).Days
+	log.Printf("I! fetchMetrics %v days: %v", metrics, days)
+
+	if r.Capabilities.AccountMetrics {
+		for _, m := range metrics {
+			metricData, err := s.dataReader.GetDaysMetric(ctx, m, nil, []string{}, days...)
+			if err != nil {
+				log.Printf("W! fetchMetrics %s:%v", m, err)
+				continue
+			}
+			storeDailyMetrics(s.dailyMetrics[m], metricData)
+		}
+		return nil
+	}
+	for regionId, instanceList := range s.regionInstancesMap {
+		var ids = make([]string, 0, len(instanceList))
+		for _, i := range instanceList {
+			ids = append(ids, i.InstanceId)
+		}
+		p, err := s.newRegionProvider(regionId)
+		if err != nil {
+			log.Printf("W! newRegionProvider for %s, %v", regionId, err)
+			continue
+		}
+		for _, m := range metrics {
+			metricData, err := s.dataReader.GetDaysMetric(ctx, m, p, ids, days...)
+			if err != nil {
+				log.Printf("W! fetchMetrics %s of %s:%v", m, regionId, err)
+				continue
+			}
+			storeDailyMetrics(s.dailyMetrics[m], metricData)
+		}
+	}
+	return nil
+}
+
+// storeDailyMetrics the samples of the same day are merged, eg: of the regions
+func storeDailyMetrics(daily *sync.Map, metricData []data.DailyMetric) {
+	for _, v := range metricData {
+		if d, ok := daily.Load(v.Day); ok {
+			metricDay := d.(data.DailyMetric)
+			metricDay.Samples = append(metricDay.Samples, v.Samples...)
+			daily.Store(v.Day, metricDay)
+			continue
+		}
+		daily.Store(v.Day, v)
+	}
+}
+
 func (s *UtilizationDataBean) fetchRecentInstanceList(ctx context.Context) error {
 	d := s.bp.GetRecentDayBillingDate()
 	recentDay := d.Days[0]
@@ -318,7 +502,7 @@ func (s *UtilizationDataBean) fetchRecentInstanceList(ctx context.Context) error
 		var regionName string
 		regionName, ok = s.regionMap[detail.RegionId]
 		if !ok {
-			regionName = "未知"
+			regionName = i18n.T("region.unknown")
 		}
 		detail.RegionName = regionName
 		s.recentInstancesMap.Store(k, detail)
@@ -346,6 +530,43 @@ func (s *UtilizationDataBean) getRecentInstanceListFromLocal(ctx context.Context
 	return nil
 }
 
+// loadInstanceBills 实例账单获取失败不影响利用率分析
+func (s *UtilizationDataBean) loadInstanceBills(ctx context.Context) error {
+	bills, err := s.dataReader.GetInstanceBills(ctx, s.bp.GetRecentMonth())
+	if providers.IsNotSupported(err) {
+		s.addUnsupported(providers.CapabilityInstanceBills, data.AnalysisInstanceCost)
+		return nil
+	}
+	if err != nil {
+		log.Printf("W! loadInstanceBills:%v", err)
+		return nil
+	}
+	for instanceId, bill := range bills {
+		s.instanceBills.Store(instanceId, bill)
+	}
+	log.Printf("I! loadInstanceBills success,len=%d", len(bills))
+	return nil
+}
+
+// loadCommitments 承诺使用数据获取失败不影响利用率分析
+func (s *UtilizationDataBean) loadCommitments(ctx context.Context) error {
+	nowT := s.bp.GetNowT()
+	end := time.Date(nowT.Year(), nowT.Month(), nowT.Day(), 0, 0, 0, 0, nowT.Location())
+	start := end.AddDate(0, 0, -int(s.commitmentDays))
+	commitments, err := s.dataReader.GetCommitments(ctx, start.Format("2006-01-02"), end.Format("2006-01-02"))
+	if providers.IsNotSupported(err) {
+		s.addUnsupported(providers.CapabilityCommitments, data.AnalysisCommitment)
+		return nil
+	}
+	if err != nil {
+		log.Printf("W! loadCommitments:%v", err)
+		return nil
+	}
+	s.commitments = commitments
+	log.Printf("I! loadCommitments success,len=%d", len(commitments.List))
+	return nil
+}
+
 func (s *UtilizationDataBean) GetUtilizationAnalysisPipeLine() []func(context.Context) error {
 	var pipeLine []func(context.Context) error
 
@@ -354,7 +575,8 @@ func (s *UtilizationDataBean) GetUtilizationAnalysisPipeLine() []func(context.Co
 	)
 
 	// 有的厂商要先去拉取所有实例，然后才能去抓监控数据
-	if s.provider.ProviderType() != cloud.AlibabaCloud {
+	r, _ := providers.Lookup(s.cloudAccount.Provider)
+	if !r.Capabilities.AccountMetrics {
 		pipeLine = append(pipeLine, s.getAllInstances)
 	}
 
@@ -364,8 +586,11 @@ func (s *UtilizationDataBean) GetUtilizationAnalysisPipeLine() []func(context.Co
 		s.getPreviousDay,
 		s.getRecent14DaysDate,
 	)
+	if s.recentDays > 0 {
+		pipeLine = append(pipeLine, s.getRecentXDaysDate)
+	}
 
-	if s.provider.ProviderType() == cloud.AlibabaCloud {
+	if r.Capabilities.AccountMetrics {
 		pipeLine = append(pipeLine, s.fetchCpuUtilization, s.fetchMemoryUtilization, s.fetchRecentInstanceList)
 	} else {
 		pipeLine = append(pipeLine,
@@ -374,11 +599,53 @@ func (s *UtilizationDataBean) GetUtilizationAnalysisPipeLine() []func(context.Co
 			s.getRecentInstanceListFromLocal,
 		)
 	}
+	if len(s.metrics) > 0 {
+		pipeLine = append(pipeLine, s.fetchMetrics)
+	}
+	if s.fetchInstanceBills {
+		pipeLine = append(pipeLine, s.loadInstanceBills)
+	}
+	if s.commitmentDays > 0 {
+		pipeLine = append(pipeLine, s.loadCommitments)
+	}
 
 	return pipeLine
 }
 
+// requiredCapabilities the utilization of the account is not analyzed without any of them
+func (s *UtilizationDataBean) requiredCapabilities() []providers.Capability {
+	if r, _ := providers.Lookup(s.cloudAccount.Provider); r.Capabilities.AccountMetrics {
+		return []providers.Capability{providers.CapabilityMetrics, providers.CapabilityRegions, providers.CapabilitySubscriptions}
+	}
+	return []providers.Capability{providers.CapabilityMetrics, providers.CapabilityRegions, providers.CapabilityInventory}
+}
+
+func (s *UtilizationDataBean) addUnsupported(c providers.Capability, analysis string) {
+	s.unsupported = append(s.unsupported, data.Unsupported{
+		AccountName: s.cloudAccount.Name,
+		Provider:    s.cloudAccount.Provider,
+		Capability:  string(c),
+		Analysis:    analysis,
+	})
+}
+
+// RunPipeline a providers.NotSupportedError if a required capability is missing, the optional ones are only recorded
 func (s *UtilizationDataBean) RunPipeline(ctx context.Context) error {
+	if s.provider == nil {
+		return fmt.Errorf("provider[%s] of %s is not initialized", s.cloudAccount.Provider, s.cloudAccount.Name)
+	}
+	var missing error
+	for _, c := range s.requiredCapabilities() {
+		if !providers.Supports(s.provider, c) {
+			s.addUnsupported(c, data.AnalysisUtilization)
+			if missing == nil {
+				missing = providers.NotSupported(s.provider, c)
+			}
+		}
+	}
+	if missing != nil {
+		return missing
+	}
 	var err error
 	for _, f := range s.GetUtilizationAnalysisPipeLine() {
 		err = f(ctx)
@@ -392,3 +659,25 @@ func (s *UtilizationDataBean) RunPipeline(ctx context.Context) error {
 func (s *UtilizationDataBean) GetUtilizationMap() (*sync.Map, *sync.Map, *sync.Map) {
 	return &s.dailyCpu, &s.dailyMemory, &s.recentInstancesMap
 }
+
+// GetMetricMap metric -> day -> data.DailyMetric, of the metrics set and mapped by the provider
+func (s *UtilizationDataBean) GetMetricMap() map[providerTypes.MetricItem]*sync.Map {
+	return s.dailyMetrics
+}
+
+func (s *UtilizationDataBean) GetInstanceBillMap() *sync.Map {
+	return &s.instanceBills
+}
+
+func (s *UtilizationDataBean) GetCommitments() data.Commitments {
+	return s.commitments
+}
+
+func (s *UtilizationDataBean) GetRegionScan() data.RegionScan {
+	return s.regionScan
+}
+
+// GetUnsupported the data left out as the provider lacks the capabilities
+func (s *UtilizationDataBean) GetUnsupported() []data.Unsupported {
+	return s.unsupported
+}
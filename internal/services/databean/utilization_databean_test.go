@@ -2,9 +2,17 @@ package databean
 
 import (
 	"context"
+	"errors"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/providers"
+	providerTypes "github.com/galaxy-future/costpilot/internal/providers/types"
+	"github.com/galaxy-future/costpilot/internal/services/datareader"
+	cloudTypes "github.com/galaxy-future/costpilot/internal/types"
 	"github.com/galaxy-future/costpilot/tools"
 	"github.com/stretchr/testify/assert"
 )
@@ -20,3 +28,142 @@ func TestUtilizationDataBean_loadRegionMap(t *testing.T) {
 	assert.NoError(t, s.getRecent14DaysDate(ctx))
 	assert.Equal(t, 14, len(s.dateRange.Days))
 }
+
+type regionProvider struct {
+	providers.Provider
+	region string
+	active *int32
+	peak   *int32
+}
+
+func (p *regionProvider) ProviderType() cloud.Provider {
+	return cloud.AWSCloud
+}
+
+func (p *regionProvider) DescribeInstances(context.Context, providerTypes.DescribeInstancesRequest) (providerTypes.DescribeInstances, error) {
+	n := atomic.AddInt32(p.active, 1)
+	defer atomic.AddInt32(p.active, -1)
+	for {
+		peak := atomic.LoadInt32(p.peak)
+		if n <= peak || atomic.CompareAndSwapInt32(p.peak, peak, n) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	switch p.region {
+	case "us-west-1":
+		return providerTypes.DescribeInstances{}, errors.New("timeout")
+	case "eu-west-1":
+		panic("nil pointer")
+	}
+	return providerTypes.DescribeInstances{List: []providerTypes.ItemDescribeInstance{{InstanceId: "i-" + p.region}}}, nil
+}
+
+func TestUtilizationDataBean_getAllInstances(t *testing.T) {
+	var active, peak int32
+	_getRegionProvider = func(_ cloud.Provider, _ providerTypes.Credential, regionId string) (providers.Provider, error) {
+		if regionId == "ap-east-1" {
+			return nil, errors.New("region ap-east-1 is not enabled")
+		}
+		return &regionProvider{region: regionId, active: &active, peak: &peak}, nil
+	}
+	defer func() { _getRegionProvider = providers.GetProvider }()
+
+	s := &UtilizationDataBean{
+		cloudAccount: cloudTypes.CloudAccount{Provider: cloud.AWSCloud, Name: "aws",
+			Regions: []string{"us-*", "eu-west-1", "ap-east-1", "ca-central-1"}, ExcludeRegions: []string{"us-east-2"}, RegionConcurrency: 2},
+		regionMap: map[string]string{"us-east-1": "", "us-east-2": "", "us-west-1": "", "us-west-2": "", "eu-west-1": "",
+			"ap-east-1": "", "ap-northeast-1": ""},
+		regionInstancesMap: make(map[string][]data.InstanceDetail),
+		allInstancesMap:    make(map[string]data.InstanceDetail),
+	}
+	s.dataReader = datareader.NewUtilization(nil)
+	assert.NoError(t, s.getAllInstances(context.TODO()))
+
+	r := s.GetRegionScan()
+	assert.Equal(t, []string{"us-east-1", "us-west-2"}, r.Scanned)
+	assert.Equal(t, []data.RegionSkip{
+		{RegionId: "ap-northeast-1", Reason: "excluded by regions/exclude_regions"},
+		{RegionId: "ca-central-1", Reason: "not available to the account"},
+		{RegionId: "us-east-2", Reason: "excluded by regions/exclude_regions"},
+	}, r.Skipped)
+	assert.Equal(t, []data.RegionSkip{
+		{RegionId: "ap-east-1", Reason: "region ap-east-1 is not enabled"},
+		{RegionId: "eu-west-1", Reason: "panic: nil pointer"},
+		{RegionId: "us-west-1", Reason: "timeout"},
+	}, r.Failed)
+	assert.Len(t, s.allInstancesMap, 2)
+	assert.LessOrEqual(t, peak, int32(2))
+}
+
+type billingOnlyProvider struct{}
+
+func (billingOnlyProvider) ProviderType() cloud.Provider {
+	return cloud.TencentCloud
+}
+
+func TestUtilizationDataBean_RunPipeline_NotSupported(t *testing.T) {
+	p := billingOnlyProvider{}
+	s := &UtilizationDataBean{
+		cloudAccount: cloudTypes.CloudAccount{Provider: cloud.TencentCloud, Name: "tx"},
+		provider:     p,
+		dataReader:   datareader.NewUtilization(p),
+		bp:           tools.NewBillDatePilot().SetNowT(time.Now()),
+	}
+	err := s.RunPipeline(context.TODO())
+	assert.True(t, providers.IsNotSupported(err))
+	assert.EqualError(t, err, "provider[TencentCloud] does not support metrics")
+
+	assert.NoError(t, s.loadCommitments(context.TODO()))
+	unsupported := func(c providers.Capability, analysis string) data.Unsupported {
+		return data.Unsupported{AccountName: "tx", Provider: cloud.TencentCloud, Capability: string(c), Analysis: analysis}
+	}
+	assert.Equal(t, []data.Unsupported{
+		unsupported(providers.CapabilityMetrics, data.AnalysisUtilization),
+		unsupported(providers.CapabilityRegions, data.AnalysisUtilization),
+		unsupported(providers.CapabilityInventory, data.AnalysisUtilization),
+		unsupported(providers.CapabilityCommitments, data.AnalysisCommitment),
+	}, s.GetUnsupported())
+}
+
+type metricsProvider struct{}
+
+func (metricsProvider) ProviderType() cloud.Provider {
+	return cloud.AWSCloud
+}
+
+func (metricsProvider) DescribeMetricList(_ context.Context, r providerTypes.DescribeMetricListRequest) (providerTypes.DescribeMetricList, error) {
+	var ret providerTypes.DescribeMetricList
+	for _, id := range r.Filter.InstanceIds {
+		ret.List = append(ret.List,
+			providerTypes.MetricSample{InstanceId: id, Average: 10, Max: 30},
+			providerTypes.MetricSample{InstanceId: id, Average: 20, Max: 40})
+	}
+	return ret, nil
+}
+
+func TestUtilizationDataBean_fetchMetrics(t *testing.T) {
+	_getRegionProvider = func(cloud.Provider, providerTypes.Credential, string) (providers.Provider, error) {
+		return metricsProvider{}, nil
+	}
+	defer func() { _getRegionProvider = providers.GetProvider }()
+
+	nowT := time.Date(2022, 10, 15, 8, 0, 0, 0, time.Local)
+	s := NewUtilization(cloudTypes.CloudAccount{Provider: cloud.AWSCloud, Name: "aws"}, nowT)
+	s.SetMetrics(providerTypes.MetricItemCPUUtilization, providerTypes.MetricItemDiskReadIOPS, providerTypes.MetricItemLoadAverage,
+		providerTypes.MetricItemDiskReadIOPS, "gpu.utilization")
+	assert.Equal(t, []providerTypes.MetricItem{providerTypes.MetricItemDiskReadIOPS, providerTypes.MetricItemLoadAverage}, s.metrics)
+
+	s.regionInstancesMap = map[string][]data.InstanceDetail{"us-east-1": {{InstanceId: "i-1"}}, "us-west-2": {{InstanceId: "i-2"}}}
+	assert.NoError(t, s.fetchMetrics(context.TODO()))
+
+	assert.Equal(t, []data.Unsupported{{AccountName: "aws", Provider: cloud.AWSCloud, Capability: "load.average", Analysis: data.AnalysisMetric}},
+		s.GetUnsupported())
+	metrics := s.GetMetricMap()
+	assert.Len(t, metrics, 1)
+	val, ok := metrics[providerTypes.MetricItemDiskReadIOPS].Load("2022-10-14")
+	assert.True(t, ok)
+	day := val.(data.DailyMetric)
+	assert.Equal(t, providerTypes.MetricItemDiskReadIOPS, day.Metric)
+	assert.ElementsMatch(t, []data.InstanceMetric{{InstanceId: "i-1", Average: 15, Max: 40}, {InstanceId: "i-2", Average: 15, Max: 40}}, day.Samples)
+}
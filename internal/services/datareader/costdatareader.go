@@ -5,6 +5,7 @@ import (
 	"log"
 	"time"
 
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
 	"github.com/galaxy-future/costpilot/internal/data"
 	"github.com/galaxy-future/costpilot/internal/providers"
 	"github.com/galaxy-future/costpilot/internal/providers/types"
@@ -15,6 +16,9 @@ import (
 
 type CostDataReader struct {
 	_provider providers.Provider
+
+	amortized bool
+	costBasis types.CostBasis
 }
 
 func NewCostDataReader(p providers.Provider) *CostDataReader {
@@ -23,6 +27,18 @@ func NewCostDataReader(p providers.Provider) *CostDataReader {
 	}
 }
 
+// SetAmortized request the amortized cost, the provider may not support it, see data.DailyBilling.Amortized
+func (s *CostDataReader) SetAmortized(yes bool) *CostDataReader {
+	s.amortized = yes
+	return s
+}
+
+// SetCostBasis which amount of the bills to read, default types.CostBasisDiscount
+func (s *CostDataReader) SetCostBasis(basis types.CostBasis) *CostDataReader {
+	s.costBasis = basis
+	return s
+}
+
 // GetDailyCost
 // date 2022-09-06 | isGroupByProduct true/false
 func (s *CostDataReader) GetDailyCost(ctx context.Context, day string, isGroupByProduct bool) (data.DailyBilling, error) {
@@ -35,8 +51,14 @@ func (s *CostDataReader) GetDailyCost(ctx context.Context, day string, isGroupBy
 		BillingDate:      day,
 		IsGroupByProduct: isGroupByProduct,
 		Granularity:      types.Daily,
+		IsAmortized:      s.amortized,
+		CostBasis:        s.costBasis,
+	}
+	billing, ok := s._provider.(providers.BillingProvider)
+	if !ok {
+		return data.DailyBilling{}, providers.NotSupported(s._provider, providers.CapabilityBilling)
 	}
-	resp, err := s._provider.QueryAccountBill(ctx, params)
+	resp, err := billing.QueryAccountBill(ctx, params)
 	if err != nil {
 		log.Printf("E! [D] QueryAccountBill error[%v]\n", err)
 		return data.DailyBilling{}, err
@@ -44,6 +66,7 @@ func (s *CostDataReader) GetDailyCost(ctx context.Context, day string, isGroupBy
 	result := data.DailyBilling{
 		Day:             day,
 		ProductsBilling: make(map[string]data.ProductBilling, 0),
+		Amortized:       resp.IsAmortized,
 	}
 	for _, d := range resp.Items.Item {
 		result.TotalAmount = tools.Float64Add(result.TotalAmount, cast.ToFloat64(d.PretaxAmount))
@@ -120,8 +143,14 @@ func (s *CostDataReader) GetMonthlyCost(ctx context.Context, month string, isGro
 		BillingCycle:     month,
 		IsGroupByProduct: isGroupByProduct,
 		Granularity:      types.Monthly,
+		IsAmortized:      s.amortized,
+		CostBasis:        s.costBasis,
 	}
-	resp, err := s._provider.QueryAccountBill(ctx, params)
+	billing, ok := s._provider.(providers.BillingProvider)
+	if !ok {
+		return data.MonthlyBilling{}, providers.NotSupported(s._provider, providers.CapabilityBilling)
+	}
+	resp, err := billing.QueryAccountBill(ctx, params)
 	if err != nil {
 		log.Printf("E! [M] QueryAccountBill error[%v]\n", err)
 		return data.MonthlyBilling{}, err
@@ -129,6 +158,7 @@ func (s *CostDataReader) GetMonthlyCost(ctx context.Context, month string, isGro
 	result := data.MonthlyBilling{
 		Month:           month,
 		ProductsBilling: make(map[string]data.ProductBilling, 0),
+		Amortized:       resp.IsAmortized,
 	}
 	for _, d := range resp.Items.Item {
 		result.TotalAmount = tools.Float64Add(result.TotalAmount, cast.ToFloat64(d.PretaxAmount))
@@ -192,3 +222,62 @@ func (s *CostDataReader) GetMonthsCost(ctx context.Context, isGroupByProduct boo
 	log.Printf("get GetMonthsCost[%v] done \n", months)
 	return result, nil
 }
+
+// GetPrepaidCharges subscription charges of the months, with the end day of the service bought
+// month 2022-09
+func (s *CostDataReader) GetPrepaidCharges(ctx context.Context, months ...string) ([]data.PrepaidCharge, error) {
+	var result []data.PrepaidCharge
+	instanceBill, ok := s._provider.(providers.InstanceBillProvider)
+	if !ok {
+		return nil, providers.NotSupported(s._provider, providers.CapabilityInstanceBills)
+	}
+	for _, month := range months {
+		resp, err := instanceBill.DescribeInstanceBill(ctx, types.DescribeInstanceBillRequest{
+			BillingCycle:     month,
+			Granularity:      types.Daily,
+			SubscriptionType: cloud.PrePaid,
+		}, true)
+		if err != nil {
+			log.Printf("E! [M] DescribeInstanceBill error[%v]\n", err)
+			return nil, err
+		}
+		for _, item := range resp.Items {
+			if item.SubscriptionType != cloud.PrePaid || item.PretaxAmount == 0 {
+				continue
+			}
+			day, err := time.ParseInLocation("2006-01-02", item.BillingDate, time.Local)
+			if err != nil {
+				continue
+			}
+			end := servicePeriodEnd(day, item.ServicePeriod, item.ServicePeriodUnit)
+			if !end.After(day.AddDate(0, 0, 1)) { // nothing to spread
+				continue
+			}
+			result = append(result, data.PrepaidCharge{
+				InstanceId:  item.InstanceId,
+				PipCode:     item.PipCode.String(),
+				ProductName: item.ProductName,
+				Currency:    item.Currency,
+				Amount:      item.PretaxAmount,
+				Day:         item.BillingDate,
+				EndDay:      end.Format("2006-01-02"),
+			})
+		}
+	}
+	log.Printf("I! GetPrepaidCharges[%v] done, len=%d\n", months, len(result))
+	return result, nil
+}
+
+func servicePeriodEnd(start time.Time, period int, unit types.ServicePeriodUnit) time.Time {
+	switch unit {
+	case types.ServicePeriodYear:
+		return start.AddDate(period, 0, 0)
+	case types.ServicePeriodMonth:
+		return start.AddDate(0, period, 0)
+	case types.ServicePeriodDay:
+		return start.AddDate(0, 0, period)
+	case types.ServicePeriodHour:
+		return start.AddDate(0, 0, (period+23)/24)
+	}
+	return start
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/galaxy-future/costpilot/internal/constants/cloud"
 	"github.com/galaxy-future/costpilot/internal/data"
 	"github.com/galaxy-future/costpilot/internal/providers"
+	"github.com/galaxy-future/costpilot/internal/providers/types"
 	jsoniter "github.com/json-iterator/go"
 )
 
@@ -22,7 +23,7 @@ func TestCostDataRader_GetDailyCost(t *testing.T) {
 		date             string
 		isGroupByProduct bool
 	}
-	provider, err := providers.GetProvider(cloud.AlibabaCloud, _AK, _SK, "cn-beijing")
+	provider, err := providers.GetProvider(cloud.AlibabaCloud, types.Credential{AK: _AK, SK: _SK}, "cn-beijing")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -84,7 +85,7 @@ func TestCostDataRader_GetMonthlyCost(t *testing.T) {
 		month            string
 		isGroupByProduct bool
 	}
-	provider, err := providers.GetProvider(cloud.AlibabaCloud, _AK, _SK, "cn-beijing")
+	provider, err := providers.GetProvider(cloud.AlibabaCloud, types.Credential{AK: _AK, SK: _SK}, "cn-beijing")
 	if err != nil {
 		t.Fatal(err)
 	}
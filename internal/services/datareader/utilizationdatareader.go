@@ -3,9 +3,11 @@ package datareader
 import (
 	"context"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/i18n"
 	"github.com/galaxy-future/costpilot/internal/providers"
 	"github.com/galaxy-future/costpilot/internal/providers/types"
 	"github.com/galaxy-future/costpilot/tools"
@@ -39,7 +41,11 @@ func (s *UtilizationDataReader) GetDailyCpuUtilization(ctx context.Context, day
 	if p != nil {
 		provider = p
 	}
-	resp, err := provider.DescribeMetricList(ctx, types.DescribeMetricListRequest{
+	metrics, ok := provider.(providers.MetricsProvider)
+	if !ok {
+		return data.DailyCpuUtilization{}, providers.NotSupported(provider, providers.CapabilityMetrics)
+	}
+	resp, err := metrics.DescribeMetricList(ctx, types.DescribeMetricListRequest{
 		MetricName: types.MetricItemCPUUtilization,
 		Period:     "86400", // 一天
 		StartTime:  startTime,
@@ -59,6 +65,7 @@ func (s *UtilizationDataReader) GetDailyCpuUtilization(ctx context.Context, day
 		result.Utilization = append(result.Utilization, data.InstanceCpuUtilization{
 			InstanceId:      v.InstanceId,
 			UsedUtilization: v.Average,
+			MaxUtilization:  v.Max,
 		})
 	}
 	return result, nil
@@ -115,7 +122,11 @@ func (s *UtilizationDataReader) GetDailyMemoryUtilization(ctx context.Context, p
 	if p != nil {
 		provider = p
 	}
-	resp, err := provider.DescribeMetricList(ctx, types.DescribeMetricListRequest{
+	metrics, ok := provider.(providers.MetricsProvider)
+	if !ok {
+		return data.DailyMemoryUtilization{}, providers.NotSupported(provider, providers.CapabilityMetrics)
+	}
+	resp, err := metrics.DescribeMetricList(ctx, types.DescribeMetricListRequest{
 		MetricName: types.MetricItemMemoryUsedUtilization,
 		Period:     "86400", // 一天
 		StartTime:  startTime,
@@ -137,6 +148,7 @@ func (s *UtilizationDataReader) GetDailyMemoryUtilization(ctx context.Context, p
 		result.Utilization = append(result.Utilization, data.InstanceMemoryUtilization{
 			InstanceId:      v.InstanceId,
 			UsedUtilization: v.Average,
+			MaxUtilization:  v.Max,
 		})
 	}
 	return result, nil
@@ -178,8 +190,110 @@ func (s *UtilizationDataReader) GetDaysMemoryUtilization(ctx context.Context, p
 	return result, nil
 }
 
+// GetDailyMetric a catalog metric of the instances in a day, the samples of an instance are averaged, eg: of its disks
+func (s *UtilizationDataReader) GetDailyMetric(ctx context.Context, metric types.MetricItem, p providers.Provider, instanceIds []string, day string) (data.DailyMetric, error) {
+	if !tools.IsValidDayDate(day) {
+		log.Printf("W! invalid day[%v]\n", day)
+		return data.DailyMetric{}, nil
+	}
+
+	startTime, err := time.ParseInLocation("2006-01-02", day, time.Local)
+	if err != nil {
+		return data.DailyMetric{}, nil
+	}
+	endTime := startTime.AddDate(0, 0, +1)
+
+	provider := s._provider
+	if p != nil {
+		provider = p
+	}
+	metrics, ok := provider.(providers.MetricsProvider)
+	if !ok {
+		return data.DailyMetric{}, providers.NotSupported(provider, providers.CapabilityMetrics)
+	}
+	resp, err := metrics.DescribeMetricList(ctx, types.DescribeMetricListRequest{
+		MetricName: metric,
+		Period:     "86400", // 一天
+		StartTime:  startTime,
+		EndTime:    endTime,
+		Filter:     types.MetricListInstanceFilter{InstanceIds: instanceIds},
+	})
+	if err != nil {
+		return data.DailyMetric{}, err
+	}
+
+	result := data.DailyMetric{
+		Provider: provider.ProviderType(),
+		Metric:   metric,
+		Day:      day,
+	}
+	index := make(map[string]int)
+	counts := make(map[string]int)
+	for _, v := range resp.List {
+		i, ok := index[v.InstanceId]
+		if !ok {
+			i = len(result.Samples)
+			index[v.InstanceId] = i
+			result.Samples = append(result.Samples, data.InstanceMetric{InstanceId: v.InstanceId})
+		}
+		sample := &result.Samples[i]
+		counts[v.InstanceId]++
+		sample.Average += (v.Average - sample.Average) / float64(counts[v.InstanceId])
+		if v.Max > sample.Max {
+			sample.Max = v.Max
+		}
+	}
+	return result, nil
+}
+
+func (s *UtilizationDataReader) GetDaysMetric(ctx context.Context, metric types.MetricItem, p providers.Provider, instanceIds []string, days ...string) ([]data.DailyMetric, error) {
+	var (
+		result []data.DailyMetric
+		mu     sync.Mutex
+	)
+	if len(days) == 0 {
+		return result, nil
+	}
+	sg, ctx := errgroup.WithContext(ctx)
+	rCnt := 0
+	for _, day := range days {
+		d := day
+		sg.Go(func() error {
+			select {
+			case <-ctx.Done():
+				log.Printf("I! Canceled GetDays[%s]\n", d)
+				return nil
+			default:
+				res, err := s.GetDailyMetric(ctx, metric, p, instanceIds, d)
+				if err != nil {
+					log.Printf("E! GetDailyMetric %s [%v], error=[%v]", metric, d, err)
+					return err
+				}
+				log.Printf("I! GetDailyMetric %s [%s]", metric, d)
+				mu.Lock()
+				result = append(result, res)
+				mu.Unlock()
+				return nil
+			}
+		})
+		rCnt++
+		if rCnt%10 == 0 {
+			time.Sleep(200 * time.Millisecond)
+		}
+	}
+	if err := sg.Wait(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetInstanceList the details of the instances, those not available any more are looked up in the instance bills if supported
 func (s *UtilizationDataReader) GetInstanceList(ctx context.Context, instanceIdList ...string) ([]data.InstanceDetail, error) {
-	resp, err := s._provider.QueryAvailableInstances(ctx, types.QueryAvailableInstancesRequest{
+	subscription, ok := s._provider.(providers.SubscriptionProvider)
+	if !ok {
+		return []data.InstanceDetail{}, providers.NotSupported(s._provider, providers.CapabilitySubscriptions)
+	}
+	resp, err := subscription.QueryAvailableInstances(ctx, types.QueryAvailableInstancesRequest{
 		InstanceIdList: instanceIdList,
 	})
 	if err != nil {
@@ -199,6 +313,10 @@ func (s *UtilizationDataReader) GetInstanceList(ctx context.Context, instanceIdL
 		availableIdMap[instance.InstanceId] = true
 		result = append(result, i)
 	}
+	instanceBill, ok := s._provider.(providers.InstanceBillProvider)
+	if !ok {
+		return result, nil
+	}
 	var invalidIdList []string
 	for _, id := range instanceIdList {
 		if !availableIdMap[id] {
@@ -208,7 +326,7 @@ func (s *UtilizationDataReader) GetInstanceList(ctx context.Context, instanceIdL
 	dateTool := tools.NewBillDatePilot().SetNowT(time.Now())
 	cycle := dateTool.GetRecentMonth()
 	for _, i := range invalidIdList {
-		resp2, err2 := s._provider.DescribeInstanceBill(ctx, types.DescribeInstanceBillRequest{
+		resp2, err2 := instanceBill.DescribeInstanceBill(ctx, types.DescribeInstanceBillRequest{
 			BillingCycle: cycle,
 			Granularity:  types.Monthly,
 			InstanceId:   i,
@@ -235,9 +353,13 @@ func (s *UtilizationDataReader) GetInstanceList(ctx context.Context, instanceIdL
 // GetAllRegionMap  k->v: regionId->regionName
 func (s *UtilizationDataReader) GetAllRegionMap(ctx context.Context) (map[string]string, error) {
 	result := make(map[string]string)
-	resp, err := s._provider.DescribeRegions(ctx, types.DescribeRegionsRequest{
+	regions, ok := s._provider.(providers.RegionProvider)
+	if !ok {
+		return result, providers.NotSupported(s._provider, providers.CapabilityRegions)
+	}
+	resp, err := regions.DescribeRegions(ctx, types.DescribeRegionsRequest{
 		ResourceType: types.ResourceTypeInstance,
-		Language:     types.RegionLanguageZHCN,
+		Language:     regionLanguage(),
 	})
 	if err != nil {
 		return result, err
@@ -248,10 +370,22 @@ func (s *UtilizationDataReader) GetAllRegionMap(ctx context.Context) (map[string
 	return result, nil
 }
 
+// regionLanguage the region names are requested in the language of the report
+func regionLanguage() types.RegionLanguage {
+	if i18n.GetLanguage() == i18n.EnUS {
+		return types.RegionLanguageENUS
+	}
+	return types.RegionLanguageZHCN
+}
+
 func (s *UtilizationDataReader) GetInstanceByRegionProvider(ctx context.Context, p providers.Provider, regionId string) ([]data.InstanceDetail, error) {
 	var result []data.InstanceDetail
 
-	resp, err := p.DescribeInstances(ctx, types.DescribeInstancesRequest{})
+	inventory, ok := p.(providers.InventoryProvider)
+	if !ok {
+		return result, providers.NotSupported(p, providers.CapabilityInventory)
+	}
+	resp, err := inventory.DescribeInstances(ctx, types.DescribeInstancesRequest{})
 	if err != nil {
 		return result, err
 	}
@@ -260,9 +394,87 @@ func (s *UtilizationDataReader) GetInstanceByRegionProvider(ctx context.Context,
 			Provider:         p.ProviderType(),
 			InstanceId:       i.InstanceId,
 			RegionId:         regionId,
+			InstanceSpec:     i.InstanceType,
 			SubscriptionType: i.SubscriptionType,
 		})
 	}
 
 	return result, nil
 }
+
+// GetInstanceBills k->v: instanceId->data.InstanceBill, billing items of the same instance are summed
+func (s *UtilizationDataReader) GetInstanceBills(ctx context.Context, billingCycle string) (map[string]data.InstanceBill, error) {
+	result := make(map[string]data.InstanceBill)
+	instanceBill, ok := s._provider.(providers.InstanceBillProvider)
+	if !ok {
+		return result, providers.NotSupported(s._provider, providers.CapabilityInstanceBills)
+	}
+	resp, err := instanceBill.DescribeInstanceBill(ctx, types.DescribeInstanceBillRequest{
+		BillingCycle: billingCycle,
+		Granularity:  types.Monthly,
+	}, true)
+	if err != nil {
+		return result, err
+	}
+	for _, item := range resp.Items {
+		if item.InstanceId == "" {
+			continue
+		}
+		bill, ok := result[item.InstanceId]
+		if !ok {
+			bill = data.InstanceBill{
+				InstanceId:       item.InstanceId,
+				BillingCycle:     billingCycle,
+				Region:           item.Region,
+				InstanceSpec:     item.InstanceSpec,
+				SubscriptionType: item.SubscriptionType,
+				Currency:         item.Currency,
+			}
+		}
+		if bill.InstanceSpec == "" {
+			bill.InstanceSpec = item.InstanceSpec
+		}
+		bill.PretaxAmount = tools.Float64Add(bill.PretaxAmount, item.PretaxAmount)
+		result[item.InstanceId] = bill
+	}
+	return result, nil
+}
+
+// GetCommitments coverage/utilization of [startDate, endDate) and the active commitments
+func (s *UtilizationDataReader) GetCommitments(ctx context.Context, startDate, endDate string) (data.Commitments, error) {
+	var result data.Commitments
+	commitments, ok := s._provider.(providers.CommitmentProvider)
+	if !ok {
+		return result, providers.NotSupported(s._provider, providers.CapabilityCommitments)
+	}
+	resp, err := commitments.QueryCommitments(ctx, types.QueryCommitmentsRequest{
+		StartDate: startDate,
+		EndDate:   endDate,
+	})
+	if err != nil {
+		return result, err
+	}
+	for _, u := range resp.Usages {
+		result.Usages = append(result.Usages, data.CommitmentUsage{
+			Type:            string(u.Type),
+			Unit:            u.Unit,
+			CoveredUsage:    u.CoveredUsage,
+			EligibleUsage:   u.EligibleUsage,
+			UsedCommitment:  u.UsedCommitment,
+			TotalCommitment: u.TotalCommitment,
+		})
+	}
+	for _, c := range resp.List {
+		result.List = append(result.List, data.Commitment{
+			CommitmentId: c.CommitmentId,
+			Type:         string(c.Type),
+			Description:  c.Description,
+			RegionId:     c.RegionId,
+			Count:        c.Count,
+			StartTime:    c.StartTime,
+			EndTime:      c.EndTime,
+			AutoRenew:    c.AutoRenew,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,246 @@
+package forecast
+
+import (
+	"context"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/tools"
+)
+
+const (
+	ModelRunRate  = "run rate"
+	ModelLinear   = "linear trend"
+	ModelSeasonal = "linear trend + seasonality"
+
+	_z95               = 1.96 // 95% confidence
+	_historyMonths     = 24
+	_minTrendMonths    = 3
+	_minSeasonalMonths = 20 // every calendar month should be observed, most of them twice
+	_runRateDays       = 7
+	_nextMonths        = 3
+)
+
+// Forecaster projects the spend of the current month by the recent daily run rate,
+// and the following months by a linear trend plus calendar month seasonality fitted on monthly bills.
+type Forecaster struct {
+	monthsBilling *sync.Map // key : month , val : data.MonthlyBilling
+	daysBilling   *sync.Map // key : day , val : data.DailyBilling
+
+	bp *tools.BillingDatePilot
+}
+
+func NewForecaster(monthsBilling, daysBilling *sync.Map, t time.Time) *Forecaster {
+	return &Forecaster{
+		monthsBilling: monthsBilling,
+		daysBilling:   daysBilling,
+		bp:            tools.NewBillDatePilot().SetNowT(t),
+	}
+}
+
+func (s *Forecaster) Forecast(_ context.Context) data.CostForecast {
+	y := s.bp.GetNowT().AddDate(0, 0, -1)
+	recentMonth := time.Date(y.Year(), y.Month(), 1, 0, 0, 0, 0, y.Location())
+	ret := data.CostForecast{Model: ModelRunRate}
+
+	// current month: month to date + run rate of the remaining days
+	ret.CurrentMonthActual = s.sumBillingDate(s.bp.GetRecentMonthBillingDate(true))
+	elapsed := y.Day()
+	remaining := daysInMonth(recentMonth) - elapsed
+	rate, sd := s.recentDailyRate(ret.CurrentMonthActual, elapsed)
+	currentHalf := _z95 * sd * math.Sqrt(float64(remaining))
+	ret.CurrentMonth = newPoint(recentMonth.Format("2006-01"), ret.CurrentMonthActual+rate*float64(remaining), currentHalf)
+
+	// following months
+	var ts, ys []float64
+	var months []time.Month
+	for i := _historyMonths; i >= 1; i-- {
+		m := recentMonth.AddDate(0, -i, 0)
+		if val, ok := s.monthsBilling.Load(m.Format("2006-01")); ok {
+			ts = append(ts, float64(_historyMonths-i))
+			ys = append(ys, val.(data.MonthlyBilling).TotalAmount)
+			months = append(months, m.Month())
+		}
+		if m.Year() == recentMonth.Year() {
+			if val, ok := s.monthsBilling.Load(m.Format("2006-01")); ok {
+				ret.YearToDate += val.(data.MonthlyBilling).TotalAmount
+			}
+		}
+	}
+	ret.HistoryMonths = len(ys)
+	yearEnd := ret.YearToDate + ret.CurrentMonth.Amount
+	yearEndVariance := math.Pow(currentHalf/_z95, 2)
+	ret.YearToDate += ret.CurrentMonthActual
+
+	m := fit(ts, ys, months)
+	if m != nil {
+		ret.Model, ret.Trend = m.name(), m.b
+	}
+	monthsAhead := 12 - int(recentMonth.Month())
+	if monthsAhead < _nextMonths {
+		monthsAhead = _nextMonths
+	}
+	for k := 1; k <= monthsAhead; k++ {
+		month := recentMonth.AddDate(0, k, 0)
+		var amount, half float64
+		if m != nil {
+			amount, half = m.predict(float64(_historyMonths+k), month.Month())
+		} else {
+			days := float64(daysInMonth(month))
+			amount, half = rate*days, _z95*sd*math.Sqrt(days)
+		}
+		if k <= _nextMonths {
+			ret.NextMonths = append(ret.NextMonths, newPoint(month.Format("2006-01"), amount, half))
+		}
+		if month.Year() == recentMonth.Year() {
+			yearEnd += amount
+			yearEndVariance += math.Pow(half/_z95, 2)
+		}
+	}
+	ret.YearEnd = newPoint(recentMonth.Format("2006"), yearEnd, _z95*math.Sqrt(yearEndVariance))
+	log.Printf("I! forecast done, model[%s] history months[%d]", ret.Model, ret.HistoryMonths)
+	return ret
+}
+
+// recentDailyRate mean and standard deviation of the recent daily bills
+func (s *Forecaster) recentDailyRate(monthToDate float64, elapsed int) (float64, float64) {
+	var values []float64
+	for _, d := range s.bp.GetRecentXDaysBillingDate(_runRateDays).Days {
+		if val, ok := s.daysBilling.Load(d); ok {
+			values = append(values, val.(data.DailyBilling).TotalAmount)
+		}
+	}
+	if len(values) == 0 {
+		if elapsed == 0 {
+			return 0, 0
+		}
+		return monthToDate / float64(elapsed), 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	if len(values) == 1 {
+		return mean, 0
+	}
+	var sse float64
+	for _, v := range values {
+		sse += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(sse / float64(len(values)-1))
+}
+
+func (s *Forecaster) sumBillingDate(date tools.BillingDate) float64 {
+	var sum float64
+	for _, m := range date.Months {
+		if val, ok := s.monthsBilling.Load(m); ok {
+			sum += val.(data.MonthlyBilling).TotalAmount
+		}
+	}
+	for _, d := range date.Days {
+		if val, ok := s.daysBilling.Load(d); ok {
+			sum += val.(data.DailyBilling).TotalAmount
+		}
+	}
+	return sum
+}
+
+// model y = a + b*t + seasonal[month]
+type model struct {
+	a, b     float64
+	seasonal map[time.Month]float64
+
+	n          int
+	sigma      float64 // standard deviation of residuals
+	tMean, sxx float64
+}
+
+// fit least squares trend, then average the residuals of each calendar month as its seasonal effect
+func fit(ts, ys []float64, months []time.Month) *model {
+	n := len(ys)
+	if n < _minTrendMonths {
+		return nil
+	}
+	var tSum, ySum float64
+	for i := range ys {
+		tSum += ts[i]
+		ySum += ys[i]
+	}
+	m := &model{n: n, tMean: tSum / float64(n), seasonal: make(map[time.Month]float64)}
+	yMean := ySum / float64(n)
+	var sxy float64
+	for i := range ys {
+		m.sxx += (ts[i] - m.tMean) * (ts[i] - m.tMean)
+		sxy += (ts[i] - m.tMean) * (ys[i] - yMean)
+	}
+	if m.sxx > 0 {
+		m.b = sxy / m.sxx
+	}
+	m.a = yMean - m.b*m.tMean
+
+	dof := n - 2
+	if n >= _minSeasonalMonths {
+		sums := make(map[time.Month]float64)
+		counts := make(map[time.Month]int)
+		for i := range ys {
+			sums[months[i]] += ys[i] - (m.a + m.b*ts[i])
+			counts[months[i]]++
+		}
+		var total float64
+		for month, sum := range sums {
+			m.seasonal[month] = sum / float64(counts[month])
+			total += m.seasonal[month]
+		}
+		for month := range m.seasonal { // seasonal effects sum to zero
+			m.seasonal[month] -= total / float64(len(m.seasonal))
+		}
+		dof -= len(m.seasonal) - 1
+	}
+	if dof < 1 {
+		dof = 1
+	}
+	var sse float64
+	for i := range ys {
+		r := ys[i] - (m.a + m.b*ts[i] + m.seasonal[months[i]])
+		sse += r * r
+	}
+	m.sigma = math.Sqrt(sse / float64(dof))
+	return m
+}
+
+func (m *model) name() string {
+	if len(m.seasonal) > 0 {
+		return ModelSeasonal
+	}
+	return ModelLinear
+}
+
+// predict returns the amount and the half width of its prediction interval
+func (m *model) predict(t float64, month time.Month) (float64, float64) {
+	amount := m.a + m.b*t + m.seasonal[month]
+	if amount < 0 {
+		amount = 0
+	}
+	leverage := 1 / float64(m.n)
+	if m.sxx > 0 {
+		leverage += (t - m.tMean) * (t - m.tMean) / m.sxx
+	}
+	return amount, _z95 * m.sigma * math.Sqrt(1+leverage)
+}
+
+func newPoint(period string, amount, half float64) data.ForecastPoint {
+	return data.ForecastPoint{
+		Period: period,
+		Amount: amount,
+		Lower:  math.Max(0, amount-half),
+		Upper:  amount + half,
+	}
+}
+
+func daysInMonth(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+}
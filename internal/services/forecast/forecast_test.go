@@ -0,0 +1,64 @@
+package forecast
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForecaster_Forecast(t *testing.T) {
+	nowT := time.Date(2022, 10, 11, 10, 0, 0, 0, time.Local) // 10 days of october elapsed
+	var monthsBilling, daysBilling sync.Map
+	for i := 1; i <= 12; i++ { // 2021-10 ~ 2022-09, grows 10 per month
+		m := time.Date(2022, 10, 1, 0, 0, 0, 0, time.Local).AddDate(0, -i, 0).Format("2006-01")
+		monthsBilling.Store(m, data.MonthlyBilling{Month: m, TotalAmount: 1000 - 10*float64(i-1)})
+	}
+	for day := 1; day <= 10; day++ {
+		d := time.Date(2022, 10, day, 0, 0, 0, 0, time.Local).Format("2006-01-02")
+		daysBilling.Store(d, data.DailyBilling{Day: d, TotalAmount: 35})
+	}
+
+	got := NewForecaster(&monthsBilling, &daysBilling, nowT).Forecast(context.Background())
+	assert.Equal(t, ModelLinear, got.Model)
+	assert.Equal(t, 12, got.HistoryMonths)
+	assert.InDelta(t, 10, got.Trend, 0.001)
+	assert.InDelta(t, 350, got.CurrentMonthActual, 0.001)
+	assert.Equal(t, "2022-10", got.CurrentMonth.Period)
+	assert.InDelta(t, 35*31, got.CurrentMonth.Amount, 0.001)
+	assert.InDelta(t, got.CurrentMonth.Amount, got.CurrentMonth.Upper, 0.001) // flat daily bills
+
+	assert.Equal(t, 3, len(got.NextMonths))
+	assert.Equal(t, "2022-11", got.NextMonths[0].Period)
+	assert.InDelta(t, 1020, got.NextMonths[0].Amount, 0.001)
+	assert.InDelta(t, 1030, got.NextMonths[1].Amount, 0.001)
+	assert.Equal(t, "2023-01", got.NextMonths[2].Period)
+
+	ytd := 0.0
+	for i := 1; i <= 9; i++ {
+		ytd += 1000 - 10*float64(i-1)
+	}
+	assert.InDelta(t, ytd+350, got.YearToDate, 0.001)
+	assert.Equal(t, "2022", got.YearEnd.Period)
+	assert.InDelta(t, ytd+35*31+1020+1030, got.YearEnd.Amount, 0.001)
+}
+
+func TestForecaster_ForecastRunRate(t *testing.T) {
+	nowT := time.Date(2022, 10, 1, 10, 0, 0, 0, time.Local) // september is complete
+	var monthsBilling, daysBilling sync.Map
+	for day := 24; day <= 30; day++ {
+		d := time.Date(2022, 9, day, 0, 0, 0, 0, time.Local).Format("2006-01-02")
+		daysBilling.Store(d, data.DailyBilling{Day: d, TotalAmount: 10})
+	}
+	monthsBilling.Store("2022-08", data.MonthlyBilling{Month: "2022-08", TotalAmount: 300})
+
+	got := NewForecaster(&monthsBilling, &daysBilling, nowT).Forecast(context.Background())
+	assert.Equal(t, ModelRunRate, got.Model)
+	assert.Equal(t, "2022-09", got.CurrentMonth.Period)
+	assert.InDelta(t, got.CurrentMonthActual, got.CurrentMonth.Amount, 0.001)
+	assert.InDelta(t, 310, got.NextMonths[0].Amount, 0.001) // october 31 days
+	assert.InDelta(t, 300, got.NextMonths[1].Amount, 0.001)
+}
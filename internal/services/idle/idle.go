@@ -0,0 +1,180 @@
+package idle
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/types"
+	"github.com/galaxy-future/costpilot/tools"
+)
+
+// Detector lists the instances whose average and peak utilization over the window stay below the idle
+// or underutilized thresholds, joined with their instance bills.
+type Detector struct {
+	cfg                 types.IdleInstanceDetection
+	accountUtilizations []data.AccountUtilizationMap
+
+	bp *tools.BillingDatePilot
+}
+
+func NewDetector(cfg types.IdleInstanceDetection, accountUtilizations []data.AccountUtilizationMap, t time.Time) *Detector {
+	return &Detector{
+		cfg:                 cfg,
+		accountUtilizations: accountUtilizations,
+		bp:                  tools.NewBillDatePilot().SetNowT(t),
+	}
+}
+
+// Detect returns idle and underutilized instances, the most expensive first
+func (s *Detector) Detect(_ context.Context) []data.IdleInstance {
+	var ret []data.IdleInstance
+	days := s.bp.GetRecentXDaysBillingDate(int32(s.cfg.GetWindowDays())).Days
+	for _, a := range s.accountUtilizations {
+		ret = append(ret, s.detectAccount(a, days)...)
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		if ret[i].MonthlyCost != ret[j].MonthlyCost {
+			return ret[i].MonthlyCost > ret[j].MonthlyCost
+		}
+		return ret[i].Level == data.InstanceUsageIdle && ret[j].Level != data.InstanceUsageIdle
+	})
+	log.Printf("I! detect idle instances done, found %d", len(ret))
+	return ret
+}
+
+func (s *Detector) detectAccount(a data.AccountUtilizationMap, days []string) []data.IdleInstance {
+	var ret []data.IdleInstance
+	minDays := (len(days) + 1) / 2 // instances created recently are not judged
+	idle, underutilized := s.cfg.GetIdle(), s.cfg.GetUnderutilized()
+	for instanceId, u := range SummarizeUsage(a, days) {
+		if u.Days < minDays {
+			continue
+		}
+		instance := data.IdleInstance{
+			AccountName: a.AccountName,
+			Provider:    a.Provider,
+			InstanceId:  instanceId,
+			Days:        u.Days,
+			CpuAvg:      u.CpuAvg,
+			CpuPeak:     u.CpuPeak,
+			HasMemory:   u.HasMemory,
+			MemoryAvg:   u.MemoryAvg,
+			MemoryPeak:  u.MemoryPeak,
+		}
+		switch {
+		case below(instance, idle):
+			instance.Level = data.InstanceUsageIdle
+		case below(instance, underutilized):
+			instance.Level = data.InstanceUsageUnderutilized
+		default:
+			continue
+		}
+		s.fillDetail(a, &instance)
+		ret = append(ret, instance)
+	}
+	return ret
+}
+
+// SummarizeUsage average and peak utilization of every instance over days, key : instanceId.
+// the peak falls back to the daily average when the provider does not report the maximum
+func SummarizeUsage(a data.AccountUtilizationMap, days []string) map[string]data.InstanceUsage {
+	type sum struct {
+		cpuDays, memoryDays int
+		cpu, memory         float64
+	}
+	ret := make(map[string]data.InstanceUsage)
+	sums := make(map[string]*sum)
+	for _, d := range days {
+		if a.DailyCpu != nil {
+			if val, ok := a.DailyCpu.Load(d); ok {
+				for _, u := range val.(data.DailyCpuUtilization).Utilization {
+					if _, ok := sums[u.InstanceId]; !ok {
+						sums[u.InstanceId] = &sum{}
+					}
+					sums[u.InstanceId].cpuDays++
+					sums[u.InstanceId].cpu += u.UsedUtilization
+					i := ret[u.InstanceId]
+					i.CpuPeak = math.Max(i.CpuPeak, math.Max(u.MaxUtilization, u.UsedUtilization))
+					ret[u.InstanceId] = i
+				}
+			}
+		}
+		if a.DailyMemory != nil {
+			if val, ok := a.DailyMemory.Load(d); ok {
+				for _, u := range val.(data.DailyMemoryUtilization).Utilization {
+					if _, ok := sums[u.InstanceId]; !ok {
+						sums[u.InstanceId] = &sum{}
+					}
+					sums[u.InstanceId].memoryDays++
+					sums[u.InstanceId].memory += u.UsedUtilization
+					i := ret[u.InstanceId]
+					i.MemoryPeak = math.Max(i.MemoryPeak, math.Max(u.MaxUtilization, u.UsedUtilization))
+					ret[u.InstanceId] = i
+				}
+			}
+		}
+	}
+	for instanceId, i := range ret {
+		sm := sums[instanceId]
+		i.InstanceId, i.Days, i.HasMemory = instanceId, sm.cpuDays, sm.memoryDays > 0
+		if sm.cpuDays > 0 {
+			i.CpuAvg = sm.cpu / float64(sm.cpuDays)
+		}
+		if i.HasMemory {
+			i.MemoryAvg = sm.memory / float64(sm.memoryDays)
+		}
+		ret[instanceId] = i
+	}
+	return ret
+}
+
+func below(i data.IdleInstance, t types.UtilizationThreshold) bool {
+	if i.CpuAvg >= t.CpuAvg || i.CpuPeak >= t.CpuPeak {
+		return false
+	}
+	if i.HasMemory && (i.MemoryAvg >= t.MemoryAvg || i.MemoryPeak >= t.MemoryPeak) {
+		return false
+	}
+	return true
+}
+
+// fillDetail join the instance detail and the instance bill of the recent month
+func (s *Detector) fillDetail(a data.AccountUtilizationMap, i *data.IdleInstance) {
+	if a.RecentInstances != nil {
+		if val, ok := a.RecentInstances.Load(fmt.Sprintf("%s:%s", a.Provider, i.InstanceId)); ok {
+			detail := val.(data.InstanceDetail)
+			i.RegionId, i.RegionName, i.InstanceSpec, i.SubscriptionType = detail.RegionId, detail.RegionName, detail.InstanceSpec, detail.SubscriptionType
+		}
+	}
+	if a.InstanceBills == nil {
+		return
+	}
+	val, ok := a.InstanceBills.Load(i.InstanceId)
+	if !ok {
+		return
+	}
+	bill := val.(data.InstanceBill)
+	i.HasBill, i.Currency = true, bill.Currency
+	if bill.InstanceSpec != "" {
+		i.InstanceSpec = bill.InstanceSpec
+	}
+	if i.RegionName == "" {
+		i.RegionName = bill.Region
+	}
+	if i.SubscriptionType == "" {
+		i.SubscriptionType = bill.SubscriptionType
+	}
+	i.MonthlyCost = EstimateMonthlyCost(bill, s.bp.GetNowT())
+}
+
+// EstimateMonthlyCost month to date instance bill -> whole month
+func EstimateMonthlyCost(bill data.InstanceBill, nowT time.Time) float64 {
+	y := nowT.AddDate(0, 0, -1)
+	daysInMonth := time.Date(y.Year(), y.Month()+1, 0, 0, 0, 0, 0, y.Location()).Day()
+	return bill.PretaxAmount / float64(y.Day()) * float64(daysInMonth)
+}
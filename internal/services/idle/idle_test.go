@@ -0,0 +1,73 @@
+package idle
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetector_Detect(t *testing.T) {
+	nowT := time.Date(2022, 10, 11, 8, 0, 0, 0, time.Local) // 10 days of october elapsed
+	var dailyCpu, dailyMemory, recentInstances, instanceBills sync.Map
+	for i := 1; i <= 14; i++ {
+		day := nowT.AddDate(0, 0, -i).Format("2006-01-02")
+		cpu := data.DailyCpuUtilization{Provider: cloud.AlibabaCloud, Day: day, Utilization: []data.InstanceCpuUtilization{
+			{InstanceId: "i-idle", UsedUtilization: 1, MaxUtilization: 3},
+			{InstanceId: "i-low", UsedUtilization: 10, MaxUtilization: 30},
+			{InstanceId: "i-busy", UsedUtilization: 60, MaxUtilization: 90},
+			{InstanceId: "i-memory", UsedUtilization: 2, MaxUtilization: 5},
+		}}
+		if i <= 3 {
+			cpu.Utilization = append(cpu.Utilization, data.InstanceCpuUtilization{InstanceId: "i-new", UsedUtilization: 1})
+		}
+		dailyCpu.Store(day, cpu)
+		dailyMemory.Store(day, data.DailyMemoryUtilization{Provider: cloud.AlibabaCloud, Day: day, Utilization: []data.InstanceMemoryUtilization{
+			{InstanceId: "i-idle", UsedUtilization: 5},
+			{InstanceId: "i-memory", UsedUtilization: 80},
+		}})
+	}
+	recentInstances.Store("AlibabaCloud:i-idle", data.InstanceDetail{
+		Provider: cloud.AlibabaCloud, InstanceId: "i-idle", RegionId: "cn-beijing", RegionName: "华北2（北京）", SubscriptionType: cloud.PostPaid,
+	})
+	instanceBills.Store("i-idle", data.InstanceBill{InstanceId: "i-idle", InstanceSpec: "ecs.g6.large", Currency: "CNY", PretaxAmount: 100})
+	instanceBills.Store("i-low", data.InstanceBill{InstanceId: "i-low", Region: "华东1（杭州）", SubscriptionType: cloud.PrePaid, Currency: "CNY", PretaxAmount: 10})
+
+	accounts := []data.AccountUtilizationMap{{
+		AccountName:     "ali-prod",
+		Provider:        cloud.AlibabaCloud,
+		DailyCpu:        &dailyCpu,
+		DailyMemory:     &dailyMemory,
+		RecentInstances: &recentInstances,
+		InstanceBills:   &instanceBills,
+	}}
+	got := NewDetector(types.IdleInstanceDetection{}, accounts, nowT).Detect(context.Background())
+	assert.Equal(t, 2, len(got))
+
+	assert.Equal(t, "i-idle", got[0].InstanceId)
+	assert.Equal(t, data.InstanceUsageIdle, got[0].Level)
+	assert.Equal(t, 14, got[0].Days)
+	assert.InDelta(t, 3, got[0].CpuPeak, 0.001)
+	assert.True(t, got[0].HasMemory)
+	assert.Equal(t, "华北2（北京）", got[0].RegionName)
+	assert.Equal(t, "ecs.g6.large", got[0].InstanceSpec)
+	assert.InDelta(t, 310, got[0].MonthlyCost, 0.001)
+
+	assert.Equal(t, "i-low", got[1].InstanceId)
+	assert.Equal(t, data.InstanceUsageUnderutilized, got[1].Level)
+	assert.False(t, got[1].HasMemory)
+	assert.Equal(t, "华东1（杭州）", got[1].RegionName)
+	assert.Equal(t, cloud.PrePaid, got[1].SubscriptionType)
+	assert.InDelta(t, 31, got[1].MonthlyCost, 0.001)
+
+	// the fields left unset keep their defaults
+	got = NewDetector(types.IdleInstanceDetection{Idle: types.UtilizationThreshold{CpuAvg: 2}}, accounts, nowT).Detect(context.Background())
+	assert.Equal(t, 2, len(got))
+	assert.Equal(t, "i-idle", got[0].InstanceId)
+	assert.Equal(t, data.InstanceUsageIdle, got[0].Level)
+}
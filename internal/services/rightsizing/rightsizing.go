@@ -0,0 +1,162 @@
+package rightsizing
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/catalog"
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/services/idle"
+	"github.com/galaxy-future/costpilot/internal/types"
+	"github.com/galaxy-future/costpilot/tools"
+)
+
+// Advisor recommends terminating idle instances, and downsizing the others to the cheapest type of the same family
+// that keeps the peak utilization below the target.
+type Advisor struct {
+	cfg                 types.Rightsizing
+	catalogs            catalog.Catalogs
+	accountUtilizations []data.AccountUtilizationMap
+	idleInstances       []data.IdleInstance
+
+	bp *tools.BillingDatePilot
+}
+
+func NewAdvisor(cfg types.Rightsizing, catalogs catalog.Catalogs, accountUtilizations []data.AccountUtilizationMap, idleInstances []data.IdleInstance, t time.Time) *Advisor {
+	return &Advisor{
+		cfg:                 cfg,
+		catalogs:            catalogs,
+		accountUtilizations: accountUtilizations,
+		idleInstances:       idleInstances,
+		bp:                  tools.NewBillDatePilot().SetNowT(t),
+	}
+}
+
+// Advise returns opportunities with positive savings, the largest savings first
+func (s *Advisor) Advise(_ context.Context) []data.OptimizationOpportunity {
+	var ret []data.OptimizationOpportunity
+	idleIds := make(map[string]bool)
+	for _, i := range s.idleInstances {
+		if i.Level != data.InstanceUsageIdle {
+			continue
+		}
+		idleIds[i.InstanceId] = true
+		if o, ok := s.terminate(i); ok {
+			ret = append(ret, o)
+		}
+	}
+	days := s.bp.GetRecentXDaysBillingDate(int32(s.cfg.GetWindowDays())).Days
+	for _, a := range s.accountUtilizations {
+		for instanceId, u := range idle.SummarizeUsage(a, days) {
+			if idleIds[instanceId] || u.Days < (len(days)+1)/2 {
+				continue
+			}
+			if o, ok := s.downsize(a, u); ok {
+				ret = append(ret, o)
+			}
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].MonthlySavings > ret[j].MonthlySavings
+	})
+	log.Printf("I! advise rightsizing done, found %d opportunities", len(ret))
+	return ret
+}
+
+func (s *Advisor) terminate(i data.IdleInstance) (data.OptimizationOpportunity, bool) {
+	o := data.OptimizationOpportunity{
+		AccountName: i.AccountName,
+		Provider:    i.Provider,
+		InstanceId:  i.InstanceId,
+		RegionName:  i.RegionName,
+		Kind:        data.OptimizationTerminate,
+		CurrentType: i.InstanceSpec,
+		CpuPeak:     i.CpuPeak,
+		HasMemory:   i.HasMemory,
+		MemoryPeak:  i.MemoryPeak,
+		Currency:    i.Currency,
+	}
+	if i.HasBill {
+		o.MonthlyCost = i.MonthlyCost
+	} else if c, ok := s.catalogs.Get(i.Provider); ok {
+		if t, ok := c.Lookup(i.InstanceSpec); ok {
+			o.MonthlyCost, o.Currency = t.MonthlyPrice(), c.Currency
+		}
+	}
+	o.MonthlySavings = o.MonthlyCost
+	return o, o.MonthlySavings > 0 && o.MonthlySavings >= s.cfg.MinMonthlySavings
+}
+
+// downsize the instance type is taken from the instance bill, or from the inventory for the providers without instance bills,
+// whose costs are then estimated by the catalog prices
+func (s *Advisor) downsize(a data.AccountUtilizationMap, u data.InstanceUsage) (data.OptimizationOpportunity, bool) {
+	var (
+		bill   data.InstanceBill
+		detail data.InstanceDetail
+	)
+	if a.InstanceBills != nil {
+		if val, ok := a.InstanceBills.Load(u.InstanceId); ok {
+			bill = val.(data.InstanceBill)
+		}
+	}
+	if a.RecentInstances != nil {
+		if val, ok := a.RecentInstances.Load(string(a.Provider) + ":" + u.InstanceId); ok {
+			detail = val.(data.InstanceDetail)
+		}
+	}
+	spec := bill.InstanceSpec
+	if spec == "" {
+		spec = detail.InstanceSpec
+	}
+	c, ok := s.catalogs.Get(a.Provider)
+	if !ok {
+		return data.OptimizationOpportunity{}, false
+	}
+	current, ok := c.Lookup(spec)
+	if !ok {
+		return data.OptimizationOpportunity{}, false
+	}
+	target := s.cfg.GetTargetUtilization()
+	requiredCpu := current.VCPU * u.CpuPeak / target
+	requiredMemory := 0.0 // unknown without memory metrics, only cpu is considered
+	if u.HasMemory {
+		requiredMemory = current.Memory * u.MemoryPeak / target
+	}
+	var recommended *catalog.InstanceType
+	for _, t := range c.Family(current.Family) { // cheapest first
+		if t.Price < current.Price && t.VCPU >= requiredCpu && t.Memory >= requiredMemory {
+			recommended = &t
+			break
+		}
+	}
+	if recommended == nil {
+		return data.OptimizationOpportunity{}, false
+	}
+	o := data.OptimizationOpportunity{
+		AccountName:     a.AccountName,
+		Provider:        a.Provider,
+		InstanceId:      u.InstanceId,
+		RegionName:      bill.Region,
+		Kind:            data.OptimizationDownsize,
+		CurrentType:     current.Type,
+		RecommendedType: recommended.Type,
+		CpuPeak:         u.CpuPeak,
+		HasMemory:       u.HasMemory,
+		MemoryPeak:      u.MemoryPeak,
+		MonthlyCost:     current.MonthlyPrice(),
+		MonthlySavings:  current.MonthlyPrice() - recommended.MonthlyPrice(),
+		Currency:        c.Currency,
+	}
+	if detail.RegionName != "" {
+		o.RegionName = detail.RegionName
+	}
+	if cost := idle.EstimateMonthlyCost(bill, s.bp.GetNowT()); cost > 0 { // the actual bill includes discounts
+		o.MonthlyCost, o.Currency = cost, bill.Currency
+		o.MonthlySavings = cost * (1 - recommended.Price/current.Price)
+	}
+	o.MonthlySavings = math.Max(o.MonthlySavings, 0)
+	return o, o.MonthlySavings > 0 && o.MonthlySavings >= s.cfg.MinMonthlySavings
+}
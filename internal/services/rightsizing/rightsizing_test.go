@@ -0,0 +1,94 @@
+package rightsizing
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/catalog"
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdvisor_Advise(t *testing.T) {
+	nowT := time.Date(2022, 10, 11, 8, 0, 0, 0, time.Local)
+	var dailyCpu, dailyMemory, instanceBills sync.Map
+	for i := 1; i <= 14; i++ {
+		day := nowT.AddDate(0, 0, -i).Format("2006-01-02")
+		dailyCpu.Store(day, data.DailyCpuUtilization{Day: day, Utilization: []data.InstanceCpuUtilization{
+			{InstanceId: "i-big", UsedUtilization: 10, MaxUtilization: 30},    // 16 vcpu * 30% / 70% -> 8 vcpu
+			{InstanceId: "i-memory", UsedUtilization: 10, MaxUtilization: 20}, // memory bound
+			{InstanceId: "i-busy", UsedUtilization: 60, MaxUtilization: 95},
+			{InstanceId: "i-unknown", UsedUtilization: 5, MaxUtilization: 20},
+		}})
+		dailyMemory.Store(day, data.DailyMemoryUtilization{Day: day, Utilization: []data.InstanceMemoryUtilization{
+			{InstanceId: "i-memory", UsedUtilization: 60, MaxUtilization: 90},
+		}})
+	}
+	instanceBills.Store("i-big", data.InstanceBill{InstanceId: "i-big", InstanceSpec: "ecs.g6.4xlarge", Region: "华东1（杭州）", Currency: "CNY", PretaxAmount: 1000})
+	instanceBills.Store("i-memory", data.InstanceBill{InstanceId: "i-memory", InstanceSpec: "ecs.g6.xlarge", Currency: "CNY"})
+	instanceBills.Store("i-busy", data.InstanceBill{InstanceId: "i-busy", InstanceSpec: "ecs.g6.large", Currency: "CNY"})
+	instanceBills.Store("i-unknown", data.InstanceBill{InstanceId: "i-unknown", InstanceSpec: "ecs.unknown.large", Currency: "CNY"})
+
+	catalogs, err := catalog.Load("")
+	assert.NoError(t, err)
+	accounts := []data.AccountUtilizationMap{{
+		AccountName:   "ali-prod",
+		Provider:      cloud.AlibabaCloud,
+		DailyCpu:      &dailyCpu,
+		DailyMemory:   &dailyMemory,
+		InstanceBills: &instanceBills,
+	}}
+	idleInstances := []data.IdleInstance{
+		{AccountName: "ali-prod", Provider: cloud.AlibabaCloud, InstanceId: "i-idle", Level: data.InstanceUsageIdle, HasBill: true, MonthlyCost: 200, Currency: "CNY"},
+		{AccountName: "ali-prod", Provider: cloud.AlibabaCloud, InstanceId: "i-idle-no-bill", Level: data.InstanceUsageIdle, InstanceSpec: "ecs.c6.large"},
+		{AccountName: "ali-prod", Provider: cloud.AlibabaCloud, InstanceId: "i-low", Level: data.InstanceUsageUnderutilized},
+	}
+	got := NewAdvisor(types.Rightsizing{}, catalogs, accounts, idleInstances, nowT).Advise(context.Background())
+	assert.Equal(t, 3, len(got))
+
+	assert.Equal(t, "i-big", got[0].InstanceId)
+	assert.Equal(t, data.OptimizationDownsize, got[0].Kind)
+	assert.Equal(t, "ecs.g6.2xlarge", got[0].RecommendedType)
+	assert.InDelta(t, 3100, got[0].MonthlyCost, 0.001) // 1000 in 10 days
+	assert.InDelta(t, 1550, got[0].MonthlySavings, 0.001)
+
+	assert.Equal(t, "i-idle-no-bill", got[1].InstanceId)
+	assert.Equal(t, data.OptimizationTerminate, got[1].Kind)
+	assert.InDelta(t, 0.62*catalog.HoursPerMonth, got[1].MonthlySavings, 0.001)
+
+	assert.Equal(t, "i-idle", got[2].InstanceId)
+	assert.InDelta(t, 200, got[2].MonthlySavings, 0.001)
+}
+
+func TestAdvisor_Advise_WithoutInstanceBills(t *testing.T) {
+	nowT := time.Date(2022, 10, 11, 8, 0, 0, 0, time.Local)
+	var dailyCpu, recentInstances sync.Map
+	for i := 1; i <= 14; i++ {
+		day := nowT.AddDate(0, 0, -i).Format("2006-01-02")
+		dailyCpu.Store(day, data.DailyCpuUtilization{Day: day, Utilization: []data.InstanceCpuUtilization{
+			{InstanceId: "i-big", UsedUtilization: 10, MaxUtilization: 30},
+		}})
+	}
+	recentInstances.Store("AWSCloud:i-big", data.InstanceDetail{Provider: cloud.AWSCloud, InstanceId: "i-big", RegionName: "us-east-1", InstanceSpec: "m5.4xlarge"})
+
+	catalogs, err := catalog.Load("")
+	assert.NoError(t, err)
+	accounts := []data.AccountUtilizationMap{{
+		AccountName:     "aws-prod",
+		Provider:        cloud.AWSCloud,
+		DailyCpu:        &dailyCpu,
+		RecentInstances: &recentInstances,
+	}}
+	got := NewAdvisor(types.Rightsizing{}, catalogs, accounts, nil, nowT).Advise(context.Background())
+	assert.Equal(t, 1, len(got))
+	assert.Equal(t, data.OptimizationDownsize, got[0].Kind)
+	assert.Equal(t, "m5.4xlarge", got[0].CurrentType)
+	assert.Equal(t, "m5.2xlarge", got[0].RecommendedType)
+	assert.Equal(t, "us-east-1", got[0].RegionName)
+	assert.Equal(t, "USD", got[0].Currency)
+	assert.Greater(t, got[0].MonthlySavings, 0.0)
+}
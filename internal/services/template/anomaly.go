@@ -0,0 +1,98 @@
+package template
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/constants"
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/i18n"
+	"github.com/galaxy-future/costpilot/internal/template"
+	"github.com/galaxy-future/costpilot/tools"
+	jsoniter "github.com/json-iterator/go"
+)
+
+type AnomalyTemplate struct {
+	bp *tools.BillingDatePilot
+
+	Anomalies []data.CostAnomaly
+}
+
+func NewAnomalyTemplate(anomalies []data.CostAnomaly, t time.Time) *AnomalyTemplate {
+	return &AnomalyTemplate{
+		bp:        tools.NewBillDatePilot().SetNowT(t),
+		Anomalies: anomalies,
+	}
+}
+
+func anomalyColumns() []template.ItemInTableColumns {
+	return []template.ItemInTableColumns{
+		{Key: "day", Title: i18n.T("col.day")},
+		{Key: "account", Title: i18n.T("col.account")},
+		{Key: "provider", Title: i18n.T("col.provider")},
+		{Key: "product", Title: i18n.T("col.product")},
+		{Key: "amount", Title: i18n.T("col.amount"), Sortable: true},
+		{Key: "baseline", Title: i18n.T("col.baseline"), Sortable: true},
+		{Key: "ratio", Title: i18n.T("col.change"), Sortable: true},
+		{Key: "severityName", Title: i18n.T("col.severity")},
+		{Key: "contributors", Title: i18n.T("col.contributors")},
+	}
+}
+
+func (s *AnomalyTemplate) Assemble(_ context.Context) template.AnomalyAnalysis {
+	recentDay := s.bp.GetRecentDayBillingDate()
+	ret := template.AnomalyAnalysis{
+		Title:        i18n.T("anomaly.title"),
+		DataCycle:    recentDay.Days[0] + " 23:59:59",
+		AccountTotal: i18n.T("anomaly.account_total"),
+		Columns:      anomalyColumns(),
+		Anomalies:    make([]template.ItemInAnomalies, 0, len(s.Anomalies)),
+	}
+	for _, a := range s.Anomalies {
+		contributors := make([]string, 0, len(a.Contributors))
+		for _, c := range a.Contributors {
+			contributors = append(contributors, fmt.Sprintf("%s %+.2f", c.ProductName, c.Deviation))
+		}
+		ret.Anomalies = append(ret.Anomalies, template.ItemInAnomalies{
+			Day:          a.Day,
+			Account:      a.AccountName,
+			Provider:     i18n.ProviderName(a.Provider),
+			Product:      a.ProductName,
+			Amount:       fmt.Sprintf("%.2f", a.Amount),
+			Baseline:     fmt.Sprintf("%.2f", a.Baseline),
+			Ratio:        tools.RatioString(fmt.Sprintf("%.2f", a.Baseline), fmt.Sprintf("%.2f", a.Amount)),
+			Score:        fmt.Sprintf("%.1f", a.Score),
+			Direction:    string(a.Direction),
+			Severity:     string(a.Severity),
+			SeverityName: i18n.T("anomaly.severity." + string(a.Severity)),
+			Unit:         tools.CurrencyUnit(a.Currency),
+			Contributors: contributors,
+		})
+	}
+	return ret
+}
+
+// Export append the anomalies to data-set.js, and write them as json for other tools
+func (s *AnomalyTemplate) Export(_ context.Context, aa template.AnomalyAnalysis) error {
+	c, err := template.ParseAnomalyTemplate(aa)
+	if err != nil {
+		return err
+	}
+	if err = appendJsData(c); err != nil {
+		return err
+	}
+	j, err := jsoniter.MarshalIndent(map[string]interface{}{
+		"data_cycle": aa.DataCycle,
+		"anomalies":  s.Anomalies,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = publishFile(constants.GetAnomalyJsonPath(), j); err != nil {
+		return err
+	}
+	log.Printf("I! AnomalyAnalysis done")
+	return nil
+}
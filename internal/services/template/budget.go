@@ -0,0 +1,84 @@
+package template
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/i18n"
+	"github.com/galaxy-future/costpilot/internal/template"
+	"github.com/galaxy-future/costpilot/tools"
+)
+
+type BudgetTemplate struct {
+	bp *tools.BillingDatePilot
+
+	Statuses []data.BudgetStatus
+}
+
+func NewBudgetTemplate(statuses []data.BudgetStatus, t time.Time) *BudgetTemplate {
+	return &BudgetTemplate{
+		bp:       tools.NewBillDatePilot().SetNowT(t),
+		Statuses: statuses,
+	}
+}
+
+func budgetColumns() []template.ItemInTableColumns {
+	return []template.ItemInTableColumns{
+		{Key: "name", Title: i18n.T("col.budget")},
+		{Key: "periodRange", Title: i18n.T("col.period")},
+		{Key: "amount", Title: i18n.T("col.budget_amount"), Sortable: true},
+		{Key: "spent", Title: i18n.T("col.spent"), Sortable: true},
+		{Key: "projected", Title: i18n.T("col.projected"), Sortable: true},
+		{Key: "percent", Title: i18n.T("col.percent"), Sortable: true},
+		{Key: "projectedPercent", Title: i18n.T("col.projected_percent"), Sortable: true},
+		{Key: "stateName", Title: i18n.T("col.state")},
+	}
+}
+
+func (s *BudgetTemplate) Assemble(_ context.Context) template.BudgetAnalysis {
+	recentDay := s.bp.GetRecentDayBillingDate()
+	ret := template.BudgetAnalysis{
+		Title:     i18n.T("budget.title"),
+		DataCycle: recentDay.Days[0] + " 23:59:59",
+		Columns:   budgetColumns(),
+		Budgets:   make([]template.ItemInBudgets, 0, len(s.Statuses)),
+	}
+	for _, st := range s.Statuses {
+		thresholds := make([]string, 0, len(st.Budget.GetThresholds()))
+		for _, t := range st.Budget.GetThresholds() {
+			thresholds = append(thresholds, fmt.Sprintf("%.0f", t))
+		}
+		ret.Budgets = append(ret.Budgets, template.ItemInBudgets{
+			Name:             st.Budget.Name,
+			Scope:            string(st.Budget.Scope),
+			Target:           st.Budget.Target,
+			Period:           string(st.Budget.Period),
+			PeriodRange:      st.PeriodStart + " ~ " + st.PeriodEnd,
+			Amount:           fmt.Sprintf("%.2f", st.Budget.Amount),
+			Spent:            fmt.Sprintf("%.2f", st.Spent),
+			Projected:        fmt.Sprintf("%.2f", st.Projected),
+			Percent:          fmt.Sprintf("%.2f", st.Percent),
+			ProjectedPercent: fmt.Sprintf("%.2f", st.ProjectedPercent),
+			Thresholds:       thresholds,
+			Unit:             tools.CurrencyUnit(st.Currency),
+			State:            string(st.State),
+			StateName:        i18n.T("budget.state." + string(st.State)),
+		})
+	}
+	return ret
+}
+
+func (s *BudgetTemplate) Export(_ context.Context, ba template.BudgetAnalysis) error {
+	c, err := template.ParseBudgetTemplate(ba)
+	if err != nil {
+		return err
+	}
+	if err = appendJsData(c); err != nil {
+		return err
+	}
+	log.Printf("I! BudgetAnalysis done")
+	return nil
+}
@@ -0,0 +1,153 @@
+package template
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/i18n"
+	"github.com/galaxy-future/costpilot/internal/template"
+	"github.com/galaxy-future/costpilot/tools"
+)
+
+// coverageColumns the titles follow the language, so they are built on each assembly
+func coverageColumns() []template.ItemInTableColumns {
+	return []template.ItemInTableColumns{
+		{Key: "account", Title: i18n.T("col.account")},
+		{Key: "provider", Title: i18n.T("col.provider")},
+		{Key: "type", Title: i18n.T("col.commitment_type")},
+		{Key: "covered", Title: i18n.T("col.covered"), Sortable: true},
+		{Key: "eligible", Title: i18n.T("col.eligible"), Sortable: true},
+		{Key: "unit", Title: i18n.T("col.unit")},
+		{Key: "coverage", Title: i18n.T("col.coverage"), Sortable: true},
+		{Key: "utilization", Title: i18n.T("col.utilization"), Sortable: true},
+	}
+}
+
+func expiringColumns() []template.ItemInTableColumns {
+	return []template.ItemInTableColumns{
+		{Key: "account", Title: i18n.T("col.account")},
+		{Key: "provider", Title: i18n.T("col.provider")},
+		{Key: "commitmentId", Title: i18n.T("col.commitment_id")},
+		{Key: "type", Title: i18n.T("col.commitment_type")},
+		{Key: "description", Title: i18n.T("col.description")},
+		{Key: "region", Title: i18n.T("col.region")},
+		{Key: "count", Title: i18n.T("col.count"), Sortable: true},
+		{Key: "endTime", Title: i18n.T("col.end_time")},
+		{Key: "daysLeft", Title: i18n.T("col.days_left"), Sortable: true},
+		{Key: "bucket", Title: i18n.T("col.bucket")},
+		{Key: "autoRenew", Title: i18n.T("col.auto_renew")},
+	}
+}
+
+type CommitmentTemplate struct {
+	bp *tools.BillingDatePilot
+
+	Analysis data.CommitmentAnalysis
+	days     int
+}
+
+func NewCommitmentTemplate(analysis data.CommitmentAnalysis, days int, t time.Time) *CommitmentTemplate {
+	return &CommitmentTemplate{
+		bp:       tools.NewBillDatePilot().SetNowT(t),
+		Analysis: analysis,
+		days:     days,
+	}
+}
+
+func (s *CommitmentTemplate) Assemble(_ context.Context) template.CommitmentAnalysis {
+	date := s.bp.GetRecentXDaysBillingDate(int32(s.days))
+	ret := template.CommitmentAnalysis{
+		Title:           i18n.T("commitment.title"),
+		CoverageTitle:   i18n.T("commitment.coverages"),
+		CoverageColumns: coverageColumns(),
+		Coverages:       make([]template.ItemInCoverages, 0, len(s.Analysis.Coverages)),
+		ExpiringTitle:   i18n.T("commitment.expiring"),
+		ExpiringColumns: expiringColumns(),
+		Expiring:        make([]template.ItemInExpiringCommits, 0, len(s.Analysis.Expiring)),
+	}
+	if len(date.Days) > 0 {
+		ret.DataCycle = date.Days[len(date.Days)-1] + " ~ " + date.Days[0]
+	}
+	for _, p := range s.Analysis.Providers {
+		ret.Statistics = append(ret.Statistics, template.ItemInStatistics{
+			SCycle:  i18n.T("commitment.coverage", i18n.ProviderName(p.Provider), commitmentTypeName(p.Type)),
+			SAmount: fmt.Sprintf("%.2f", p.Coverage),
+		})
+		if p.HasUtilization {
+			ret.Statistics = append(ret.Statistics, template.ItemInStatistics{
+				SCycle:  i18n.T("commitment.usage", i18n.ProviderName(p.Provider), commitmentTypeName(p.Type)),
+				SAmount: fmt.Sprintf("%.2f", p.Utilization),
+			})
+		}
+	}
+	for _, c := range s.Analysis.Coverages {
+		item := template.ItemInCoverages{
+			Account:     c.AccountName,
+			Provider:    i18n.ProviderName(c.Provider),
+			Type:        commitmentTypeName(c.Type),
+			Covered:     fmt.Sprintf("%.2f", c.CoveredUsage),
+			Eligible:    fmt.Sprintf("%.2f", c.EligibleUsage),
+			Unit:        commitmentUnitName(c.Unit),
+			Coverage:    fmt.Sprintf("%.2f", c.Coverage),
+			Utilization: _invalidValue,
+		}
+		if c.HasUtilization {
+			item.Utilization = fmt.Sprintf("%.2f", c.Utilization)
+		}
+		ret.Coverages = append(ret.Coverages, item)
+	}
+	for _, e := range s.Analysis.Expiring {
+		item := template.ItemInExpiringCommits{
+			Account:      e.AccountName,
+			Provider:     i18n.ProviderName(e.Provider),
+			CommitmentId: e.CommitmentId,
+			Type:         commitmentTypeName(e.Type),
+			Description:  e.Description,
+			Region:       e.RegionId,
+			Count:        e.Count,
+			EndTime:      e.EndTime.Format("2006-01-02 15:04:05"),
+			DaysLeft:     e.DaysLeft,
+			Bucket:       i18n.T("commitment.bucket", e.WithinDays),
+			AutoRenew:    i18n.T("no"),
+		}
+		if e.AutoRenew {
+			item.AutoRenew = i18n.T("yes")
+		}
+		ret.Expiring = append(ret.Expiring, item)
+	}
+	return ret
+}
+
+func (s *CommitmentTemplate) Export(_ context.Context, ca template.CommitmentAnalysis) error {
+	c, err := template.ParseCommitmentTemplate(ca)
+	if err != nil {
+		return err
+	}
+	if err = appendJsData(c); err != nil {
+		return err
+	}
+	log.Printf("I! CommitmentAnalysis done")
+	return nil
+}
+
+func commitmentTypeName(t string) string {
+	switch t {
+	case "ReservedInstance":
+		return i18n.T("commitment.reserved")
+	case "SavingsPlan":
+		return i18n.T("commitment.savings")
+	case "Subscription":
+		return i18n.T("commitment.subscribed")
+	}
+	return t
+}
+
+func commitmentUnitName(unit string) string {
+	if unit == "Hours" {
+		return i18n.T("unit.hours")
+	}
+	return tools.CurrencyUnit(unit)
+}
@@ -11,8 +11,10 @@ import (
 	"github.com/galaxy-future/costpilot/internal/constants"
 	"github.com/galaxy-future/costpilot/internal/constants/cloud"
 	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/i18n"
 	"github.com/galaxy-future/costpilot/internal/providers/types"
 	"github.com/galaxy-future/costpilot/internal/template"
+	cfgTypes "github.com/galaxy-future/costpilot/internal/types"
 	"github.com/galaxy-future/costpilot/tools"
 	"github.com/spf13/cast"
 )
@@ -23,6 +25,11 @@ type CostTemplate struct {
 
 	bp           *tools.BillingDatePilot
 	analysisData template.AnalysisData
+	forecast     *data.CostForecast
+	costView     cfgTypes.CostView
+	costBasis    types.CostBasis
+	alternative  *CostTemplate
+	unsupported  []data.Unsupported
 
 	provider cloud.Provider // tmp solution for multiple cloud provider TODO delete
 }
@@ -40,6 +47,31 @@ func (s *CostTemplate) SetProvider(provider cloud.Provider) {
 	s.provider = provider
 }
 
+// SetUnsupported the accounts and the data left out of the analysis
+func (s *CostTemplate) SetUnsupported(unsupported []data.Unsupported) {
+	s.unsupported = unsupported
+}
+
+// SetForecast 设置后月度成本走势追加预测值, 统计追加全年预计
+func (s *CostTemplate) SetForecast(forecast *data.CostForecast) {
+	s.forecast = forecast
+}
+
+// SetCostView 标记成本视图, 默认为现金视图
+func (s *CostTemplate) SetCostView(view cfgTypes.CostView) {
+	s.costView = view
+}
+
+// SetCostBasis 标记账单金额口径, 默认为优惠后金额
+func (s *CostTemplate) SetCostBasis(basis types.CostBasis) {
+	s.costBasis = basis
+}
+
+// SetAlternativeView 另一个成本视图, 导出后可在报告中切换
+func (s *CostTemplate) SetAlternativeView(alternative *CostTemplate) {
+	s.alternative = alternative
+}
+
 // CombineBilling 重新组合并制定 DaysBilling, MonthsBilling
 func (s *CostTemplate) CombineBilling(ctx context.Context, monthsBillingList, daysBillingList []*sync.Map) error {
 	for _, monthMap := range monthsBillingList {
@@ -111,7 +143,7 @@ func (s *CostTemplate) FormatDayStatistics(ctx context.Context) (template.CostAn
 		template.ItemInRatios{
 			Chart: template.ChartInRatios{
 				ID:       "productTypeRatio",
-				Title:    "日成本构成比例",
+				Title:    i18n.T("cost.ratio.product_day"),
 				MidUnit:  s.extractCurrencyUnit(),
 				MidValue: "",
 				Data:     s.productTypeRatioData(recentDay),
@@ -120,7 +152,7 @@ func (s *CostTemplate) FormatDayStatistics(ctx context.Context) (template.CostAn
 		template.ItemInRatios{
 			Chart: template.ChartInRatios{
 				ID:       "providerTypeRatio",
-				Title:    "日成本云厂商比例",
+				Title:    i18n.T("cost.ratio.provider_day"),
 				MidUnit:  s.extractCurrencyUnit(),
 				MidValue: "",
 				Data:     s.providerTypeRatioData(recentDay),
@@ -129,7 +161,7 @@ func (s *CostTemplate) FormatDayStatistics(ctx context.Context) (template.CostAn
 		template.ItemInRatios{
 			Chart: template.ChartInRatios{
 				ID:       "chargeTypeRatio",
-				Title:    "云服务器日花费付费类型",
+				Title:    i18n.T("cost.ratio.charge_day"),
 				MidUnit:  s.extractCurrencyUnit(),
 				MidValue: "",
 				Data:     s.chargeTypeRatioData(recentDay),
@@ -144,10 +176,10 @@ func (s *CostTemplate) FormatDayStatistics(ctx context.Context) (template.CostAn
 	costAnalysisByDay.CostTrend = &template.CostTrend{
 		Chart: template.ChartInCostTrend{
 			ID:     "costTrend",
-			Title:  "成本走势",
+			Title:  i18n.T("cost.trend"),
 			XData:  s.getLast14Days(),
 			Series: s.getDayItemInSeries(),
-			YTitle: []string{"成本 (" + s.extractCurrencyUnit() + ")", "变化率 (%)"},
+			YTitle: []string{i18n.T("cost.trend.y_cost", s.extractCurrencyUnit()), i18n.T("cost.trend.y_ratio")},
 			TooltipUnit: template.TooltipUnit{
 				Bar:  s.extractCurrencyUnit(),
 				Line: "%",
@@ -175,7 +207,7 @@ func (s *CostTemplate) FormatMonthStatistics(ctx context.Context) (template.Cost
 		template.ItemInRatios{
 			Chart: template.ChartInRatios{
 				ID:       "productTypeRatio",
-				Title:    "月成本构成比例",
+				Title:    i18n.T("cost.ratio.product_month"),
 				MidUnit:  s.extractCurrencyUnit(),
 				MidValue: "",
 				Data:     s.productTypeRatioData(recentMonth),
@@ -184,7 +216,7 @@ func (s *CostTemplate) FormatMonthStatistics(ctx context.Context) (template.Cost
 		template.ItemInRatios{
 			Chart: template.ChartInRatios{
 				ID:       "providerTypeRatio",
-				Title:    "月成本云厂商比例",
+				Title:    i18n.T("cost.ratio.provider_month"),
 				MidUnit:  s.extractCurrencyUnit(),
 				MidValue: "",
 				Data:     s.providerTypeRatioData(recentMonth),
@@ -193,7 +225,7 @@ func (s *CostTemplate) FormatMonthStatistics(ctx context.Context) (template.Cost
 		template.ItemInRatios{
 			Chart: template.ChartInRatios{
 				ID:       "chargeTypeRatio",
-				Title:    "云服务器月花费付费类型",
+				Title:    i18n.T("cost.ratio.charge_month"),
 				MidUnit:  s.extractCurrencyUnit(),
 				MidValue: "",
 				Data:     s.chargeTypeRatioData(recentMonth),
@@ -208,10 +240,10 @@ func (s *CostTemplate) FormatMonthStatistics(ctx context.Context) (template.Cost
 	costAnalysisByMonth.CostTrend = &template.CostTrend{
 		Chart: template.ChartInCostTrend{
 			ID:     "costTrend",
-			Title:  "成本走势",
+			Title:  i18n.T("cost.trend"),
 			XData:  s.getLast12Months(),
 			Series: s.getMonthItemInSeries(),
-			YTitle: []string{"成本 (" + s.extractCurrencyUnit() + ")", "变化率 (%)"},
+			YTitle: []string{i18n.T("cost.trend.y_cost", s.extractCurrencyUnit()), i18n.T("cost.trend.y_ratio")},
 			TooltipUnit: template.TooltipUnit{
 				Bar:  s.extractCurrencyUnit(),
 				Line: "%",
@@ -278,7 +310,7 @@ func (s *CostTemplate) productTypeRatioData(date tools.BillingDate) []template.I
 func (s *CostTemplate) providerTypeRatioData(date tools.BillingDate) []template.ItemInRatioData {
 	return []template.ItemInRatioData{
 		{
-			Name:  s.provider.StringCN(),
+			Name:  i18n.ProviderName(s.provider),
 			Value: s.sumBillingDateAmount(date),
 		},
 	}
@@ -314,7 +346,7 @@ func (s *CostTemplate) chargeTypeRatioData(date tools.BillingDate) []template.It
 	}
 	for k, v := range totalMap {
 		ret = append(ret, template.ItemInRatioData{
-			Name:  k.StringCN(),
+			Name:  i18n.SubscriptionTypeName(k),
 			Value: fmt.Sprintf("%.2f", v),
 		})
 	}
@@ -341,30 +373,35 @@ func (s *CostTemplate) getLast12Months() []string {
 	if len(ret.Days) != 0 {
 		months = append(months, ret.Days[0][:7]) // 2022-10-10 -> 2022-10
 	}
+	if s.forecast != nil {
+		for _, p := range s.forecast.NextMonths {
+			months = append(months, p.Period)
+		}
+	}
 	return months
 }
 func (s *CostTemplate) getDayItemInSeries() []template.ItemInSeries {
 	r := []template.ItemInSeries{
 		template.ItemInSeries{
-			Name:       "成本(本期)",
+			Name:       i18n.T("cost.series.current"),
 			Type:       "bar",
 			YAxisIndex: 0,
 			Data:       s.amountInLastXDays(15, true), // 近 14 天的每日成本
 		},
 		template.ItemInSeries{
-			Name:       "成本(上一年同期)",
+			Name:       i18n.T("cost.series.last_year"),
 			Type:       "bar",
 			YAxisIndex: 0,
 			Data:       s.amountInLastXDays(15, false), // 去年同期 14 天的每日成本
 		},
 		template.ItemInSeries{
-			Name:       "环比上一天",
+			Name:       i18n.T("cost.series.day_over_day"),
 			Type:       "line",
 			YAxisIndex: 1,
 			Data:       nil,
 		},
 		template.ItemInSeries{
-			Name:       "同比上一年",
+			Name:       i18n.T("cost.series.year_over"),
 			Type:       "line",
 			YAxisIndex: 1,
 			Data:       nil,
@@ -381,8 +418,13 @@ func (s *CostTemplate) getDayItemInSeries() []template.ItemInSeries {
 	r[2].Data = chainRatios  // 环比上一天 ["22.34", ... "45.67"]
 	r[3].Data = yrOnyrRatios // 同比上一年 ["22.34", "--",... "45.67"]
 
-	r[0].Data = r[0].Data[1:] // 成本(本期),只需保留 14 个值
-	r[1].Data = r[1].Data[1:] // 成本(上一年同期),只需保留 14 个值
+	// the days are missing if no account of the grant is billed in them
+	if len(r[0].Data) > 0 {
+		r[0].Data = r[0].Data[1:] // 成本(本期),只需保留 14 个值
+	}
+	if len(r[1].Data) > 0 {
+		r[1].Data = r[1].Data[1:] // 成本(上一年同期),只需保留 14 个值
+	}
 	// r[3].Data = r[3].Data[1:] //同比上一年,只需保留 14 个值
 
 	return r
@@ -390,25 +432,25 @@ func (s *CostTemplate) getDayItemInSeries() []template.ItemInSeries {
 func (s *CostTemplate) getMonthItemInSeries() []template.ItemInSeries {
 	r := []template.ItemInSeries{
 		template.ItemInSeries{
-			Name:       "成本(本期)",
+			Name:       i18n.T("cost.series.current"),
 			Type:       "bar",
 			YAxisIndex: 0,
 			Data:       s.amountInLastXMonths(13, true), // 近 12 月的每月成本
 		},
 		template.ItemInSeries{
-			Name:       "成本(上一年同期)",
+			Name:       i18n.T("cost.series.last_year"),
 			Type:       "bar",
 			YAxisIndex: 0,
 			Data:       s.amountInLastXMonths(13, false), // 去年同期 12 月的每月成本
 		},
 		template.ItemInSeries{
-			Name:       "环比上一月",
+			Name:       i18n.T("cost.series.month_over"),
 			Type:       "line",
 			YAxisIndex: 1,
 			Data:       nil,
 		},
 		template.ItemInSeries{
-			Name:       "同比上一年",
+			Name:       i18n.T("cost.series.year_over"),
 			Type:       "line",
 			YAxisIndex: 1,
 			Data:       nil,
@@ -434,6 +476,30 @@ func (s *CostTemplate) getMonthItemInSeries() []template.ItemInSeries {
 	// r[1].Data = r[1].Data[1:] //成本(上一年同期),只需保留 14 个值
 	// r[3].Data = r[3].Data[1:] //同比上一年,只需保留 14 个值
 
+	if s.forecast != nil {
+		r = append(r, s.getForecastItemInSeries(len(r[0].Data)-1)...)
+	}
+	return r
+}
+
+// getForecastItemInSeries 本月及未来几个月的预测值与 95% 置信区间, 前 n 个月为空
+func (s *CostTemplate) getForecastItemInSeries(n int) []template.ItemInSeries {
+	r := []template.ItemInSeries{
+		{Name: i18n.T("cost.series.forecast"), Type: "line", YAxisIndex: 0},
+		{Name: i18n.T("cost.series.upper"), Type: "line", YAxisIndex: 0},
+		{Name: i18n.T("cost.series.lower"), Type: "line", YAxisIndex: 0},
+	}
+	for i := 0; i < n; i++ {
+		for j := range r {
+			r[j].Data = append(r[j].Data, "--")
+		}
+	}
+	points := append([]data.ForecastPoint{s.forecast.CurrentMonth}, s.forecast.NextMonths...)
+	for _, p := range points {
+		r[0].Data = append(r[0].Data, fmt.Sprintf("%.2f", p.Amount))
+		r[1].Data = append(r[1].Data, fmt.Sprintf("%.2f", p.Upper))
+		r[2].Data = append(r[2].Data, fmt.Sprintf("%.2f", p.Lower))
+	}
 	return r
 }
 func (s *CostTemplate) amountInLastXDays(n int32, isRecentYear bool) []string {
@@ -484,34 +550,47 @@ func (s *CostTemplate) getStatistics() []template.ItemInStatistics {
 	previousYear := s.bp.GetPreviousYearBillingDate()
 	statistics := []template.ItemInStatistics{
 		template.ItemInStatistics{
-			SCycle:     yesterdayT.Format("2006年01月02日累计"),
+			SCycle:     i18n.T("stat.day", yesterdayT.Format(i18n.T("layout.day"))),
 			SAmount:    s.sumBillingDateAmount(yesterday),
-			SPreCycle:  "前一天同期",
+			SPreCycle:  i18n.T("stat.prev_day"),
 			SPreAmount: s.sumBillingDateAmount(beforeYesterday),
 			SRatio:     "",
 		},
 		template.ItemInStatistics{
-			SCycle:     yesterdayT.Format("2006年01月累计"),
+			SCycle:     i18n.T("stat.month", yesterdayT.Format(i18n.T("layout.month"))),
 			SAmount:    s.sumBillingDateAmount(recentMonth),
-			SPreCycle:  "上月同期",
+			SPreCycle:  i18n.T("stat.prev_month"),
 			SPreAmount: s.sumBillingDateAmount(previousMonth),
 			SRatio:     "",
 		},
 		template.ItemInStatistics{
-			SCycle:     fmt.Sprintf("%d年第%d季度累计", s.bp.GetRecentYear(), s.bp.GetRecentQuarter()),
+			SCycle:     i18n.T("stat.quarter", s.bp.GetRecentYear(), s.bp.GetRecentQuarter()),
 			SAmount:    s.sumBillingDateAmount(recentQuarter),
-			SPreCycle:  "上季度同期",
+			SPreCycle:  i18n.T("stat.prev_quarter"),
 			SPreAmount: s.sumBillingDateAmount(previousQuarter),
 			SRatio:     "",
 		},
 		template.ItemInStatistics{
-			SCycle:     fmt.Sprintf("%d年累计", s.bp.GetRecentYear()),
+			SCycle:     i18n.T("stat.year", s.bp.GetRecentYear()),
 			SAmount:    s.sumBillingDateAmount(recentYear),
-			SPreCycle:  "上年同期",
+			SPreCycle:  i18n.T("stat.prev_year"),
 			SPreAmount: s.sumBillingDateAmount(previousYear),
 			SRatio:     "",
 		},
 	}
+	if s.forecast != nil {
+		var previousYearMonths []string
+		for m := time.January; m <= time.December; m++ {
+			previousYearMonths = append(previousYearMonths, fmt.Sprintf("%d-%02d", s.bp.GetRecentYear()-1, m))
+		}
+		statistics = append(statistics, template.ItemInStatistics{
+			SCycle:     i18n.T("stat.year_forecast", s.bp.GetRecentYear()),
+			SAmount:    fmt.Sprintf("%.2f", s.forecast.YearEnd.Amount),
+			SPreCycle:  i18n.T("stat.prev_full_year"),
+			SPreAmount: s.sumBillingDateAmount(tools.BillingDate{Months: previousYearMonths}),
+			SRatio:     "",
+		})
+	}
 	// 单独计算 s_ratio
 	for i, v := range statistics {
 		statistics[i].SRatio = tools.RatioString(v.SPreAmount, v.SAmount)
@@ -520,25 +599,66 @@ func (s *CostTemplate) getStatistics() []template.ItemInStatistics {
 	return statistics
 }
 
-func (s *CostTemplate) ExportCostAnalysis(ctx context.Context) error {
+// FormatAnalysisData
+func (s *CostTemplate) FormatAnalysisData(ctx context.Context) (template.AnalysisData, error) {
 	dayAnalysis, err := s.FormatDayStatistics(ctx)
 	if err != nil {
-		return err
+		return template.AnalysisData{}, err
 	}
 	monthAnalysis, err := s.FormatMonthStatistics(ctx)
 	if err != nil {
-		return err
+		return template.AnalysisData{}, err
+	}
+	view := s.costView
+	if view == "" {
+		view = cfgTypes.CostViewCash
 	}
 	ad := template.AnalysisData{
+		CostView:            string(view),
+		CostViewName:        i18n.T("cost.view.cash"),
+		CostBasis:           s.costBasis.OrDefault().String(),
+		CostBasisName:       costBasisName(s.costBasis),
 		CostAnalysisByDay:   dayAnalysis,
 		CostAnalysisByMonth: monthAnalysis,
+		Unsupported:         unsupportedItems(s.unsupported),
+	}
+	if view == cfgTypes.CostViewAmortized {
+		ad.CostViewName = i18n.T("cost.view.amortized")
 	}
+	if len(ad.Unsupported) > 0 {
+		ad.UnsupportedTitle, ad.UnsupportedColumns = i18n.T("report.unsupported"), unsupportedColumns()
+	}
+	return ad, nil
+}
+
+// AssembleAnalysisData the data of FormatAnalysisData with the alternative view
+func (s *CostTemplate) AssembleAnalysisData(ctx context.Context) (template.AnalysisData, error) {
+	ad, err := s.FormatAnalysisData(ctx)
+	if err != nil {
+		return template.AnalysisData{}, err
+	}
+	if s.alternative != nil {
+		alternative, err := s.alternative.FormatAnalysisData(ctx)
+		if err != nil {
+			return template.AnalysisData{}, err
+		}
+		ad.AlternativeView = &alternative
+	}
+	return ad, nil
+}
+
+func (s *CostTemplate) ExportCostAnalysis(ctx context.Context) error {
+	ad, err := s.AssembleAnalysisData(ctx)
+	if err != nil {
+		return err
+	}
+	s.analysisData = ad
 	c, err := template.ParseCostTemplate(ad)
 	if err != nil {
 		return err
 	}
 	// log.Printf("I! template content:%s", c)
-	err = ioutil.WriteFile(constants.GetJsDataPath(), []byte(c), 0644)
+	err = ioutil.WriteFile(constants.GetJsDataStagingPath(), []byte(c), 0644)
 	if err != nil {
 		return err
 	}
@@ -546,6 +666,24 @@ func (s *CostTemplate) ExportCostAnalysis(ctx context.Context) error {
 	log.Printf("I! ExportCostAnalysis done")
 	return nil
 }
+
+// GetAnalysisData the data exported by ExportCostAnalysis
+func (s *CostTemplate) GetAnalysisData() template.AnalysisData {
+	return s.analysisData
+}
+
+func costBasisName(basis types.CostBasis) string {
+	switch basis.OrDefault() {
+	case types.CostBasisList:
+		return i18n.T("cost.basis.list")
+	case types.CostBasisNet:
+		return i18n.T("cost.basis.net")
+	case types.CostBasisTaxInclusive:
+		return i18n.T("cost.basis.tax_incl")
+	}
+	return i18n.T("cost.basis.discount")
+}
+
 func (s *CostTemplate) extractCurrencyUnit() (result string) {
 	s.DaysBilling.Range(func(key, value interface{}) bool {
 		for _, v := range value.(data.DailyBilling).ProductsBilling {
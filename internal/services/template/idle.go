@@ -0,0 +1,128 @@
+package template
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/constants"
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/i18n"
+	"github.com/galaxy-future/costpilot/internal/template"
+	"github.com/galaxy-future/costpilot/tools"
+)
+
+func idleInstanceColumns() []template.ItemInTableColumns {
+	return []template.ItemInTableColumns{
+		{Key: "account", Title: i18n.T("col.account")},
+		{Key: "provider", Title: i18n.T("col.provider")},
+		{Key: "instanceId", Title: i18n.T("col.instance_id")},
+		{Key: "region", Title: i18n.T("col.region")},
+		{Key: "instanceSpec", Title: i18n.T("col.instance_spec")},
+		{Key: "subscriptionType", Title: i18n.T("col.charge_type")},
+		{Key: "level", Title: i18n.T("col.level")},
+		{Key: "cpuAvg", Title: i18n.T("col.cpu_avg"), Sortable: true},
+		{Key: "cpuPeak", Title: i18n.T("col.cpu_peak"), Sortable: true},
+		{Key: "memoryAvg", Title: i18n.T("col.memory_avg"), Sortable: true},
+		{Key: "memoryPeak", Title: i18n.T("col.memory_peak"), Sortable: true},
+		{Key: "monthlyCost", Title: i18n.T("col.monthly_cost"), Sortable: true},
+	}
+}
+
+type IdleInstanceTemplate struct {
+	bp *tools.BillingDatePilot
+
+	Instances []data.IdleInstance
+}
+
+func NewIdleInstanceTemplate(instances []data.IdleInstance, t time.Time) *IdleInstanceTemplate {
+	return &IdleInstanceTemplate{
+		bp:        tools.NewBillDatePilot().SetNowT(t),
+		Instances: instances,
+	}
+}
+
+func (s *IdleInstanceTemplate) Assemble(_ context.Context) template.IdleInstanceAnalysis {
+	recentDay := s.bp.GetRecentDayBillingDate()
+	ret := template.IdleInstanceAnalysis{
+		Title:     i18n.T("idle.title"),
+		DataCycle: recentDay.Days[0] + " 23:59:59",
+		Columns:   idleInstanceColumns(),
+		Instances: make([]template.ItemInIdleInstances, 0, len(s.Instances)),
+	}
+	for _, i := range s.Instances {
+		item := template.ItemInIdleInstances{
+			Account:          i.AccountName,
+			Provider:         i18n.ProviderName(i.Provider),
+			InstanceId:       i.InstanceId,
+			Region:           i.RegionName,
+			InstanceSpec:     i.InstanceSpec,
+			SubscriptionType: _invalidValue,
+			Level:            i18n.T("idle.level.low"),
+			CpuAvg:           fmt.Sprintf("%.2f", i.CpuAvg),
+			CpuPeak:          fmt.Sprintf("%.2f", i.CpuPeak),
+			MemoryAvg:        _invalidValue,
+			MemoryPeak:       _invalidValue,
+			MonthlyCost:      _invalidValue,
+			Unit:             tools.CurrencyUnit(i.Currency),
+		}
+		if i.SubscriptionType != "" {
+			item.SubscriptionType = i18n.SubscriptionTypeName(i.SubscriptionType)
+		}
+		if i.Level == data.InstanceUsageIdle {
+			item.Level = i18n.T("idle.level.idle")
+		}
+		if i.HasMemory {
+			item.MemoryAvg, item.MemoryPeak = fmt.Sprintf("%.2f", i.MemoryAvg), fmt.Sprintf("%.2f", i.MemoryPeak)
+		}
+		if i.HasBill {
+			item.MonthlyCost = fmt.Sprintf("%.2f", i.MonthlyCost)
+		}
+		ret.Instances = append(ret.Instances, item)
+	}
+	return ret
+}
+
+// Export append the instances to data-set.js, and write them as csv to hand over to the owners
+func (s *IdleInstanceTemplate) Export(_ context.Context, ia template.IdleInstanceAnalysis) error {
+	c, err := template.ParseIdleInstanceTemplate(ia)
+	if err != nil {
+		return err
+	}
+	if err = appendJsData(c); err != nil {
+		return err
+	}
+	b, err := FormatIdleInstanceCsv(ia)
+	if err != nil {
+		return err
+	}
+	if err = publishFile(constants.GetIdleInstanceCsvPath(), b); err != nil {
+		return err
+	}
+	log.Printf("I! IdleInstanceAnalysis done")
+	return nil
+}
+
+func FormatIdleInstanceCsv(ia template.IdleInstanceAnalysis) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	header := make([]string, 0, len(ia.Columns)+1)
+	for _, c := range ia.Columns {
+		header = append(header, c.Title)
+	}
+	header = append(header, i18n.T("col.currency"))
+	records := [][]string{header}
+	for _, i := range ia.Instances {
+		records = append(records, []string{
+			i.Account, i.Provider, i.InstanceId, i.Region, i.InstanceSpec, i.SubscriptionType, i.Level,
+			i.CpuAvg, i.CpuPeak, i.MemoryAvg, i.MemoryPeak, i.MonthlyCost, i.Unit,
+		})
+	}
+	if err := w.WriteAll(records); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,103 @@
+package template
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/i18n"
+	"github.com/galaxy-future/costpilot/internal/template"
+	"github.com/galaxy-future/costpilot/tools"
+)
+
+func optimizationColumns() []template.ItemInTableColumns {
+	return []template.ItemInTableColumns{
+		{Key: "account", Title: i18n.T("col.account")},
+		{Key: "provider", Title: i18n.T("col.provider")},
+		{Key: "instanceId", Title: i18n.T("col.instance_id")},
+		{Key: "region", Title: i18n.T("col.region")},
+		{Key: "action", Title: i18n.T("col.action")},
+		{Key: "currentType", Title: i18n.T("col.current_type")},
+		{Key: "recommendedType", Title: i18n.T("col.recommended_type")},
+		{Key: "cpuPeak", Title: i18n.T("col.cpu_peak"), Sortable: true},
+		{Key: "memoryPeak", Title: i18n.T("col.memory_peak"), Sortable: true},
+		{Key: "monthlyCost", Title: i18n.T("col.monthly_cost"), Sortable: true},
+		{Key: "monthlySavings", Title: i18n.T("col.monthly_savings"), Sortable: true},
+	}
+}
+
+type OptimizationTemplate struct {
+	bp *tools.BillingDatePilot
+
+	Opportunities []data.OptimizationOpportunity
+}
+
+func NewOptimizationTemplate(opportunities []data.OptimizationOpportunity, t time.Time) *OptimizationTemplate {
+	return &OptimizationTemplate{
+		bp:            tools.NewBillDatePilot().SetNowT(t),
+		Opportunities: opportunities,
+	}
+}
+
+func (s *OptimizationTemplate) Assemble(_ context.Context) template.OptimizationAnalysis {
+	recentDay := s.bp.GetRecentDayBillingDate()
+	ret := template.OptimizationAnalysis{
+		Title:         i18n.T("optimization.title"),
+		DataCycle:     recentDay.Days[0] + " 23:59:59",
+		Columns:       optimizationColumns(),
+		Opportunities: make([]template.ItemInOptimizations, 0, len(s.Opportunities)),
+	}
+	totals := make(map[string]float64) // key : currency unit
+	for _, o := range s.Opportunities {
+		unit := tools.CurrencyUnit(o.Currency)
+		totals[unit] = tools.Float64Add(totals[unit], o.MonthlySavings)
+		item := template.ItemInOptimizations{
+			Account:         o.AccountName,
+			Provider:        i18n.ProviderName(o.Provider),
+			InstanceId:      o.InstanceId,
+			Region:          o.RegionName,
+			Action:          i18n.T("action.downsize"),
+			CurrentType:     o.CurrentType,
+			RecommendedType: o.RecommendedType,
+			CpuPeak:         fmt.Sprintf("%.2f", o.CpuPeak),
+			MemoryPeak:      _invalidValue,
+			MonthlyCost:     fmt.Sprintf("%.2f", o.MonthlyCost),
+			MonthlySavings:  fmt.Sprintf("%.2f", o.MonthlySavings),
+			Unit:            unit,
+		}
+		if o.Kind == data.OptimizationTerminate {
+			item.Action, item.RecommendedType = i18n.T("action.terminate"), _invalidValue
+		}
+		if o.HasMemory {
+			item.MemoryPeak = fmt.Sprintf("%.2f", o.MemoryPeak)
+		}
+		ret.Opportunities = append(ret.Opportunities, item)
+	}
+	units := make([]string, 0, len(totals))
+	for unit := range totals {
+		units = append(units, unit)
+	}
+	sort.Strings(units)
+	for _, unit := range units {
+		ret.Statistics = append(ret.Statistics, template.ItemInStatistics{
+			SCycle:  i18n.T("optimization.savings", unit),
+			SAmount: fmt.Sprintf("%.2f", totals[unit]),
+		})
+	}
+	return ret
+}
+
+func (s *OptimizationTemplate) Export(_ context.Context, oa template.OptimizationAnalysis) error {
+	c, err := template.ParseOptimizationTemplate(oa)
+	if err != nil {
+		return err
+	}
+	if err = appendJsData(c); err != nil {
+		return err
+	}
+	log.Printf("I! OptimizationAnalysis done")
+	return nil
+}
@@ -0,0 +1,35 @@
+package template
+
+import (
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/i18n"
+	"github.com/galaxy-future/costpilot/internal/template"
+)
+
+// unsupportedColumns the columns of the unsupported table of the dashboard
+func unsupportedColumns() []template.ItemInTableColumns {
+	return []template.ItemInTableColumns{
+		{Key: "account", Title: i18n.T("col.account")},
+		{Key: "provider", Title: i18n.T("col.provider")},
+		{Key: "capability", Title: i18n.T("report.col.capability")},
+		{Key: "impact", Title: i18n.T("report.col.impact")},
+	}
+}
+
+// unsupportedItems the capabilities and the impacts in the language of the report
+func unsupportedItems(unsupported []data.Unsupported) []template.ItemInUnsupported {
+	var items []template.ItemInUnsupported
+	for _, u := range unsupported {
+		capability := i18n.T("capability." + u.Capability)
+		if u.Analysis == data.AnalysisMetric {
+			capability = i18n.T("metric." + u.Capability)
+		}
+		items = append(items, template.ItemInUnsupported{
+			Account:    u.AccountName,
+			Provider:   i18n.ProviderName(u.Provider),
+			Capability: capability,
+			Impact:     i18n.T("unsupported." + u.Analysis),
+		})
+	}
+	return items
+}
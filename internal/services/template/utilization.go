@@ -5,12 +5,15 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/galaxy-future/costpilot/internal/constants"
 	"github.com/galaxy-future/costpilot/internal/constants/cloud"
 	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/i18n"
+	providerTypes "github.com/galaxy-future/costpilot/internal/providers/types"
 	"github.com/galaxy-future/costpilot/internal/template"
 	"github.com/galaxy-future/costpilot/tools"
 	"github.com/spf13/cast"
@@ -24,6 +27,10 @@ type UtilizationTemplate struct {
 	CpuUtilization     *sync.Map // key : day , val : []data.DailyCpuUtilization
 	MemoryUtilization  *sync.Map // key : day , val : []data.DailyMemoryUtilization
 	RecentInstanceList []data.InstanceDetail
+	DailyMetrics       map[providerTypes.MetricItem]*sync.Map // key : day , val : []data.DailyMetric
+
+	metrics     []providerTypes.MetricItem
+	unsupported []data.Unsupported
 }
 
 func NewUtilization(t time.Time) *UtilizationTemplate {
@@ -32,6 +39,11 @@ func NewUtilization(t time.Time) *UtilizationTemplate {
 	}
 }
 
+// SetUnsupported the accounts and the data left out of the analysis
+func (s *UtilizationTemplate) SetUnsupported(unsupported []data.Unsupported) {
+	s.unsupported = unsupported
+}
+
 func (s *UtilizationTemplate) AssignData(dailyCpuProviders, dailyMemoryProviders, recentInstancesProviders []*sync.Map) {
 	// 以账号为维度，遍历各账号下每天资源使用率
 	for _, dailyCpuProvider := range dailyCpuProviders {
@@ -105,6 +117,105 @@ func (s *UtilizationTemplate) AssignData(dailyCpuProviders, dailyMemoryProviders
 	}
 }
 
+// AssignMetrics the catalog metrics charted in the order of the config, the samples of the accounts are merged by day
+func (s *UtilizationTemplate) AssignMetrics(metrics []providerTypes.MetricItem, dailyMetricProviders []map[providerTypes.MetricItem]*sync.Map) {
+	s.metrics = nil
+	for _, m := range metrics {
+		if _, ok := providerTypes.LookupMetric(m); !ok || m == providerTypes.MetricItemCPUUtilization || m == providerTypes.MetricItemMemoryUsedUtilization {
+			continue
+		}
+		s.metrics = append(s.metrics, m)
+	}
+	s.DailyMetrics = make(map[providerTypes.MetricItem]*sync.Map)
+	for _, provider := range dailyMetricProviders {
+		for m, daily := range provider {
+			merged, ok := s.DailyMetrics[m]
+			if !ok {
+				merged = &sync.Map{}
+				s.DailyMetrics[m] = merged
+			}
+			daily.Range(func(key, value interface{}) bool {
+				var list []data.DailyMetric
+				if val, ok := merged.Load(key); ok {
+					list = val.([]data.DailyMetric)
+				}
+				merged.Store(key, append(list, value.(data.DailyMetric)))
+				return true
+			})
+		}
+	}
+}
+
+// averagingMetric the average of the instances in the days, nil if no sample
+func (s *UtilizationTemplate) averagingMetric(m providerTypes.MetricItem, date tools.BillingDate) *float64 {
+	daily, ok := s.DailyMetrics[m]
+	if !ok {
+		return nil
+	}
+	var (
+		total float64
+		num   = 0
+	)
+	for _, d := range date.Days {
+		if val, ok := daily.Load(d); ok {
+			for _, list := range val.([]data.DailyMetric) {
+				for _, i := range list.Samples {
+					num++
+					total += i.Average
+				}
+			}
+		}
+	}
+	if num == 0 {
+		return nil
+	}
+	avg := total / float64(num)
+	return &avg
+}
+
+// getMetricInLastXDays the daily averages in the unit scaled by divisor
+func (s *UtilizationTemplate) getMetricInLastXDays(m providerTypes.MetricItem, n int32, divisor float64) []string {
+	dateRange := s.bp.GetRecentXDaysBillingDate(n)
+	ret := make([]string, 0, len(dateRange.Days))
+	for _, d := range dateRange.Days {
+		v := s.averagingMetric(m, tools.BillingDate{Days: []string{d}})
+		if v == nil {
+			ret = append(ret, _invalidValue)
+			continue
+		}
+		ret = append(ret, fmt.Sprintf("%.2f", *v/divisor))
+	}
+	return ret
+}
+
+// scaleMetricUnit the divisor and the unit shown for the largest daily average, eg: 2048 B/s is shown as 2 KB/s
+func (s *UtilizationTemplate) scaleMetricUnit(d providerTypes.MetricDefinition, n int32) (float64, string) {
+	var base float64
+	switch d.Unit {
+	case providerTypes.MetricUnitBytesPerSecond:
+		base = 1024
+	case providerTypes.MetricUnitBitsPerSecond:
+		base = 1000
+	default:
+		return 1, string(d.Unit)
+	}
+	var max float64
+	for _, day := range s.bp.GetRecentXDaysBillingDate(n).Days {
+		if v := s.averagingMetric(d.Item, tools.BillingDate{Days: []string{day}}); v != nil && *v > max {
+			max = *v
+		}
+	}
+	divisor, prefix := 1.0, ""
+	for _, p := range []string{"K", "M", "G"} {
+		if max < divisor*base {
+			break
+		}
+		divisor *= base
+		prefix = p
+	}
+	return divisor, prefix + string(d.Unit)
+}
+
 func (s *UtilizationTemplate) averagingCpuUsedRatio(date tools.BillingDate) string {
 	var (
 		total float64
@@ -177,28 +288,28 @@ func (s *UtilizationTemplate) getStatistics() []template.UtilizeAnalysisStatisti
 
 	return []template.UtilizeAnalysisStatisticsItem{
 		{
-			SCycle:     "CPU 平均利用率",
+			SCycle:     i18n.T("util.cpu_avg"),
 			SAmount:    s.averagingCpuUsedRatio(yesterday),
 			SUnit:      "%",
-			SPreCycle:  "前日数据",
+			SPreCycle:  i18n.T("util.prev_day"),
 			SPreAmount: s.averagingCpuUsedRatio(beforeYesterday),
 			SPreUnit:   "%",
 		},
 		{
-			SCycle:     "内存平均利用率",
+			SCycle:     i18n.T("util.memory_avg"),
 			SAmount:    s.averagingMemoryUsedRatio(yesterday),
 			SUnit:      "%",
-			SPreCycle:  "前日数据",
+			SPreCycle:  i18n.T("util.prev_day"),
 			SPreAmount: s.averagingMemoryUsedRatio(beforeYesterday),
 			SPreUnit:   "%",
 		},
 		{
-			SCycle:     "云服务器",
+			SCycle:     i18n.T("util.servers"),
 			SAmount:    s.sumSvrNum(yesterday),
-			SUnit:      "台",
-			SPreCycle:  "前日数据",
+			SUnit:      i18n.T("unit.server"),
+			SPreCycle:  i18n.T("util.prev_day"),
 			SPreAmount: s.sumSvrNum(beforeYesterday),
-			SPreUnit:   "台",
+			SPreUnit:   i18n.T("unit.server"),
 		},
 	}
 }
@@ -211,7 +322,7 @@ func (s *UtilizationTemplate) extractSubscriptionType() []template.ItemInRatioDa
 	var itemData []template.ItemInRatioData
 	for subscriptionType, i := range radioMap {
 		itemData = append(itemData, template.ItemInRatioData{
-			Name:  subscriptionType.StringCN(),
+			Name:  i18n.SubscriptionTypeName(subscriptionType),
 			Value: fmt.Sprintf("%d", i),
 		})
 	}
@@ -222,7 +333,7 @@ func (s *UtilizationTemplate) extractSubscriptionType() []template.ItemInRatioDa
 func (s *UtilizationTemplate) extractRegion() []template.ItemInRatioData {
 	radioMap := make(map[string]int)
 	for _, i := range s.RecentInstanceList {
-		k := fmt.Sprintf("%s-%s", i.Provider.StringCN(), i.RegionName)
+		k := fmt.Sprintf("%s-%s", i18n.ProviderName(i.Provider), i.RegionName)
 		radioMap[k]++
 	}
 	var itemData []template.ItemInRatioData
@@ -244,7 +355,7 @@ func (s *UtilizationTemplate) extractProvider() []template.ItemInRatioData {
 	var itemData []template.ItemInRatioData
 	for p, n := range radioMap {
 		itemData = append(itemData, template.ItemInRatioData{
-			Name:  p.StringCN(),
+			Name:  i18n.ProviderName(p),
 			Value: fmt.Sprintf("%d", n),
 		})
 	}
@@ -308,17 +419,62 @@ func (s *UtilizationTemplate) getMemoryUsedInLastXDays(n int32) []string {
 	return ret
 }
 
+// getUtilizeTrendSeries the catalog metrics in percent are shown with cpu and memory
 func (s *UtilizationTemplate) getUtilizeTrendSeries() []template.UtilizeAnalysisItemInSeries {
-	return []template.UtilizeAnalysisItemInSeries{
+	series := []template.UtilizeAnalysisItemInSeries{
 		{
-			Name: "CPU",
+			Name: i18n.T("util.series.cpu"),
 			Data: s.getCpuUsedInLastXDays(14),
 		},
 		{
-			Name: "内存",
+			Name: i18n.T("util.series.memory"),
 			Data: s.getMemoryUsedInLastXDays(14),
 		},
 	}
+	for _, m := range s.metrics {
+		if d, _ := providerTypes.LookupMetric(m); d.Unit != providerTypes.MetricUnitPercent {
+			continue
+		}
+		if _, ok := s.DailyMetrics[m]; !ok {
+			continue
+		}
+		series = append(series, template.UtilizeAnalysisItemInSeries{
+			Name: i18n.T("metric." + string(m)),
+			Data: s.getMetricInLastXDays(m, 14, 1),
+		})
+	}
+	return series
+}
+
+// getMetricTrends a chart of each catalog metric fetched from any account
+func (s *UtilizationTemplate) getMetricTrends() []template.UtilizeAnalysisUtilizeTrend {
+	var trends []template.UtilizeAnalysisUtilizeTrend
+	for _, m := range s.metrics {
+		if _, ok := s.DailyMetrics[m]; !ok {
+			continue
+		}
+		d, _ := providerTypes.LookupMetric(m)
+		divisor, unit := s.scaleMetricUnit(d, 14)
+		title := i18n.T("metric." + string(m))
+		yTitle := title
+		if unit != "" {
+			yTitle = fmt.Sprintf("%s (%s)", title, unit)
+		}
+		trends = append(trends, template.UtilizeAnalysisUtilizeTrend{
+			Chart: template.ChartUtilizeTrend{
+				ID:    "metricTrend_" + strings.ReplaceAll(string(m), ".", "_"),
+				Title: title,
+				XData: s.getLast14Days(),
+				Series: []template.UtilizeAnalysisItemInSeries{{
+					Name: i18n.T("util.series.avg"),
+					Data: s.getMetricInLastXDays(m, 14, divisor),
+				}},
+				YTitle:      []string{yTitle},
+				TooltipUnit: template.TooltipUnit{Line: unit},
+			},
+		})
+	}
+	return trends
 }
 
 func (s *UtilizationTemplate) getCpuTrendSeries() []template.UtilizeAnalysisItemInSeries {
@@ -340,7 +496,7 @@ func (s *UtilizationTemplate) getCpuTrendSeries() []template.UtilizeAnalysisItem
 			Data: s.getDailyDistributionByCpuRadio(60, 80),
 		},
 		{
-			Name: "80%以上",
+			Name: i18n.T("util.cpu_above_80"),
 			Data: s.getDailyDistributionByCpuRadio(80, 100),
 		},
 	}
@@ -369,24 +525,24 @@ func (s *UtilizationTemplate) Assemble(_ context.Context) template.UtilizeAnalys
 		{
 			Chart: template.ChartInRatios{
 				ID:      "chargeTypeRatio",
-				Title:   "服务器付费类型",
-				MidUnit: "台",
+				Title:   i18n.T("util.ratio.charge"),
+				MidUnit: i18n.T("unit.server"),
 				Data:    s.extractSubscriptionType(),
 			},
 		},
 		{
 			Chart: template.ChartInRatios{
 				ID:      "providerTypeRatio",
-				Title:   "服务器云厂商分布",
-				MidUnit: "台",
+				Title:   i18n.T("util.ratio.provider"),
+				MidUnit: i18n.T("unit.server"),
 				Data:    s.extractProvider(),
 			},
 		},
 		{
 			Chart: template.ChartInRatios{
 				ID:      "regionTypeRatio",
-				Title:   "云服务器地域分布",
-				MidUnit: "台",
+				Title:   i18n.T("util.ratio.region"),
+				MidUnit: i18n.T("unit.server"),
 				Data:    s.extractRegion(),
 			},
 		},
@@ -395,12 +551,12 @@ func (s *UtilizationTemplate) Assemble(_ context.Context) template.UtilizeAnalys
 	utilizeAnalysisByDay.CpuTrend = &template.UtilizeAnalysisCpuTrend{
 		Chart: template.ChartCpuTrend{
 			ID:     "cpuTrend",
-			Title:  "CPU 利用率分布",
+			Title:  i18n.T("util.cpu_trend"),
 			XData:  s.getLast14Days(),
 			Series: s.getCpuTrendSeries(),
-			YTitle: []string{"机器数 (个)"},
+			YTitle: []string{i18n.T("util.cpu_trend.y")},
 			TooltipUnit: template.TooltipUnit{
-				Bar: "个",
+				Bar: i18n.T("unit.count"),
 			},
 			Style: template.ChartTrendStyle{Stack: "total"},
 		},
@@ -408,15 +564,16 @@ func (s *UtilizationTemplate) Assemble(_ context.Context) template.UtilizeAnalys
 	utilizeAnalysisByDay.UtilizeTrend = &template.UtilizeAnalysisUtilizeTrend{
 		Chart: template.ChartUtilizeTrend{
 			ID:     "utilizeTrend",
-			Title:  "利用率走势",
+			Title:  i18n.T("util.utilize_trend"),
 			XData:  s.getLast14Days(),
 			Series: s.getUtilizeTrendSeries(),
-			YTitle: []string{"利用率（%）"},
+			YTitle: []string{i18n.T("util.utilize_trend.y")},
 			TooltipUnit: template.TooltipUnit{
 				Line: "%",
 			},
 		},
 	}
+	utilizeAnalysisByDay.MetricTrends = s.getMetricTrends()
 	// 单独计算 MidValue
 	for i, v := range utilizeAnalysisByDay.Ratios {
 		utilizeAnalysisByDay.Ratios[i].Chart.MidValue = s.sumRatiosChartMidValue(v.Chart.Data)
@@ -426,7 +583,11 @@ func (s *UtilizationTemplate) Assemble(_ context.Context) template.UtilizeAnalys
 		utilizeAnalysisByDay.Statistics[i].SRatio = tools.RatioString(v.SPreAmount, v.SAmount)
 	}
 
-	return template.UtilizeAnalysis{AnalysisByDay: utilizeAnalysisByDay}
+	ua := template.UtilizeAnalysis{AnalysisByDay: utilizeAnalysisByDay, Unsupported: unsupportedItems(s.unsupported)}
+	if len(ua.Unsupported) > 0 {
+		ua.UnsupportedTitle, ua.UnsupportedColumns = i18n.T("report.unsupported"), unsupportedColumns()
+	}
+	return ua
 }
 
 func (s *UtilizationTemplate) Export(_ context.Context, ua template.UtilizeAnalysis) error {
@@ -434,15 +595,33 @@ func (s *UtilizationTemplate) Export(_ context.Context, ua template.UtilizeAnaly
 	if err != nil {
 		return err
 	}
-	jsContent, err := os.ReadFile(constants.GetJsDataPath())
-	if err != nil {
+	if err = appendJsData(c); err != nil {
 		return err
 	}
-	err = os.WriteFile(constants.GetJsDataPath(), append(jsContent, []byte(c)...), 0644)
+
+	log.Printf("I! UtilizeAnalysis done")
+	return nil
+}
+
+// appendJsData append a parsed template to the data file written by CostTemplate.ExportCostAnalysis
+func appendJsData(c string) error {
+	jsContent, err := os.ReadFile(constants.GetJsDataStagingPath())
 	if err != nil {
 		return err
 	}
+	return os.WriteFile(constants.GetJsDataStagingPath(), append(jsContent, []byte(c)...), 0644)
+}
 
-	log.Printf("I! UtilizeAnalysis done")
-	return nil
+// PublishJsData replace the data file of the website with the one assembled by the exports
+func PublishJsData() error {
+	return os.Rename(constants.GetJsDataStagingPath(), constants.GetJsDataPath())
+}
+
+// publishFile write a file of the website by a rename, so it is never read partially while served
+func publishFile(file string, b []byte) error {
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
 }
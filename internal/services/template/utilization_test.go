@@ -0,0 +1,70 @@
+package template
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	"github.com/galaxy-future/costpilot/internal/data"
+	"github.com/galaxy-future/costpilot/internal/i18n"
+	"github.com/galaxy-future/costpilot/internal/providers/types"
+	"github.com/galaxy-future/costpilot/internal/template"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUtilizationTemplate_MetricTrends(t *testing.T) {
+	dailyMetrics := func(p cloud.Provider, m types.MetricItem, day string, v float64) map[types.MetricItem]*sync.Map {
+		var daily sync.Map
+		daily.Store(day, data.DailyMetric{Provider: p, Metric: m, Day: day, Samples: []data.InstanceMetric{{InstanceId: "i-" + string(p), Average: v}}})
+		return map[types.MetricItem]*sync.Map{m: &daily}
+	}
+	u := NewUtilization(time.Date(2022, 10, 15, 8, 0, 0, 0, time.Local))
+	u.AssignData(nil, nil, nil)
+	u.AssignMetrics([]types.MetricItem{types.MetricItemCPUUtilization, types.MetricItemNetworkInBandwidth, types.MetricItemDiskUsedUtilization,
+		types.MetricItemLoadAverage}, []map[types.MetricItem]*sync.Map{
+		dailyMetrics(cloud.AlibabaCloud, types.MetricItemNetworkInBandwidth, "2022-10-14", 3000000),
+		dailyMetrics(cloud.AWSCloud, types.MetricItemNetworkInBandwidth, "2022-10-14", 1000000),
+		dailyMetrics(cloud.AWSCloud, types.MetricItemDiskUsedUtilization, "2022-10-13", 55.5),
+	})
+	analysis := u.Assemble(context.TODO()).AnalysisByDay
+
+	series := analysis.UtilizeTrend.Chart.Series
+	assert.Len(t, series, 3)
+	assert.Equal(t, i18n.T("metric.disk.used.utilization"), series[2].Name)
+	assert.Equal(t, []string{"55.50", "--"}, series[2].Data[12:])
+
+	assert.Len(t, analysis.MetricTrends, 2)
+	network := analysis.MetricTrends[0].Chart
+	assert.Equal(t, "metricTrend_network_in_bandwidth", network.ID)
+	assert.Equal(t, "Mbit/s", network.TooltipUnit.Line)
+	assert.Equal(t, []string{"--", "2.00"}, network.Series[0].Data[12:])
+	assert.Equal(t, "%", analysis.MetricTrends[1].Chart.TooltipUnit.Line)
+}
+
+func TestUtilizationTemplate_Unsupported(t *testing.T) {
+	u := NewUtilization(time.Date(2022, 10, 15, 8, 0, 0, 0, time.Local))
+	u.AssignData(nil, nil, nil)
+	assert.Empty(t, u.Assemble(context.TODO()).UnsupportedColumns)
+
+	u.SetUnsupported([]data.Unsupported{{AccountName: "dev", Provider: cloud.AWSCloud,
+		Capability: string(types.MetricItemDiskUsedUtilization), Analysis: data.AnalysisMetric}})
+	analysis := u.Assemble(context.TODO())
+	assert.Equal(t, i18n.T("report.unsupported"), analysis.UnsupportedTitle)
+	assert.Len(t, analysis.UnsupportedColumns, 4)
+	assert.Equal(t, []template.ItemInUnsupported{{Account: "dev", Provider: i18n.ProviderName(cloud.AWSCloud),
+		Capability: i18n.T("metric.disk.used.utilization"), Impact: i18n.T("unsupported.metric")}}, analysis.Unsupported)
+}
+
+func TestPublishFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "anomalies.json")
+	assert.NoError(t, os.WriteFile(file, []byte("old"), 0644))
+	assert.NoError(t, publishFile(file, []byte("new")))
+	b, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Equal(t, "new", string(b))
+	assert.NoFileExists(t, file+".tmp")
+}
@@ -0,0 +1,47 @@
+package template
+
+import (
+	"bytes"
+	"text/template"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const anomalyTemplate = `
+window.anomalyAnalysis = {{.}}
+`
+
+type AnomalyAnalysis struct {
+	Title        string               `json:"title"`
+	DataCycle    string               `json:"dataCycle"`
+	AccountTotal string               `json:"accountTotal"` // shown as the product of the account total
+	Columns      []ItemInTableColumns `json:"columns"`
+	Anomalies    []ItemInAnomalies    `json:"anomalies"`
+}
+
+type ItemInAnomalies struct {
+	Day          string   `json:"day"`
+	Account      string   `json:"account"`
+	Provider     string   `json:"provider"`
+	Product      string   `json:"product"` // empty for the account total
+	Amount       string   `json:"amount"`
+	Baseline     string   `json:"baseline"`
+	Ratio        string   `json:"ratio"` // (amount - baseline)/baseline in percent
+	Score        string   `json:"score"`
+	Direction    string   `json:"direction"` // spike | drop
+	Severity     string   `json:"severity"`  // warning | critical
+	SeverityName string   `json:"severityName"`
+	Unit         string   `json:"unit"`
+	Contributors []string `json:"contributors"` // ["云服务器 ECS +120.00"]
+}
+
+func ParseAnomalyTemplate(aa AnomalyAnalysis) (string, error) {
+	s, _ := jsoniter.MarshalToString(aa)
+	tmpl, _ := template.New("anomaly_template").Parse(anomalyTemplate)
+	var buf bytes.Buffer
+	err := tmpl.Execute(&buf, s)
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
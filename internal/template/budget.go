@@ -0,0 +1,47 @@
+package template
+
+import (
+	"bytes"
+	"text/template"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const budgetTemplate = `
+window.budgetAnalysis = {{.}}
+`
+
+type BudgetAnalysis struct {
+	Title     string               `json:"title"`
+	DataCycle string               `json:"dataCycle"`
+	Columns   []ItemInTableColumns `json:"columns"`
+	Budgets   []ItemInBudgets      `json:"budgets"`
+}
+
+type ItemInBudgets struct {
+	Name             string   `json:"name"`
+	Scope            string   `json:"scope"`
+	Target           string   `json:"target"`
+	Period           string   `json:"period"`
+	PeriodRange      string   `json:"periodRange"` // 2022-10-01 ~ 2022-10-31
+	Amount           string   `json:"amount"`
+	Spent            string   `json:"spent"`
+	Projected        string   `json:"projected"`
+	Percent          string   `json:"percent"`
+	ProjectedPercent string   `json:"projectedPercent"`
+	Thresholds       []string `json:"thresholds"`
+	Unit             string   `json:"unit"`
+	State            string   `json:"state"` // ok | warning | exceeded
+	StateName        string   `json:"stateName"`
+}
+
+func ParseBudgetTemplate(ba BudgetAnalysis) (string, error) {
+	s, _ := jsoniter.MarshalToString(ba)
+	tmpl, _ := template.New("budget_template").Parse(budgetTemplate)
+	var buf bytes.Buffer
+	err := tmpl.Execute(&buf, s)
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
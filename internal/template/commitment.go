@@ -0,0 +1,60 @@
+package template
+
+import (
+	"bytes"
+	"text/template"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const commitmentTemplate = `
+window.commitmentAnalysis = {{.}}
+`
+
+type CommitmentAnalysis struct {
+	Title           string                  `json:"title"`
+	DataCycle       string                  `json:"dataCycle"`
+	Statistics      []ItemInStatistics      `json:"statistics"` // coverage per provider and commitment type
+	CoverageTitle   string                  `json:"coverageTitle"`
+	CoverageColumns []ItemInTableColumns    `json:"coverageColumns"`
+	Coverages       []ItemInCoverages       `json:"coverages"`
+	ExpiringTitle   string                  `json:"expiringTitle"`
+	ExpiringColumns []ItemInTableColumns    `json:"expiringColumns"`
+	Expiring        []ItemInExpiringCommits `json:"expiring"`
+}
+
+type ItemInCoverages struct {
+	Account     string `json:"account"`
+	Provider    string `json:"provider"`
+	Type        string `json:"type"` // 预留实例 | 节省计划 | 包年包月
+	Covered     string `json:"covered"`
+	Eligible    string `json:"eligible"`
+	Unit        string `json:"unit"`
+	Coverage    string `json:"coverage"`
+	Utilization string `json:"utilization"`
+}
+
+type ItemInExpiringCommits struct {
+	Account      string `json:"account"`
+	Provider     string `json:"provider"`
+	CommitmentId string `json:"commitmentId"`
+	Type         string `json:"type"`
+	Description  string `json:"description"`
+	Region       string `json:"region"`
+	Count        int    `json:"count"`
+	EndTime      string `json:"endTime"`
+	DaysLeft     int    `json:"daysLeft"`
+	Bucket       string `json:"bucket"` // 30天内到期
+	AutoRenew    string `json:"autoRenew"`
+}
+
+func ParseCommitmentTemplate(ca CommitmentAnalysis) (string, error) {
+	s, _ := jsoniter.MarshalToString(ca)
+	tmpl, _ := template.New("commitment_template").Parse(commitmentTemplate)
+	var buf bytes.Buffer
+	err := tmpl.Execute(&buf, s)
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -12,8 +12,25 @@ window.costAnalysis = {{.}}
 `
 
 type AnalysisData struct {
-	CostAnalysisByDay   CostAnalysis `json:"costAnalysisByDay"`
-	CostAnalysisByMonth CostAnalysis `json:"costAnalysisByMonth"`
+	CostView            string        `json:"costView"`      // cash | amortized
+	CostViewName        string        `json:"costViewName"`  // 现金视图 | 分摊视图
+	CostBasis           string        `json:"costBasis"`     // list | discount | net | tax_inclusive
+	CostBasisName       string        `json:"costBasisName"` // 原价 | 优惠后 | 净额 | 含税
+	CostAnalysisByDay   CostAnalysis  `json:"costAnalysisByDay"`
+	CostAnalysisByMonth CostAnalysis  `json:"costAnalysisByMonth"`
+	AlternativeView     *AnalysisData `json:"alternativeView,omitempty"` // the other cost view to toggle to
+
+	Unsupported        []ItemInUnsupported  `json:"unsupported,omitempty"`
+	UnsupportedTitle   string               `json:"unsupportedTitle,omitempty"`
+	UnsupportedColumns []ItemInTableColumns `json:"unsupportedColumns,omitempty"`
+}
+
+// ItemInUnsupported the data of an account left out as its provider lacks the capability
+type ItemInUnsupported struct {
+	Account    string `json:"account"`
+	Provider   string `json:"provider"`
+	Capability string `json:"capability"` // 账单
+	Impact     string `json:"impact"`     // 成本分析未包含该账号
 }
 
 type CostAnalysis struct {
@@ -0,0 +1,53 @@
+package template
+
+import (
+	"bytes"
+	"text/template"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const idleInstanceTemplate = `
+window.idleInstanceAnalysis = {{.}}
+`
+
+type IdleInstanceAnalysis struct {
+	Title     string                `json:"title"`
+	DataCycle string                `json:"dataCycle"`
+	Columns   []ItemInTableColumns  `json:"columns"`
+	Instances []ItemInIdleInstances `json:"instances"`
+}
+
+// ItemInTableColumns Key is the json key of a row, sortable columns are sorted by their numeric value
+type ItemInTableColumns struct {
+	Key      string `json:"key"`
+	Title    string `json:"title"`
+	Sortable bool   `json:"sortable"`
+}
+
+type ItemInIdleInstances struct {
+	Account          string `json:"account"`
+	Provider         string `json:"provider"`
+	InstanceId       string `json:"instanceId"`
+	Region           string `json:"region"`
+	InstanceSpec     string `json:"instanceSpec"`
+	SubscriptionType string `json:"subscriptionType"`
+	Level            string `json:"level"` // 闲置 | 低利用率
+	CpuAvg           string `json:"cpuAvg"`
+	CpuPeak          string `json:"cpuPeak"`
+	MemoryAvg        string `json:"memoryAvg"`
+	MemoryPeak       string `json:"memoryPeak"`
+	MonthlyCost      string `json:"monthlyCost"`
+	Unit             string `json:"unit"`
+}
+
+func ParseIdleInstanceTemplate(ia IdleInstanceAnalysis) (string, error) {
+	s, _ := jsoniter.MarshalToString(ia)
+	tmpl, _ := template.New("idle_instance_template").Parse(idleInstanceTemplate)
+	var buf bytes.Buffer
+	err := tmpl.Execute(&buf, s)
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,46 @@
+package template
+
+import (
+	"bytes"
+	"text/template"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const optimizationTemplate = `
+window.optimizationAnalysis = {{.}}
+`
+
+type OptimizationAnalysis struct {
+	Title         string                `json:"title"`
+	DataCycle     string                `json:"dataCycle"`
+	Statistics    []ItemInStatistics    `json:"statistics"` // total monthly savings per currency
+	Columns       []ItemInTableColumns  `json:"columns"`
+	Opportunities []ItemInOptimizations `json:"opportunities"`
+}
+
+type ItemInOptimizations struct {
+	Account         string `json:"account"`
+	Provider        string `json:"provider"`
+	InstanceId      string `json:"instanceId"`
+	Region          string `json:"region"`
+	Action          string `json:"action"` // 停用 | 降配
+	CurrentType     string `json:"currentType"`
+	RecommendedType string `json:"recommendedType"`
+	CpuPeak         string `json:"cpuPeak"`
+	MemoryPeak      string `json:"memoryPeak"`
+	MonthlyCost     string `json:"monthlyCost"`
+	MonthlySavings  string `json:"monthlySavings"`
+	Unit            string `json:"unit"`
+}
+
+func ParseOptimizationTemplate(oa OptimizationAnalysis) (string, error) {
+	s, _ := jsoniter.MarshalToString(oa)
+	tmpl, _ := template.New("optimization_template").Parse(optimizationTemplate)
+	var buf bytes.Buffer
+	err := tmpl.Execute(&buf, s)
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
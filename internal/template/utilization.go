@@ -59,7 +59,10 @@ type UtilizeAnalysisStatisticsItem struct {
 }
 
 type UtilizeAnalysis struct {
-	AnalysisByDay UtilizeAnalysisByDay `json:"utilizeAnalysisByDay"`
+	AnalysisByDay      UtilizeAnalysisByDay `json:"utilizeAnalysisByDay"`
+	Unsupported        []ItemInUnsupported  `json:"unsupported,omitempty"`
+	UnsupportedTitle   string               `json:"unsupportedTitle,omitempty"`
+	UnsupportedColumns []ItemInTableColumns `json:"unsupportedColumns,omitempty"`
 }
 type UtilizeAnalysisByDay struct {
 	ViewType     string                          `json:"viewType"`
@@ -68,6 +71,7 @@ type UtilizeAnalysisByDay struct {
 	Ratios       []ItemInRatios                  `json:"ratios"`
 	UtilizeTrend *UtilizeAnalysisUtilizeTrend    `json:"utilizeTrend"`
 	CpuTrend     *UtilizeAnalysisCpuTrend        `json:"cpuTrend"`
+	MetricTrends []UtilizeAnalysisUtilizeTrend   `json:"metricTrends,omitempty"` // the catalog metrics besides cpu and memory
 }
 
 func ParseUtilizeAnalysisTemplate(ua UtilizeAnalysis) (string, error) {
@@ -1,6 +1,11 @@
 package types
 
-import "github.com/galaxy-future/costpilot/internal/constants/cloud"
+import (
+	"path"
+
+	"github.com/galaxy-future/costpilot/internal/constants/cloud"
+	providerTypes "github.com/galaxy-future/costpilot/internal/providers/types"
+)
 
 type CloudAccount struct {
 	Provider cloud.Provider `json:"provider" yaml:"provider"`
@@ -8,4 +13,81 @@ type CloudAccount struct {
 	SK       string         `json:"sk" yaml:"sk"`
 	RegionID string         `json:"region_id" yaml:"region_id"`
 	Name     string         `json:"name" yaml:"name"`
+
+	AKFile       string       `json:"ak_file" yaml:"ak_file"`             // read ak from the file, eg: a mounted secret
+	SKFile       string       `json:"sk_file" yaml:"sk_file"`             // read sk from the file
+	Vault        *VaultSecret `json:"vault" yaml:"vault"`                 // read ak/sk from a vault kv secret
+	DefaultChain bool         `json:"default_chain" yaml:"default_chain"` // no ak/sk, resolved by the sdk from the environment, profile or instance role
+	Profile      string       `json:"profile" yaml:"profile"`             // profile of the default chain, AWSCloud only
+
+	AssumeRole      *providerTypes.AssumeRole `json:"assume_role" yaml:"assume_role"`           // access the account by a role assumed with the credential above
+	DiscoverMembers *DiscoverMembers          `json:"discover_members" yaml:"discover_members"` // collect the member accounts of the organization too
+
+	Regions           []string `json:"regions" yaml:"regions"`                       // regions scanned for instances, default all, eg: [cn-*, us-east-1]
+	ExcludeRegions    []string `json:"exclude_regions" yaml:"exclude_regions"`       // regions never scanned, wildcards as regions
+	RegionConcurrency int      `json:"region_concurrency" yaml:"region_concurrency"` // regions scanned at the same time, default 4
+}
+
+// IsRegionIncluded the patterns of regions and exclude_regions are matched by path.Match
+func (a CloudAccount) IsRegionIncluded(regionId string) bool {
+	for _, e := range a.ExcludeRegions {
+		if ok, _ := path.Match(e, regionId); ok {
+			return false
+		}
+	}
+	if len(a.Regions) == 0 {
+		return true
+	}
+	for _, r := range a.Regions {
+		if ok, _ := path.Match(r, regionId); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (a CloudAccount) GetRegionConcurrency() int {
+	if a.RegionConcurrency <= 0 {
+		return 4
+	}
+	return a.RegionConcurrency
+}
+
+// DiscoverMembers the member accounts of the organization managed by the account are collected by their roles
+type DiscoverMembers struct {
+	Enabled    bool     `json:"enabled" yaml:"enabled"`
+	RoleName   string   `json:"role_name" yaml:"role_name"`     // default OrganizationAccountAccessRole of AWS, ResourceDirectoryAccountAccessRole of Alibaba Cloud, required by Tencent Cloud
+	ExternalID string   `json:"external_id" yaml:"external_id"` // aws only
+	Include    []string `json:"include" yaml:"include"`         // ids of the members, default all
+	Exclude    []string `json:"exclude" yaml:"exclude"`         // ids of the members
+	SkipSelf   bool     `json:"skip_self" yaml:"skip_self"`     // do not collect the management account itself
+}
+
+// IsMemberIncluded
+func (d DiscoverMembers) IsMemberIncluded(id string) bool {
+	for _, e := range d.Exclude {
+		if e == id {
+			return false
+		}
+	}
+	if len(d.Include) == 0 {
+		return true
+	}
+	for _, i := range d.Include {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Credential the ak/sk must have been resolved from the files and the vault
+func (a CloudAccount) Credential() providerTypes.Credential {
+	return providerTypes.Credential{
+		AK:           a.AK,
+		SK:           a.SK,
+		DefaultChain: a.DefaultChain,
+		Profile:      a.Profile,
+		AssumeRole:   a.AssumeRole,
+	}
 }
@@ -0,0 +1,28 @@
+package types
+
+type CostView string
+
+const (
+	CostViewCash      CostView = "cash"      // charges on the day they are billed
+	CostViewAmortized CostView = "amortized" // prepaid charges spread over their service period
+)
+
+type Amortization struct {
+	Enabled        bool     `json:"enabled" yaml:"enabled"`                 // opt-in, the prepaid charges bought before the lookback are left as cash
+	DefaultView    CostView `json:"default_view" yaml:"default_view"`       // cash | amortized, default cash
+	LookbackMonths int      `json:"lookback_months" yaml:"lookback_months"` // months of prepaid charges to spread by ourselves, default 12
+}
+
+func (a Amortization) GetDefaultView() CostView {
+	if a.DefaultView == CostViewAmortized {
+		return CostViewAmortized
+	}
+	return CostViewCash
+}
+
+func (a Amortization) GetLookbackMonths() int {
+	if a.LookbackMonths <= 0 {
+		return 12
+	}
+	return a.LookbackMonths
+}
@@ -0,0 +1,37 @@
+package types
+
+type AnomalyDetection struct {
+	Enabled    bool    `json:"enabled" yaml:"enabled"`         // opt-in, the daily bills by product of the window are fetched in addition
+	WindowDays int     `json:"window_days" yaml:"window_days"` // trailing days used as baseline, default 14
+	DetectDays int     `json:"detect_days" yaml:"detect_days"` // recent days to detect, default 14
+	Threshold  float64 `json:"threshold" yaml:"threshold"`     // robust z-score, default 3.5
+	MinAmount  float64 `json:"min_amount" yaml:"min_amount"`   // ignore deviations smaller than this amount, default 1
+}
+
+func (a AnomalyDetection) GetWindowDays() int {
+	if a.WindowDays <= 0 {
+		return 14
+	}
+	return a.WindowDays
+}
+
+func (a AnomalyDetection) GetDetectDays() int {
+	if a.DetectDays <= 0 {
+		return 14
+	}
+	return a.DetectDays
+}
+
+func (a AnomalyDetection) GetThreshold() float64 {
+	if a.Threshold <= 0 {
+		return 3.5
+	}
+	return a.Threshold
+}
+
+func (a AnomalyDetection) GetMinAmount() float64 {
+	if a.MinAmount <= 0 {
+		return 1
+	}
+	return a.MinAmount
+}
@@ -0,0 +1,73 @@
+package types
+
+import "time"
+
+type Role string
+
+const (
+	RoleAdmin  Role = "admin"  // view all accounts, trigger refreshes
+	RoleViewer Role = "viewer" // view the granted accounts
+
+	AllAccounts = "*"
+)
+
+// Grant role and accounts of a principal, accounts are ignored for admins, ["*"] grants all of them to a viewer
+type Grant struct {
+	Role     Role     `json:"role" yaml:"role"`
+	Accounts []string `json:"accounts" yaml:"accounts"`
+}
+
+type AuthToken struct {
+	Name  string `json:"name" yaml:"name"`
+	Token string `json:"token" yaml:"token"` // sent as "Authorization: Bearer <token>"
+	Grant `yaml:",inline"`
+}
+
+type BasicUser struct {
+	Username     string `json:"username" yaml:"username"`
+	Password     string `json:"password" yaml:"password"`           // plain text, or
+	PasswordHash string `json:"password_hash" yaml:"password_hash"` // bcrypt hash
+	Grant        `yaml:",inline"`
+}
+
+type OIDCUser struct {
+	Email string `json:"email" yaml:"email"`
+	Grant `yaml:",inline"`
+}
+
+type OIDC struct {
+	Issuer       string     `json:"issuer" yaml:"issuer"` // discovered by {issuer}/.well-known/openid-configuration
+	ClientID     string     `json:"client_id" yaml:"client_id"`
+	ClientSecret string     `json:"client_secret" yaml:"client_secret"`
+	RedirectURL  string     `json:"redirect_url" yaml:"redirect_url"`   // https://{host}/auth/callback
+	Scopes       []string   `json:"scopes" yaml:"scopes"`               // default openid email profile
+	Users        []OIDCUser `json:"users" yaml:"users"`                 // matched by the email claim
+	DefaultGrant *Grant     `json:"default_grant" yaml:"default_grant"` // grant of the other users, nil to deny them
+}
+
+type Auth struct {
+	Tokens        []AuthToken   `json:"tokens" yaml:"tokens"`
+	BasicUsers    []BasicUser   `json:"basic_users" yaml:"basic_users"`
+	OIDC          *OIDC         `json:"oidc" yaml:"oidc"`
+	SessionSecret string        `json:"session_secret" yaml:"session_secret"` // signs the login sessions of oidc
+	SessionTTL    time.Duration `json:"session_ttl" yaml:"session_ttl"`       // default 12h
+}
+
+// Enabled the server is open to everyone if no method is configured
+func (a Auth) Enabled() bool {
+	return len(a.Tokens) > 0 || len(a.BasicUsers) > 0 || a.OIDC != nil
+}
+
+func (a Auth) GetSessionTTL() time.Duration {
+	if a.SessionTTL <= 0 {
+		return 12 * time.Hour
+	}
+	return a.SessionTTL
+}
+
+func (o OIDC) GetScopes() []string {
+	if len(o.Scopes) == 0 {
+		return []string{"openid", "email", "profile"}
+	}
+	return o.Scopes
+}
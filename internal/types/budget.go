@@ -0,0 +1,35 @@
+package types
+
+type (
+	BudgetScope  string
+	BudgetPeriod string
+)
+
+const (
+	BudgetScopeAll      BudgetScope = "all"
+	BudgetScopeAccount  BudgetScope = "account"
+	BudgetScopeProvider BudgetScope = "provider"
+	BudgetScopeProduct  BudgetScope = "product"
+
+	BudgetPeriodMonthly   BudgetPeriod = "monthly"
+	BudgetPeriodQuarterly BudgetPeriod = "quarterly"
+)
+
+// DefaultBudgetThresholds alert thresholds in percent, used when none is configured
+var DefaultBudgetThresholds = []float64{50, 80, 100}
+
+type Budget struct {
+	Name       string       `json:"name" yaml:"name"`
+	Scope      BudgetScope  `json:"scope" yaml:"scope"`   // all | account | provider | product
+	Target     string       `json:"target" yaml:"target"` // account name | provider | product code or name, empty when scope is all
+	Period     BudgetPeriod `json:"period" yaml:"period"` // monthly | quarterly
+	Amount     float64      `json:"amount" yaml:"amount"`
+	Thresholds []float64    `json:"thresholds" yaml:"thresholds"` // percent of amount, eg: [50, 80, 100]
+}
+
+func (b Budget) GetThresholds() []float64 {
+	if len(b.Thresholds) == 0 {
+		return DefaultBudgetThresholds
+	}
+	return b.Thresholds
+}
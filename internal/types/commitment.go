@@ -0,0 +1,31 @@
+package types
+
+import "sort"
+
+type CommitmentAnalysis struct {
+	Disabled     bool  `json:"disabled" yaml:"disabled"`
+	WindowDays   int   `json:"window_days" yaml:"window_days"`     // default 30
+	ExpiringDays []int `json:"expiring_days" yaml:"expiring_days"` // default [30, 60, 90]
+}
+
+func (c CommitmentAnalysis) GetWindowDays() int {
+	if c.WindowDays <= 0 {
+		return 30
+	}
+	return c.WindowDays
+}
+
+// GetExpiringDays ascending expiring buckets
+func (c CommitmentAnalysis) GetExpiringDays() []int {
+	var ret []int
+	for _, d := range c.ExpiringDays {
+		if d > 0 {
+			ret = append(ret, d)
+		}
+	}
+	if len(ret) == 0 {
+		return []int{30, 60, 90}
+	}
+	sort.Ints(ret)
+	return ret
+}
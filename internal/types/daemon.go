@@ -0,0 +1,21 @@
+package types
+
+type Daemon struct {
+	Enabled  bool   `json:"enabled" yaml:"enabled"`
+	Schedule string `json:"schedule" yaml:"schedule"` // cron of the analysis, default daily at 09:00
+	Listen   string `json:"listen" yaml:"listen"`     // address of the server, default :8504
+}
+
+func (d Daemon) GetSchedule() string {
+	if d.Schedule == "" {
+		return "0 9 * * *"
+	}
+	return d.Schedule
+}
+
+func (d Daemon) GetListen() string {
+	if d.Listen == "" {
+		return ":8504"
+	}
+	return d.Listen
+}
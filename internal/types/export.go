@@ -0,0 +1,22 @@
+package types
+
+const (
+	ExportJSON    = "json"
+	ExportCSV     = "csv"
+	ExportXLSX    = "xlsx"
+	ExportParquet = "parquet"
+)
+
+// Export machine readable files of the daily, monthly and product cost, and the website as a single html file
+type Export struct {
+	Formats []string `json:"formats" yaml:"formats"` // json | csv | xlsx | parquet
+	Dir     string   `json:"dir" yaml:"dir"`         // default export
+	HTML    string   `json:"html" yaml:"html"`       // path of the self-contained html report, not written by default
+}
+
+func (e Export) GetDir() string {
+	if e.Dir == "" {
+		return "export"
+	}
+	return e.Dir
+}
@@ -0,0 +1,22 @@
+package types
+
+// Exporter serves /metrics only, the analysis reruns by schedule
+type Exporter struct {
+	Enabled  bool   `json:"enabled" yaml:"enabled"`
+	Schedule string `json:"schedule" yaml:"schedule"` // cron of the analysis, default every 6 hours
+	Listen   string `json:"listen" yaml:"listen"`     // address of the exporter, default :9504
+}
+
+func (e Exporter) GetSchedule() string {
+	if e.Schedule == "" {
+		return "0 */6 * * *"
+	}
+	return e.Schedule
+}
+
+func (e Exporter) GetListen() string {
+	if e.Listen == "" {
+		return ":9504"
+	}
+	return e.Listen
+}
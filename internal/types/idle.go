@@ -0,0 +1,48 @@
+package types
+
+// UtilizationThreshold an instance matches when every utilization stays below the threshold, in percent
+type UtilizationThreshold struct {
+	CpuAvg     float64 `json:"cpu_avg" yaml:"cpu_avg"`
+	CpuPeak    float64 `json:"cpu_peak" yaml:"cpu_peak"`
+	MemoryAvg  float64 `json:"memory_avg" yaml:"memory_avg"`   // ignored when memory metrics are absent
+	MemoryPeak float64 `json:"memory_peak" yaml:"memory_peak"` // ignored when memory metrics are absent
+}
+
+// withDefaults the fields left unset take the default, so a threshold may set only some of them
+func (u UtilizationThreshold) withDefaults(def UtilizationThreshold) UtilizationThreshold {
+	if u.CpuAvg <= 0 {
+		u.CpuAvg = def.CpuAvg
+	}
+	if u.CpuPeak <= 0 {
+		u.CpuPeak = def.CpuPeak
+	}
+	if u.MemoryAvg <= 0 {
+		u.MemoryAvg = def.MemoryAvg
+	}
+	if u.MemoryPeak <= 0 {
+		u.MemoryPeak = def.MemoryPeak
+	}
+	return u
+}
+
+type IdleInstanceDetection struct {
+	Disabled      bool                 `json:"disabled" yaml:"disabled"`
+	WindowDays    int                  `json:"window_days" yaml:"window_days"` // default 14
+	Idle          UtilizationThreshold `json:"idle" yaml:"idle"`
+	Underutilized UtilizationThreshold `json:"underutilized" yaml:"underutilized"`
+}
+
+func (d IdleInstanceDetection) GetWindowDays() int {
+	if d.WindowDays <= 0 {
+		return 14
+	}
+	return d.WindowDays
+}
+
+func (d IdleInstanceDetection) GetIdle() UtilizationThreshold {
+	return d.Idle.withDefaults(UtilizationThreshold{CpuAvg: 5, CpuPeak: 10, MemoryAvg: 10, MemoryPeak: 20})
+}
+
+func (d IdleInstanceDetection) GetUnderutilized() UtilizationThreshold {
+	return d.Underutilized.withDefaults(UtilizationThreshold{CpuAvg: 20, CpuPeak: 40, MemoryAvg: 30, MemoryPeak: 50})
+}
@@ -0,0 +1,81 @@
+package types
+
+type (
+	NotifierType string
+	NotifyEvent  string
+)
+
+const (
+	NotifierWebhook  NotifierType = "webhook"
+	NotifierSlack    NotifierType = "slack"
+	NotifierDingTalk NotifierType = "dingtalk"
+	NotifierFeishu   NotifierType = "feishu" // Feishu / Lark
+	NotifierWeCom    NotifierType = "wecom"
+	NotifierEmail    NotifierType = "email"
+
+	NotifyEventDigest NotifyEvent = "digest" // daily digest after each run
+	NotifyEventAlert  NotifyEvent = "alert"  // budget, anomaly and commitment alerts
+)
+
+type SMTP struct {
+	Host     string   `json:"host" yaml:"host"`
+	Port     int      `json:"port" yaml:"port"` // default 587
+	Username string   `json:"username" yaml:"username"`
+	Password string   `json:"password" yaml:"password"`
+	From     string   `json:"from" yaml:"from"`
+	To       []string `json:"to" yaml:"to"`
+}
+
+func (s SMTP) GetPort() int {
+	if s.Port == 0 {
+		return 587
+	}
+	return s.Port
+}
+
+// NotifyTemplates text/template of the messages, the defaults are used if empty
+type NotifyTemplates struct {
+	Digest string `json:"digest" yaml:"digest"` // executed with the digest
+	Alert  string `json:"alert" yaml:"alert"`   // executed with the alert event
+}
+
+type Notifier struct {
+	Name        string            `json:"name" yaml:"name"`
+	Type        NotifierType      `json:"type" yaml:"type"`                 // webhook | slack | dingtalk | feishu | wecom | email
+	URL         string            `json:"url" yaml:"url"`                   // webhook url, required except for email
+	Secret      string            `json:"secret" yaml:"secret"`             // signing secret of dingtalk, feishu and webhook
+	Headers     map[string]string `json:"headers" yaml:"headers"`           // extra headers of webhook
+	SMTP        SMTP              `json:"smtp" yaml:"smtp"`                 // required for email
+	Events      []NotifyEvent     `json:"events" yaml:"events"`             // digest | alert, default both
+	MinSeverity string            `json:"min_severity" yaml:"min_severity"` // info | warning | critical, default info
+	Templates   NotifyTemplates   `json:"templates" yaml:"templates"`
+}
+
+func (n Notifier) GetEvents() []NotifyEvent {
+	if len(n.Events) == 0 {
+		return []NotifyEvent{NotifyEventDigest, NotifyEventAlert}
+	}
+	return n.Events
+}
+
+func (n Notifier) Subscribes(e NotifyEvent) bool {
+	for _, v := range n.GetEvents() {
+		if v == e {
+			return true
+		}
+	}
+	return false
+}
+
+type Notification struct {
+	DryRun    bool       `json:"dry_run" yaml:"dry_run"`       // print the payloads instead of sending them
+	StateFile string     `json:"state_file" yaml:"state_file"` // what was sent, so the digest goes once a day and each alert once
+	Notifiers []Notifier `json:"notifiers" yaml:"notifiers"`
+}
+
+func (n Notification) GetStateFile() string {
+	if n.StateFile == "" {
+		return "data/notify-state.json"
+	}
+	return n.StateFile
+}
@@ -0,0 +1,23 @@
+package types
+
+type Rightsizing struct {
+	Disabled          bool    `json:"disabled" yaml:"disabled"`
+	CatalogDir        string  `json:"catalog_dir" yaml:"catalog_dir"`               // user instance catalogs, override the bundled ones
+	WindowDays        int     `json:"window_days" yaml:"window_days"`               // default 14
+	TargetUtilization float64 `json:"target_utilization" yaml:"target_utilization"` // peak utilization allowed on the recommended type, default 70
+	MinMonthlySavings float64 `json:"min_monthly_savings" yaml:"min_monthly_savings"`
+}
+
+func (r Rightsizing) GetWindowDays() int {
+	if r.WindowDays <= 0 {
+		return 14
+	}
+	return r.WindowDays
+}
+
+func (r Rightsizing) GetTargetUtilization() float64 {
+	if r.TargetUtilization <= 0 || r.TargetUtilization > 100 {
+		return 70
+	}
+	return r.TargetUtilization
+}
@@ -0,0 +1,8 @@
+package types
+
+import providerTypes "github.com/galaxy-future/costpilot/internal/providers/types"
+
+// UtilizationAnalysis the charts of the utilization analysis besides cpu and memory
+type UtilizationAnalysis struct {
+	Metrics []providerTypes.MetricItem `json:"metrics" yaml:"metrics"` // of the metric catalog, eg: disk.used.utilization, none by default
+}
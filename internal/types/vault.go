@@ -0,0 +1,46 @@
+package types
+
+import "os"
+
+// Vault the HashiCorp Vault server of the secrets referenced by the cloud accounts
+type Vault struct {
+	Address   string `json:"address" yaml:"address"`       // default $VAULT_ADDR
+	Token     string `json:"token" yaml:"token"`           // default $VAULT_TOKEN
+	TokenFile string `json:"token_file" yaml:"token_file"` // read the token from the file, eg: ~/.vault-token of `vault login`
+	Namespace string `json:"namespace" yaml:"namespace"`   // enterprise namespace, default $VAULT_NAMESPACE
+}
+
+func (v Vault) GetAddress() string {
+	if v.Address == "" {
+		return os.Getenv("VAULT_ADDR")
+	}
+	return v.Address
+}
+
+func (v Vault) GetNamespace() string {
+	if v.Namespace == "" {
+		return os.Getenv("VAULT_NAMESPACE")
+	}
+	return v.Namespace
+}
+
+// VaultSecret a kv secret holding the ak/sk of a cloud account
+type VaultSecret struct {
+	Path  string `json:"path" yaml:"path"`     // eg: secret/data/costpilot/aliyun of kv v2, or secret/costpilot/aliyun of kv v1
+	AKKey string `json:"ak_key" yaml:"ak_key"` // default ak
+	SKKey string `json:"sk_key" yaml:"sk_key"` // default sk
+}
+
+func (s VaultSecret) GetAKKey() string {
+	if s.AKKey == "" {
+		return "ak"
+	}
+	return s.AKKey
+}
+
+func (s VaultSecret) GetSKKey() string {
+	if s.SKKey == "" {
+		return "sk"
+	}
+	return s.SKKey
+}
@@ -2,35 +2,173 @@ package main
 
 import (
 	"context"
+	"flag"
+	"log"
 	"os"
+	"strings"
+	"time"
 
 	_ "github.com/galaxy-future/costpilot/tools"
 
+	"github.com/galaxy-future/costpilot/internal/api"
+	"github.com/galaxy-future/costpilot/internal/data"
 	"github.com/galaxy-future/costpilot/internal/domain"
+	"github.com/galaxy-future/costpilot/internal/export"
+	"github.com/galaxy-future/costpilot/internal/i18n"
+	"github.com/galaxy-future/costpilot/internal/metrics"
+	"github.com/galaxy-future/costpilot/internal/notify"
+	"github.com/galaxy-future/costpilot/internal/report"
+	"github.com/galaxy-future/costpilot/internal/services"
+	"github.com/galaxy-future/costpilot/internal/services/template"
 
 	"github.com/galaxy-future/costpilot/internal/config"
 )
 
 func main() {
 	ctx := context.Background()
+	flag.Parse()
+	if flag.Arg(0) == "config" {
+		os.Exit(runConfigCommand(flag.Args()[1:]))
+	}
 	printVersion()
 	if err := config.Init(); err != nil {
 		os.Exit(1)
 	}
+	if err := applyFlags(config.GetGlobalConfig()); err != nil {
+		os.Exit(1)
+	}
+	i18n.SetLanguage(config.GetGlobalConfig().GetLanguage())
+
+	if cfg := config.GetGlobalConfig().Daemon; cfg.Enabled {
+		if err := _runDaemon(ctx, cfg); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if cfg := config.GetGlobalConfig().Exporter; cfg.Enabled {
+		if err := _runExporter(ctx, cfg); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	collector := metrics.NewCollector()
+	snapshot, err := collect(ctx, collector)
+	if err != nil {
+		os.Exit(1)
+	}
+	if err := output(snapshot, collector); err != nil {
+		os.Exit(1)
+	}
+
+	os.Exit(0)
+}
+
+// collect analyze and update the metrics, a failed run keeps the metrics of the last successful one
+func collect(ctx context.Context, collector *metrics.Collector) (*api.Snapshot, error) {
+	start := time.Now()
+	snapshot, err := analyze(ctx)
+	collector.Update(snapshot, time.Since(start), err)
+	return snapshot, err
+}
+
+// analyze run the pipelines, then publish the website data
+func analyze(ctx context.Context) (*api.Snapshot, error) {
+	discoveryFailures := services.DiscoverCloudAccounts(ctx)
 
 	a := domain.NewCostAnalysisDomain()
 	if err := a.RunPipeline(ctx); err != nil {
-		os.Exit(1)
+		return nil, err
 	}
 
 	b := domain.NewResourceUtilizationDomain()
 	if err := b.RunPipeline(ctx); err != nil {
-		os.Exit(1)
+		return nil, err
 	}
 
-	if err := output(); err != nil {
-		os.Exit(1)
+	if err := template.PublishJsData(); err != nil {
+		return nil, err
+	}
+	if err := reportRun(); err != nil {
+		return nil, err
+	}
+	if err := exportRun(a); err != nil {
+		return nil, err
 	}
+	notifyRun(ctx, a, b)
+	return &api.Snapshot{
+		GeneratedAt:         a.GetNowT(),
+		Accounts:            services.GetCloudAccounts(),
+		AccountBillings:     a.GetAccountBillings(),
+		AccountUtilizations: b.GetAccountUtilizations(),
+		Unsupported:         append(a.GetUnsupported(), b.GetUnsupported()...),
+		DiscoveryFailures:   discoveryFailures,
+		AlternativeBillings: a.GetAlternativeBillings(),
+		AccountCommitments:  b.GetAccountCommitments(),
+		BudgetStatuses:      a.GetBudgetStatuses(),
+		Anomalies:           a.GetAnomalies(),
+		IdleInstances:       b.GetIdleInstances(),
+		Opportunities:       b.GetOpportunities(),
+		CostAnalysis:        a.GetAnalysisData(),
+		UtilizeAnalysis:     b.GetUtilizeAnalysis(),
+		BudgetAnalysis:      a.GetBudgetAnalysis(),
+		AnomalyAnalysis:     a.GetAnomalyAnalysis(),
+		IdleAnalysis:        b.GetIdleInstanceAnalysis(),
+		Optimization:        b.GetOptimizationAnalysis(),
+		Commitment:          b.GetCommitmentAnalysis(),
+	}, nil
+}
 
-	os.Exit(0)
+// exportRun write the machine readable files of the run
+func exportRun(a *domain.CostAnalysisDomain) error {
+	cfg := config.GetGlobalConfig().Export
+	if len(cfg.Formats) == 0 {
+		return nil
+	}
+	files, err := export.Run(cfg, export.BuildDataset(a.GetNowT(), a.GetAccountBillings()))
+	if err != nil {
+		log.Printf("E! %v\n", err)
+		return err
+	}
+	log.Printf("I! exported %s", strings.Join(files, ", "))
+	return nil
+}
+
+// reportRun write the website of the run as a single html file
+func reportRun() error {
+	file := config.GetGlobalConfig().Export.HTML
+	if file == "" {
+		return nil
+	}
+	if err := report.WriteHTML("website", file); err != nil {
+		log.Printf("E! write html report: %v\n", err)
+		return err
+	}
+	log.Printf("I! html report written to %s", file)
+	return nil
+}
+
+// notifyRun send the digest and the alerts of the run, failed notifiers do not fail the run
+func notifyRun(ctx context.Context, a *domain.CostAnalysisDomain, b *domain.ResourceUtilizationDomain) {
+	cfg := config.GetGlobalConfig().Notification
+	if len(cfg.Notifiers) == 0 {
+		return
+	}
+	dispatcher, err := notify.NewDispatcher(cfg)
+	if err != nil {
+		log.Printf("E! %v\n", err)
+		return
+	}
+	state, err := notify.LoadState(cfg.GetStateFile())
+	if err != nil {
+		log.Printf("E! load notify state: %v\n", err)
+		return
+	}
+	events := append(append([]data.AlertEvent{}, a.GetAlertEvents()...), b.GetAlertEvents()...)
+	if err := dispatcher.SetState(state).Notify(ctx, notify.BuildDigest(a.GetNowT(), a.GetAccountBillings()), events); err != nil {
+		log.Printf("E! %v\n", err)
+	}
+	if err := state.Save(); err != nil {
+		log.Printf("E! save notify state: %v\n", err)
+	}
 }
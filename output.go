@@ -2,21 +2,30 @@ package main
 
 import (
 	"log"
-	"net/http"
 	"os"
 
+	"github.com/galaxy-future/costpilot/internal/api"
+	"github.com/galaxy-future/costpilot/internal/config"
+	"github.com/galaxy-future/costpilot/internal/metrics"
+	"github.com/galaxy-future/costpilot/internal/report"
 	"github.com/galaxy-future/costpilot/tools"
-	"github.com/gin-gonic/gin"
 )
 
-func output() error {
+func output(snapshot *api.Snapshot, collector *metrics.Collector) error {
+	if *_format != "" {
+		return _runReport(snapshot, *_format)
+	}
 	if os.Getenv("ENV") == "docker" {
-		return _runServer()
+		return _runServer(snapshot, collector)
 	}
 	return _runCmd()
 }
 
 func _runCmd() error {
+	if file := config.GetGlobalConfig().Export.HTML; file != "" {
+		log.Printf("I! costpilot analysis completed! open %s in a browser to view the result", file)
+		return nil
+	}
 	if err := tools.ShowHtml("website/index.html"); err != nil {
 		return err
 	}
@@ -24,11 +33,35 @@ func _runCmd() error {
 	return nil
 }
 
-func _runServer() error {
-	gin.SetMode(gin.ReleaseMode)
-	r := gin.Default()
-	r.StaticFS("/website", http.Dir("./website"))
+// _runReport print the report to the terminal, neither the browser nor the server is started
+func _runReport(snapshot *api.Snapshot, format string) error {
+	content := report.Content{
+		Cost:              snapshot.CostAnalysis,
+		Utilization:       snapshot.UtilizeAnalysis,
+		Budgets:           snapshot.BudgetAnalysis,
+		Anomalies:         snapshot.AnomalyAnalysis,
+		IdleInstances:     snapshot.IdleAnalysis,
+		Optimization:      snapshot.Optimization,
+		Commitment:        snapshot.Commitment,
+		DiscoveryFailures: snapshot.DiscoveryFailures,
+		RegionScans:       snapshot.AccountUtilizations,
+	}
+	if err := report.Render(os.Stdout, format, content); err != nil {
+		log.Printf("E! %v\n", err)
+		return err
+	}
+	return nil
+}
+
+func _runServer(snapshot *api.Snapshot, collector *metrics.Collector) error {
+	r, err := _newRouter(api.NewServer().SetSnapshot(snapshot), collector)
+	if err != nil {
+		log.Printf("E! %v\n", err)
+		return err
+	}
 	log.Println("visit http://localhost:8504/website , check the cost analysis")
+	log.Println("json api is served at http://localhost:8504/api/v1 , eg: /api/v1/cost/daily?start=2022-10-01&end=2022-10-31")
+	log.Println("prometheus metrics are served at http://localhost:8504/metrics")
 	if err := r.Run(":8504"); err != nil {
 		log.Printf("E! %v\n", err)
 		return err
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/galaxy-future/costpilot/internal/api"
+	"github.com/galaxy-future/costpilot/internal/auth"
+	"github.com/galaxy-future/costpilot/internal/config"
+	"github.com/galaxy-future/costpilot/internal/metrics"
+	"github.com/galaxy-future/costpilot/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// _newRouter the report, the json api and the metrics, all behind the auth middleware
+func _newRouter(server *api.Server, collector *metrics.Collector) (*gin.Engine, error) {
+	r, a, err := _newEngine()
+	if err != nil {
+		return nil, err
+	}
+	// the report is generated once for all accounts, the viewers granted some of them get its data rebuilt
+	// from those accounts, like /api/v1 filters every endpoint by the grant
+	r.Group("/", a.Middleware(), server.FilterWebsite(_accountNames)).StaticFS("/website", http.Dir("./website"))
+	server.Register(r.Group("/", a.Middleware()))
+	_registerMetrics(r, a, collector)
+	return r, nil
+}
+
+// _newExporterRouter only the metrics
+func _newExporterRouter(collector *metrics.Collector) (*gin.Engine, error) {
+	r, a, err := _newEngine()
+	if err != nil {
+		return nil, err
+	}
+	_registerMetrics(r, a, collector)
+	return r, nil
+}
+
+func _newEngine() (*gin.Engine, *auth.Auth, error) {
+	a, err := auth.New(config.GetGlobalConfig().Auth)
+	if err != nil {
+		return nil, nil, err
+	}
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.Default()
+	a.Register(r)
+	return r, a, nil
+}
+
+// _registerMetrics the samples of the accounts not granted are left out like the report
+func _registerMetrics(r gin.IRouter, a *auth.Auth, collector *metrics.Collector) {
+	r.GET("/metrics", a.Middleware(), collector.Serve)
+}
+
+func _accountNames() []string {
+	accounts := services.GetCloudAccounts()
+	names := make([]string, 0, len(accounts))
+	for _, a := range accounts {
+		names = append(names, a.Name)
+	}
+	return names
+}
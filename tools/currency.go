@@ -1,5 +1,11 @@
 package tools
 
+import (
+	"sync"
+
+	"github.com/galaxy-future/costpilot/internal/data"
+)
+
 func CurrencyUnit(name string) (result string) {
 	switch name {
 	case "USD":
@@ -9,3 +15,36 @@ func CurrencyUnit(name string) (result string) {
 	}
 	return
 }
+
+// ExtractCurrency returns the currency of the first product item, empty if not found
+func ExtractCurrency(products map[string]data.ProductBilling) string {
+	for _, p := range products {
+		if len(p.Items) > 0 && p.Items[0].Currency != "" {
+			return p.Items[0].Currency
+		}
+	}
+	return ""
+}
+
+// AccountCurrency the currency of the first bill which has one, for the bills without product detail which have none
+func AccountCurrency(a data.AccountBillingMap) string {
+	var currency string
+	for _, m := range []*sync.Map{a.DaysBilling, a.MonthsBilling} {
+		if m == nil {
+			continue
+		}
+		m.Range(func(_, value interface{}) bool {
+			switch b := value.(type) {
+			case data.DailyBilling:
+				currency = ExtractCurrency(b.ProductsBilling)
+			case data.MonthlyBilling:
+				currency = ExtractCurrency(b.ProductsBilling)
+			}
+			return currency == ""
+		})
+		if currency != "" {
+			break
+		}
+	}
+	return currency
+}
@@ -278,6 +278,23 @@ func (p *BillingDatePilot) GetPreviousDayBillingDate() BillingDate {
 	}
 }
 
+// GetMonthToDateBillingDate
+// the days of the month of yesterday until yesterday, and the same days of the month before capped by its length,
+// eg: 2022-10-10 -> [2022-09-01, ..., 2022-09-09, 2022-10-01, ..., 2022-10-09]
+func (p *BillingDatePilot) GetMonthToDateBillingDate() BillingDate {
+	yesterday := p._nowT.AddDate(0, 0, -1)
+	monthStart := time.Date(yesterday.Year(), yesterday.Month(), 1, 0, 0, 0, 0, yesterday.Location())
+	lastMonthStart := monthStart.AddDate(0, -1, 0)
+	ret := BillingDate{Days: []string{}}
+	for i := lastMonthStart; i.Day() <= yesterday.Day() && i.Before(monthStart); i = i.AddDate(0, 0, 1) {
+		ret.Days = append(ret.Days, i.Format("2006-01-02"))
+	}
+	for i := monthStart; !i.After(yesterday); i = i.AddDate(0, 0, 1) {
+		ret.Days = append(ret.Days, i.Format("2006-01-02"))
+	}
+	return ret
+}
+
 // GetRecentQuarter
 func (p *BillingDatePilot) GetRecentQuarter() int {
 	month := int(p._nowT.AddDate(0, 0, -1).Month())
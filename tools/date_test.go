@@ -364,3 +364,18 @@ func TestGetLastXMonthsBillingDate(t *testing.T) {
 	p.SetNowT(tt)
 	fmt.Println(p.GetRecentXMonthsBillingDate(2))
 }
+
+func TestBillingDatePilot_GetMonthToDateBillingDate(t *testing.T) {
+	p := NewBillDatePilot()
+	p.SetNowT(time.Date(2022, 10, 3, 8, 0, 0, 0, time.Local))
+	want := []string{"2022-09-01", "2022-09-02", "2022-10-01", "2022-10-02"}
+	if got := p.GetMonthToDateBillingDate().Days; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetMonthToDateBillingDate() = %v, want %v", got, want)
+	}
+	// the first day of a month compares the whole month of yesterday, the month before is capped by its length
+	p.SetNowT(time.Date(2022, 4, 1, 8, 0, 0, 0, time.Local))
+	got := p.GetMonthToDateBillingDate().Days
+	if len(got) != 28+31 || got[0] != "2022-02-01" || got[27] != "2022-02-28" || got[28] != "2022-03-01" || got[58] != "2022-03-31" {
+		t.Errorf("GetMonthToDateBillingDate() = %v", got)
+	}
+}
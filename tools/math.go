@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"sort"
 
 	"github.com/galaxy-future/costpilot/internal/data"
 
@@ -38,6 +39,7 @@ func AddDailyBilling(x, y data.DailyBilling) data.DailyBilling {
 	var ret data.DailyBilling
 	ret.TotalAmount = x.TotalAmount + y.TotalAmount
 	ret.Day = y.Day
+	ret.Amortized = x.Amortized && y.Amortized
 	ret.ProductsBilling = make(map[string]data.ProductBilling)
 	for pipcode, bill := range x.ProductsBilling {
 		ret.ProductsBilling[pipcode] = bill
@@ -57,6 +59,7 @@ func AddMonthlyBilling(x, y data.MonthlyBilling) data.MonthlyBilling {
 	var ret data.MonthlyBilling
 	ret.TotalAmount = x.TotalAmount + y.TotalAmount
 	ret.Month = y.Month
+	ret.Amortized = x.Amortized && y.Amortized
 	ret.ProductsBilling = make(map[string]data.ProductBilling)
 	for pipcode, bill := range x.ProductsBilling {
 		ret.ProductsBilling[pipcode] = bill
@@ -95,6 +98,32 @@ func AddProductBilling(x, y data.ProductBilling) (data.ProductBilling, error) {
 	}
 	return ret, nil
 }
+
+// Median returns 0 for empty values
+func Median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// MedianAbsoluteDeviation median(|x - median(x)|), a robust measure of dispersion
+func MedianAbsoluteDeviation(values []float64) float64 {
+	m := Median(values)
+	deviations := make([]float64, 0, len(values))
+	for _, v := range values {
+		deviations = append(deviations, math.Abs(v-m))
+	}
+	return Median(deviations)
+}
+
 func SumByInstanceId(utilizations []data.InstanceCpuUtilization) int {
 	temp := make(map[string]bool)
 	for _, v := range utilizations {
@@ -89,3 +89,27 @@ func TestAddProductBilling(t *testing.T) {
 	fmt.Println(AddProductBilling(x, y))
 
 }
+
+func TestMedian(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   float64
+		wantMD float64
+	}{
+		{name: "empty", values: nil, want: 0, wantMD: 0},
+		{name: "odd", values: []float64{5, 1, 3}, want: 3, wantMD: 2},
+		{name: "even", values: []float64{4, 1, 3, 2}, want: 2.5, wantMD: 1},
+		{name: "outlier", values: []float64{10, 10, 11, 9, 100}, want: 10, wantMD: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Median(tt.values); got != tt.want {
+				t.Errorf("Median() = %v, want %v", got, tt.want)
+			}
+			if got := MedianAbsoluteDeviation(tt.values); got != tt.wantMD {
+				t.Errorf("MedianAbsoluteDeviation() = %v, want %v", got, tt.wantMD)
+			}
+		})
+	}
+}
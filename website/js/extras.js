@@ -0,0 +1,493 @@
+/*
+ * the panels of the data the bundled dashboard predates, rendered from the globals of data-set.js:
+ * the metric trends of utilization_analysis.metrics, the budgets, the cost anomalies, the idle instances, the
+ * optimization opportunities, the commitments, and the accounts and the data left out as their providers lack the
+ * capabilities, which the cards above count as zeros.
+ * the panels are appended to the scrolling space of the dashboard once it is mounted, or to the body without it;
+ * the switch of the cash and the amortized cost views is put at its top, the view is swapped by view.js.
+ */
+(function () {
+  'use strict';
+
+  var SVG = 'http://www.w3.org/2000/svg';
+  var COLORS = ['#5470c6', '#91cc75', '#fac858', '#ee6666', '#73c0de', '#3ba272', '#fc8452', '#9a60b4', '#ea7ccc'];
+  var STATES = {ok: '#00b42a', warning: '#ff7d00', exceeded: '#f53f3f', critical: '#f53f3f'};
+  var STYLE = [
+    '.cp-extras{box-sizing:border-box;padding:0 10px 10px;color:#1d2129;font-size:14px}',
+    '.cp-panel{background:#fff;border-radius:8px;padding:16px 20px;margin-top:10px}',
+    '.cp-panel h3{margin:0 0 12px;font-size:16px;font-weight:500}',
+    '.cp-charts{display:flex;flex-wrap:wrap;justify-content:space-between}',
+    '.cp-chart{width:calc(50% - 5px);box-sizing:border-box}',
+    '.cp-legend{display:flex;flex-wrap:wrap;gap:4px 16px;font-size:12px;color:#4e5969;margin-bottom:4px}',
+    '.cp-legend i{display:inline-block;width:10px;height:10px;border-radius:2px;margin-right:4px}',
+    '.cp-chart svg{width:100%;height:auto}',
+    '.cp-chart text{font-size:11px;fill:#86909c}',
+    '.cp-table{width:100%;border-collapse:collapse}',
+    '.cp-table th,.cp-table td{padding:8px 12px;border-bottom:1px solid #e5e6eb;text-align:left}',
+    '.cp-table th{background:#f7f8fa;font-weight:500;color:#4e5969}',
+    '.cp-table th.cp-sortable{cursor:pointer;user-select:none}',
+    '.cp-bar{position:relative;width:160px;height:8px;border-radius:4px;background:#f2f3f5;display:inline-block;',
+    'vertical-align:middle;margin-right:8px;overflow:hidden}',
+    '.cp-bar i{position:absolute;left:0;top:0;bottom:0;border-radius:4px}',
+    '.cp-bar b{position:absolute;top:0;bottom:0;width:1px;background:#86909c}',
+    '.cp-tag{display:inline-block;padding:0 8px;border-radius:2px;color:#fff;font-size:12px;line-height:20px}',
+    '.cp-list{margin:0;padding:0;list-style:none;font-size:12px;color:#4e5969}',
+    '.cp-stats{display:flex;flex-wrap:wrap;gap:10px;margin-bottom:12px}',
+    '.cp-stat{flex:1;min-width:160px;background:#f7f8fa;border-radius:4px;padding:10px 12px}',
+    '.cp-stat span{display:block;font-size:12px;color:#86909c}',
+    '.cp-stat strong{font-size:20px;font-weight:500}',
+    '.cp-panel h4{margin:16px 0 8px;font-size:14px;font-weight:500;color:#4e5969}',
+    '.cp-view{display:flex;justify-content:flex-end;padding:10px 10px 0}',
+    '.cp-view a{padding:4px 12px;border:1px solid #e5e6eb;background:#fff;color:#4e5969;font-size:13px;text-decoration:none}',
+    '.cp-view a:first-child{border-radius:4px 0 0 4px}',
+    '.cp-view a:last-child{border-radius:0 4px 4px 0}',
+    '.cp-view a.cp-view-on{background:#165dff;border-color:#165dff;color:#fff}'
+  ].join('');
+
+  function el(tag, className, text) {
+    var e = document.createElement(tag);
+    if (className) {
+      e.className = className;
+    }
+    if (text !== undefined) {
+      e.textContent = text;
+    }
+    return e;
+  }
+
+  function svg(tag, attrs) {
+    var e = document.createElementNS(SVG, tag);
+    Object.keys(attrs || {}).forEach(function (k) {
+      e.setAttribute(k, attrs[k]);
+    });
+    return e;
+  }
+
+  // value the charts mark the missing values by "--", "-" or ""
+  function value(v) {
+    var f = parseFloat(v);
+    return isNaN(f) ? null : f;
+  }
+
+  function label(f) {
+    if (Math.abs(f) >= 1e9) {
+      return (f / 1e9).toFixed(1) + 'G';
+    }
+    if (Math.abs(f) >= 1e6) {
+      return (f / 1e6).toFixed(1) + 'M';
+    }
+    if (Math.abs(f) >= 1e3) {
+      return (f / 1e3).toFixed(1) + 'K';
+    }
+    return String(Math.round(f * 100) / 100);
+  }
+
+  // lineChart a line of each series over xData, the missing values break the lines
+  function lineChart(chart) {
+    var w = 560, h = 220, left = 48, right = 12, top = 12, bottom = 28;
+    var box = el('div', 'cp-chart');
+    box.appendChild(el('h3', '', chart.title + (chart.yTitle && chart.yTitle.length ? ' · ' + chart.yTitle.join(' ') : '')));
+    var legend = el('div', 'cp-legend');
+    var xData = chart.xData || [];
+    var series = chart.series || [];
+    var max = 0;
+    series.forEach(function (s, i) {
+      var item = el('span', '', s.name);
+      var mark = el('i');
+      mark.style.background = COLORS[i % COLORS.length];
+      item.insertBefore(mark, item.firstChild);
+      legend.appendChild(item);
+      (s.data || []).forEach(function (v) {
+        var f = value(v);
+        if (f !== null && f > max) {
+          max = f;
+        }
+      });
+    });
+    box.appendChild(legend);
+
+    var root = svg('svg', {viewBox: '0 0 ' + w + ' ' + h, role: 'img'});
+    var x = function (i) {
+      return left + (xData.length > 1 ? i * (w - left - right) / (xData.length - 1) : (w - left - right) / 2);
+    };
+    var y = function (f) {
+      return top + (h - top - bottom) * (1 - (max > 0 ? f / max : 0));
+    };
+    [0, 0.5, 1].forEach(function (r) {
+      var yy = y(max * r);
+      root.appendChild(svg('line', {x1: left, x2: w - right, y1: yy, y2: yy, stroke: '#e0e6f1'}));
+      var t = svg('text', {x: left - 6, y: yy + 4, 'text-anchor': 'end'});
+      t.textContent = label(max * r);
+      root.appendChild(t);
+    });
+    if (xData.length > 0) {
+      [0, xData.length - 1].forEach(function (i, n) {
+        var t = svg('text', {x: x(i), y: h - 8, 'text-anchor': n === 0 ? 'start' : 'end'});
+        t.textContent = xData[i];
+        root.appendChild(t);
+      });
+    }
+    var unit = (chart.tooltipUnit && chart.tooltipUnit.line) || '';
+    var lines = svg('g'), dots = svg('g');
+    root.appendChild(lines);
+    root.appendChild(dots);
+    series.forEach(function (s, n) {
+      var color = COLORS[n % COLORS.length];
+      var d = '', pen = 'M';
+      (s.data || []).forEach(function (v, i) {
+        var f = value(v);
+        if (f === null) {
+          pen = 'M';
+          return;
+        }
+        d += pen + x(i).toFixed(1) + ',' + y(f).toFixed(1);
+        pen = 'L';
+        var dot = svg('circle', {cx: x(i), cy: y(f), r: 2.5, fill: color});
+        var tip = svg('title');
+        tip.textContent = s.name + ' ' + xData[i] + ': ' + v + unit;
+        dot.appendChild(tip);
+        dots.appendChild(dot);
+      });
+      if (d) {
+        lines.appendChild(svg('path', {d: d, fill: 'none', stroke: color, 'stroke-width': 2}));
+      }
+    });
+    box.appendChild(root);
+    return box;
+  }
+
+  // title the title of an analysis followed by its data cycle
+  function title(analysis) {
+    return analysis.title + (analysis.dataCycle ? ' · ' + analysis.dataCycle : '');
+  }
+
+  // grid the rows picked by the keys of the columns, cells renders the columns of its keys instead.
+  // a click on a sortable column sorts the rows by its numeric value, descending first, the missing values last;
+  // the rows are sorted by sortKey at first if given
+  function grid(columns, rows, cells, sortKey) {
+    var t = el('table', 'cp-table');
+    var head = el('tr');
+    var body = el('tbody');
+    var sorted = {key: sortKey || '', desc: true};
+    var mark = function () {
+      Array.prototype.forEach.call(head.childNodes, function (h, i) {
+        h.textContent = columns[i].title + (columns[i].key === sorted.key ? (sorted.desc ? ' ↓' : ' ↑') : '');
+      });
+    };
+    var render = function () {
+      body.textContent = '';
+      var ordered = rows.slice();
+      if (sorted.key) {
+        ordered.sort(function (a, b) {
+          var x = value(a[sorted.key]), y = value(b[sorted.key]);
+          if (x === null || y === null) {
+            return (x === null) - (y === null);
+          }
+          return sorted.desc ? y - x : x - y;
+        });
+      }
+      ordered.forEach(function (r) {
+        var tr = el('tr');
+        columns.forEach(function (c) {
+          var cell = cells && cells[c.key] ? cells[c.key](r, c.key) : r[c.key];
+          var td = el('td');
+          if (cell && cell.nodeType) {
+            td.appendChild(cell);
+          } else {
+            td.textContent = cell === undefined || cell === null ? '' : String(cell);
+          }
+          tr.appendChild(td);
+        });
+        body.appendChild(tr);
+      });
+    };
+    columns.forEach(function (c) {
+      var th = el('th', c.sortable ? 'cp-sortable' : '', c.title);
+      if (c.sortable) {
+        th.addEventListener('click', function () {
+          sorted.desc = sorted.key === c.key ? !sorted.desc : true;
+          sorted.key = c.key;
+          mark();
+          render();
+        });
+      }
+      head.appendChild(th);
+    });
+    var thead = el('thead');
+    thead.appendChild(head);
+    t.appendChild(thead);
+    t.appendChild(body);
+    mark();
+    render();
+    return t;
+  }
+
+  // table a panel of the grid under the title
+  function table(title, columns, rows, cells, sortKey) {
+    var panel = el('div', 'cp-panel');
+    panel.appendChild(el('h3', '', title));
+    panel.appendChild(grid(columns, rows, cells, sortKey));
+    return panel;
+  }
+
+  // unsupported the rows of the cost and the utilization analysis, the same row once
+  function unsupported(analyses) {
+    var title = '', columns = [], rows = [], seen = {};
+    analyses.forEach(function (a) {
+      if (!a.unsupported || a.unsupported.length === 0) {
+        return;
+      }
+      title = title || a.unsupportedTitle;
+      columns = columns.length ? columns : a.unsupportedColumns || [];
+      a.unsupported.forEach(function (r) {
+        var key = [r.account, r.provider, r.capability, r.impact].join('\u0000');
+        if (!seen[key]) {
+          seen[key] = true;
+          rows.push(r);
+        }
+      });
+    });
+    return rows.length && columns.length ? table(title, columns, rows) : null;
+  }
+
+  // money the amount followed by the unit of the row, the missing amounts as they are
+  function money(r, key) {
+    return value(r[key]) === null ? r[key] : r[key] + (r.unit || '');
+  }
+
+  // budgets the spent percent of each budget as a bar colored by its state, the projected percent shaded behind it
+  // and the thresholds marked on it
+  function budgets(analysis) {
+    if (!analysis.budgets || analysis.budgets.length === 0 || !analysis.columns) {
+      return null;
+    }
+    return table(title(analysis), analysis.columns, analysis.budgets, {
+      amount: money,
+      spent: money,
+      projected: money,
+      percent: function (r) {
+        var color = STATES[r.state] || COLORS[0];
+        var box = el('span');
+        var bar = el('span', 'cp-bar');
+        var projected = el('i'), spent = el('i');
+        projected.style.width = Math.min(value(r.projectedPercent) || 0, 100) + '%';
+        projected.style.background = color;
+        projected.style.opacity = '0.3';
+        spent.style.width = Math.min(value(r.percent) || 0, 100) + '%';
+        spent.style.background = color;
+        bar.appendChild(projected);
+        bar.appendChild(spent);
+        (r.thresholds || []).forEach(function (t) {
+          if (value(t) < 100) {
+            var mark = el('b');
+            mark.style.left = value(t) + '%';
+            bar.appendChild(mark);
+          }
+        });
+        box.appendChild(bar);
+        box.appendChild(document.createTextNode(r.percent));
+        return box;
+      },
+      stateName: function (r) {
+        var state = el('span', '', r.stateName);
+        state.style.color = STATES[r.state] || '';
+        return state;
+      }
+    });
+  }
+
+  // anomalies the severity as a tag, the change signed, and the products which moved the most one per line
+  function anomalies(analysis) {
+    if (!analysis.anomalies || analysis.anomalies.length === 0 || !analysis.columns) {
+      return null;
+    }
+    return table(title(analysis), analysis.columns, analysis.anomalies, {
+      product: function (r) {
+        return r.product || analysis.accountTotal;
+      },
+      amount: money,
+      baseline: money,
+      ratio: function (r) {
+        var f = value(r.ratio);
+        return f === null ? r.ratio : (f > 0 ? '+' : '') + r.ratio;
+      },
+      severityName: function (r) {
+        var tag = el('span', 'cp-tag', r.severityName);
+        tag.style.background = STATES[r.severity] || '';
+        return tag;
+      },
+      contributors: function (r) {
+        var list = el('ul', 'cp-list');
+        (r.contributors || []).forEach(function (c) {
+          list.appendChild(el('li', '', c));
+        });
+        return list;
+      }
+    });
+  }
+
+  // statistics the figures of an analysis as cards under the title of its panel
+  function statistics(panel, items) {
+    if (!items || items.length === 0) {
+      return panel;
+    }
+    var stats = el('div', 'cp-stats');
+    items.forEach(function (s) {
+      var stat = el('div', 'cp-stat');
+      stat.appendChild(el('span', '', s.sCycle));
+      stat.appendChild(el('strong', '', s.sAmount));
+      stats.appendChild(stat);
+    });
+    panel.insertBefore(stats, panel.childNodes[1] || null);
+    return panel;
+  }
+
+  // idle the idle and underutilized instances, the costliest first
+  function idle(analysis) {
+    if (!analysis.instances || analysis.instances.length === 0 || !analysis.columns) {
+      return null;
+    }
+    return table(title(analysis), analysis.columns, analysis.instances, {monthlyCost: money}, 'monthlyCost');
+  }
+
+  // optimization the savings of each currency, and the opportunities which save the most first
+  function optimization(analysis) {
+    if (!analysis.opportunities || analysis.opportunities.length === 0 || !analysis.columns) {
+      return null;
+    }
+    var panel = table(title(analysis), analysis.columns, analysis.opportunities,
+      {monthlyCost: money, monthlySavings: money}, 'monthlySavings');
+    return statistics(panel, analysis.statistics);
+  }
+
+  // percent the percent as a bar, the missing values as they are
+  function percent(r, key) {
+    var f = value(r[key]);
+    if (f === null) {
+      return r[key];
+    }
+    var box = el('span');
+    var bar = el('span', 'cp-bar');
+    var fill = el('i');
+    fill.style.width = Math.min(Math.max(f, 0), 100) + '%';
+    fill.style.background = COLORS[0];
+    bar.appendChild(fill);
+    box.appendChild(bar);
+    box.appendChild(document.createTextNode(r[key]));
+    return box;
+  }
+
+  // commitment the coverage and the utilization of the reserved instances, the savings plans and the subscriptions,
+  // and the commitments expiring soon, the earliest first
+  function commitment(analysis) {
+    var coverages = analysis.coverages || [], expiring = analysis.expiring || [];
+    if (coverages.length === 0 && expiring.length === 0) {
+      return null;
+    }
+    var panel = el('div', 'cp-panel');
+    panel.appendChild(el('h3', '', title(analysis)));
+    statistics(panel, analysis.statistics);
+    if (coverages.length > 0 && analysis.coverageColumns) {
+      panel.appendChild(el('h4', '', analysis.coverageTitle));
+      panel.appendChild(grid(analysis.coverageColumns, coverages, {coverage: percent, utilization: percent}));
+    }
+    if (expiring.length > 0 && analysis.expiringColumns) {
+      panel.appendChild(el('h4', '', analysis.expiringTitle));
+      panel.appendChild(grid(analysis.expiringColumns, expiring));
+    }
+    return panel;
+  }
+
+  // viewHref the page with the cost view, the other parameters kept
+  function viewHref(view) {
+    var params = window.location.search.replace(/^\?/, '').split('&').filter(function (p) {
+      return p && p.indexOf('view=') !== 0;
+    });
+    params.push('view=' + encodeURIComponent(view));
+    return '?' + params.join('&') + window.location.hash;
+  }
+
+  // viewSwitch the cash and the amortized cost views if data-set.js has both, the other one is a link to it
+  function viewSwitch(cost) {
+    var other = cost.alternativeView;
+    if (!other) {
+      return null;
+    }
+    var box = el('div', 'cp-view');
+    box.id = 'costpilot-view';
+    (cost.costView === 'cash' ? [cost, other] : [other, cost]).forEach(function (v) {
+      var item = el('a', v === cost ? 'cp-view-on' : '', v.costViewName);
+      if (v !== cost) {
+        item.href = viewHref(v.costView);
+      }
+      box.appendChild(item);
+    });
+    return box;
+  }
+
+  function build() {
+    var utilize = window.utilizeAnalysis || {};
+    var trends = (utilize.utilizeAnalysisByDay || {}).metricTrends || [];
+    var extras = el('div', 'cp-extras');
+    extras.id = 'costpilot-extras';
+    if (trends.length > 0) {
+      var panel = el('div', 'cp-panel cp-charts');
+      trends.forEach(function (t) {
+        panel.appendChild(lineChart(t.chart));
+      });
+      extras.appendChild(panel);
+    }
+    [budgets(window.budgetAnalysis || {}), anomalies(window.anomalyAnalysis || {}),
+      idle(window.idleInstanceAnalysis || {}), optimization(window.optimizationAnalysis || {}),
+      commitment(window.commitmentAnalysis || {})].forEach(function (panel) {
+      if (panel) {
+        extras.appendChild(panel);
+      }
+    });
+    var left = unsupported([window.costAnalysis || {}, utilize]);
+    if (left) {
+      extras.appendChild(left);
+    }
+    return extras.childNodes.length > 0 ? extras : null;
+  }
+
+  function mount() {
+    var extras = build(), view = viewSwitch(window.costAnalysis || {});
+    if (!extras && !view) {
+      return;
+    }
+    var style = el('style', '', STYLE);
+    document.head.appendChild(style);
+    var place = function () {
+      var space = document.querySelector('.analysis-space');
+      if (space && extras && extras.parentNode !== space) {
+        space.appendChild(extras);
+      }
+      if (space && view && space.firstChild !== view) {
+        space.insertBefore(view, space.firstChild);
+      }
+      return !!space;
+    };
+    var app = document.getElementById('app');
+    if (!app || !window.MutationObserver) {
+      if (!place()) {
+        if (view) {
+          document.body.insertBefore(view, document.body.firstChild);
+        }
+        if (extras) {
+          document.body.appendChild(extras);
+        }
+      }
+      return;
+    }
+    // the dashboard is mounted by a chunk loaded after this script, the panels follow it when it is rendered again
+    place();
+    new MutationObserver(place).observe(app, {childList: true, subtree: true});
+  }
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', mount);
+  } else {
+    mount();
+  }
+})();
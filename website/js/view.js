@@ -0,0 +1,27 @@
+/*
+ * the cost view of the dashboard, picked by ?view=cash or ?view=amortized before the dashboard reads data-set.js:
+ * window.costAnalysis is swapped with its alternativeView if that is the view asked for, the switch above the extra
+ * panels reloads the page with the other view. the data left out by the providers is the same in both views.
+ */
+(function () {
+  'use strict';
+
+  var match = /[?&]view=([^&#]*)/.exec(window.location.search);
+  var cost = window.costAnalysis;
+  if (!match || !cost || !cost.alternativeView || cost.alternativeView.costView !== decodeURIComponent(match[1])) {
+    return;
+  }
+  var shown = cost.alternativeView, other = {};
+  Object.keys(cost).forEach(function (k) {
+    if (k !== 'alternativeView') {
+      other[k] = cost[k];
+    }
+  });
+  ['unsupported', 'unsupportedTitle', 'unsupportedColumns'].forEach(function (k) {
+    if (shown[k] === undefined) {
+      shown[k] = cost[k];
+    }
+  });
+  shown.alternativeView = other;
+  window.costAnalysis = shown;
+})();